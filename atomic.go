@@ -0,0 +1,194 @@
+package s3lib
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// tempKeyInfix separates the final key from the random suffix of the
+// temporary key UploadAtomic stages the payload under
+const tempKeyInfix = ".tmp-"
+
+// UploadAtomic publishes r under key without readers ever seeing a partial
+// object. The payload is uploaded to a temporary key next to key, verified
+// against the size and checksum that were read, and then copied over key
+// server-side, keeping its metadata and content type. The temporary object
+// is deleted whether or not publishing succeeds; if only that final delete
+// fails, the result is returned together with the error.
+//
+// IfNoneMatch, SkipIfUnchanged, KeyStrategy and SSECustomerKey are not
+// supported. Object Lock settings apply to the published object only.
+func (c *S3Client) UploadAtomic(ctx context.Context, bucket, key string, r io.Reader, opts *UploadOptions) (result *UploadResult, err error) {
+	if r == nil {
+		return nil, ErrNilValue
+	}
+	if bucket == "" {
+		return nil, ErrInvalidBucket
+	}
+	if err := c.checkClientSideKey(); err != nil {
+		return nil, err
+	}
+	staged := UploadOptions{}
+	if opts != nil {
+		staged = *opts
+	}
+	if staged.IfNoneMatch || staged.SkipIfUnchanged || staged.KeyStrategy != nil || staged.SSECustomerKey != nil {
+		return nil, fmt.Errorf("%w: IfNoneMatch, SkipIfUnchanged, KeyStrategy and SSECustomerKey are not supported by UploadAtomic", ErrInvalidOptions)
+	}
+	if err := staged.validate(); err != nil {
+		return nil, err
+	}
+	key, err = c.resolveKey(key)
+	if err != nil {
+		return nil, err
+	}
+	tempKey := NewUUIDKey(key+tempKeyInfix, "")
+	if err := validateKey(tempKey); err != nil {
+		return nil, err
+	}
+
+	// A locked temporary object could not be deleted
+	staged.ObjectLockMode, staged.ObjectLockRetainUntil, staged.ObjectLockLegalHold = "", time.Time{}, false
+	if staged.ChecksumAlgorithm == ChecksumNone {
+		staged.ChecksumAlgorithm = ChecksumSHA256
+	}
+
+	defer func() {
+		_, delErr := c.s3Client.DeleteObjectWithContext(context.WithoutCancel(ctx), &s3.DeleteObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(tempKey),
+		})
+		if delErr != nil && err == nil {
+			err = fmt.Errorf("published %s but failed to delete temporary object %s: %w", key, tempKey, delErr)
+		}
+	}()
+
+	sum := sha256.New()
+	counter := &countingWriter{}
+	body := io.TeeReader(r, io.MultiWriter(sum, counter))
+	if _, err := c.streamUpload(ctx, bucket, tempKey, body, &staged); err != nil {
+		return nil, err
+	}
+
+	head, err := c.s3Client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket:       aws.String(bucket),
+		Key:          aws.String(tempKey),
+		ChecksumMode: aws.String(s3.ChecksumModeEnabled),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify temporary object: %w", uploadError(err))
+	}
+	if !staged.Compress {
+		if err := verifyStaged(head, counter.n, staged.ChecksumAlgorithm, sum); err != nil {
+			return nil, err
+		}
+	}
+
+	if aws.Int64Value(head.ContentLength) > MaxCopyPartSize {
+		// CopyObject is limited to 5GB; compose the copy from parts instead
+		composed := staged
+		if opts != nil {
+			composed.ObjectLockMode, composed.ObjectLockRetainUntil, composed.ObjectLockLegalHold = opts.ObjectLockMode, opts.ObjectLockRetainUntil, opts.ObjectLockLegalHold
+		}
+		composed.ContentType = aws.StringValue(head.ContentType)
+		composed.ContentEncoding = aws.StringValue(head.ContentEncoding)
+		composed.Metadata = aws.StringValueMap(head.Metadata)
+		composed.Compress, composed.ComputeMD5 = false, false
+		return c.ComposeObjects(ctx, bucket, c.relativeKey(key), []string{c.relativeKey(tempKey)}, &composed)
+	}
+	return c.publishCopy(ctx, bucket, tempKey, key, aws.StringValue(head.ETag), opts)
+}
+
+// publishCopy copies the staged object over key, keeping its metadata and
+// tags and applying the destination settings from opts
+func (c *S3Client) publishCopy(ctx context.Context, bucket, tempKey, key, etag string, opts *UploadOptions) (*UploadResult, error) {
+	input := &s3.CopyObjectInput{
+		Bucket:            aws.String(bucket),
+		Key:               aws.String(key),
+		CopySource:        aws.String(copySource(bucket, tempKey, "")),
+		CopySourceIfMatch: aws.String(etag),
+		MetadataDirective: aws.String(s3.MetadataDirectiveCopy),
+		TaggingDirective:  aws.String(s3.TaggingDirectiveCopy),
+		ChecksumAlgorithm: aws.String(string(ChecksumSHA256)),
+	}
+	if opts != nil {
+		if opts.ChecksumAlgorithm != ChecksumNone {
+			input.ChecksumAlgorithm = aws.String(string(opts.ChecksumAlgorithm))
+		}
+		if opts.ACL != "" {
+			input.ACL = aws.String(string(opts.ACL))
+		}
+		if opts.StorageClass != "" {
+			input.StorageClass = aws.String(string(opts.StorageClass))
+		}
+		if opts.ServerSideEncryption != "" {
+			input.ServerSideEncryption = aws.String(opts.ServerSideEncryption)
+		}
+		if opts.SSEKMSKeyID != "" {
+			input.SSEKMSKeyId = aws.String(opts.SSEKMSKeyID)
+		}
+		if opts.ObjectLockMode != "" {
+			input.ObjectLockMode = aws.String(opts.ObjectLockMode)
+			input.ObjectLockRetainUntilDate = aws.Time(opts.ObjectLockRetainUntil)
+		}
+		if opts.ObjectLockLegalHold {
+			input.ObjectLockLegalHoldStatus = aws.String(s3.ObjectLockLegalHoldStatusOn)
+		}
+	}
+
+	out, err := withRetry(ctx, c.config.MaxRetries, func() (*s3.CopyObjectOutput, error) {
+		return c.s3Client.CopyObjectWithContext(ctx, input)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to publish object: %w", uploadError(err))
+	}
+
+	result := &UploadResult{
+		Key:        c.relativeKey(key),
+		Location:   c.objectURL(bucket, key),
+		VersionID:  aws.StringValue(out.VersionId),
+		Expiration: aws.StringValue(out.Expiration),
+	}
+	if copied := out.CopyObjectResult; copied != nil {
+		result.ETag = aws.StringValue(copied.ETag)
+		result.setChecksum(copied.ChecksumSHA256, copied.ChecksumCRC32C, copied.ChecksumCRC32, copied.ChecksumSHA1)
+	}
+	return result, nil
+}
+
+// verifyStaged checks the uploaded object has the size and, where S3 reports
+// a full object SHA-256, the checksum of the bytes that were read
+func verifyStaged(head *s3.HeadObjectOutput, size int64, alg ChecksumAlgorithm, sum hash.Hash) error {
+	if got := aws.Int64Value(head.ContentLength); got != size {
+		return fmt.Errorf("%w: uploaded object is %d bytes, read %d", ErrChecksumMismatch, got, size)
+	}
+	// Multipart objects report a checksum of part checksums ("...-N"),
+	// which S3 has already verified part by part
+	remote := aws.StringValue(head.ChecksumSHA256)
+	if alg != ChecksumSHA256 || remote == "" || strings.Contains(remote, "-") {
+		return nil
+	}
+	if local := base64.StdEncoding.EncodeToString(sum.Sum(nil)); remote != local {
+		return fmt.Errorf("%w: uploaded object SHA-256 %s, read %s", ErrChecksumMismatch, remote, local)
+	}
+	return nil
+}
+
+// countingWriter counts the bytes written to it
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,127 @@
+package s3lib
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// atomicTransport fakes the S3 calls made by UploadAtomic and records them
+func atomicTransport(copyStatus int, mu *sync.Mutex, calls *[]string) roundTripFunc {
+	return func(req *http.Request) (*http.Response, error) {
+		call := req.Method + " " + strings.TrimPrefix(req.URL.Path, "/"+testBucket+"/")
+		if src := req.Header.Get("x-amz-copy-source"); src != "" {
+			call += " from " + src
+		}
+		mu.Lock()
+		*calls = append(*calls, call)
+		mu.Unlock()
+
+		resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: req}
+		switch {
+		case req.Method == http.MethodHead:
+			resp.Header.Set("Content-Length", strconv.Itoa(len(testFileContent)))
+			resp.Header.Set("ETag", `"staged"`)
+		case req.Header.Get("x-amz-copy-source") != "" && copyStatus != http.StatusOK:
+			return fakeErrorResponse(req, copyStatus, "AccessDenied"), nil
+		case req.Header.Get("x-amz-copy-source") != "":
+			resp.Body = xmlBody(`<CopyObjectResult><ETag>"published"</ETag></CopyObjectResult>`)
+		}
+		return resp, nil
+	}
+}
+
+// TestS3Client_UploadAtomic tests the payload is staged, copied into place
+// and the temporary object removed
+func TestS3Client_UploadAtomic(t *testing.T) {
+	var mu sync.Mutex
+	var calls []string
+	client := setupFakeClient(t, atomicTransport(http.StatusOK, &mu, &calls))
+
+	result, err := client.UploadAtomic(context.Background(), testBucket, "site/index.html", bytes.NewReader(testFileContent), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "site/index.html", result.Key)
+	assert.Equal(t, `"published"`, result.ETag)
+
+	require.Len(t, calls, 4)
+	assert.True(t, strings.HasPrefix(calls[0], "PUT site/index.html.tmp-"))
+	assert.True(t, strings.HasPrefix(calls[2], "PUT site/index.html from "+testBucket+"/site/index.html.tmp-"))
+	assert.True(t, strings.HasPrefix(calls[3], "DELETE site/index.html.tmp-"))
+}
+
+// TestS3Client_UploadAtomicCleanup tests the temporary object is removed
+// when publishing fails
+func TestS3Client_UploadAtomicCleanup(t *testing.T) {
+	var mu sync.Mutex
+	var calls []string
+	client := setupFakeClient(t, atomicTransport(http.StatusForbidden, &mu, &calls))
+
+	_, err := client.UploadAtomic(context.Background(), testBucket, "site/index.html", bytes.NewReader(testFileContent), nil)
+	assert.Error(t, err)
+	require.NotEmpty(t, calls)
+	assert.True(t, strings.HasPrefix(calls[len(calls)-1], "DELETE site/index.html.tmp-"))
+}
+
+// TestS3Client_UploadAtomicOptions tests unsupported options are rejected
+func TestS3Client_UploadAtomicOptions(t *testing.T) {
+	client := setupTestClient(t)
+
+	_, err := client.UploadAtomic(context.Background(), testBucket, testFileName, bytes.NewReader(testFileContent), &UploadOptions{IfNoneMatch: true})
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+	_, err = client.UploadAtomic(context.Background(), testBucket, testFileName, nil, nil)
+	assert.ErrorIs(t, err, ErrNilValue)
+}
+
+// TestVerifyStaged tests size and checksum verification of staged objects
+func TestVerifyStaged(t *testing.T) {
+	size := int64(len(testFileContent))
+	sum := sha256.New()
+	sum.Write(testFileContent)
+	checksum := ChecksumSHA256.Checksum(testFileContent)
+
+	tests := []struct {
+		name    string
+		head    *s3.HeadObjectOutput
+		wantErr bool
+	}{
+		{
+			name: "Matching checksum",
+			head: &s3.HeadObjectOutput{ContentLength: aws.Int64(size), ChecksumSHA256: aws.String(checksum)},
+		},
+		{
+			name: "Multipart checksum",
+			head: &s3.HeadObjectOutput{ContentLength: aws.Int64(size), ChecksumSHA256: aws.String("abc-3")},
+		},
+		{
+			name:    "Size mismatch",
+			head:    &s3.HeadObjectOutput{ContentLength: aws.Int64(size - 1), ChecksumSHA256: aws.String(checksum)},
+			wantErr: true,
+		},
+		{
+			name:    "Checksum mismatch",
+			head:    &s3.HeadObjectOutput{ContentLength: aws.Int64(size), ChecksumSHA256: aws.String(ChecksumSHA256.Checksum([]byte("other")))},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyStaged(tt.head, size, ChecksumSHA256, sum)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrChecksumMismatch)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
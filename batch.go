@@ -0,0 +1,142 @@
+package s3lib
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// UploadResult represents the outcome of uploading a single object
+type UploadResult struct {
+	Key       string `json:"key"`
+	Location  string `json:"location,omitempty"`
+	ETag      string `json:"etag,omitempty"`
+	VersionID string `json:"version_id,omitempty"` // Set on versioned buckets
+	Err       error  `json:"-"`
+
+	// Checksum is the additional checksum S3 verified, when
+	// UploadOptions.ChecksumAlgorithm was set. ChecksumSHA256 repeats it
+	// when the algorithm is ChecksumSHA256.
+	Checksum          string            `json:"checksum,omitempty"`
+	ChecksumAlgorithm ChecksumAlgorithm `json:"checksum_algorithm,omitempty"`
+	ChecksumSHA256    string            `json:"checksum_sha256,omitempty"`
+
+	// Expiration is the x-amz-expiration value when a lifecycle rule will
+	// expire the object, e.g. `expiry-date="...", rule-id="..."`
+	Expiration string `json:"expiration,omitempty"`
+
+	// Skipped is set when UploadOptions.SkipIfUnchanged found the object
+	// already up to date and nothing was uploaded
+	Skipped bool `json:"skipped,omitempty"`
+}
+
+// UploadFiles uploads every entry of files (key to content) using a pool of
+// concurrency workers. A failed key does not abort its siblings; only context
+// cancellation stops the batch. Results are sorted by key.
+func (c *S3Client) UploadFiles(ctx context.Context, bucket string, files map[string][]byte, opts *UploadOptions, concurrency int) ([]UploadResult, error) {
+	if bucket == "" {
+		return nil, ErrInvalidBucket
+	}
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+
+	keys := make([]string, 0, len(files))
+	for key := range files {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	results := make([]UploadResult, len(keys))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				key := results[idx].Key
+				result, err := c.uploadBytes(ctx, bucket, key, files[key], opts)
+				if err != nil {
+					results[idx].Err = err
+					continue
+				}
+				results[idx] = *result
+			}
+		}()
+	}
+
+	for i, key := range keys {
+		results[i].Key = key
+		if ctx.Err() != nil {
+			results[i].Err = ctx.Err()
+			continue
+		}
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			results[i].Err = ctx.Err()
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results, ctx.Err()
+}
+
+// DownloadFiles downloads every key using a pool of concurrency workers and
+// returns the contents of the keys that succeeded and the error of each key
+// that failed. A failed key does not abort its siblings; once ctx is done,
+// keys not yet started fail with the context's error. Duplicate keys are
+// downloaded once.
+func (c *S3Client) DownloadFiles(ctx context.Context, bucket string, keys []string, concurrency int) (map[string][]byte, map[string]error) {
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+
+	data := make(map[string][]byte, len(keys))
+	errs := make(map[string]error)
+	var mu sync.Mutex
+
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for key := range jobs {
+				content, err := c.DownloadFile(ctx, bucket, key)
+				mu.Lock()
+				if err != nil {
+					errs[key] = err
+				} else {
+					data[key] = content
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		if ctx.Err() == nil {
+			select {
+			case jobs <- key:
+				continue
+			case <-ctx.Done():
+			}
+		}
+		mu.Lock()
+		errs[key] = ctx.Err()
+		mu.Unlock()
+	}
+	close(jobs)
+	wg.Wait()
+
+	return data, errs
+}
@@ -0,0 +1,97 @@
+package s3lib
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestS3Client_UploadFiles tests the UploadFiles function
+func TestS3Client_UploadFiles(t *testing.T) {
+	objects := map[string]*memoryObject{}
+	client := setupFakeClient(t, memoryServer(objects))
+
+	files := make(map[string][]byte)
+	for i := 0; i < 12; i++ {
+		files[fmt.Sprintf("batch/report-%02d.txt", i)] = []byte(fmt.Sprintf("report %d", i))
+	}
+
+	t.Run("Valid batch", func(t *testing.T) {
+		results, err := client.UploadFiles(context.Background(), testBucket, files, nil, 4)
+		require.NoError(t, err)
+		require.Len(t, results, len(files))
+		for i, r := range results {
+			assert.Equal(t, fmt.Sprintf("batch/report-%02d.txt", i), r.Key)
+			assert.NoError(t, r.Err)
+			assert.NotEmpty(t, r.Location)
+		}
+		for key, data := range files {
+			require.Contains(t, objects, key)
+			assert.Equal(t, data, objects[key].data)
+		}
+	})
+
+	t.Run("Partial failure", func(t *testing.T) {
+		batch := map[string][]byte{
+			"batch/ok.txt": testFileContent,
+			"":             testFileContent,
+		}
+		results, err := client.UploadFiles(context.Background(), testBucket, batch, nil, 2)
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+		assert.ErrorIs(t, results[0].Err, ErrInvalidKey)
+		assert.NoError(t, results[1].Err)
+	})
+
+	t.Run("Cancelled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		results, err := client.UploadFiles(ctx, testBucket, files, nil, 2)
+		assert.ErrorIs(t, err, context.Canceled)
+		require.Len(t, results, len(files))
+		for _, r := range results {
+			assert.Error(t, r.Err)
+		}
+	})
+
+	t.Run("Empty bucket", func(t *testing.T) {
+		_, err := client.UploadFiles(context.Background(), "", files, nil, 2)
+		assert.ErrorIs(t, err, ErrInvalidBucket)
+	})
+}
+
+// TestS3Client_DownloadFiles tests per-key results and cancellation
+func TestS3Client_DownloadFiles(t *testing.T) {
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		if strings.HasSuffix(req.URL.Path, "/missing.txt") {
+			return fakeErrorResponse(req, http.StatusNotFound, "NoSuchKey"), nil
+		}
+		return objectServer(testFileContent)(req)
+	})
+	keys := []string{"a.txt", "b.txt", "missing.txt", "a.txt"}
+
+	t.Run("Partial failure", func(t *testing.T) {
+		data, errs := client.DownloadFiles(context.Background(), testBucket, keys, 2)
+		assert.Len(t, data, 2)
+		assert.Equal(t, testFileContent, data["a.txt"])
+		assert.Equal(t, testFileContent, data["b.txt"])
+		require.Len(t, errs, 1)
+		assert.ErrorIs(t, errs["missing.txt"], ErrFileNotFound)
+	})
+
+	t.Run("Cancelled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		data, errs := client.DownloadFiles(ctx, testBucket, keys, 2)
+		assert.Empty(t, data)
+		require.Len(t, errs, 3)
+		for _, err := range errs {
+			assert.Error(t, err)
+		}
+	})
+}
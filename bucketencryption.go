@@ -0,0 +1,128 @@
+package s3lib
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// BucketEncryption is the default encryption S3 applies to objects
+// uploaded to a bucket without encryption headers of their own
+type BucketEncryption struct {
+	// Configured is false when the bucket has no default encryption rule,
+	// in which case the other fields are empty
+	Configured bool `json:"configured"`
+	// Algorithm is SSEAES256 or SSEKMS
+	Algorithm string `json:"algorithm,omitempty"`
+	// KMSKeyID is the KMS key of SSEKMS, or empty for the AWS managed key
+	KMSKeyID string `json:"kms_key_id,omitempty"`
+	// BucketKeyEnabled reports that SSE-KMS uses an S3 Bucket Key, which
+	// cuts the number of KMS requests
+	BucketKeyEnabled bool `json:"bucket_key_enabled,omitempty"`
+}
+
+// SetBucketEncryption sets the default encryption of bucket to algo,
+// SSEAES256 or SSEKMS. With SSEKMS, kmsKeyID selects the key (the AWS
+// managed key if empty) and bucketKeyEnabled enables an S3 Bucket Key.
+func (c *S3Client) SetBucketEncryption(ctx context.Context, bucket, algo, kmsKeyID string, bucketKeyEnabled bool) error {
+	if bucket == "" {
+		return ErrInvalidBucket
+	}
+	if algo == "" {
+		return fmt.Errorf("%w: no encryption algorithm; use DeleteBucketEncryption to remove the default", ErrInvalidOptions)
+	}
+	if err := validateServerSideEncryption(algo, kmsKeyID); err != nil {
+		return err
+	}
+	if bucketKeyEnabled && algo != SSEKMS {
+		return fmt.Errorf("%w: bucket keys require %q", ErrInvalidOptions, SSEKMS)
+	}
+
+	byDefault := &s3.ServerSideEncryptionByDefault{SSEAlgorithm: aws.String(algo)}
+	if kmsKeyID != "" {
+		byDefault.KMSMasterKeyID = aws.String(kmsKeyID)
+	}
+	_, err := c.s3Client.PutBucketEncryptionWithContext(ctx, &s3.PutBucketEncryptionInput{
+		Bucket: aws.String(bucket),
+		ServerSideEncryptionConfiguration: &s3.ServerSideEncryptionConfiguration{
+			Rules: []*s3.ServerSideEncryptionRule{{
+				ApplyServerSideEncryptionByDefault: byDefault,
+				BucketKeyEnabled:                   aws.Bool(bucketKeyEnabled),
+			}},
+		},
+	})
+	if err != nil {
+		return bucketEncryptionError(err)
+	}
+	return nil
+}
+
+// GetBucketEncryption returns the default encryption of bucket. A bucket
+// without one, which S3 reports as an error, gives a result with Configured
+// false.
+func (c *S3Client) GetBucketEncryption(ctx context.Context, bucket string) (*BucketEncryption, error) {
+	if bucket == "" {
+		return nil, ErrInvalidBucket
+	}
+
+	out, err := c.s3Client.GetBucketEncryptionWithContext(ctx, &s3.GetBucketEncryptionInput{
+		Bucket: aws.String(bucket),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == "ServerSideEncryptionConfigurationNotFoundError" {
+			return &BucketEncryption{}, nil
+		}
+		return nil, bucketEncryptionError(err)
+	}
+	// S3 allows a single rule
+	if config := out.ServerSideEncryptionConfiguration; config != nil {
+		for _, rule := range config.Rules {
+			if rule.ApplyServerSideEncryptionByDefault == nil {
+				continue
+			}
+			return &BucketEncryption{
+				Configured:       true,
+				Algorithm:        aws.StringValue(rule.ApplyServerSideEncryptionByDefault.SSEAlgorithm),
+				KMSKeyID:         aws.StringValue(rule.ApplyServerSideEncryptionByDefault.KMSMasterKeyID),
+				BucketKeyEnabled: aws.BoolValue(rule.BucketKeyEnabled),
+			}, nil
+		}
+	}
+	return &BucketEncryption{}, nil
+}
+
+// DeleteBucketEncryption removes the default encryption of bucket. On AWS,
+// buckets then fall back to SSE-S3, which S3 applies to every new object.
+func (c *S3Client) DeleteBucketEncryption(ctx context.Context, bucket string) error {
+	if bucket == "" {
+		return ErrInvalidBucket
+	}
+	_, err := c.s3Client.DeleteBucketEncryptionWithContext(ctx, &s3.DeleteBucketEncryptionInput{
+		Bucket: aws.String(bucket),
+	})
+	if err != nil {
+		return bucketEncryptionError(err)
+	}
+	return nil
+}
+
+// bucketEncryptionError maps a failed bucket encryption request to the
+// library's error values
+func bucketEncryptionError(err error) error {
+	if aerr, ok := err.(awserr.Error); ok {
+		switch aerr.Code() {
+		case s3.ErrCodeNoSuchBucket:
+			return ErrInvalidBucket
+		case "AccessDenied":
+			return fmt.Errorf("%w: %w", ErrAccessDenied, aerr)
+		case "InvalidArgument", "MalformedXML":
+			return fmt.Errorf("%w: %w", ErrInvalidOptions, aerr)
+		default:
+			return fmt.Errorf("AWS error: %w", aerr)
+		}
+	}
+	return fmt.Errorf("failed to access bucket encryption: %w", err)
+}
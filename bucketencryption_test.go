@@ -0,0 +1,60 @@
+package s3lib
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestS3Client_BucketEncryption tests the default rule set is read back,
+// a bucket without one reads as not configured, and invalid settings are
+// never sent
+func TestS3Client_BucketEncryption(t *testing.T) {
+	var stored string
+	var requests int
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		requests++
+		resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: req}
+		switch req.Method {
+		case http.MethodPut:
+			data, _ := io.ReadAll(req.Body)
+			stored = string(data)
+		case http.MethodGet:
+			if stored == "" {
+				return fakeErrorResponse(req, http.StatusNotFound, "ServerSideEncryptionConfigurationNotFoundError"), nil
+			}
+			resp.Body = xmlBody(stored)
+		case http.MethodDelete:
+			stored = ""
+			resp.StatusCode = http.StatusNoContent
+		}
+		return resp, nil
+	})
+	ctx := context.Background()
+
+	config, err := client.GetBucketEncryption(ctx, testBucket)
+	require.NoError(t, err)
+	assert.Equal(t, &BucketEncryption{}, config)
+
+	keyID := "arn:aws:kms:us-west-2:111122223333:key/abcd"
+	require.NoError(t, client.SetBucketEncryption(ctx, testBucket, SSEKMS, keyID, true))
+	config, err = client.GetBucketEncryption(ctx, testBucket)
+	require.NoError(t, err)
+	assert.Equal(t, &BucketEncryption{Configured: true, Algorithm: SSEKMS, KMSKeyID: keyID, BucketKeyEnabled: true}, config)
+
+	require.NoError(t, client.DeleteBucketEncryption(ctx, testBucket))
+	config, err = client.GetBucketEncryption(ctx, testBucket)
+	require.NoError(t, err)
+	assert.False(t, config.Configured)
+
+	sent := requests
+	assert.ErrorIs(t, client.SetBucketEncryption(ctx, testBucket, "", "", false), ErrInvalidOptions)
+	assert.ErrorIs(t, client.SetBucketEncryption(ctx, testBucket, "DES", "", false), ErrInvalidOptions)
+	assert.ErrorIs(t, client.SetBucketEncryption(ctx, testBucket, SSEAES256, keyID, false), ErrInvalidOptions)
+	assert.ErrorIs(t, client.SetBucketEncryption(ctx, testBucket, SSEAES256, "", true), ErrInvalidOptions)
+	assert.Equal(t, sent, requests)
+}
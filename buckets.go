@@ -0,0 +1,329 @@
+package s3lib
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// BucketInfo describes a bucket owned by the client's credentials
+type BucketInfo struct {
+	Name         string    `json:"name"`
+	CreationDate time.Time `json:"creation_date"`
+
+	// Region is where the bucket lives, when the client already knows it from
+	// an earlier lookup. ListBuckets does not report regions; use
+	// GetBucketRegion to resolve one.
+	Region string `json:"region,omitempty"`
+
+	// Owner is the canonical user ID of the buckets' owner and
+	// OwnerDisplayName their name, where the backend returns them
+	Owner            string `json:"owner,omitempty"`
+	OwnerDisplayName string `json:"owner_display_name,omitempty"`
+}
+
+// ListBuckets lists the buckets owned by the client's credentials.
+// Credentials without the s3:ListAllMyBuckets permission, common for keys
+// scoped to a few buckets, get ErrAccessDenied.
+func (c *S3Client) ListBuckets(ctx context.Context) ([]BucketInfo, error) {
+	// ListBuckets is not paginated; every bucket comes back in one response
+	out, err := c.s3Client.ListBucketsWithContext(ctx, &s3.ListBucketsInput{})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok {
+			switch aerr.Code() {
+			case "AccessDenied":
+				return nil, fmt.Errorf("%w: cannot list buckets", ErrAccessDenied)
+			default:
+				return nil, fmt.Errorf("AWS error: %w", aerr)
+			}
+		}
+		return nil, fmt.Errorf("failed to list buckets: %w", err)
+	}
+
+	buckets := make([]BucketInfo, 0, len(out.Buckets))
+	for _, b := range out.Buckets {
+		info := BucketInfo{
+			Name:         aws.StringValue(b.Name),
+			CreationDate: aws.TimeValue(b.CreationDate),
+		}
+		info.Region, _ = c.regions.get(info.Name)
+		if out.Owner != nil {
+			info.Owner = aws.StringValue(out.Owner.ID)
+			info.OwnerDisplayName = aws.StringValue(out.Owner.DisplayName)
+		}
+		buckets = append(buckets, info)
+	}
+	return buckets, nil
+}
+
+// BucketExists reports whether bucket exists. A missing bucket gives
+// (false, nil); any other failure is returned. S3 answers 403 both for a
+// bucket owned by another account and for one the credentials may not
+// access, so that is ErrAccessDenied rather than false. A bucket in another
+// region than the client's gives ErrWrongRegion naming its region.
+func (c *S3Client) BucketExists(ctx context.Context, bucket string) (bool, error) {
+	if bucket == "" {
+		return false, ErrInvalidBucket
+	}
+
+	var region string
+	_, err := c.s3Client.HeadBucketWithContext(ctx, &s3.HeadBucketInput{
+		Bucket: aws.String(bucket),
+	}, captureBucketRegion(&region))
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok {
+			switch aerr.Code() {
+			case "NotFound", s3.ErrCodeNoSuchBucket:
+				c.regions.invalidate(bucket)
+				return false, nil
+			case "Forbidden", "AccessDenied":
+				return false, fmt.Errorf("%w: %w", ErrAccessDenied, aerr)
+			case "BucketRegionError":
+				// The SDK reports S3's 301 for a bucket in another region
+				// with this code, whatever the response body says
+				c.regions.set(bucket, region)
+				return false, fmt.Errorf("%w: %s is in %q", ErrWrongRegion, bucket, region)
+			default:
+				return false, fmt.Errorf("AWS error: %w", aerr)
+			}
+		}
+		return false, fmt.Errorf("failed to check bucket: %w", err)
+	}
+	c.regions.set(bucket, region)
+	return true, nil
+}
+
+// captureBucketRegion returns a request option that stores the
+// x-amz-bucket-region header of the response in dst, which S3 sets even on
+// the redirect it answers for a bucket in another region
+func captureBucketRegion(dst *string) request.Option {
+	return func(r *request.Request) {
+		r.Handlers.Complete.PushBack(func(r *request.Request) {
+			if r.HTTPResponse != nil {
+				*dst = r.HTTPResponse.Header.Get("x-amz-bucket-region")
+			}
+		})
+	}
+}
+
+// ObjectOwnership controls whether a bucket's ACLs are used and who owns
+// objects other accounts upload to it
+type ObjectOwnership string
+
+// Object Ownership settings
+const (
+	// ObjectOwnershipBucketOwnerEnforced disables ACLs; the bucket owner
+	// owns every object. S3 applies it to new buckets by default.
+	ObjectOwnershipBucketOwnerEnforced ObjectOwnership = "BucketOwnerEnforced"
+	// ObjectOwnershipBucketOwnerPreferred makes the bucket owner own
+	// objects uploaded with the bucket-owner-full-control ACL
+	ObjectOwnershipBucketOwnerPreferred ObjectOwnership = "BucketOwnerPreferred"
+	// ObjectOwnershipObjectWriter makes the uploading account own its objects
+	ObjectOwnershipObjectWriter ObjectOwnership = "ObjectWriter"
+)
+
+// CreateBucketOptions represents optional parameters for CreateBucket
+type CreateBucketOptions struct {
+	// Region is where the bucket is created (default the client's region)
+	Region string
+	// Versioning enables versioning as soon as the bucket is created
+	Versioning bool
+	// ObjectOwnership is the bucket's Object Ownership setting (default
+	// BucketOwnerEnforced)
+	ObjectOwnership ObjectOwnership
+}
+
+// validate checks the options against S3's constraints
+func (o *CreateBucketOptions) validate() error {
+	if o == nil {
+		return nil
+	}
+	switch o.ObjectOwnership {
+	case "", ObjectOwnershipBucketOwnerEnforced, ObjectOwnershipBucketOwnerPreferred, ObjectOwnershipObjectWriter:
+		return nil
+	}
+	return fmt.Errorf("%w: unknown object ownership %q", ErrInvalidOptions, o.ObjectOwnership)
+}
+
+// CreateBucket creates bucket in the client's region, or opts.Region. A name
+// already taken gives ErrBucketExists, whether by another account or by the
+// client's own; use EnsureBucket to accept a bucket that is already there.
+// In us-east-1 S3 accepts creating a bucket the account already owns. If
+// enabling versioning fails the bucket is still created and the error is
+// returned.
+func (c *S3Client) CreateBucket(ctx context.Context, bucket string, opts *CreateBucketOptions) error {
+	if err := c.createBucket(ctx, bucket, opts); err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeBucketAlreadyOwnedByYou {
+			return fmt.Errorf("%w: %s is already owned by you", ErrBucketExists, bucket)
+		}
+		return err
+	}
+	return nil
+}
+
+// createBucket implements CreateBucket, returning BucketAlreadyOwnedByYou
+// as the SDK's error so EnsureBucket can accept it
+func (c *S3Client) createBucket(ctx context.Context, bucket string, opts *CreateBucketOptions) error {
+	if bucket == "" {
+		return ErrInvalidBucket
+	}
+	if err := opts.validate(); err != nil {
+		return err
+	}
+	if opts == nil {
+		opts = &CreateBucketOptions{}
+	}
+
+	input := &s3.CreateBucketInput{Bucket: aws.String(bucket)}
+	region := opts.Region
+	if region == "" {
+		region = c.config.Region
+	}
+	// us-east-1 is the default location and S3 rejects it as a constraint,
+	// but the configuration is still sent: left nil, the SDK would fill in
+	// the client's region
+	input.CreateBucketConfiguration = &s3.CreateBucketConfiguration{}
+	if region != "" && region != "us-east-1" {
+		input.CreateBucketConfiguration.LocationConstraint = aws.String(region)
+	}
+	if opts.ObjectOwnership != "" {
+		input.ObjectOwnership = aws.String(string(opts.ObjectOwnership))
+	}
+	// Requests for the new bucket must be sent to, and signed for, its region
+	target := c
+	if region != "" && region != c.config.Region {
+		regional := *c
+		regional.s3Client = s3.New(c.session, aws.NewConfig().WithRegion(region))
+		regional.config.Region = region
+		target = &regional
+	}
+	if _, err := target.s3Client.CreateBucketWithContext(ctx, input); err != nil {
+		if aerr, ok := err.(awserr.Error); ok {
+			switch aerr.Code() {
+			case s3.ErrCodeBucketAlreadyOwnedByYou:
+				return aerr
+			case s3.ErrCodeBucketAlreadyExists:
+				return fmt.Errorf("%w: %s is owned by another account", ErrBucketExists, bucket)
+			case "AccessDenied":
+				return fmt.Errorf("%w: cannot create bucket %s", ErrAccessDenied, bucket)
+			default:
+				return fmt.Errorf("AWS error: %w", aerr)
+			}
+		}
+		return fmt.Errorf("failed to create bucket: %w", err)
+	}
+	if region != "" {
+		c.regions.set(bucket, region)
+	}
+
+	if opts.Versioning {
+		if err := target.SetBucketVersioning(ctx, bucket, true); err != nil {
+			return fmt.Errorf("bucket created but failed to enable versioning: %w", err)
+		}
+	}
+	return nil
+}
+
+// EnsureBucket creates bucket in the client's region unless it already
+// exists. A bucket the credentials already own is left as it is, including
+// one created concurrently; a name taken by another account gives
+// ErrBucketExists, and an existing bucket the credentials may not access
+// ErrAccessDenied.
+func (c *S3Client) EnsureBucket(ctx context.Context, bucket string) error {
+	exists, err := c.BucketExists(ctx, bucket)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+	if err := c.createBucket(ctx, bucket, nil); err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeBucketAlreadyOwnedByYou {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// DeleteBucket deletes bucket, which S3 only allows once it holds no
+// objects, versions or delete markers; otherwise it returns
+// ErrBucketNotEmpty. With force, every version and delete marker in the
+// bucket is deleted first, regardless of the client's key prefix. Deleting
+// cannot be undone. Versions S3 refuses to delete, such as ones under
+// Object Lock, leave the bucket in place and are reported in the error.
+func (c *S3Client) DeleteBucket(ctx context.Context, bucket string, force bool) error {
+	if bucket == "" {
+		return ErrInvalidBucket
+	}
+	if force {
+		if err := c.emptyBucket(ctx, bucket); err != nil {
+			return err
+		}
+	}
+
+	_, err := c.s3Client.DeleteBucketWithContext(ctx, &s3.DeleteBucketInput{
+		Bucket: aws.String(bucket),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok {
+			switch aerr.Code() {
+			case "BucketNotEmpty":
+				return fmt.Errorf("%w: %s", ErrBucketNotEmpty, bucket)
+			case s3.ErrCodeNoSuchBucket:
+				return ErrInvalidBucket
+			case "AccessDenied":
+				return fmt.Errorf("%w: cannot delete bucket %s", ErrAccessDenied, bucket)
+			default:
+				return fmt.Errorf("AWS error: %w", aerr)
+			}
+		}
+		return fmt.Errorf("failed to delete bucket: %w", err)
+	}
+	c.regions.invalidate(bucket)
+	return nil
+}
+
+// emptyBucket deletes every version and delete marker in bucket, a page of
+// the listing at a time
+func (c *S3Client) emptyBucket(ctx context.Context, bucket string) error {
+	var (
+		failed    []DeleteError
+		deleteErr error
+	)
+	err := c.s3Client.ListObjectVersionsPagesWithContext(ctx, &s3.ListObjectVersionsInput{
+		Bucket: aws.String(bucket),
+	}, func(page *s3.ListObjectVersionsOutput, lastPage bool) bool {
+		objects := make([]*s3.ObjectIdentifier, 0, len(page.Versions)+len(page.DeleteMarkers))
+		for _, v := range page.Versions {
+			objects = append(objects, &s3.ObjectIdentifier{Key: v.Key, VersionId: v.VersionId})
+		}
+		for _, m := range page.DeleteMarkers {
+			objects = append(objects, &s3.ObjectIdentifier{Key: m.Key, VersionId: m.VersionId})
+		}
+		for start := 0; start < len(objects); start += maxDeleteKeys {
+			errs, err := c.deleteBatch(ctx, bucket, objects[start:min(start+maxDeleteKeys, len(objects))])
+			if err != nil {
+				deleteErr = err
+				return false
+			}
+			failed = append(failed, errs...)
+		}
+		return true
+	})
+	if deleteErr != nil {
+		return deleteErr
+	}
+	if err != nil {
+		return listError(err)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%w: failed to delete %d versions in %s: %w", ErrBucketNotEmpty, len(failed), bucket, failed[0])
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package s3lib
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestS3Client_ListBuckets tests buckets are returned with their owner and
+// any region already cached
+func TestS3Client_ListBuckets(t *testing.T) {
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		body := "<ListAllMyBucketsResult><Owner><ID>abc123</ID><DisplayName>alice</DisplayName></Owner><Buckets>" +
+			"<Bucket><Name>logs</Name><CreationDate>2024-01-02T03:04:05.000Z</CreationDate></Bucket>" +
+			"<Bucket><Name>media</Name><CreationDate>2024-02-02T00:00:00.000Z</CreationDate></Bucket>" +
+			"</Buckets></ListAllMyBucketsResult>"
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: xmlBody(body), Request: req}, nil
+	})
+	client.regions.set("media", "eu-west-1")
+
+	buckets, err := client.ListBuckets(context.Background())
+	require.NoError(t, err)
+	require.Len(t, buckets, 2)
+	assert.Equal(t, "logs", buckets[0].Name)
+	assert.True(t, buckets[0].CreationDate.Equal(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)))
+	assert.Equal(t, "abc123", buckets[0].Owner)
+	assert.Equal(t, "alice", buckets[0].OwnerDisplayName)
+	assert.Empty(t, buckets[0].Region)
+	assert.Equal(t, "media", buckets[1].Name)
+	assert.Equal(t, "abc123", buckets[1].Owner)
+	assert.Equal(t, "eu-west-1", buckets[1].Region)
+}
+
+// TestS3Client_ListBucketsAccessDenied tests restricted credentials get
+// ErrAccessDenied
+func TestS3Client_ListBucketsAccessDenied(t *testing.T) {
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		return fakeErrorResponse(req, http.StatusForbidden, "AccessDenied"), nil
+	})
+
+	_, err := client.ListBuckets(context.Background())
+	assert.ErrorIs(t, err, ErrAccessDenied)
+}
+
+// TestS3Client_BucketExists tests a missing bucket is not an error but a
+// denied or misplaced one is
+func TestS3Client_BucketExists(t *testing.T) {
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		switch req.URL.Path {
+		case "/missing":
+			return fakeErrorResponse(req, http.StatusNotFound, "NotFound"), nil
+		case "/denied":
+			return fakeErrorResponse(req, http.StatusForbidden, "Forbidden"), nil
+		case "/elsewhere":
+			resp := fakeErrorResponse(req, http.StatusMovedPermanently, "PermanentRedirect")
+			resp.Header.Set("x-amz-bucket-region", "eu-west-1")
+			return resp, nil
+		}
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: req}, nil
+	})
+	ctx := context.Background()
+
+	exists, err := client.BucketExists(ctx, testBucket)
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	exists, err = client.BucketExists(ctx, "missing")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	_, err = client.BucketExists(ctx, "denied")
+	assert.ErrorIs(t, err, ErrAccessDenied)
+
+	_, err = client.BucketExists(ctx, "elsewhere")
+	assert.ErrorIs(t, err, ErrWrongRegion)
+	assert.ErrorContains(t, err, "eu-west-1")
+}
+
+// TestS3Client_EnsureBucket tests a missing bucket is created in the
+// client's region and one already owned is left alone
+func TestS3Client_EnsureBucket(t *testing.T) {
+	var created []string
+	var body string
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		if req.Method == http.MethodHead {
+			if req.URL.Path == "/"+testBucket {
+				return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: req}, nil
+			}
+			return fakeErrorResponse(req, http.StatusNotFound, "NotFound"), nil
+		}
+		created = append(created, req.URL.Path)
+		data, _ := io.ReadAll(req.Body)
+		body = string(data)
+		switch req.URL.Path {
+		case "/raced":
+			return fakeErrorResponse(req, http.StatusConflict, "BucketAlreadyOwnedByYou"), nil
+		case "/taken":
+			return fakeErrorResponse(req, http.StatusConflict, "BucketAlreadyExists"), nil
+		}
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: req}, nil
+	})
+	client.config.Region = "eu-west-1"
+	ctx := context.Background()
+
+	require.NoError(t, client.EnsureBucket(ctx, testBucket))
+	assert.Empty(t, created)
+
+	require.NoError(t, client.EnsureBucket(ctx, "fresh"))
+	assert.Equal(t, []string{"/fresh"}, created)
+	assert.Contains(t, body, "<LocationConstraint>eu-west-1</LocationConstraint>")
+
+	assert.NoError(t, client.EnsureBucket(ctx, "raced"))
+	assert.ErrorIs(t, client.EnsureBucket(ctx, "taken"), ErrBucketExists)
+}
+
+// TestS3Client_CreateBucket tests the location constraint, ownership and
+// versioning are applied and a taken name is an error
+func TestS3Client_CreateBucket(t *testing.T) {
+	var requests []string
+	var body string
+	var headers http.Header
+	var versioningAuth string
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		requests = append(requests, req.Method+" "+req.URL.Path+"?"+req.URL.RawQuery)
+		if req.URL.Path == "/mine" {
+			return fakeErrorResponse(req, http.StatusConflict, "BucketAlreadyOwnedByYou"), nil
+		}
+		if req.URL.Query().Has("versioning") {
+			versioningAuth = req.Header.Get("Authorization")
+		} else {
+			data, _ := io.ReadAll(req.Body)
+			body = string(data)
+			headers = req.Header
+		}
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: req}, nil
+	})
+	ctx := context.Background()
+
+	err := client.CreateBucket(ctx, "archive", &CreateBucketOptions{
+		Region:          "ap-south-1",
+		Versioning:      true,
+		ObjectOwnership: ObjectOwnershipBucketOwnerPreferred,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"PUT /archive?", "PUT /archive?versioning="}, requests)
+	assert.Contains(t, versioningAuth, "/ap-south-1/s3/")
+	assert.Contains(t, body, "<LocationConstraint>ap-south-1</LocationConstraint>")
+	assert.Contains(t, headers.Get("Authorization"), "/ap-south-1/s3/")
+	assert.Equal(t, "BucketOwnerPreferred", headers.Get("x-amz-object-ownership"))
+
+	t.Run("client region", func(t *testing.T) {
+		require.NoError(t, client.CreateBucket(ctx, "local", nil))
+		assert.Contains(t, body, "<LocationConstraint>"+testConfig.Region+"</LocationConstraint>")
+		assert.Contains(t, headers.Get("Authorization"), "/"+testConfig.Region+"/s3/")
+	})
+
+	t.Run("us-east-1", func(t *testing.T) {
+		require.NoError(t, client.CreateBucket(ctx, "legacy", &CreateBucketOptions{Region: "us-east-1"}))
+		assert.Contains(t, body, "<CreateBucketConfiguration")
+		assert.NotContains(t, body, "LocationConstraint")
+		assert.Contains(t, headers.Get("Authorization"), "/us-east-1/s3/")
+	})
+
+	t.Run("already owned", func(t *testing.T) {
+		assert.ErrorIs(t, client.CreateBucket(ctx, "mine", nil), ErrBucketExists)
+	})
+
+	t.Run("invalid ownership", func(t *testing.T) {
+		err := client.CreateBucket(ctx, "archive", &CreateBucketOptions{ObjectOwnership: "Anyone"})
+		assert.ErrorIs(t, err, ErrInvalidOptions)
+	})
+}
+
+// TestS3Client_DeleteBucket tests a non-empty bucket is refused unless
+// forced, which deletes every version and delete marker first
+func TestS3Client_DeleteBucket(t *testing.T) {
+	var deleted deleteRequest
+	var bucketDeletes int
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: req}
+		switch {
+		case req.Method == http.MethodGet:
+			resp.Body = xmlBody("<ListVersionsResult>" +
+				"<Version><Key>a.txt</Key><VersionId>v1</VersionId></Version>" +
+				"<DeleteMarker><Key>a.txt</Key><VersionId>v2</VersionId></DeleteMarker>" +
+				"<Version><Key>b.txt</Key><VersionId>null</VersionId></Version>" +
+				"</ListVersionsResult>")
+		case req.Method == http.MethodPost:
+			data, _ := io.ReadAll(req.Body)
+			require.NoError(t, xml.Unmarshal(data, &deleted))
+			resp.Body = xmlBody("<DeleteResult></DeleteResult>")
+		case req.Method == http.MethodDelete:
+			bucketDeletes++
+			if len(deleted.Objects) == 0 {
+				return fakeErrorResponse(req, http.StatusConflict, "BucketNotEmpty"), nil
+			}
+			resp.StatusCode = http.StatusNoContent
+		}
+		return resp, nil
+	})
+	ctx := context.Background()
+
+	assert.ErrorIs(t, client.DeleteBucket(ctx, testBucket, false), ErrBucketNotEmpty)
+
+	require.NoError(t, client.DeleteBucket(ctx, testBucket, true))
+	assert.Equal(t, 2, bucketDeletes)
+	require.Len(t, deleted.Objects, 3)
+	assert.Equal(t, "v1", deleted.Objects[0].VersionID)
+	assert.Equal(t, "b.txt", deleted.Objects[1].Key)
+	assert.Equal(t, "v2", deleted.Objects[2].VersionID)
+}
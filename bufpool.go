@@ -0,0 +1,114 @@
+package s3lib
+
+import (
+	"math/bits"
+	"sync"
+)
+
+// Download buffers are pooled in power of two size classes from 4KB to
+// 64MB. Larger objects get a buffer of their own so the pool never pins
+// more than a few very large slices.
+const (
+	minPooledBufferShift = 12
+	maxPooledBufferShift = 26
+)
+
+// downloadBufferPools holds *[]byte of capacity 1<<(minPooledBufferShift+i)
+var downloadBufferPools [maxPooledBufferShift - minPooledBufferShift + 1]sync.Pool
+
+// bufferClass returns the index of the pool holding buffers of at least
+// size bytes, or -1 if size is too large to pool
+func bufferClass(size int64) int {
+	shift := bits.Len64(uint64(size - 1))
+	if size <= 1 || shift < minPooledBufferShift {
+		return 0
+	}
+	if shift > maxPooledBufferShift {
+		return -1
+	}
+	return shift - minPooledBufferShift
+}
+
+// downloadBuffer is an io.WriterAt collecting a download in a pooled slice.
+// reserve sizes it once the object's size is known, so it does not have to
+// grow while parts arrive. The contents must be copied out, or no longer
+// referenced, before release returns the slice to the pool.
+type downloadBuffer struct {
+	mu    sync.Mutex
+	buf   []byte
+	class int // pool buf was taken from, or -1
+}
+
+func newDownloadBuffer() *downloadBuffer {
+	return &downloadBuffer{class: -1}
+}
+
+// reserve ensures the buffer can hold size bytes without growing
+func (b *downloadBuffer) reserve(size int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.reserveLocked(size)
+}
+
+func (b *downloadBuffer) reserveLocked(size int64) {
+	if size <= int64(cap(b.buf)) {
+		return
+	}
+
+	class := bufferClass(size)
+	var buf []byte
+	switch {
+	case class < 0:
+		buf = make([]byte, 0, size)
+	default:
+		if p, ok := downloadBufferPools[class].Get().(*[]byte); ok {
+			buf = (*p)[:0]
+		} else {
+			buf = make([]byte, 0, 1<<(minPooledBufferShift+class))
+		}
+	}
+	buf = append(buf, b.buf...)
+
+	b.releaseLocked()
+	b.buf, b.class = buf, class
+}
+
+// WriteAt implements io.WriterAt. Parts may be written concurrently and in
+// any order.
+func (b *downloadBuffer) WriteAt(p []byte, off int64) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	end := off + int64(len(p))
+	if end > int64(cap(b.buf)) {
+		// Double at least, so unreserved downloads grow in amortized time
+		b.reserveLocked(max(end, 2*int64(cap(b.buf))))
+	}
+	if end > int64(len(b.buf)) {
+		b.buf = b.buf[:end]
+	}
+	copy(b.buf[off:], p)
+	return len(p), nil
+}
+
+// bytes returns the contents, which alias the pooled slice
+func (b *downloadBuffer) bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf
+}
+
+// release returns the slice to its pool. The buffer is empty afterwards.
+func (b *downloadBuffer) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.releaseLocked()
+}
+
+func (b *downloadBuffer) releaseLocked() {
+	if b.class >= 0 {
+		buf := b.buf[:0]
+		downloadBufferPools[b.class].Put(&buf)
+	}
+	b.buf, b.class = nil, -1
+}
@@ -0,0 +1,296 @@
+package s3lib
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cacheTempPrefix starts the names of cache entries still being written
+const cacheTempPrefix = ".tmp-"
+
+// diskCache keeps downloaded objects in Config.CacheDir. Each entry is a
+// single file holding a JSON header line followed by the object's content,
+// written to a temporary file and renamed into place so concurrent writers,
+// including other processes sharing the directory, never leave a torn
+// entry. Entries are evicted least recently used first once the directory
+// exceeds maxSize; hits refresh an entry's modification time.
+type diskCache struct {
+	dir     string
+	maxSize int64
+
+	mu sync.Mutex // serializes eviction scans
+}
+
+// cacheHeader describes a cached object
+type cacheHeader struct {
+	Bucket       string            `json:"bucket"`
+	Key          string            `json:"key"`
+	ETag         string            `json:"etag"`
+	ContentType  string            `json:"content_type,omitempty"`
+	LastModified time.Time         `json:"last_modified"`
+	VersionID    string            `json:"version_id,omitempty"`
+	Metadata     map[string]string `json:"metadata,omitempty"`
+	Decompressed bool              `json:"decompressed,omitempty"`
+	Size         int64             `json:"size"`
+}
+
+// newDiskCache returns a cache in dir, creating the directory if needed
+func newDiskCache(dir string, maxSize int64) (*diskCache, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	return &diskCache{dir: dir, maxSize: maxSize}, nil
+}
+
+// path returns the file of the entry for an object. Names are hashed so any
+// key maps to a single flat, valid file name.
+func (d *diskCache) path(bucket, key string) string {
+	sum := sha256.Sum256([]byte(bucket + "\x00" + key))
+	return filepath.Join(d.dir, hex.EncodeToString(sum[:]))
+}
+
+// etag returns the ETag of the cached copy of an object, if there is one
+func (d *diskCache) etag(bucket, key string) (string, bool) {
+	f, err := os.Open(d.path(bucket, key))
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+
+	header, _, err := readCacheHeader(f)
+	if err != nil || header.Bucket != bucket || header.Key != key || header.ETag == "" {
+		return "", false
+	}
+	return header.ETag, true
+}
+
+// load returns the cached copy of an object if its ETag is etag. Entries
+// that cannot be read back in full are removed.
+func (d *diskCache) load(bucket, key, etag string) (*DownloadResult, bool) {
+	path := d.path(bucket, key)
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, false
+	}
+	defer f.Close()
+
+	header, r, err := readCacheHeader(f)
+	if err != nil || header.Bucket != bucket || header.Key != key || header.ETag != etag {
+		return nil, false
+	}
+	data, err := io.ReadAll(r)
+	if err != nil || int64(len(data)) != header.Size {
+		os.Remove(path)
+		return nil, false
+	}
+
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return &DownloadResult{
+		Data:         data,
+		ContentType:  header.ContentType,
+		ETag:         header.ETag,
+		LastModified: header.LastModified,
+		VersionID:    header.VersionID,
+		Metadata:     header.Metadata,
+		Decompressed: header.Decompressed,
+		Cached:       true,
+	}, true
+}
+
+// store caches a downloaded object, replacing any previous entry, and then
+// evicts entries beyond the size limit
+func (d *diskCache) store(bucket, key string, result *DownloadResult) error {
+	if d.maxSize > 0 && int64(len(result.Data)) > d.maxSize {
+		return nil
+	}
+
+	tmp, err := os.CreateTemp(d.dir, cacheTempPrefix+"*")
+	if err != nil {
+		return fmt.Errorf("failed to create cache entry: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	err = json.NewEncoder(w).Encode(cacheHeader{
+		Bucket:       bucket,
+		Key:          key,
+		ETag:         result.ETag,
+		ContentType:  result.ContentType,
+		LastModified: result.LastModified,
+		VersionID:    result.VersionID,
+		Metadata:     result.Metadata,
+		Decompressed: result.Decompressed,
+		Size:         int64(len(result.Data)),
+	})
+	if err == nil {
+		_, err = w.Write(result.Data)
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), d.path(bucket, key)); err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+
+	return d.evict()
+}
+
+// remove deletes the entry for an object, if there is one
+func (d *diskCache) remove(bucket, key string) error {
+	if err := os.Remove(d.path(bucket, key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove cache entry: %w", err)
+	}
+	return nil
+}
+
+// evict removes the least recently used entries until the cache fits in
+// maxSize
+func (d *diskCache) evict() error {
+	if d.maxSize <= 0 {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	dirEntries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read cache directory: %w", err)
+	}
+	var entries []fs.FileInfo
+	var total int64
+	for _, e := range dirEntries {
+		if !e.Type().IsRegular() || strings.HasPrefix(e.Name(), cacheTempPrefix) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue // removed concurrently
+		}
+		entries = append(entries, info)
+		total += info.Size()
+	}
+	if total <= d.maxSize {
+		return nil
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ModTime().Before(entries[j].ModTime()) })
+	for _, info := range entries {
+		if total <= d.maxSize {
+			break
+		}
+		if err := os.Remove(filepath.Join(d.dir, info.Name())); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to evict cache entry: %w", err)
+		}
+		total -= info.Size()
+	}
+	return nil
+}
+
+// readCacheHeader reads the header line of an entry and returns a reader
+// positioned at its content
+func readCacheHeader(f io.Reader) (*cacheHeader, io.Reader, error) {
+	r := bufio.NewReader(f)
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		return nil, nil, err
+	}
+	var header cacheHeader
+	if err := json.Unmarshal(line, &header); err != nil {
+		return nil, nil, err
+	}
+	return &header, r, nil
+}
+
+// cacheable reports whether a download with opts may be served from and
+// stored in the cache. Versioned, conditional and decompressing downloads
+// bypass it, as do objects encrypted with a customer key, whose plaintext
+// must not be written to disk.
+func (o *DownloadOptions) cacheable() bool {
+	if o == nil {
+		return true
+	}
+	return o.SSECustomerKey == nil && !o.AutoDecompress && o.VersionID == "" &&
+		o.IfNoneMatch == "" && o.IfModifiedSince.IsZero()
+}
+
+// InvalidateCache removes the cached copy of an object, if any, so the next
+// download fetches it in full. It does nothing without Config.CacheDir.
+func (c *S3Client) InvalidateCache(bucket, key string) error {
+	if c.cache == nil {
+		return nil
+	}
+	if bucket == "" {
+		return ErrInvalidBucket
+	}
+	key, err := c.resolveKey(key)
+	if err != nil {
+		return err
+	}
+	return c.cache.remove(bucket, key)
+}
+
+// cachedDownload serves DownloadFileResult from the cache when S3 confirms
+// the cached copy is current and otherwise downloads the object with
+// download and caches it. key is the full object key.
+func (c *S3Client) cachedDownload(ctx context.Context, bucket, key string, opts *DownloadOptions, download func(*DownloadOptions) (*DownloadResult, error)) (*DownloadResult, error) {
+	etag, ok := c.cache.etag(bucket, key)
+	if ok {
+		revalidate := DownloadOptions{}
+		if opts != nil {
+			revalidate = *opts
+		}
+		revalidate.IfNoneMatch = etag
+		result, err := download(&revalidate)
+		if errors.Is(err, ErrNotModified) {
+			if cached, ok := c.cache.load(bucket, key, etag); ok {
+				return cached, nil
+			}
+			// The entry vanished or was replaced since it was revalidated
+			result, err = download(opts)
+		}
+		if err != nil {
+			return nil, err
+		}
+		c.storeCached(bucket, key, result)
+		return result, nil
+	}
+
+	result, err := download(opts)
+	if err != nil {
+		return nil, err
+	}
+	c.storeCached(bucket, key, result)
+	return result, nil
+}
+
+// storeCached caches a download. Client-side encrypted objects are not
+// cached, so their plaintext is never written to disk. Failing to cache does
+// not fail the download.
+func (c *S3Client) storeCached(bucket, key string, result *DownloadResult) {
+	if result.encrypted {
+		return
+	}
+	if err := c.cache.store(bucket, key, result); err != nil && c.debugMode {
+		fmt.Printf("failed to cache %s: %v\n", key, err)
+	}
+}
@@ -0,0 +1,179 @@
+package s3lib
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDiskCache tests entries are stored, matched by ETag and removed
+func TestDiskCache(t *testing.T) {
+	cache, err := newDiskCache(t.TempDir(), 0)
+	require.NoError(t, err)
+
+	_, ok := cache.etag(testBucket, testFileName)
+	assert.False(t, ok)
+
+	result := &DownloadResult{
+		Data:        testFileContent,
+		ETag:        `"abc"`,
+		ContentType: "text/plain",
+		Metadata:    map[string]string{"owner": "test"},
+	}
+	require.NoError(t, cache.store(testBucket, testFileName, result))
+
+	etag, ok := cache.etag(testBucket, testFileName)
+	require.True(t, ok)
+	assert.Equal(t, `"abc"`, etag)
+
+	cached, ok := cache.load(testBucket, testFileName, `"abc"`)
+	require.True(t, ok)
+	assert.Equal(t, testFileContent, cached.Data)
+	assert.Equal(t, "text/plain", cached.ContentType)
+	assert.Equal(t, "test", cached.Metadata["owner"])
+	assert.True(t, cached.Cached)
+
+	_, ok = cache.load(testBucket, testFileName, `"other"`)
+	assert.False(t, ok, "stale ETag must not be served")
+	_, ok = cache.load("other-bucket", testFileName, `"abc"`)
+	assert.False(t, ok)
+
+	require.NoError(t, cache.remove(testBucket, testFileName))
+	require.NoError(t, cache.remove(testBucket, testFileName))
+	_, ok = cache.etag(testBucket, testFileName)
+	assert.False(t, ok)
+}
+
+// TestDiskCacheTruncatedEntry tests an entry missing content is discarded
+func TestDiskCacheTruncatedEntry(t *testing.T) {
+	cache, err := newDiskCache(t.TempDir(), 0)
+	require.NoError(t, err)
+	require.NoError(t, cache.store(testBucket, testFileName, &DownloadResult{Data: testFileContent, ETag: `"abc"`}))
+
+	path := cache.path(testBucket, testFileName)
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.NoError(t, os.Truncate(path, info.Size()-1))
+
+	_, ok := cache.load(testBucket, testFileName, `"abc"`)
+	assert.False(t, ok)
+	assert.NoFileExists(t, path)
+}
+
+// TestDiskCacheEviction tests the least recently used entries are evicted
+// once the cache exceeds its size limit
+func TestDiskCacheEviction(t *testing.T) {
+	data := make([]byte, 1000)
+	cache, err := newDiskCache(t.TempDir(), 2500)
+	require.NoError(t, err)
+
+	old := time.Now().Add(-time.Hour)
+	for i, key := range []string{"a", "b"} {
+		require.NoError(t, cache.store(testBucket, key, &DownloadResult{Data: data, ETag: `"abc"`}))
+		at := old.Add(time.Duration(i) * time.Minute)
+		require.NoError(t, os.Chtimes(cache.path(testBucket, key), at, at))
+	}
+
+	// Reading "a" makes "b" the least recently used
+	_, ok := cache.load(testBucket, "a", `"abc"`)
+	require.True(t, ok)
+
+	require.NoError(t, cache.store(testBucket, "c", &DownloadResult{Data: data, ETag: `"abc"`}))
+	assert.FileExists(t, cache.path(testBucket, "a"))
+	assert.NoFileExists(t, cache.path(testBucket, "b"))
+	assert.FileExists(t, cache.path(testBucket, "c"))
+
+	// Objects larger than the whole cache are not stored
+	require.NoError(t, cache.store(testBucket, "d", &DownloadResult{Data: make([]byte, 3000), ETag: `"abc"`}))
+	assert.NoFileExists(t, cache.path(testBucket, "d"))
+}
+
+// TestDiskCacheConcurrentStores tests concurrent writers of one entry leave
+// a complete copy of one of them
+func TestDiskCacheConcurrentStores(t *testing.T) {
+	cache, err := newDiskCache(t.TempDir(), 0)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			data := make([]byte, 64*1024)
+			for j := range data {
+				data[j] = byte(i)
+			}
+			assert.NoError(t, cache.store(testBucket, testFileName, &DownloadResult{Data: data, ETag: fmt.Sprintf(`"%d"`, i)}))
+		}(i)
+	}
+	wg.Wait()
+
+	etag, ok := cache.etag(testBucket, testFileName)
+	require.True(t, ok)
+	cached, ok := cache.load(testBucket, testFileName, etag)
+	require.True(t, ok)
+	require.Len(t, cached.Data, 64*1024)
+	assert.Equal(t, fmt.Sprintf(`"%d"`, cached.Data[0]), etag)
+	for _, b := range cached.Data {
+		require.Equal(t, cached.Data[0], b)
+	}
+
+	entries, err := os.ReadDir(cache.dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "temporary files must be cleaned up")
+}
+
+// TestDownloadOptionsCacheable tests which downloads bypass the cache
+func TestDownloadOptionsCacheable(t *testing.T) {
+	assert.True(t, (*DownloadOptions)(nil).cacheable())
+	assert.True(t, (&DownloadOptions{VerifyChecksum: true}).cacheable())
+	assert.False(t, (&DownloadOptions{VersionID: "v1"}).cacheable())
+	assert.False(t, (&DownloadOptions{IfNoneMatch: `"abc"`}).cacheable())
+	assert.False(t, (&DownloadOptions{AutoDecompress: true}).cacheable())
+	assert.False(t, (&DownloadOptions{SSECustomerKey: make([]byte, 32)}).cacheable())
+}
+
+// TestS3Client_DownloadFileCached tests a cached object is revalidated with
+// If-None-Match and served from disk when S3 answers 304 Not Modified
+func TestS3Client_DownloadFileCached(t *testing.T) {
+	var conditional []string
+	serve := objectServer(testFileContent)
+	cfg := testConfig
+	cfg.Endpoint = "http://s3.fake.local"
+	cfg.CacheDir = t.TempDir()
+	cfg.HTTPClient = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Method == http.MethodGet {
+			conditional = append(conditional, req.Header.Get("If-None-Match"))
+			if req.Header.Get("If-None-Match") == `"abc"` {
+				return &http.Response{StatusCode: http.StatusNotModified, Header: http.Header{}, Body: http.NoBody, Request: req}, nil
+			}
+		}
+		return serve(req)
+	})}
+	client, err := NewS3Client(cfg)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	result, err := client.DownloadFileResult(ctx, testBucket, testFileName, nil)
+	require.NoError(t, err)
+	assert.False(t, result.Cached)
+
+	result, err = client.DownloadFileResult(ctx, testBucket, testFileName, nil)
+	require.NoError(t, err)
+	assert.True(t, result.Cached)
+	assert.Equal(t, testFileContent, result.Data)
+
+	require.NoError(t, client.InvalidateCache(testBucket, testFileName))
+	result, err = client.DownloadFileResult(ctx, testBucket, testFileName, nil)
+	require.NoError(t, err)
+	assert.False(t, result.Cached)
+
+	assert.Equal(t, []string{"", `"abc"`, ""}, conditional)
+}
@@ -0,0 +1,241 @@
+package s3lib
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// contentMD5Header is the HTTP header S3 uses to verify payload integrity
+const contentMD5Header = "Content-MD5"
+
+// withContentMD5 is a request option that sets the Content-MD5 header on
+// PutObject and UploadPart requests, so every part of a multipart upload
+// carries its own digest and S3 rejects corrupted transfers
+func withContentMD5(r *request.Request) {
+	r.Handlers.Build.PushBack(func(r *request.Request) {
+		if r.Operation == nil || (r.Operation.Name != "PutObject" && r.Operation.Name != "UploadPart") {
+			return
+		}
+		if r.Body == nil || r.HTTPRequest.Header.Get(contentMD5Header) != "" {
+			return
+		}
+
+		sum, err := md5Base64(r.Body)
+		if err != nil {
+			r.Error = awserr.New("ContentMD5Error", "failed to compute Content-MD5", err)
+			return
+		}
+		r.HTTPRequest.Header.Set(contentMD5Header, sum)
+	})
+}
+
+// ChecksumAlgorithm identifies an additional checksum S3 verifies on upload
+type ChecksumAlgorithm string
+
+// Supported checksum algorithms
+const (
+	ChecksumNone   ChecksumAlgorithm = ""
+	ChecksumCRC32  ChecksumAlgorithm = "CRC32"
+	ChecksumCRC32C ChecksumAlgorithm = "CRC32C"
+	ChecksumSHA1   ChecksumAlgorithm = "SHA1"
+	ChecksumSHA256 ChecksumAlgorithm = "SHA256"
+)
+
+// valid reports whether the algorithm is one the library can compute
+func (a ChecksumAlgorithm) valid() bool {
+	switch a {
+	case ChecksumNone, ChecksumCRC32, ChecksumCRC32C, ChecksumSHA1, ChecksumSHA256:
+		return true
+	}
+	return false
+}
+
+// header returns the x-amz-checksum-* header carrying this checksum
+func (a ChecksumAlgorithm) header() string {
+	return "x-amz-checksum-" + strings.ToLower(string(a))
+}
+
+// newHash returns a hash computing this checksum
+func (a ChecksumAlgorithm) newHash() hash.Hash {
+	switch a {
+	case ChecksumCRC32:
+		return crc32.NewIEEE()
+	case ChecksumCRC32C:
+		return crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	case ChecksumSHA1:
+		return sha1.New()
+	default:
+		return sha256.New()
+	}
+}
+
+// Checksum computes the base64 encoded checksum of data in the format S3
+// reports it
+func (a ChecksumAlgorithm) Checksum(data []byte) string {
+	h := a.newHash()
+	h.Write(data)
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// withChecksum returns a request option that computes the checksum of the
+// body of PutObject and UploadPart requests and sends it for S3 to verify
+func withChecksum(alg ChecksumAlgorithm) request.Option {
+	return func(r *request.Request) {
+		r.Handlers.Build.PushBack(func(r *request.Request) {
+			if r.Operation == nil || (r.Operation.Name != "PutObject" && r.Operation.Name != "UploadPart") {
+				return
+			}
+			if r.Body == nil {
+				return
+			}
+
+			sum, err := hashBase64(r.Body, alg.newHash())
+			if err != nil {
+				r.Error = awserr.New("ChecksumError", "failed to compute checksum", err)
+				return
+			}
+			r.HTTPRequest.Header.Set("x-amz-sdk-checksum-algorithm", string(alg))
+			r.HTTPRequest.Header.Set(alg.header(), sum)
+		})
+	}
+}
+
+// setChecksum records the first checksum S3 returned, in order of preference
+func (r *UploadResult) setChecksum(sha256Sum, crc32cSum, crc32Sum, sha1Sum *string) {
+	r.ChecksumAlgorithm, r.Checksum = pickChecksum(sha256Sum, crc32cSum, crc32Sum, sha1Sum)
+	r.ChecksumSHA256 = aws.StringValue(sha256Sum)
+}
+
+// pickChecksum returns the algorithm and value of the first non-empty checksum
+func pickChecksum(sha256Sum, crc32cSum, crc32Sum, sha1Sum *string) (ChecksumAlgorithm, string) {
+	switch {
+	case aws.StringValue(sha256Sum) != "":
+		return ChecksumSHA256, *sha256Sum
+	case aws.StringValue(crc32cSum) != "":
+		return ChecksumCRC32C, *crc32cSum
+	case aws.StringValue(crc32Sum) != "":
+		return ChecksumCRC32, *crc32Sum
+	case aws.StringValue(sha1Sum) != "":
+		return ChecksumSHA1, *sha1Sum
+	}
+	return ChecksumNone, ""
+}
+
+// md5Base64 returns the base64 encoded MD5 of the remainder of body and
+// rewinds it to where it started
+func md5Base64(body io.ReadSeeker) (string, error) {
+	return hashBase64(body, md5.New())
+}
+
+// hashBase64 returns the base64 encoded digest of the remainder of body and
+// rewinds it to where it started
+func hashBase64(body io.ReadSeeker, h hash.Hash) (string, error) {
+	if err := digest(body, h); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
+}
+
+// digest writes the remainder of body to w, typically one or more hashes,
+// and rewinds body to where it started
+func digest(body io.ReadSeeker, w io.Writer) error {
+	start, err := body.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, body); err != nil {
+		return err
+	}
+	_, err = body.Seek(start, io.SeekStart)
+	return err
+}
+
+// checksumVerifier hashes downloaded bytes and compares the result with the
+// checksum S3 stored for the object
+type checksumVerifier struct {
+	h    hash.Hash
+	name string
+	want string
+	hex  bool // want is a hex encoded MD5 ETag rather than base64
+}
+
+// newChecksumVerifier returns a verifier for the first stored checksum, or
+// for the ETag when there is none and etagIsMD5. It returns nil when nothing
+// can be verified: multipart checksums and ETags cover the parts rather than
+// the object and carry a "-N" part count suffix.
+func newChecksumVerifier(sha256Sum, crc32cSum, crc32Sum, sha1Sum *string, etag string, etagIsMD5 bool) *checksumVerifier {
+	if alg, sum := pickChecksum(sha256Sum, crc32cSum, crc32Sum, sha1Sum); alg != ChecksumNone {
+		if strings.Contains(sum, "-") {
+			return nil
+		}
+		return &checksumVerifier{h: alg.newHash(), name: string(alg), want: sum}
+	}
+	etag = strings.Trim(etag, `"`)
+	if !etagIsMD5 || etag == "" || strings.Contains(etag, "-") {
+		return nil
+	}
+	return &checksumVerifier{h: md5.New(), name: "MD5", want: etag, hex: true}
+}
+
+// etagIsMD5 reports whether a single part object's ETag is the MD5 of its
+// content, which is not the case under SSE-KMS or SSE-C
+func etagIsMD5(sse, sseCustomerAlgorithm *string) bool {
+	return aws.StringValue(sseCustomerAlgorithm) == "" && !strings.HasPrefix(aws.StringValue(sse), s3.ServerSideEncryptionAwsKms)
+}
+
+// Write adds downloaded bytes to the digest
+func (v *checksumVerifier) Write(p []byte) (int, error) {
+	return v.h.Write(p)
+}
+
+// verify returns ErrChecksumMismatch if the bytes written do not match the
+// stored checksum. A nil verifier accepts everything.
+func (v *checksumVerifier) verify() error {
+	if v == nil {
+		return nil
+	}
+	sum := v.h.Sum(nil)
+	got := base64.StdEncoding.EncodeToString(sum)
+	if v.hex {
+		got = hex.EncodeToString(sum)
+	}
+	if got != v.want {
+		return fmt.Errorf("%w: %s of downloaded data is %s, stored %s", ErrChecksumMismatch, v.name, got, v.want)
+	}
+	return nil
+}
+
+// verifyingBody hashes a response body as it is read and, at the end of the
+// body, reports a checksum mismatch in place of io.EOF
+type verifyingBody struct {
+	rc io.ReadCloser
+	v  *checksumVerifier
+}
+
+func (b *verifyingBody) Read(p []byte) (int, error) {
+	n, err := b.rc.Read(p)
+	b.v.Write(p[:n])
+	if err == io.EOF {
+		if verr := b.v.verify(); verr != nil {
+			return n, verr
+		}
+	}
+	return n, err
+}
+
+func (b *verifyingBody) Close() error {
+	return b.rc.Close()
+}
@@ -0,0 +1,304 @@
+package s3lib
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestS3Client_UploadFileComputeMD5 tests that Content-MD5 is sent and that a
+// digest mismatch reported by S3 surfaces as ErrChecksumMismatch
+func TestS3Client_UploadFileComputeMD5(t *testing.T) {
+	sum := md5.Sum(testFileContent)
+	wantMD5 := base64.StdEncoding.EncodeToString(sum[:])
+
+	var mu sync.Mutex
+	var gotMD5 []string
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		gotMD5 = append(gotMD5, req.Header.Get("Content-MD5"))
+		mu.Unlock()
+		return fakeErrorResponse(req, http.StatusBadRequest, "BadDigest"), nil
+	})
+
+	_, err := client.UploadFile(context.Background(), testBucket, testFileName, testFileContent, &UploadOptions{ComputeMD5: true})
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+	require.NotEmpty(t, gotMD5)
+	assert.Equal(t, wantMD5, gotMD5[0])
+}
+
+// TestS3Client_UploadFileComputeMD5Multipart tests each part of a multipart
+// upload carries the Content-MD5 of its own body
+func TestS3Client_UploadFileComputeMD5Multipart(t *testing.T) {
+	var mu sync.Mutex
+	var received int64
+	partMD5 := map[string]bool{} // part number -> header matched body
+	completed := false
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: req}
+		query := req.URL.Query()
+		switch {
+		case query.Has("uploads"):
+			resp.Body = xmlBody("<InitiateMultipartUploadResult><UploadId>up-1</UploadId></InitiateMultipartUploadResult>")
+		case query.Has("partNumber"):
+			body, err := io.ReadAll(req.Body)
+			if err != nil {
+				return nil, err
+			}
+			sum := md5.Sum(body)
+			mu.Lock()
+			received += int64(len(body))
+			partMD5[query.Get("partNumber")] = req.Header.Get("Content-MD5") == base64.StdEncoding.EncodeToString(sum[:])
+			mu.Unlock()
+			resp.Header.Set("ETag", `"`+hex.EncodeToString(sum[:])+`"`)
+		case query.Has("uploadId"):
+			completed = true
+			resp.Body = xmlBody(`<CompleteMultipartUploadResult><ETag>"md5-2"</ETag></CompleteMultipartUploadResult>`)
+		default:
+			return fakeErrorResponse(req, http.StatusBadRequest, "UnexpectedRequest"), nil
+		}
+		return resp, nil
+	})
+
+	data := bytes.Repeat([]byte("m"), int(MinPartSize)+1)
+	location, err := client.UploadFile(context.Background(), testBucket, "test-md5-multipart.bin", data, &UploadOptions{ComputeMD5: true})
+	require.NoError(t, err)
+	assert.NotEmpty(t, location)
+	assert.True(t, completed)
+	assert.Equal(t, int64(len(data)), received)
+	assert.Equal(t, map[string]bool{"1": true, "2": true}, partMD5)
+}
+
+// TestMD5Base64 tests digest computation preserves the reader position
+func TestMD5Base64(t *testing.T) {
+	r := bytes.NewReader([]byte("xxhello"))
+	_, err := r.Seek(2, 0)
+	require.NoError(t, err)
+
+	got, err := md5Base64(r)
+	require.NoError(t, err)
+
+	sum := md5.Sum([]byte("hello"))
+	assert.Equal(t, base64.StdEncoding.EncodeToString(sum[:]), got)
+
+	pos, err := r.Seek(0, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), pos)
+}
+
+// TestS3Client_UploadFileChecksumAlgorithm tests the checksum header sent for
+// each algorithm and the rejection of unsupported algorithms
+func TestS3Client_UploadFileChecksumAlgorithm(t *testing.T) {
+	tests := []struct {
+		name      string
+		algorithm ChecksumAlgorithm
+		header    string
+		wantErr   error
+	}{
+		{
+			name:      "SHA256",
+			algorithm: ChecksumSHA256,
+			header:    "x-amz-checksum-sha256",
+		},
+		{
+			name:      "CRC32C",
+			algorithm: ChecksumCRC32C,
+			header:    "x-amz-checksum-crc32c",
+		},
+		{
+			name:      "unsupported algorithm",
+			algorithm: ChecksumAlgorithm("MD4"),
+			wantErr:   ErrInvalidOptions,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+				got = req.Header.Get(tt.header)
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     http.Header{http.CanonicalHeaderKey(tt.header): []string{got}},
+					Body:       http.NoBody,
+					Request:    req,
+				}, nil
+			})
+
+			result, err := client.uploadBytes(context.Background(), testBucket, testFileName, testFileContent, &UploadOptions{ChecksumAlgorithm: tt.algorithm})
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.algorithm.Checksum(testFileContent), got)
+			assert.Equal(t, tt.algorithm, result.ChecksumAlgorithm)
+			assert.Equal(t, got, result.Checksum)
+		})
+	}
+}
+
+// TestS3Client_GetFileInfoChecksum tests the stored checksum is reported
+func TestS3Client_GetFileInfoChecksum(t *testing.T) {
+	objects := map[string]*memoryObject{}
+	client := setupFakeClient(t, memoryServer(objects))
+	ctx := context.Background()
+
+	_, err := client.UploadFile(ctx, testBucket, testFileName, testFileContent, &UploadOptions{ChecksumAlgorithm: ChecksumSHA256})
+	require.NoError(t, err)
+
+	require.Contains(t, objects, testFileName)
+	assert.Equal(t, ChecksumSHA256.Checksum(testFileContent), objects[testFileName].header.Get("X-Amz-Checksum-Sha256"))
+
+	info, err := client.GetFileInfo(ctx, testBucket, testFileName)
+	require.NoError(t, err)
+	assert.Equal(t, ChecksumSHA256, info.ChecksumAlgorithm)
+	assert.Equal(t, ChecksumSHA256.Checksum(testFileContent), info.Checksum)
+
+	_, err = client.UploadFile(ctx, testBucket, "plain.txt", testFileContent, nil)
+	require.NoError(t, err)
+	info, err = client.GetFileInfo(ctx, testBucket, "plain.txt")
+	require.NoError(t, err)
+	assert.Equal(t, ChecksumNone, info.ChecksumAlgorithm)
+	assert.Empty(t, info.Checksum)
+}
+
+// TestS3Client_UploadReaderChecksum tests the checksum of an object written by
+// the uploader is read back from the stored object
+func TestS3Client_UploadReaderChecksum(t *testing.T) {
+	want := ChecksumSHA256.Checksum(testFileContent)
+	var headMode string
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		header := http.Header{"Etag": []string{`"etag"`}}
+		if req.Method == http.MethodHead {
+			headMode = req.Header.Get("X-Amz-Checksum-Mode")
+			header.Set("X-Amz-Checksum-Sha256", want)
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     header,
+			Body:       http.NoBody,
+			Request:    req,
+		}, nil
+	})
+
+	result, err := client.uploadReader(context.Background(), testBucket, testFileName, bytes.NewReader(testFileContent), &UploadOptions{ChecksumAlgorithm: ChecksumSHA256})
+	require.NoError(t, err)
+	assert.Equal(t, "ENABLED", headMode)
+	assert.Equal(t, ChecksumSHA256, result.ChecksumAlgorithm)
+	assert.Equal(t, want, result.Checksum)
+}
+
+// TestChecksumAlgorithm_Checksum tests checksums against known values
+func TestChecksumAlgorithm_Checksum(t *testing.T) {
+	tests := []struct {
+		algorithm ChecksumAlgorithm
+		want      string
+	}{
+		{ChecksumCRC32, "NhCmhg=="},
+		{ChecksumCRC32C, "mnG7TA=="},
+		{ChecksumSHA1, "qvTGHdzF6KLavt4PO0gs2a6pQ00="},
+		{ChecksumSHA256, "LPJNul+wow4m6DsqxbninhsWHlwfp0JecwQzYpOLmCQ="},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.algorithm), func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.algorithm.Checksum([]byte("hello")))
+		})
+	}
+}
+
+// TestNewChecksumVerifier tests which stored values are verified and that
+// mismatches are reported
+func TestNewChecksumVerifier(t *testing.T) {
+	md5Sum := md5.Sum(testFileContent)
+	etag := `"` + hex.EncodeToString(md5Sum[:]) + `"`
+	sha := aws.String(ChecksumSHA256.Checksum(testFileContent))
+
+	tests := []struct {
+		name     string
+		verifier *checksumVerifier
+		wantNil  bool
+		wantErr  error
+	}{
+		{
+			name:     "SHA256 match",
+			verifier: newChecksumVerifier(sha, nil, nil, nil, etag, true),
+		},
+		{
+			name:     "CRC32C mismatch",
+			verifier: newChecksumVerifier(nil, aws.String(ChecksumCRC32C.Checksum([]byte("other"))), nil, nil, etag, true),
+			wantErr:  ErrChecksumMismatch,
+		},
+		{
+			name:     "MD5 ETag match",
+			verifier: newChecksumVerifier(nil, nil, nil, nil, etag, true),
+		},
+		{
+			name:     "MD5 ETag mismatch",
+			verifier: newChecksumVerifier(nil, nil, nil, nil, `"00000000000000000000000000000000"`, true),
+			wantErr:  ErrChecksumMismatch,
+		},
+		{
+			name:     "Multipart ETag",
+			verifier: newChecksumVerifier(nil, nil, nil, nil, `"0123456789abcdef0123456789abcdef-3"`, true),
+			wantNil:  true,
+		},
+		{
+			name:     "Composite checksum",
+			verifier: newChecksumVerifier(aws.String("abc=-3"), nil, nil, nil, etag, true),
+			wantNil:  true,
+		},
+		{
+			name:     "KMS encrypted ETag",
+			verifier: newChecksumVerifier(nil, nil, nil, nil, etag, etagIsMD5(aws.String("aws:kms"), nil)),
+			wantNil:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantNil {
+				assert.Nil(t, tt.verifier)
+				return
+			}
+			require.NotNil(t, tt.verifier)
+			tt.verifier.Write(testFileContent)
+			err := tt.verifier.verify()
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+// TestS3Client_DownloadStreamVerifyChecksum tests a corrupted stream fails
+// on its final read
+func TestS3Client_DownloadStreamVerifyChecksum(t *testing.T) {
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"X-Amz-Checksum-Sha256": []string{ChecksumSHA256.Checksum([]byte("other"))}},
+			Body:       io.NopCloser(bytes.NewReader(testFileContent)),
+			Request:    req,
+		}, nil
+	})
+
+	body, _, err := client.DownloadStreamWithOptions(context.Background(), testBucket, testFileName, &DownloadOptions{VerifyChecksum: true})
+	require.NoError(t, err)
+	defer body.Close()
+	_, err = io.ReadAll(body)
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+}
@@ -0,0 +1,212 @@
+package s3lib
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// MaxCopyPartSize is the largest range a single UploadPartCopy can copy.
+// Larger sources are copied as several parts.
+const MaxCopyPartSize int64 = 5 * 1024 * 1024 * 1024
+
+// copyPart is one UploadPartCopy of a composed object
+type copyPart struct {
+	source string // URL-encoded bucket/key
+	etag   string // source ETag, so a source replaced mid-way fails the copy
+	rng    string // byte range, empty to copy the whole source
+}
+
+// ComposeObjects concatenates sourceKeys, in order, into destKey without
+// downloading them, using a multipart upload with one UploadPartCopy per
+// source (or per 5GB of a larger source). Every source except the last must
+// be at least 5MB, S3's minimum part size. If any step fails the multipart
+// upload is aborted so no orphaned parts remain.
+func (c *S3Client) ComposeObjects(ctx context.Context, bucket, destKey string, sourceKeys []string, opts *UploadOptions) (*UploadResult, error) {
+	if bucket == "" {
+		return nil, ErrInvalidBucket
+	}
+	if len(sourceKeys) == 0 {
+		return nil, fmt.Errorf("%w: no source keys to compose", ErrInvalidOptions)
+	}
+	if opts != nil && (opts.ComputeMD5 || opts.Compress || opts.SSECustomerKey != nil || opts.SkipIfUnchanged) {
+		return nil, fmt.Errorf("%w: ComputeMD5, Compress, SSECustomerKey and SkipIfUnchanged are not supported when composing objects", ErrInvalidOptions)
+	}
+	destKey, err := c.resolveKey(destKey)
+	if err != nil {
+		return nil, err
+	}
+	input, err := newUploadInput(bucket, destKey, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Plan every part before starting the upload so invalid sources do not
+	// leave an upload to clean up
+	parts, err := c.planCopyParts(ctx, bucket, sourceKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := c.s3Client.CreateMultipartUploadWithContext(ctx, createMultipartUploadInput(input), opts.requestOptions()...)
+	if err != nil {
+		return nil, multipartError(err, "failed to create multipart upload")
+	}
+	uploadID := aws.StringValue(created.UploadId)
+
+	result, err := c.copyParts(ctx, bucket, destKey, uploadID, parts, 1, opts)
+	if err != nil {
+		// Abort even if ctx was cancelled, which is a common cause of failure
+		if abortErr := c.abortMultipartUpload(context.WithoutCancel(ctx), bucket, destKey, uploadID); abortErr != nil {
+			return nil, fmt.Errorf("%w (abort of upload %s also failed: %v)", err, uploadID, abortErr)
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+// planCopyParts looks up each source and splits it into copy parts
+func (c *S3Client) planCopyParts(ctx context.Context, bucket string, sourceKeys []string) ([]copyPart, error) {
+	var parts []copyPart
+	for i, key := range sourceKeys {
+		info, err := c.GetFileInfo(ctx, bucket, key)
+		if err != nil {
+			return nil, fmt.Errorf("source %s: %w", key, err)
+		}
+		size := info.Size
+		if key, err = c.resolveKey(key); err != nil {
+			return nil, err
+		}
+		if i < len(sourceKeys)-1 && size < MinPartSize {
+			return nil, fmt.Errorf("%w: source %s is %d bytes; every source but the last must be at least %d bytes", ErrInvalidOptions, key, size, MinPartSize)
+		}
+
+		source := copySource(bucket, key, "")
+		etag := info.ETag
+		if size <= MaxCopyPartSize {
+			parts = append(parts, copyPart{source: source, etag: etag})
+			continue
+		}
+		// Split evenly so no range falls below the minimum part size
+		n := (size + MaxCopyPartSize - 1) / MaxCopyPartSize
+		chunk := (size + n - 1) / n
+		for start := int64(0); start < size; start += chunk {
+			end := min(start+chunk, size) - 1
+			parts = append(parts, copyPart{source: source, etag: etag, rng: fmt.Sprintf("bytes=%d-%d", start, end)})
+		}
+	}
+
+	if len(parts) > s3manager.MaxUploadParts {
+		return nil, fmt.Errorf("%w: composing needs %d parts, limit is %d", ErrInvalidOptions, len(parts), s3manager.MaxUploadParts)
+	}
+	return parts, nil
+}
+
+// copyParts copies each part into the multipart upload, concurrency parts
+// at a time, and completes it. The first failed part stops the rest.
+func (c *S3Client) copyParts(ctx context.Context, bucket, key, uploadID string, parts []copyPart, concurrency int, opts *UploadOptions) (*UploadResult, error) {
+	partCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	completed := make([]*s3.CompletedPart, len(parts))
+	var (
+		mu       sync.Mutex
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	sem := make(chan struct{}, max(concurrency, 1))
+	for i, part := range parts {
+		select {
+		case sem <- struct{}{}:
+		case <-partCtx.Done():
+		}
+		if partCtx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			copied, err := c.copyPart(partCtx, bucket, key, uploadID, int64(i+1), part)
+			if err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+				cancel()
+				return
+			}
+			completed[i] = copied
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	out, err := c.s3Client.CompleteMultipartUploadWithContext(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(bucket),
+		Key:             aws.String(key),
+		UploadId:        aws.String(uploadID),
+		MultipartUpload: &s3.CompletedMultipartUpload{Parts: completed},
+	}, opts.requestOptions()...)
+	if err != nil {
+		return nil, uploadError(err)
+	}
+
+	result := &UploadResult{
+		Key:        c.relativeKey(key),
+		Location:   c.objectURL(bucket, key),
+		ETag:       aws.StringValue(out.ETag),
+		VersionID:  aws.StringValue(out.VersionId),
+		Expiration: aws.StringValue(out.Expiration),
+	}
+	result.setChecksum(out.ChecksumSHA256, out.ChecksumCRC32C, out.ChecksumCRC32, out.ChecksumSHA1)
+	return result, nil
+}
+
+// copyPart copies one part into the multipart upload
+func (c *S3Client) copyPart(ctx context.Context, bucket, key, uploadID string, partNumber int64, part copyPart) (*s3.CompletedPart, error) {
+	input := &s3.UploadPartCopyInput{
+		Bucket:            aws.String(bucket),
+		Key:               aws.String(key),
+		UploadId:          aws.String(uploadID),
+		PartNumber:        aws.Int64(partNumber),
+		CopySource:        aws.String(part.source),
+		CopySourceIfMatch: aws.String(part.etag),
+	}
+	if part.rng != "" {
+		input.CopySourceRange = aws.String(part.rng)
+	}
+
+	out, err := withRetry(ctx, c.config.MaxRetries, func() (*s3.UploadPartCopyOutput, error) {
+		return c.s3Client.UploadPartCopyWithContext(ctx, input)
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == "PreconditionFailed" {
+			return nil, fmt.Errorf("%w: source of part %d", ErrObjectModified, partNumber)
+		}
+		return nil, multipartError(err, fmt.Sprintf("failed to copy part %d", partNumber))
+	}
+	copied := out.CopyPartResult
+	if copied == nil {
+		copied = &s3.CopyPartResult{}
+	}
+	return &s3.CompletedPart{
+		ETag:           copied.ETag,
+		PartNumber:     aws.Int64(partNumber),
+		ChecksumCRC32:  copied.ChecksumCRC32,
+		ChecksumCRC32C: copied.ChecksumCRC32C,
+		ChecksumSHA1:   copied.ChecksumSHA1,
+		ChecksumSHA256: copied.ChecksumSHA256,
+	}, nil
+}
@@ -0,0 +1,113 @@
+package s3lib
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// composeTransport fakes the S3 calls made by ComposeObjects. sizes maps
+// source keys to their length and copyStatus is returned for part copies.
+func composeTransport(sizes map[string]int64, copyStatus int, mu *sync.Mutex, calls *[]string) roundTripFunc {
+	return func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		*calls = append(*calls, req.Method+" "+req.URL.Path+"?"+req.URL.RawQuery)
+		mu.Unlock()
+
+		resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: req}
+		key := strings.TrimPrefix(req.URL.Path, "/"+testBucket+"/")
+		switch {
+		case req.Method == http.MethodHead:
+			size, ok := sizes[key]
+			if !ok {
+				return fakeErrorResponse(req, http.StatusNotFound, "NotFound"), nil
+			}
+			resp.Header.Set("Content-Length", strconv.FormatInt(size, 10))
+			resp.Header.Set("ETag", `"`+key+`"`)
+		case req.Method == http.MethodPost && req.URL.Query().Has("uploads"):
+			resp.Body = xmlBody("<InitiateMultipartUploadResult><UploadId>compose-id</UploadId></InitiateMultipartUploadResult>")
+		case req.Method == http.MethodPut && copyStatus != http.StatusOK:
+			return fakeErrorResponse(req, copyStatus, "AccessDenied"), nil
+		case req.Method == http.MethodPut:
+			resp.Body = xmlBody(`<CopyPartResult><ETag>"part"</ETag></CopyPartResult>`)
+		case req.Method == http.MethodPost:
+			resp.Body = xmlBody(`<CompleteMultipartUploadResult><Location>composed</Location><ETag>"composed-2"</ETag></CompleteMultipartUploadResult>`)
+		}
+		return resp, nil
+	}
+}
+
+// TestS3Client_ComposeObjects tests parts are copied in order and completed
+func TestS3Client_ComposeObjects(t *testing.T) {
+	var mu sync.Mutex
+	var calls []string
+	client := setupFakeClient(t, composeTransport(map[string]int64{"a.log": MinPartSize, "b.log": 10}, http.StatusOK, &mu, &calls))
+
+	result, err := client.ComposeObjects(context.Background(), testBucket, "daily.log", []string{"a.log", "b.log"}, nil)
+	if assert.NoError(t, err) {
+		assert.Equal(t, `"composed-2"`, result.ETag)
+	}
+	assert.Contains(t, calls, "PUT /"+testBucket+"/daily.log?partNumber=2&uploadId=compose-id")
+}
+
+// TestS3Client_ComposeObjectsAbort tests a failed part copy aborts the upload
+func TestS3Client_ComposeObjectsAbort(t *testing.T) {
+	var mu sync.Mutex
+	var calls []string
+	client := setupFakeClient(t, composeTransport(map[string]int64{"a.log": MinPartSize, "b.log": 10}, http.StatusForbidden, &mu, &calls))
+
+	_, err := client.ComposeObjects(context.Background(), testBucket, "daily.log", []string{"a.log", "b.log"}, nil)
+	assert.Error(t, err)
+	assert.Contains(t, calls, "DELETE /"+testBucket+"/daily.log?uploadId=compose-id")
+}
+
+// TestS3Client_ComposeObjectsValidation tests invalid requests fail before
+// a multipart upload is created
+func TestS3Client_ComposeObjectsValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		sources []string
+		opts    *UploadOptions
+		wantErr error
+	}{
+		{
+			name:    "No sources",
+			wantErr: ErrInvalidOptions,
+		},
+		{
+			name:    "Small source before the last",
+			sources: []string{"b.log", "a.log"},
+			wantErr: ErrInvalidOptions,
+		},
+		{
+			name:    "Missing source",
+			sources: []string{"a.log", "missing.log"},
+			wantErr: ErrFileNotFound,
+		},
+		{
+			name:    "Unsupported option",
+			sources: []string{"a.log"},
+			opts:    &UploadOptions{Compress: true},
+			wantErr: ErrInvalidOptions,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var calls []string
+			client := setupFakeClient(t, composeTransport(map[string]int64{"a.log": MinPartSize, "b.log": 10}, http.StatusOK, &mu, &calls))
+
+			_, err := client.ComposeObjects(context.Background(), testBucket, "daily.log", tt.sources, tt.opts)
+			assert.ErrorIs(t, err, tt.wantErr)
+			for _, call := range calls {
+				assert.True(t, strings.HasPrefix(call, http.MethodHead), "unexpected request %s", call)
+			}
+		})
+	}
+}
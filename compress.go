@@ -0,0 +1,66 @@
+package s3lib
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// gzipEncoding is the Content-Encoding of objects uploaded with Compress
+const gzipEncoding = "gzip"
+
+// gzipReader returns a reader yielding the gzip compressed contents of r.
+// Compression runs in a goroutine feeding a pipe, so only the uploader's part
+// buffers are held in memory. Closing the reader stops the goroutine.
+func gzipReader(r io.Reader) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		zw := gzip.NewWriter(pw)
+		_, err := io.Copy(zw, r)
+		if closeErr := zw.Close(); err == nil {
+			err = closeErr
+		}
+		pw.CloseWithError(err)
+	}()
+	return pr
+}
+
+// gunzip decompresses a gzip encoded payload
+func gunzip(data []byte) ([]byte, error) {
+	return gunzipLimit(data, 0)
+}
+
+// gunzipLimit decompresses a gzip encoded payload, failing with
+// ErrObjectTooLarge once the output exceeds limit bytes (0 = unlimited)
+func gunzipLimit(data []byte, limit int64) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDecompressionFailed, err)
+	}
+	defer zr.Close()
+
+	var r io.Reader = zr
+	if limit > 0 {
+		r = &sizeLimitReader{r: zr, limit: limit}
+	}
+	out, err := io.ReadAll(r)
+	if errors.Is(err, ErrObjectTooLarge) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDecompressionFailed, err)
+	}
+	return out, nil
+}
+
+// isGzipError reports whether err was raised by the gzip decoder itself
+// rather than by the reader feeding it. io.ErrUnexpectedEOF is left out: it
+// is just as likely a connection cut short as a truncated stream.
+func isGzipError(err error) bool {
+	var corrupt flate.CorruptInputError
+	return errors.Is(err, gzip.ErrHeader) || errors.Is(err, gzip.ErrChecksum) ||
+		errors.As(err, &corrupt)
+}
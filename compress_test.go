@@ -0,0 +1,169 @@
+package s3lib
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGzipReader tests on-the-fly compression round trips through gunzip
+func TestGzipReader(t *testing.T) {
+	data := []byte(strings.Repeat("log line 42\n", 1000))
+
+	zr := gzipReader(bytes.NewReader(data))
+	compressed, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	require.NoError(t, zr.Close())
+	assert.Less(t, len(compressed), len(data))
+
+	got, err := gunzip(compressed)
+	require.NoError(t, err)
+	assert.Equal(t, data, got)
+
+	_, err = gunzip([]byte("not gzip"))
+	assert.ErrorIs(t, err, ErrDecompressionFailed)
+
+	_, err = gunzipLimit(compressed, int64(len(data))-1)
+	assert.ErrorIs(t, err, ErrObjectTooLarge)
+
+	got, err = gunzipLimit(compressed, int64(len(data)))
+	require.NoError(t, err)
+	assert.Equal(t, data, got)
+}
+
+// TestGzipReaderClose tests closing the reader early stops compression
+func TestGzipReaderClose(t *testing.T) {
+	zr := gzipReader(io.LimitReader(&patternReader{}, 64*1024*1024))
+	buf := make([]byte, 16)
+	_, err := zr.Read(buf)
+	require.NoError(t, err)
+	require.NoError(t, zr.Close())
+}
+
+// TestS3Client_UploadFileCompress tests the compressed body and encoding header
+func TestS3Client_UploadFileCompress(t *testing.T) {
+	var encoding string
+	var body []byte
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		encoding = req.Header.Get("Content-Encoding")
+		body, _ = io.ReadAll(req.Body)
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: req}, nil
+	})
+	ctx := context.Background()
+
+	_, err := client.UploadFile(ctx, testBucket, "app.log", testFileContent, &UploadOptions{Compress: true})
+	require.NoError(t, err)
+	assert.Equal(t, "gzip", encoding)
+
+	zr, err := gzip.NewReader(bytes.NewReader(body))
+	require.NoError(t, err)
+	got, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	assert.Equal(t, testFileContent, got)
+
+	_, err = client.UploadFile(ctx, testBucket, "app.log", testFileContent, &UploadOptions{Compress: true, ContentEncoding: "br"})
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+}
+
+// TestS3Client_DownloadFileDecompress tests gzip encoded objects round trip
+// with AutoDecompress and are returned as stored by default
+func TestS3Client_DownloadFileDecompress(t *testing.T) {
+	objects := map[string]*memoryObject{}
+	client := setupFakeClient(t, memoryServer(objects))
+	ctx := context.Background()
+
+	data := []byte(strings.Repeat("compressible ", 500))
+	_, err := client.UploadFile(ctx, testBucket, "test-compressed.log", data, &UploadOptions{Compress: true})
+	require.NoError(t, err)
+	require.Contains(t, objects, "test-compressed.log")
+	assert.Equal(t, gzipEncoding, objects["test-compressed.log"].header.Get("Content-Encoding"))
+	assert.Less(t, len(objects["test-compressed.log"].data), len(data))
+
+	result, err := client.DownloadFileResult(ctx, testBucket, "test-compressed.log", &DownloadOptions{AutoDecompress: true})
+	require.NoError(t, err)
+	assert.Equal(t, data, result.Data)
+	assert.True(t, result.Decompressed)
+
+	raw, err := client.DownloadFile(ctx, testBucket, "test-compressed.log")
+	require.NoError(t, err)
+	assert.Equal(t, objects["test-compressed.log"].data, raw)
+	decompressed, err := gunzip(raw)
+	require.NoError(t, err)
+	assert.Equal(t, data, decompressed)
+}
+
+// corruptGzipServer serves a gzip encoded object whose CRC-32 trailer is damaged
+func corruptGzipServer(t *testing.T) roundTripFunc {
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	_, err := zw.Write([]byte(strings.Repeat("compressible ", 500)))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	corrupt := compressed.Bytes()
+	corrupt[len(corrupt)-6] ^= 0xff
+
+	return func(req *http.Request) (*http.Response, error) {
+		body := io.NopCloser(bytes.NewReader(corrupt))
+		if req.Method == http.MethodHead {
+			body = http.NoBody
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header: http.Header{
+				"Content-Encoding": []string{gzipEncoding},
+				"Content-Length":   []string{strconv.Itoa(len(corrupt))},
+				"Etag":             []string{`"abc"`},
+			},
+			ContentLength: int64(len(corrupt)),
+			Body:          body,
+			Request:       req,
+		}, nil
+	}
+}
+
+// TestS3Client_DownloadStreamCorruptGzip tests a corrupt gzip body fails with
+// ErrDecompressionFailed rather than an S3 error
+func TestS3Client_DownloadStreamCorruptGzip(t *testing.T) {
+	client := setupFakeClient(t, corruptGzipServer(t))
+
+	body, _, err := client.DownloadStreamWithOptions(context.Background(), testBucket, testFileName, &DownloadOptions{AutoDecompress: true})
+	require.NoError(t, err)
+	defer body.Close()
+	_, err = io.ReadAll(body)
+	assert.ErrorIs(t, err, ErrDecompressionFailed)
+}
+
+// TestS3Client_DownloadToFileCorruptGzip tests a corrupt gzip body fails the
+// download without leaving a file behind
+func TestS3Client_DownloadToFileCorruptGzip(t *testing.T) {
+	client := setupFakeClient(t, corruptGzipServer(t))
+	dir := t.TempDir()
+	localPath := filepath.Join(dir, "out.log")
+
+	_, err := client.DownloadToFileWithOptions(context.Background(), testBucket, testFileName, localPath, &DownloadOptions{AutoDecompress: true})
+	assert.ErrorIs(t, err, ErrDecompressionFailed)
+	assert.NoFileExists(t, localPath)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+// TestIsGzipError tests decoder errors are told apart from transport errors
+func TestIsGzipError(t *testing.T) {
+	assert.True(t, isGzipError(gzip.ErrChecksum))
+	assert.True(t, isGzipError(flate.CorruptInputError(3)))
+	assert.False(t, isGzipError(context.Canceled))
+	assert.False(t, isGzipError(io.ErrUnexpectedEOF))
+}
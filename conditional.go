@@ -0,0 +1,53 @@
+package s3lib
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// withIfNoneMatch is a request option that makes PutObject and
+// CompleteMultipartUpload fail with 412 Precondition Failed when the key
+// already exists, so the object is only created if absent
+func withIfNoneMatch(r *request.Request) {
+	r.Handlers.Build.PushBack(func(r *request.Request) {
+		if r.Operation == nil || (r.Operation.Name != "PutObject" && r.Operation.Name != "CompleteMultipartUpload") {
+			return
+		}
+		r.HTTPRequest.Header.Set("If-None-Match", "*")
+	})
+}
+
+// ensureAbsent implements UploadOptions.IfNoneMatch on backends without
+// conditional PUT support (Config.DisableConditionalPut) by checking for the
+// key with HeadObject first. Another writer can still create the key between
+// the check and the upload. It returns the options to upload with.
+func (c *S3Client) ensureAbsent(ctx context.Context, bucket, key string, opts *UploadOptions) (*UploadOptions, error) {
+	if opts == nil || !opts.IfNoneMatch || !c.config.DisableConditionalPut {
+		return opts, nil
+	}
+	if bucket == "" {
+		return nil, ErrInvalidBucket
+	}
+	if key == "" {
+		return nil, ErrInvalidKey
+	}
+
+	_, err := c.s3Client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err == nil {
+		return nil, ErrObjectExists
+	}
+	if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != "NotFound" {
+		return nil, uploadError(err)
+	}
+
+	unconditional := *opts
+	unconditional.IfNoneMatch = false
+	return &unconditional, nil
+}
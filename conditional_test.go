@@ -0,0 +1,73 @@
+package s3lib
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestS3Client_UploadFileIfNoneMatch tests conditional PUT headers and the
+// mapping of a failed precondition
+func TestS3Client_UploadFileIfNoneMatch(t *testing.T) {
+	var ifNoneMatch string
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		ifNoneMatch = req.Header.Get("If-None-Match")
+		return fakeErrorResponse(req, http.StatusPreconditionFailed, "PreconditionFailed"), nil
+	})
+
+	_, err := client.UploadFile(context.Background(), testBucket, testFileName, testFileContent, &UploadOptions{IfNoneMatch: true})
+	assert.ErrorIs(t, err, ErrObjectExists)
+	assert.Equal(t, "*", ifNoneMatch)
+}
+
+// TestS3Client_UploadFileIfNoneMatchFallback tests the HeadObject pre-check
+// used when conditional PUT is disabled
+func TestS3Client_UploadFileIfNoneMatchFallback(t *testing.T) {
+	tests := []struct {
+		name       string
+		headStatus int
+		wantErr    error
+		wantPut    bool
+	}{
+		{
+			name:       "Key exists",
+			headStatus: http.StatusOK,
+			wantErr:    ErrObjectExists,
+		},
+		{
+			name:       "Key absent",
+			headStatus: http.StatusNotFound,
+			wantPut:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var put bool
+			cfg := testConfig
+			cfg.Endpoint = "http://s3.fake.local"
+			cfg.DisableConditionalPut = true
+			cfg.HTTPClient = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				if req.Method == http.MethodHead {
+					return &http.Response{StatusCode: tt.headStatus, Header: http.Header{}, Body: http.NoBody, Request: req}, nil
+				}
+				put = true
+				assert.Empty(t, req.Header.Get("If-None-Match"))
+				return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: req}, nil
+			})}
+			client, err := NewS3Client(cfg)
+			require.NoError(t, err)
+
+			_, err = client.UploadFile(context.Background(), testBucket, testFileName, testFileContent, &UploadOptions{IfNoneMatch: true})
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantPut, put)
+		})
+	}
+}
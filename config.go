@@ -1,16 +1,109 @@
 package s3lib
 
-import "time"
+import (
+    "fmt"
+    "net/http"
+    "time"
+)
+
+// DefaultSinglePartThreshold is the payload size below which UploadFile uses
+// a single PutObject request instead of the multipart uploader
+const DefaultSinglePartThreshold int64 = 5 * 1024 * 1024
+
+// DefaultDownloadSpillThreshold is the content size above which DownloadAuto
+// writes an object to a temporary file instead of holding it in memory
+const DefaultDownloadSpillThreshold int64 = 32 * 1024 * 1024
+
+// MinPartSize is the smallest part size S3 accepts for multipart uploads
+const MinPartSize int64 = 5 * 1024 * 1024
 
 // Config holds the configuration for S3Client
 type Config struct {
     Region    string
     AccessKey string
     SecretKey string
-    Duration  time.Duration
+    Duration  time.Duration // Optional: default expiry of presigned download URLs
     Endpoint  string        // Optional: for S3-compatible services
     UseSSL    bool         // Optional: use HTTPS
     Debug     bool         // Optional: enable debug logging
+
+    // Optional: payloads smaller than this are uploaded with a single
+    // PutObject request (default DefaultSinglePartThreshold)
+    SinglePartThreshold int64
+
+    // Optional: multipart upload tuning passed to the s3manager uploader.
+    // UploadPartSize must be at least 5MB (S3's minimum part size).
+    UploadPartSize    int64
+    UploadConcurrency int
+
+    // Optional: ranged download tuning passed to the s3manager downloader
+    // (defaults 5MB parts and 5 concurrent requests)
+    DownloadPartSize    int64
+    DownloadConcurrency int
+
+    // Optional: HTTP client used for all requests (proxies, custom transports)
+    HTTPClient *http.Client
+
+    // Optional: 32-byte key enabling client-side AES-256-GCM encryption of
+    // UploadFile, UploadFileResult and UploadFiles payloads and transparent
+    // decryption in DownloadFile. Uploads that stream their payload, such as
+    // UploadStream, UploadFromFile or NewWriter, cannot encrypt it and fail
+    // with ErrInvalidOptions while it is set.
+    ClientSideKey []byte
+
+    // Optional: list with the original ListObjects API instead of
+    // ListObjectsV2, for S3-compatible backends known not to support it.
+    // Without it the client falls back to ListObjects the first time a
+    // backend rejects ListObjectsV2 as not implemented.
+    ForceListObjectsV1 bool
+
+    // Optional: set for S3-compatible backends without conditional PUT
+    // support. UploadOptions.IfNoneMatch then checks for the key with a
+    // HeadObject request first, which cannot rule out a concurrent writer.
+    DisableConditionalPut bool
+
+    // Optional: largest object in bytes the client will upload (0 = unlimited)
+    MaxUploadSize int64
+
+    // Optional: largest object in bytes the client will download (0 =
+    // unlimited). Gzip encoded objects downloaded with
+    // DownloadOptions.AutoDecompress are limited by their decompressed size.
+    MaxDownloadSize int64
+
+    // Optional: directory caching DownloadFile results across restarts.
+    // Cached copies are revalidated with S3 on every download and served
+    // from disk when unchanged. CacheMaxSize bounds the directory in bytes,
+    // evicting least recently used objects first (0 = unlimited).
+    CacheDir     string
+    CacheMaxSize int64
+
+    // Optional: objects whose content exceeds this many bytes are spilled to
+    // a temporary file by DownloadAuto (default DefaultDownloadSpillThreshold)
+    DownloadSpillThreshold int64
+
+    // Optional: strip leading slashes from object keys and convert
+    // backslashes (e.g. from Windows paths) to forward slashes
+    NormalizeKeys bool
+
+    // Optional: times a failed write (UploadFile, UploadFromFile, DeleteFile)
+    // is retried with exponential backoff when S3 throttles, returns a 5xx or
+    // the network fails. This is on top of the SDK's own request retries.
+    // Downloads interrupted mid-body are resumed up to this many times.
+    MaxRetries int
+
+    // Optional: aggregate upload rate in bytes per second shared by all
+    // uploads and parts in flight on the client (0 = unlimited)
+    UploadBandwidthLimit int64
+
+    // Optional: send x-amz-request-payer: requester on every request so
+    // Requester Pays buckets can be used. Override per call with
+    // WithRequestPayer.
+    RequestPayer bool
+
+    // Optional: namespace prepended to every object key, e.g. "tenants/42/".
+    // Keys returned by the client are relative to it and listings never
+    // reach outside it. See also S3Client.WithPrefix.
+    KeyPrefix string
 }
 
 // Validate checks if the configuration is valid
@@ -24,5 +117,62 @@ func (c *Config) Validate() error {
     if c.SecretKey == "" {
         return ErrInvalidConfig
     }
+    if c.SinglePartThreshold < 0 {
+        return fmt.Errorf("%w: SinglePartThreshold must not be negative", ErrInvalidConfig)
+    }
+    if c.UploadPartSize != 0 && c.UploadPartSize < MinPartSize {
+        return fmt.Errorf("%w: UploadPartSize %d is below the S3 minimum part size of %d bytes", ErrInvalidConfig, c.UploadPartSize, MinPartSize)
+    }
+    if c.UploadConcurrency < 0 {
+        return fmt.Errorf("%w: UploadConcurrency must not be negative", ErrInvalidConfig)
+    }
+    if c.DownloadPartSize < 0 {
+        return fmt.Errorf("%w: DownloadPartSize must not be negative", ErrInvalidConfig)
+    }
+    if c.DownloadConcurrency < 0 {
+        return fmt.Errorf("%w: DownloadConcurrency must not be negative", ErrInvalidConfig)
+    }
+    if c.MaxRetries < 0 {
+        return fmt.Errorf("%w: MaxRetries must not be negative", ErrInvalidConfig)
+    }
+    if c.KeyPrefix != "" {
+        if err := validateKey(c.KeyPrefix); err != nil {
+            return fmt.Errorf("%w: KeyPrefix: %w", ErrInvalidConfig, err)
+        }
+    }
+    if c.UploadBandwidthLimit < 0 {
+        return fmt.Errorf("%w: UploadBandwidthLimit must not be negative", ErrInvalidConfig)
+    }
+    if c.MaxUploadSize < 0 {
+        return fmt.Errorf("%w: MaxUploadSize must not be negative", ErrInvalidConfig)
+    }
+    if c.MaxDownloadSize < 0 {
+        return fmt.Errorf("%w: MaxDownloadSize must not be negative", ErrInvalidConfig)
+    }
+    if c.CacheMaxSize < 0 {
+        return fmt.Errorf("%w: CacheMaxSize must not be negative", ErrInvalidConfig)
+    }
+    if c.DownloadSpillThreshold < 0 {
+        return fmt.Errorf("%w: DownloadSpillThreshold must not be negative", ErrInvalidConfig)
+    }
+    if c.ClientSideKey != nil && len(c.ClientSideKey) != ClientSideKeySize {
+        return fmt.Errorf("%w: ClientSideKey must be %d bytes", ErrInvalidConfig, ClientSideKeySize)
+    }
     return nil
-}
\ No newline at end of file
+}
+
+// singlePartThreshold returns the configured threshold or the default
+func (c *Config) singlePartThreshold() int64 {
+    if c.SinglePartThreshold > 0 {
+        return c.SinglePartThreshold
+    }
+    return DefaultSinglePartThreshold
+}
+
+// downloadSpillThreshold returns the configured threshold or the default
+func (c *Config) downloadSpillThreshold() int64 {
+    if c.DownloadSpillThreshold > 0 {
+        return c.DownloadSpillThreshold
+    }
+    return DefaultDownloadSpillThreshold
+}
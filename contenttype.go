@@ -0,0 +1,68 @@
+package s3lib
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+)
+
+// sniffLen is the number of leading bytes http.DetectContentType considers
+const sniffLen = 512
+
+// detectContentType returns the content type for key, from its extension
+// when known and otherwise by sniffing the leading bytes of the payload
+func detectContentType(key string, head []byte) string {
+	if ct := mime.TypeByExtension(path.Ext(key)); ct != "" {
+		return ct
+	}
+	if len(head) > sniffLen {
+		head = head[:sniffLen]
+	}
+	return http.DetectContentType(head)
+}
+
+// withDetectedContentType returns opts, or a copy of it with a detected
+// ContentType when none was given and detection is not disabled
+func withDetectedContentType(opts *UploadOptions, key string, head []byte) *UploadOptions {
+	if opts != nil && (opts.ContentType != "" || opts.DisableContentTypeDetection) {
+		return opts
+	}
+
+	var detected UploadOptions
+	if opts != nil {
+		detected = *opts
+	}
+	detected.ContentType = detectContentType(key, head)
+	return &detected
+}
+
+// peekReader reads up to n leading bytes of r for content sniffing and
+// returns a reader that still yields the whole stream. Seekable readers are
+// rewound rather than wrapped so the uploader can still size them.
+func peekReader(r io.Reader, n int) ([]byte, io.Reader, error) {
+	rs, seekable := r.(io.ReadSeeker)
+	var start int64
+	if seekable {
+		var err error
+		if start, err = rs.Seek(0, io.SeekCurrent); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	head := make([]byte, n)
+	m, err := io.ReadFull(r, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, nil, err
+	}
+	head = head[:m]
+
+	if seekable {
+		if _, err := rs.Seek(start, io.SeekStart); err != nil {
+			return nil, nil, err
+		}
+		return head, r, nil
+	}
+	return head, io.MultiReader(bytes.NewReader(head), r), nil
+}
@@ -0,0 +1,103 @@
+package s3lib
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDetectContentType tests extension lookup and content sniffing
+func TestDetectContentType(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+		data []byte
+		want string
+	}{
+		{
+			name: "Extension",
+			key:  "images/logo.png",
+			data: []byte("not really a png"),
+			want: "image/png",
+		},
+		{
+			name: "Sniffed HTML",
+			key:  "index",
+			data: []byte("<!DOCTYPE html><html><body>hi</body></html>"),
+			want: "text/html; charset=utf-8",
+		},
+		{
+			name: "Sniffed binary",
+			key:  "blob",
+			data: []byte{0x00, 0x01, 0x02, 0x03},
+			want: "application/octet-stream",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, detectContentType(tt.key, tt.data))
+		})
+	}
+}
+
+// TestWithDetectedContentType tests explicit types and the opt-out are kept
+func TestWithDetectedContentType(t *testing.T) {
+	html := []byte("<html></html>")
+
+	got := withDetectedContentType(nil, "page", html)
+	assert.Equal(t, "text/html; charset=utf-8", got.ContentType)
+
+	opts := &UploadOptions{CacheControl: "no-cache"}
+	got = withDetectedContentType(opts, "page", html)
+	assert.Equal(t, "text/html; charset=utf-8", got.ContentType)
+	assert.Equal(t, "no-cache", got.CacheControl)
+	assert.Empty(t, opts.ContentType, "caller's options must not be modified")
+
+	opts = &UploadOptions{ContentType: "text/plain"}
+	assert.Same(t, opts, withDetectedContentType(opts, "page", html))
+
+	opts = &UploadOptions{DisableContentTypeDetection: true}
+	assert.Empty(t, withDetectedContentType(opts, "page", html).ContentType)
+}
+
+// TestPeekReader tests the peeked stream is still read in full
+func TestPeekReader(t *testing.T) {
+	data := strings.Repeat("abcdefgh", 100)
+
+	t.Run("Seekable", func(t *testing.T) {
+		r := bytes.NewReader([]byte(data))
+		head, body, err := peekReader(r, sniffLen)
+		require.NoError(t, err)
+		assert.Equal(t, data[:sniffLen], string(head))
+		assert.Same(t, r, body, "seekable readers must not be wrapped")
+
+		all, err := io.ReadAll(body)
+		require.NoError(t, err)
+		assert.Equal(t, data, string(all))
+	})
+
+	t.Run("Stream", func(t *testing.T) {
+		head, body, err := peekReader(io.NopCloser(strings.NewReader(data)), sniffLen)
+		require.NoError(t, err)
+		assert.Equal(t, data[:sniffLen], string(head))
+
+		all, err := io.ReadAll(body)
+		require.NoError(t, err)
+		assert.Equal(t, data, string(all))
+	})
+
+	t.Run("Short stream", func(t *testing.T) {
+		head, body, err := peekReader(io.NopCloser(strings.NewReader("tiny")), sniffLen)
+		require.NoError(t, err)
+		assert.Equal(t, "tiny", string(head))
+
+		all, err := io.ReadAll(body)
+		require.NoError(t, err)
+		assert.Equal(t, "tiny", string(all))
+	})
+}
@@ -0,0 +1,565 @@
+package s3lib
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// CopyOptions represents optional parameters for CopyFile
+type CopyOptions struct {
+	// SourceVersionID copies that version of the source instead of the
+	// current one
+	SourceVersionID string
+
+	// ReplaceMetadata replaces the metadata and content headers of the
+	// copy with the ones below instead of keeping the source's. Headers left
+	// empty are cleared; S3 defaults ContentType to binary/octet-stream.
+	ReplaceMetadata    bool
+	Metadata           map[string]string
+	ContentType        string
+	ContentDisposition string
+	CacheControl       string
+	ContentEncoding    string
+	ContentLanguage    string
+
+	// StorageClass and ACL of the copy; the copy gets S3's defaults if empty
+	StorageClass       StorageClass
+	ACL                ACL
+	AllowUnknownValues bool
+
+	// ServerSideEncryption and SSEKMSKeyID encrypt the copy like
+	// UploadOptions does
+	ServerSideEncryption string
+	SSEKMSKeyID          string
+
+	// PartSize is the size of each part when a source over 5GB is copied
+	// with a multipart upload (default 512MB), or when CopyBetween uploads
+	// the copy (default Config.UploadPartSize). It is raised if needed to
+	// stay within S3's part limit.
+	PartSize int64
+	// Concurrency is the number of parts copied at once (default 5, or
+	// Config.UploadConcurrency for CopyBetween)
+	Concurrency int
+
+	// Overwrite lets MoveFile replace an existing object at the destination.
+	// CopyFile always replaces the destination.
+	Overwrite bool
+}
+
+// defaultCopyPartSize is the part size of multipart copies when
+// CopyOptions.PartSize is not set
+const defaultCopyPartSize int64 = 512 * 1024 * 1024
+
+// validate checks the options against S3's constraints
+func (o *CopyOptions) validate() error {
+	if o == nil {
+		return nil
+	}
+	if !o.ReplaceMetadata && (o.Metadata != nil || o.ContentType != "" || o.ContentDisposition != "" ||
+		o.CacheControl != "" || o.ContentEncoding != "" || o.ContentLanguage != "") {
+		return fmt.Errorf("%w: metadata and content headers are only applied with ReplaceMetadata", ErrInvalidOptions)
+	}
+	if o.PartSize != 0 && (o.PartSize < MinPartSize || o.PartSize > MaxCopyPartSize) {
+		return fmt.Errorf("%w: copy part size must be between %d and %d bytes", ErrInvalidOptions, MinPartSize, MaxCopyPartSize)
+	}
+	if o.Concurrency < 0 {
+		return fmt.Errorf("%w: Concurrency must not be negative", ErrInvalidOptions)
+	}
+	if err := validateStorageClassAndACL(o.StorageClass, o.ACL, o.AllowUnknownValues); err != nil {
+		return err
+	}
+	return validateServerSideEncryption(o.ServerSideEncryption, o.SSEKMSKeyID)
+}
+
+// changes reports whether the options change anything about an object
+// copied onto itself, which S3 requires of such a copy
+func (o *CopyOptions) changes() bool {
+	return o != nil && (o.ReplaceMetadata || o.StorageClass != "" || o.ServerSideEncryption != "")
+}
+
+// CopyResult represents the object created by CopyFile
+type CopyResult struct {
+	Key             string    `json:"key"`
+	ETag            string    `json:"etag"`
+	VersionID       string    `json:"version_id,omitempty"`        // Set on versioned buckets
+	SourceVersionID string    `json:"source_version_id,omitempty"` // The version that was copied
+	LastModified    time.Time `json:"last_modified"`
+}
+
+// CopyFile copies an object server-side, without its data passing through
+// the client. Sources over 5GB, the limit of CopyObject, are copied in
+// parallel parts with a multipart upload, which is aborted if any part
+// fails. Tags are copied along with the data, and metadata too unless
+// opts.ReplaceMetadata is set. Copying an object onto itself is how its
+// metadata, storage class or encryption are changed in place, so it
+// requires opts to change one of them. If the source is replaced while it
+// is being copied, the copy fails with ErrObjectModified.
+func (c *S3Client) CopyFile(ctx context.Context, srcBucket, srcKey, dstBucket, dstKey string, opts *CopyOptions) (*CopyResult, error) {
+	if srcBucket == "" || dstBucket == "" {
+		return nil, ErrInvalidBucket
+	}
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	srcKey, err := c.resolveKey(srcKey)
+	if err != nil {
+		return nil, err
+	}
+	dstKey, err = c.resolveKey(dstKey)
+	if err != nil {
+		return nil, err
+	}
+	if srcBucket == dstBucket && srcKey == dstKey && !opts.changes() {
+		return nil, fmt.Errorf("%w: copying an object onto itself must change its metadata, storage class or encryption", ErrInvalidOptions)
+	}
+
+	var sourceVersion string
+	if opts != nil {
+		sourceVersion = opts.SourceVersionID
+	}
+	head, err := c.headCopySource(ctx, srcBucket, srcKey, sourceVersion)
+	if err != nil {
+		return nil, err
+	}
+	source := copySource(srcBucket, srcKey, sourceVersion)
+	if aws.Int64Value(head.ContentLength) > MaxCopyPartSize {
+		return c.multipartCopy(ctx, srcBucket, srcKey, source, head, dstBucket, dstKey, opts)
+	}
+
+	input := &s3.CopyObjectInput{
+		Bucket:            aws.String(dstBucket),
+		Key:               aws.String(dstKey),
+		CopySource:        aws.String(source),
+		CopySourceIfMatch: head.ETag,
+		MetadataDirective: aws.String(s3.MetadataDirectiveCopy),
+	}
+	if opts != nil {
+		if opts.ReplaceMetadata {
+			input.MetadataDirective = aws.String(s3.MetadataDirectiveReplace)
+			if opts.Metadata != nil {
+				input.Metadata = aws.StringMap(opts.Metadata)
+			}
+			if opts.ContentType != "" {
+				input.ContentType = aws.String(opts.ContentType)
+			}
+			if opts.ContentDisposition != "" {
+				input.ContentDisposition = aws.String(opts.ContentDisposition)
+			}
+			if opts.CacheControl != "" {
+				input.CacheControl = aws.String(opts.CacheControl)
+			}
+			if opts.ContentEncoding != "" {
+				input.ContentEncoding = aws.String(opts.ContentEncoding)
+			}
+			if opts.ContentLanguage != "" {
+				input.ContentLanguage = aws.String(opts.ContentLanguage)
+			}
+		}
+		if opts.StorageClass != "" {
+			input.StorageClass = aws.String(string(opts.StorageClass))
+		}
+		if opts.ACL != "" {
+			input.ACL = aws.String(string(opts.ACL))
+		}
+		if opts.ServerSideEncryption != "" {
+			input.ServerSideEncryption = aws.String(opts.ServerSideEncryption)
+		}
+		if opts.SSEKMSKeyID != "" {
+			input.SSEKMSKeyId = aws.String(opts.SSEKMSKeyID)
+		}
+	}
+
+	out, err := withRetry(ctx, c.config.MaxRetries, func() (*s3.CopyObjectOutput, error) {
+		return c.s3Client.CopyObjectWithContext(ctx, input)
+	})
+	if err != nil {
+		return nil, copyError(err)
+	}
+
+	result := &CopyResult{
+		Key:             c.relativeKey(dstKey),
+		VersionID:       aws.StringValue(out.VersionId),
+		SourceVersionID: aws.StringValue(out.CopySourceVersionId),
+	}
+	if copied := out.CopyObjectResult; copied != nil {
+		result.ETag = aws.StringValue(copied.ETag)
+		result.LastModified = aws.TimeValue(copied.LastModified)
+	}
+	return result, nil
+}
+
+// MoveFile moves an object to dstKey in the same bucket by copying it
+// server-side like CopyFile and then deleting the source. The source is only
+// deleted once the copy has succeeded. If the destination exists, MoveFile
+// returns ErrObjectExists unless opts.Overwrite is set; another writer can
+// still create it between that check and the copy. If only deleting the
+// source fails, the copy's result is returned along with the error.
+func (c *S3Client) MoveFile(ctx context.Context, bucket, srcKey, dstKey string, opts *CopyOptions) (*CopyResult, error) {
+	if bucket == "" {
+		return nil, ErrInvalidBucket
+	}
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	src, err := c.resolveKey(srcKey)
+	if err != nil {
+		return nil, err
+	}
+	dst, err := c.resolveKey(dstKey)
+	if err != nil {
+		return nil, err
+	}
+	if src == dst {
+		return nil, fmt.Errorf("%w: cannot move %s onto itself", ErrInvalidOptions, srcKey)
+	}
+
+	if opts == nil || !opts.Overwrite {
+		_, err := c.headCopySource(ctx, bucket, dst, "")
+		if err == nil {
+			return nil, ErrObjectExists
+		}
+		if !errors.Is(err, ErrFileNotFound) {
+			return nil, fmt.Errorf("failed to check destination: %w", err)
+		}
+	}
+
+	result, err := c.CopyFile(ctx, bucket, srcKey, bucket, dstKey, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.DeleteFile(ctx, bucket, srcKey); err != nil {
+		return result, fmt.Errorf("copied %s to %s but failed to delete the source: %w", srcKey, dstKey, err)
+	}
+	return result, nil
+}
+
+// CopyBetween copies an object between two clients, e.g. from a bucket in
+// one account or region to another that CopyObject cannot reach. The object
+// is streamed from src into an upload through dst, so at most about
+// PartSize times Concurrency bytes are held in memory. The content headers,
+// metadata and tags of the source are kept unless opts.ReplaceMetadata is
+// set. The source's stored checksum is verified as it is read, S3 verifies
+// a SHA-256 of every uploaded part, and the copy's size, and its SHA-256
+// where S3 reports one for the whole object, are checked against the bytes
+// that were read.
+//
+// Objects encrypted with Config.ClientSideKey cannot be copied.
+func CopyBetween(ctx context.Context, src *S3Client, srcBucket, srcKey string, dst *S3Client, dstBucket, dstKey string, opts *CopyOptions) (*CopyResult, error) {
+	if src == nil || dst == nil {
+		return nil, ErrNilValue
+	}
+	if srcBucket == "" || dstBucket == "" {
+		return nil, ErrInvalidBucket
+	}
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	if opts == nil {
+		opts = &CopyOptions{}
+	}
+	resolvedSrc, err := src.resolveKey(srcKey)
+	if err != nil {
+		return nil, err
+	}
+	resolvedDst, err := dst.resolveKey(dstKey)
+	if err != nil {
+		return nil, err
+	}
+
+	head, err := src.headCopySource(ctx, srcBucket, resolvedSrc, opts.SourceVersionID)
+	if err != nil {
+		return nil, err
+	}
+	if metadataValue(head.Metadata, clientSideEncryptionMetaKey) != "" {
+		return nil, fmt.Errorf("%w: client-side encrypted objects cannot be copied between clients", ErrInvalidOptions)
+	}
+	upload, err := src.copyBetweenOptions(ctx, srcBucket, resolvedSrc, head, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	body, info, err := src.DownloadStreamWithOptions(ctx, srcBucket, srcKey, &DownloadOptions{
+		VersionID:      opts.SourceVersionID,
+		VerifyChecksum: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	if info.ETag != aws.StringValue(head.ETag) {
+		return nil, fmt.Errorf("%w: %s", ErrObjectModified, srcKey)
+	}
+
+	size := aws.Int64Value(head.ContentLength)
+	sum := sha256.New()
+	counter := &countingWriter{}
+	uploaded, err := dst.streamUpload(ctx, dstBucket, resolvedDst, io.TeeReader(body, io.MultiWriter(sum, counter)), upload, partSizeFor(size))
+	if err != nil {
+		return nil, err
+	}
+	if counter.n != size {
+		return nil, fmt.Errorf("%w: read %d bytes of %d byte source", ErrChecksumMismatch, counter.n, size)
+	}
+
+	result := &CopyResult{
+		Key:             uploaded.Key,
+		ETag:            uploaded.ETag,
+		VersionID:       uploaded.VersionID,
+		SourceVersionID: aws.StringValue(head.VersionId),
+	}
+	input := &s3.HeadObjectInput{
+		Bucket:       aws.String(dstBucket),
+		Key:          aws.String(resolvedDst),
+		ChecksumMode: aws.String(s3.ChecksumModeEnabled),
+	}
+	if uploaded.VersionID != "" {
+		input.VersionId = aws.String(uploaded.VersionID)
+	}
+	copied, err := dst.s3Client.HeadObjectWithContext(ctx, input)
+	if err != nil {
+		return result, fmt.Errorf("failed to verify copy: %w", copyError(err))
+	}
+	if err := verifyStaged(copied, size, ChecksumSHA256, sum); err != nil {
+		return result, err
+	}
+	result.LastModified = aws.TimeValue(copied.LastModified)
+	return result, nil
+}
+
+// copyBetweenOptions returns the upload options that recreate the source
+// described by head, with the changes requested by opts
+func (c *S3Client) copyBetweenOptions(ctx context.Context, bucket, key string, head *s3.HeadObjectOutput, opts *CopyOptions) (*UploadOptions, error) {
+	upload := &UploadOptions{
+		StorageClass:                opts.StorageClass,
+		ACL:                         opts.ACL,
+		AllowUnknownValues:          opts.AllowUnknownValues,
+		ServerSideEncryption:        opts.ServerSideEncryption,
+		SSEKMSKeyID:                 opts.SSEKMSKeyID,
+		PartSize:                    opts.PartSize,
+		Concurrency:                 opts.Concurrency,
+		ChecksumAlgorithm:           ChecksumSHA256,
+		DisableContentTypeDetection: true,
+	}
+	if opts.ReplaceMetadata {
+		upload.Metadata = opts.Metadata
+		upload.ContentType = opts.ContentType
+		upload.ContentDisposition = opts.ContentDisposition
+		upload.CacheControl = opts.CacheControl
+		upload.ContentEncoding = opts.ContentEncoding
+		upload.ContentLanguage = opts.ContentLanguage
+	} else {
+		upload.Metadata = aws.StringValueMap(head.Metadata)
+		upload.ContentType = aws.StringValue(head.ContentType)
+		upload.ContentDisposition = aws.StringValue(head.ContentDisposition)
+		upload.CacheControl = aws.StringValue(head.CacheControl)
+		upload.ContentEncoding = aws.StringValue(head.ContentEncoding)
+		upload.ContentLanguage = aws.StringValue(head.ContentLanguage)
+		if expires, err := http.ParseTime(aws.StringValue(head.Expires)); err == nil {
+			upload.Expires = expires
+		}
+	}
+
+	tags, err := c.sourceTags(ctx, bucket, key, opts.SourceVersionID)
+	if err != nil {
+		return nil, err
+	}
+	if len(tags) > 0 {
+		upload.Tags = tags
+	}
+	return upload, nil
+}
+
+// sourceTags returns the tags of the source of a copy
+func (c *S3Client) sourceTags(ctx context.Context, bucket, key, versionID string) (map[string]string, error) {
+	input := &s3.GetObjectTaggingInput{Bucket: aws.String(bucket), Key: aws.String(key)}
+	if versionID != "" {
+		input.VersionId = aws.String(versionID)
+	}
+	tagging, err := c.s3Client.GetObjectTaggingWithContext(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get source tags: %w", copyError(err))
+	}
+	tags := make(map[string]string, len(tagging.TagSet))
+	for _, tag := range tagging.TagSet {
+		tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+	return tags, nil
+}
+
+// headCopySource looks up the source of a copy
+func (c *S3Client) headCopySource(ctx context.Context, bucket, key, versionID string) (*s3.HeadObjectOutput, error) {
+	input := &s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}
+	if versionID != "" {
+		input.VersionId = aws.String(versionID)
+	}
+	head, err := c.s3Client.HeadObjectWithContext(ctx, input)
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok {
+			switch aerr.Code() {
+			case "NotFound", "BadRequest":
+				return nil, ErrFileNotFound
+			case s3.ErrCodeNoSuchBucket:
+				return nil, ErrInvalidBucket
+			case "Forbidden":
+				return nil, fmt.Errorf("%w: %w", ErrAccessDenied, aerr)
+			default:
+				return nil, fmt.Errorf("AWS error: %w", aerr)
+			}
+		}
+		return nil, fmt.Errorf("failed to get copy source: %w", err)
+	}
+	return head, nil
+}
+
+// multipartCopy copies a source over 5GB into dstKey with UploadPartCopy.
+// Unlike CopyObject, a multipart upload does not carry over the source's
+// metadata and tags, so they are set on the upload from head and the
+// source's tagging.
+func (c *S3Client) multipartCopy(ctx context.Context, srcBucket, srcKey, source string, head *s3.HeadObjectOutput, dstBucket, dstKey string, opts *CopyOptions) (*CopyResult, error) {
+	if opts == nil {
+		opts = &CopyOptions{}
+	}
+	input := &s3.CreateMultipartUploadInput{
+		Bucket: aws.String(dstBucket),
+		Key:    aws.String(dstKey),
+	}
+	if opts.ReplaceMetadata {
+		if opts.Metadata != nil {
+			input.Metadata = aws.StringMap(opts.Metadata)
+		}
+		if opts.ContentType != "" {
+			input.ContentType = aws.String(opts.ContentType)
+		}
+		if opts.ContentDisposition != "" {
+			input.ContentDisposition = aws.String(opts.ContentDisposition)
+		}
+		if opts.CacheControl != "" {
+			input.CacheControl = aws.String(opts.CacheControl)
+		}
+		if opts.ContentEncoding != "" {
+			input.ContentEncoding = aws.String(opts.ContentEncoding)
+		}
+		if opts.ContentLanguage != "" {
+			input.ContentLanguage = aws.String(opts.ContentLanguage)
+		}
+	} else {
+		input.Metadata = head.Metadata
+		input.ContentType = head.ContentType
+		input.ContentDisposition = head.ContentDisposition
+		input.CacheControl = head.CacheControl
+		input.ContentEncoding = head.ContentEncoding
+		input.ContentLanguage = head.ContentLanguage
+	}
+	if opts.StorageClass != "" {
+		input.StorageClass = aws.String(string(opts.StorageClass))
+	}
+	if opts.ACL != "" {
+		input.ACL = aws.String(string(opts.ACL))
+	}
+	if opts.ServerSideEncryption != "" {
+		input.ServerSideEncryption = aws.String(opts.ServerSideEncryption)
+	}
+	if opts.SSEKMSKeyID != "" {
+		input.SSEKMSKeyId = aws.String(opts.SSEKMSKeyID)
+	}
+
+	tags, err := c.sourceTags(ctx, srcBucket, srcKey, opts.SourceVersionID)
+	if err != nil {
+		return nil, err
+	}
+	if len(tags) > 0 {
+		input.Tagging = aws.String(encodeTags(tags))
+	}
+
+	size := aws.Int64Value(head.ContentLength)
+	partSize := opts.PartSize
+	if partSize == 0 {
+		partSize = defaultCopyPartSize
+	}
+	partSize = max(partSize, (size+s3manager.MaxUploadParts-1)/s3manager.MaxUploadParts)
+	var parts []copyPart
+	for start := int64(0); start < size; start += partSize {
+		end := min(start+partSize, size) - 1
+		parts = append(parts, copyPart{source: source, etag: aws.StringValue(head.ETag), rng: fmt.Sprintf("bytes=%d-%d", start, end)})
+	}
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+
+	created, err := c.s3Client.CreateMultipartUploadWithContext(ctx, input)
+	if err != nil {
+		return nil, multipartError(err, "failed to create multipart upload")
+	}
+	uploadID := aws.StringValue(created.UploadId)
+	uploaded, err := c.copyParts(ctx, dstBucket, dstKey, uploadID, parts, concurrency, nil)
+	if err != nil {
+		// Abort even if ctx was cancelled, which is a common cause of failure
+		if abortErr := c.abortMultipartUpload(context.WithoutCancel(ctx), dstBucket, dstKey, uploadID); abortErr != nil {
+			return nil, fmt.Errorf("%w (abort of upload %s also failed: %v)", err, uploadID, abortErr)
+		}
+		return nil, err
+	}
+
+	result := &CopyResult{
+		Key:             uploaded.Key,
+		ETag:            uploaded.ETag,
+		VersionID:       uploaded.VersionID,
+		SourceVersionID: aws.StringValue(head.VersionId),
+	}
+	copied, err := c.headCopySource(ctx, dstBucket, dstKey, uploaded.VersionID)
+	if err != nil {
+		return result, fmt.Errorf("failed to verify copy: %w", err)
+	}
+	if got := aws.Int64Value(copied.ContentLength); got != size {
+		return result, fmt.Errorf("%w: copy is %d bytes, source %d", ErrChecksumMismatch, got, size)
+	}
+	result.LastModified = aws.TimeValue(copied.LastModified)
+	return result, nil
+}
+
+// copyError maps a CopyObject or UploadPartCopy failure to the library's
+// errors
+func copyError(err error) error {
+	if aerr, ok := err.(awserr.Error); ok {
+		switch aerr.Code() {
+		case s3.ErrCodeNoSuchBucket:
+			return ErrInvalidBucket
+		case s3.ErrCodeNoSuchKey, "NoSuchVersion":
+			return ErrFileNotFound
+		case "PreconditionFailed":
+			return ErrObjectModified
+		case "AccessDenied":
+			return fmt.Errorf("%w: %w", ErrAccessDenied, aerr)
+		default:
+			return fmt.Errorf("AWS error: %w", aerr)
+		}
+	}
+	return fmt.Errorf("failed to copy file: %w", err)
+}
+
+// copySource returns the x-amz-copy-source value naming bucket/key. The key
+// is URL-encoded with its slashes kept, "+" included so it is not taken for
+// an encoded space.
+func copySource(bucket, key, versionID string) string {
+	source := strings.ReplaceAll((&url.URL{Path: bucket + "/" + key}).EscapedPath(), "+", "%2B")
+	if versionID != "" {
+		source += "?versionId=" + url.QueryEscape(versionID)
+	}
+	return source
+}
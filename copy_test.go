@@ -0,0 +1,243 @@
+package s3lib
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCopySource tests keys with spaces, unicode and plus signs are encoded
+func TestCopySource(t *testing.T) {
+	assert.Equal(t, "bucket/dir/a%20b.txt", copySource("bucket", "dir/a b.txt", ""))
+	assert.Equal(t, "bucket/%C3%BCber%2Bplus", copySource("bucket", "über+plus", ""))
+	assert.Equal(t, "bucket/a.txt?versionId=v%2B1", copySource("bucket", "a.txt", "v+1"))
+}
+
+// TestS3Client_CopyFile tests the copy request and its result
+func TestS3Client_CopyFile(t *testing.T) {
+	var headers http.Header
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		if req.Method == http.MethodHead {
+			if req.URL.Path == "/"+testBucket+"/missing.txt" {
+				return fakeErrorResponse(req, http.StatusNotFound, "NotFound"), nil
+			}
+			resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: req}
+			resp.Header.Set("Content-Length", "5")
+			resp.Header.Set("ETag", `"source"`)
+			return resp, nil
+		}
+		headers = req.Header
+		resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Request: req,
+			Body: xmlBody(`<CopyObjectResult><ETag>"copied"</ETag></CopyObjectResult>`)}
+		resp.Header.Set("x-amz-version-id", "v2")
+		return resp, nil
+	})
+	ctx := context.Background()
+
+	result, err := client.CopyFile(ctx, "src-bucket", "dir/my file.txt", testBucket, "copy.txt", nil)
+	require.NoError(t, err)
+	assert.Equal(t, &CopyResult{Key: "copy.txt", ETag: `"copied"`, VersionID: "v2"}, result)
+	assert.Equal(t, "src-bucket/dir/my%20file.txt", headers.Get("x-amz-copy-source"))
+	assert.Equal(t, "COPY", headers.Get("x-amz-metadata-directive"))
+	assert.Equal(t, `"source"`, headers.Get("x-amz-copy-source-if-match"))
+
+	t.Run("metadata update in place", func(t *testing.T) {
+		_, err := client.CopyFile(ctx, testBucket, "a.txt", testBucket, "a.txt", &CopyOptions{
+			ReplaceMetadata: true,
+			ContentType:     "text/plain",
+			Metadata:        map[string]string{"owner": "ops"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "REPLACE", headers.Get("x-amz-metadata-directive"))
+		assert.Equal(t, "text/plain", headers.Get("Content-Type"))
+		assert.Equal(t, "ops", headers.Get("x-amz-meta-owner"))
+	})
+
+	t.Run("missing source", func(t *testing.T) {
+		_, err := client.CopyFile(ctx, testBucket, "missing.txt", testBucket, "b.txt", nil)
+		assert.ErrorIs(t, err, ErrFileNotFound)
+	})
+}
+
+// TestS3Client_CopyFileMultipart tests a source over 5GB is copied in
+// ranged parts carrying the source's metadata and tags
+func TestS3Client_CopyFileMultipart(t *testing.T) {
+	const size = MaxCopyPartSize + 1024
+	var mu sync.Mutex
+	var ranges []string
+	var created http.Header
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: req}
+		query := req.URL.Query()
+		switch {
+		case req.Method == http.MethodHead:
+			resp.Header.Set("Content-Length", strconv.FormatInt(size, 10))
+			resp.Header.Set("ETag", `"source"`)
+			resp.Header.Set("Content-Type", "video/mp4")
+			resp.Header.Set("x-amz-meta-title", "intro")
+		case query.Has("tagging"):
+			resp.Body = xmlBody("<Tagging><TagSet><Tag><Key>team</Key><Value>media</Value></Tag></TagSet></Tagging>")
+		case query.Has("uploads"):
+			created = req.Header
+			resp.Body = xmlBody("<InitiateMultipartUploadResult><UploadId>up-1</UploadId></InitiateMultipartUploadResult>")
+		case query.Has("partNumber"):
+			mu.Lock()
+			ranges = append(ranges, req.Header.Get("x-amz-copy-source-range"))
+			mu.Unlock()
+			resp.Body = xmlBody(`<CopyPartResult><ETag>"part"</ETag></CopyPartResult>`)
+		case query.Has("uploadId"):
+			resp.Body = xmlBody(`<CompleteMultipartUploadResult><ETag>"copied-2"</ETag></CompleteMultipartUploadResult>`)
+		}
+		return resp, nil
+	})
+
+	result, err := client.CopyFile(context.Background(), testBucket, "video.mp4", testBucket, "copy.mp4", &CopyOptions{PartSize: 1 << 30, Concurrency: 3})
+	require.NoError(t, err)
+	assert.Equal(t, `"copied-2"`, result.ETag)
+	assert.Equal(t, "video/mp4", created.Get("Content-Type"))
+	assert.Equal(t, "intro", created.Get("x-amz-meta-title"))
+	assert.Equal(t, "team=media", created.Get("x-amz-tagging"))
+
+	require.Len(t, ranges, 6)
+	assert.Contains(t, ranges, "bytes=0-1073741823")
+	assert.Contains(t, ranges, fmt.Sprintf("bytes=%d-%d", 5<<30, size-1))
+}
+
+// TestS3Client_CopyFileValidation tests invalid copies are rejected before
+// any request is made
+func TestS3Client_CopyFileValidation(t *testing.T) {
+	client := setupTestClient(t)
+	ctx := context.Background()
+
+	_, err := client.CopyFile(ctx, "", "a.txt", testBucket, "b.txt", nil)
+	assert.ErrorIs(t, err, ErrInvalidBucket)
+	_, err = client.CopyFile(ctx, testBucket, "", testBucket, "b.txt", nil)
+	assert.ErrorIs(t, err, ErrInvalidKey)
+	_, err = client.CopyFile(ctx, testBucket, "a.txt", testBucket, "a.txt", nil)
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+	_, err = client.CopyFile(ctx, testBucket, "a.txt", testBucket, "b.txt", &CopyOptions{ContentType: "text/plain"})
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+	_, err = client.CopyFile(ctx, testBucket, "a.txt", testBucket, "b.txt", &CopyOptions{PartSize: 1024})
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+	_, err = client.MoveFile(ctx, testBucket, "a.txt", "a.txt", nil)
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+}
+
+// TestS3Client_MoveFile tests the source is deleted after the copy, an
+// existing destination is kept, and a failed copy keeps the source
+func TestS3Client_MoveFile(t *testing.T) {
+	var calls []string
+	existing := map[string]bool{"a.txt": true, "taken.txt": true, "locked.txt": true}
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		key := strings.TrimPrefix(req.URL.Path, "/"+testBucket+"/")
+		calls = append(calls, req.Method+" "+key)
+		resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: req}
+		switch {
+		case req.Method == http.MethodHead && !existing[key]:
+			return fakeErrorResponse(req, http.StatusNotFound, "NotFound"), nil
+		case req.Method == http.MethodHead:
+			resp.Header.Set("Content-Length", "5")
+			resp.Header.Set("ETag", `"source"`)
+		case req.Method == http.MethodPut && key == "denied.txt":
+			return fakeErrorResponse(req, http.StatusForbidden, "AccessDenied"), nil
+		case req.Method == http.MethodPut:
+			resp.Body = xmlBody(`<CopyObjectResult><ETag>"copied"</ETag></CopyObjectResult>`)
+		case req.Method == http.MethodDelete:
+			resp.StatusCode = http.StatusNoContent
+		}
+		return resp, nil
+	})
+	ctx := context.Background()
+
+	result, err := client.MoveFile(ctx, testBucket, "a.txt", "b.txt", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "b.txt", result.Key)
+	assert.Equal(t, []string{"HEAD b.txt", "HEAD a.txt", "PUT b.txt", "DELETE a.txt"}, calls)
+
+	calls = nil
+	_, err = client.MoveFile(ctx, testBucket, "a.txt", "taken.txt", nil)
+	assert.ErrorIs(t, err, ErrObjectExists)
+	assert.Equal(t, []string{"HEAD taken.txt"}, calls)
+
+	calls = nil
+	_, err = client.MoveFile(ctx, testBucket, "a.txt", "taken.txt", &CopyOptions{Overwrite: true})
+	require.NoError(t, err)
+	assert.Equal(t, "DELETE a.txt", calls[len(calls)-1])
+
+	calls = nil
+	_, err = client.MoveFile(ctx, testBucket, "locked.txt", "denied.txt", nil)
+	assert.ErrorIs(t, err, ErrAccessDenied)
+	assert.NotContains(t, calls, "DELETE locked.txt")
+}
+
+// TestCopyBetween tests an object is streamed from one client to the other
+// with its content headers, metadata and tags, and that the source ETag is
+// verified when it is an MD5 of the content
+func TestCopyBetween(t *testing.T) {
+	source := func(etag string) *S3Client {
+		return setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+			resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: req}
+			resp.Header.Set("ETag", etag)
+			switch {
+			case req.URL.Query().Has("tagging"):
+				resp.Body = xmlBody("<Tagging><TagSet><Tag><Key>team</Key><Value>media</Value></Tag></TagSet></Tagging>")
+			case req.Method == http.MethodHead:
+				resp.Header.Set("Content-Length", "5")
+				resp.Header.Set("Content-Type", "text/plain")
+				resp.Header.Set("x-amz-meta-title", "greeting")
+			default:
+				resp.Header.Set("Content-Length", "5")
+				resp.Body = io.NopCloser(strings.NewReader("hello"))
+			}
+			return resp, nil
+		})
+	}
+	// The ETag of a single part upload is the hex MD5 of its content
+	src := source(`"5d41402abc4b2a76b9719d911017c592"`)
+
+	var put http.Header
+	var body []byte
+	dst := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: req}
+		if req.Method == http.MethodHead {
+			resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+			return resp, nil
+		}
+		put = req.Header
+		body, _ = io.ReadAll(req.Body)
+		resp.Header.Set("ETag", `"copied"`)
+		return resp, nil
+	})
+
+	result, err := CopyBetween(context.Background(), src, testBucket, "a.txt", dst, "other-bucket", "b.txt", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "b.txt", result.Key)
+	assert.Equal(t, "hello", string(body))
+	assert.Equal(t, "text/plain", put.Get("Content-Type"))
+	assert.Equal(t, "greeting", put.Get("x-amz-meta-title"))
+	assert.Equal(t, "team=media", put.Get("x-amz-tagging"))
+
+	t.Run("multipart ETag", func(t *testing.T) {
+		// A multipart ETag is not an MD5 of the content and is not verified
+		body = nil
+		_, err := CopyBetween(context.Background(), source(`"6f5902ac237024bdd0c176cb93063dc4-2"`), testBucket, "a.txt", dst, "other-bucket", "b.txt", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "hello", string(body))
+	})
+
+	t.Run("ETag mismatch", func(t *testing.T) {
+		_, err := CopyBetween(context.Background(), source(`"00000000000000000000000000000000"`), testBucket, "a.txt", dst, "other-bucket", "b.txt", nil)
+		assert.ErrorIs(t, err, ErrChecksumMismatch)
+	})
+
+	_, err = CopyBetween(context.Background(), src, testBucket, "a.txt", nil, "other-bucket", "b.txt", nil)
+	assert.ErrorIs(t, err, ErrNilValue)
+}
@@ -0,0 +1,183 @@
+package s3lib
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// maxCORSRules is the number of rules S3 allows in a bucket's CORS
+// configuration
+const maxCORSRules = 100
+
+// CORSRule lets browsers on AllowedOrigins make cross-origin requests to a
+// bucket, such as uploads to presigned URLs
+type CORSRule struct {
+	// ID names the rule (up to 255 characters)
+	ID string `json:"id,omitempty"`
+	// AllowedOrigins are the origins allowed, like "https://app.example.com".
+	// Each may contain one "*" wildcard; "*" alone allows any origin.
+	AllowedOrigins []string `json:"allowed_origins"`
+	// AllowedMethods are the HTTP methods allowed: GET, PUT, POST, DELETE
+	// or HEAD
+	AllowedMethods []string `json:"allowed_methods"`
+	// AllowedHeaders are the request headers a preflight may ask for, each
+	// with at most one "*" wildcard
+	AllowedHeaders []string `json:"allowed_headers,omitempty"`
+	// ExposeHeaders are the response headers browsers let scripts read,
+	// such as "ETag" to complete multipart uploads
+	ExposeHeaders []string `json:"expose_headers,omitempty"`
+	// MaxAgeSeconds is how long browsers may cache the preflight response
+	MaxAgeSeconds int `json:"max_age_seconds,omitempty"`
+}
+
+// validate checks the rule against S3's constraints
+func (r *CORSRule) validate() error {
+	if len(r.ID) > 255 {
+		return fmt.Errorf("%w: CORS rule ID is longer than 255 characters", ErrInvalidOptions)
+	}
+	if len(r.AllowedOrigins) == 0 || len(r.AllowedMethods) == 0 {
+		return fmt.Errorf("%w: CORS rules need at least one allowed origin and method", ErrInvalidOptions)
+	}
+	for _, origin := range r.AllowedOrigins {
+		if origin == "" || strings.Count(origin, "*") > 1 {
+			return fmt.Errorf("%w: invalid CORS origin %q", ErrInvalidOptions, origin)
+		}
+	}
+	for _, method := range r.AllowedMethods {
+		switch method {
+		case "GET", "PUT", "POST", "DELETE", "HEAD":
+		default:
+			return fmt.Errorf("%w: CORS method %q is not supported", ErrInvalidOptions, method)
+		}
+	}
+	for _, header := range r.AllowedHeaders {
+		if header == "" || strings.Count(header, "*") > 1 {
+			return fmt.Errorf("%w: invalid CORS header %q", ErrInvalidOptions, header)
+		}
+	}
+	if r.MaxAgeSeconds < 0 {
+		return fmt.Errorf("%w: MaxAgeSeconds must not be negative", ErrInvalidOptions)
+	}
+	return nil
+}
+
+// SetBucketCORS replaces the CORS configuration of bucket with rules, which
+// are validated before the request is made. Use DeleteBucketCORS to remove
+// it.
+func (c *S3Client) SetBucketCORS(ctx context.Context, bucket string, rules []CORSRule) error {
+	if bucket == "" {
+		return ErrInvalidBucket
+	}
+	if len(rules) == 0 {
+		return fmt.Errorf("%w: no CORS rules; use DeleteBucketCORS to remove the configuration", ErrInvalidOptions)
+	}
+	if len(rules) > maxCORSRules {
+		return fmt.Errorf("%w: at most %d CORS rules are allowed", ErrInvalidOptions, maxCORSRules)
+	}
+
+	config := &s3.CORSConfiguration{}
+	for i := range rules {
+		r := &rules[i]
+		if err := r.validate(); err != nil {
+			return err
+		}
+		rule := &s3.CORSRule{
+			AllowedOrigins: aws.StringSlice(r.AllowedOrigins),
+			AllowedMethods: aws.StringSlice(r.AllowedMethods),
+		}
+		if r.ID != "" {
+			rule.ID = aws.String(r.ID)
+		}
+		if len(r.AllowedHeaders) > 0 {
+			rule.AllowedHeaders = aws.StringSlice(r.AllowedHeaders)
+		}
+		if len(r.ExposeHeaders) > 0 {
+			rule.ExposeHeaders = aws.StringSlice(r.ExposeHeaders)
+		}
+		if r.MaxAgeSeconds > 0 {
+			rule.MaxAgeSeconds = aws.Int64(int64(r.MaxAgeSeconds))
+		}
+		config.CORSRules = append(config.CORSRules, rule)
+	}
+
+	_, err := c.s3Client.PutBucketCorsWithContext(ctx, &s3.PutBucketCorsInput{
+		Bucket:            aws.String(bucket),
+		CORSConfiguration: config,
+	})
+	if err != nil {
+		return corsError(err)
+	}
+	return nil
+}
+
+// GetBucketCORS returns the CORS rules of bucket, which are empty when it
+// has no CORS configuration
+func (c *S3Client) GetBucketCORS(ctx context.Context, bucket string) ([]CORSRule, error) {
+	if bucket == "" {
+		return nil, ErrInvalidBucket
+	}
+
+	out, err := c.s3Client.GetBucketCorsWithContext(ctx, &s3.GetBucketCorsInput{
+		Bucket: aws.String(bucket),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == "NoSuchCORSConfiguration" {
+			return []CORSRule{}, nil
+		}
+		return nil, corsError(err)
+	}
+	rules := make([]CORSRule, 0, len(out.CORSRules))
+	for _, r := range out.CORSRules {
+		rule := CORSRule{
+			ID:             aws.StringValue(r.ID),
+			AllowedOrigins: aws.StringValueSlice(r.AllowedOrigins),
+			AllowedMethods: aws.StringValueSlice(r.AllowedMethods),
+			MaxAgeSeconds:  int(aws.Int64Value(r.MaxAgeSeconds)),
+		}
+		if len(r.AllowedHeaders) > 0 {
+			rule.AllowedHeaders = aws.StringValueSlice(r.AllowedHeaders)
+		}
+		if len(r.ExposeHeaders) > 0 {
+			rule.ExposeHeaders = aws.StringValueSlice(r.ExposeHeaders)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// DeleteBucketCORS removes the CORS configuration of bucket. Removing one
+// that does not exist succeeds.
+func (c *S3Client) DeleteBucketCORS(ctx context.Context, bucket string) error {
+	if bucket == "" {
+		return ErrInvalidBucket
+	}
+	_, err := c.s3Client.DeleteBucketCorsWithContext(ctx, &s3.DeleteBucketCorsInput{
+		Bucket: aws.String(bucket),
+	})
+	if err != nil {
+		return corsError(err)
+	}
+	return nil
+}
+
+// corsError maps a failed CORS request to the library's error values
+func corsError(err error) error {
+	if aerr, ok := err.(awserr.Error); ok {
+		switch aerr.Code() {
+		case s3.ErrCodeNoSuchBucket:
+			return ErrInvalidBucket
+		case "AccessDenied":
+			return fmt.Errorf("%w: %w", ErrAccessDenied, aerr)
+		case "InvalidRequest", "MalformedXML":
+			return fmt.Errorf("%w: %w", ErrInvalidOptions, aerr)
+		default:
+			return fmt.Errorf("AWS error: %w", aerr)
+		}
+	}
+	return fmt.Errorf("failed to access bucket CORS: %w", err)
+}
@@ -0,0 +1,87 @@
+package s3lib
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCORSRules allow browser uploads to presigned URLs from one origin
+var testCORSRules = []CORSRule{
+	{
+		ID:             "uploads",
+		AllowedOrigins: []string{"https://app.example.com"},
+		AllowedMethods: []string{"PUT", "POST"},
+		AllowedHeaders: []string{"*"},
+		ExposeHeaders:  []string{"ETag"},
+		MaxAgeSeconds:  3000,
+	},
+	{
+		AllowedOrigins: []string{"*"},
+		AllowedMethods: []string{"GET"},
+	},
+}
+
+// TestCORSRuleValidate tests rules S3 would reject are caught first
+func TestCORSRuleValidate(t *testing.T) {
+	for i := range testCORSRules {
+		require.NoError(t, testCORSRules[i].validate())
+	}
+
+	tests := map[string]CORSRule{
+		"no origin":        {AllowedMethods: []string{"GET"}},
+		"no method":        {AllowedOrigins: []string{"*"}},
+		"bad method":       {AllowedOrigins: []string{"*"}, AllowedMethods: []string{"PATCH"}},
+		"two wildcards":    {AllowedOrigins: []string{"https://*.*.example.com"}, AllowedMethods: []string{"GET"}},
+		"negative max age": {AllowedOrigins: []string{"*"}, AllowedMethods: []string{"GET"}, MaxAgeSeconds: -1},
+	}
+	for name, rule := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.ErrorIs(t, rule.validate(), ErrInvalidOptions)
+		})
+	}
+}
+
+// TestS3Client_BucketCORS tests rules set are read back unchanged, a bucket
+// without a configuration has none, and an empty rule set is rejected
+func TestS3Client_BucketCORS(t *testing.T) {
+	var stored string
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: req}
+		switch req.Method {
+		case http.MethodPut:
+			data, _ := io.ReadAll(req.Body)
+			stored = string(data)
+		case http.MethodGet:
+			if stored == "" {
+				return fakeErrorResponse(req, http.StatusNotFound, "NoSuchCORSConfiguration"), nil
+			}
+			resp.Body = xmlBody(stored)
+		case http.MethodDelete:
+			stored = ""
+			resp.StatusCode = http.StatusNoContent
+		}
+		return resp, nil
+	})
+	ctx := context.Background()
+
+	rules, err := client.GetBucketCORS(ctx, testBucket)
+	require.NoError(t, err)
+	assert.Empty(t, rules)
+
+	require.NoError(t, client.SetBucketCORS(ctx, testBucket, testCORSRules))
+	rules, err = client.GetBucketCORS(ctx, testBucket)
+	require.NoError(t, err)
+	assert.Equal(t, testCORSRules, rules)
+
+	require.NoError(t, client.DeleteBucketCORS(ctx, testBucket))
+	rules, err = client.GetBucketCORS(ctx, testBucket)
+	require.NoError(t, err)
+	assert.Empty(t, rules)
+
+	assert.ErrorIs(t, client.SetBucketCORS(ctx, testBucket, nil), ErrInvalidOptions)
+}
@@ -0,0 +1,87 @@
+package s3lib
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// contentSHA256MetaKey is the object metadata recording the hex SHA-256 of
+// the payload as supplied to the library, written by SkipIfUnchanged uploads
+const contentSHA256MetaKey = "s3lib-sha256"
+
+// skipUnchanged implements UploadOptions.SkipIfUnchanged. It compares body
+// against the existing object and returns a Skipped result when they match.
+// Otherwise it returns the options to upload with, which record the payload
+// digest so later uploads can be compared even when the ETag is not an MD5.
+// transformed reports that the stored bytes will differ from body (gzip or
+// client-side encryption), so only the recorded digest can be compared.
+func (c *S3Client) skipUnchanged(ctx context.Context, bucket, key string, body io.ReadSeeker, size int64, transformed bool, opts *UploadOptions) (*UploadResult, *UploadOptions, error) {
+	if opts == nil || !opts.SkipIfUnchanged {
+		return nil, opts, nil
+	}
+	if bucket == "" {
+		return nil, nil, ErrInvalidBucket
+	}
+
+	sha256Hash, md5Hash := sha256.New(), md5.New()
+	if err := digest(body, io.MultiWriter(sha256Hash, md5Hash)); err != nil {
+		return nil, nil, fmt.Errorf("failed to hash upload body: %w", err)
+	}
+	sha256Sum := hex.EncodeToString(sha256Hash.Sum(nil))
+	md5Sum := hex.EncodeToString(md5Hash.Sum(nil))
+
+	var reqOpts []request.Option
+	if opts.SSECustomerKey != nil {
+		reqOpts = append(reqOpts, withSSECustomerKey(opts.SSECustomerKey))
+	}
+	head, err := c.s3Client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}, reqOpts...)
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != "NotFound" {
+			return nil, nil, uploadError(err)
+		}
+	} else if remoteMatches(head, sha256Sum, md5Sum, size, transformed) {
+		return &UploadResult{
+			Key:       c.relativeKey(key),
+			Location:  c.objectURL(bucket, key),
+			ETag:      aws.StringValue(head.ETag),
+			VersionID: aws.StringValue(head.VersionId),
+			Skipped:   true,
+		}, nil, nil
+	}
+
+	recorded := *opts
+	recorded.Metadata = make(map[string]string, len(opts.Metadata)+1)
+	for k, v := range opts.Metadata {
+		recorded.Metadata[k] = v
+	}
+	recorded.Metadata[contentSHA256MetaKey] = sha256Sum
+	return nil, &recorded, nil
+}
+
+// remoteMatches reports whether the object described by head has the same
+// content as a local payload with the given digests and size. The recorded
+// SHA-256 is preferred; an ETag is only an MD5 for single part uploads
+// without SSE-KMS or SSE-C, in which case it simply will not match.
+func remoteMatches(head *s3.HeadObjectOutput, sha256Sum, md5Sum string, size int64, transformed bool) bool {
+	if recorded := metadataValue(head.Metadata, contentSHA256MetaKey); recorded != "" {
+		return recorded == sha256Sum && (transformed || aws.Int64Value(head.ContentLength) == size)
+	}
+	if transformed || aws.Int64Value(head.ContentLength) != size {
+		return false
+	}
+	etag := strings.Trim(aws.StringValue(head.ETag), `"`)
+	return strings.EqualFold(etag, md5Sum)
+}
@@ -0,0 +1,101 @@
+package s3lib
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRemoteMatches tests the comparison of an existing object with a payload
+func TestRemoteMatches(t *testing.T) {
+	shaSum := sha256.Sum256(testFileContent)
+	sha := hex.EncodeToString(shaSum[:])
+	md5Sum := md5.Sum(testFileContent)
+	etag := `"` + hex.EncodeToString(md5Sum[:]) + `"`
+	size := int64(len(testFileContent))
+
+	tests := []struct {
+		name        string
+		head        *s3.HeadObjectOutput
+		transformed bool
+		want        bool
+	}{
+		{
+			name: "Single part ETag matches",
+			head: &s3.HeadObjectOutput{ETag: aws.String(etag), ContentLength: aws.Int64(size)},
+			want: true,
+		},
+		{
+			name: "Size differs",
+			head: &s3.HeadObjectOutput{ETag: aws.String(etag), ContentLength: aws.Int64(size + 1)},
+		},
+		{
+			name: "Multipart ETag",
+			head: &s3.HeadObjectOutput{ETag: aws.String(`"abc-2"`), ContentLength: aws.Int64(size)},
+		},
+		{
+			name: "Recorded digest matches",
+			head: &s3.HeadObjectOutput{
+				ETag:          aws.String(`"abc-2"`),
+				ContentLength: aws.Int64(size),
+				Metadata:      map[string]*string{"S3lib-Sha256": aws.String(sha)},
+			},
+			want: true,
+		},
+		{
+			name: "Recorded digest differs",
+			head: &s3.HeadObjectOutput{
+				ETag:          aws.String(etag),
+				ContentLength: aws.Int64(size),
+				Metadata:      map[string]*string{"S3lib-Sha256": aws.String("0000")},
+			},
+		},
+		{
+			name:        "Transformed payload ignores size",
+			head:        &s3.HeadObjectOutput{ContentLength: aws.Int64(7), Metadata: map[string]*string{"S3lib-Sha256": aws.String(sha)}},
+			transformed: true,
+			want:        true,
+		},
+		{
+			name:        "Transformed payload ignores ETag",
+			head:        &s3.HeadObjectOutput{ETag: aws.String(etag), ContentLength: aws.Int64(size)},
+			transformed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, remoteMatches(tt.head, sha, hex.EncodeToString(md5Sum[:]), size, tt.transformed))
+		})
+	}
+}
+
+// TestS3Client_UploadFileSkipIfUnchanged tests a repeated upload is skipped
+// and a changed payload is uploaded
+func TestS3Client_UploadFileSkipIfUnchanged(t *testing.T) {
+	objects := map[string]*memoryObject{}
+	client := setupFakeClient(t, memoryServer(objects))
+	ctx := context.Background()
+	opts := &UploadOptions{SkipIfUnchanged: true}
+
+	_, err := client.UploadFileResult(ctx, testBucket, "test-dedupe.txt", testFileContent, opts)
+	require.NoError(t, err)
+
+	result, err := client.UploadFileResult(ctx, testBucket, "test-dedupe.txt", testFileContent, opts)
+	require.NoError(t, err)
+	assert.True(t, result.Skipped)
+	assert.NotEmpty(t, result.ETag)
+
+	result, err = client.UploadFileResult(ctx, testBucket, "test-dedupe.txt", []byte("changed"), opts)
+	require.NoError(t, err)
+	assert.False(t, result.Skipped)
+	require.Contains(t, objects, "test-dedupe.txt")
+	assert.Equal(t, []byte("changed"), objects["test-dedupe.txt"].data)
+}
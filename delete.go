@@ -0,0 +1,334 @@
+package s3lib
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// maxDeleteKeys is the most keys one DeleteObjects request may name
+const maxDeleteKeys = 1000
+
+// ObjectRef names an object, or one version of it, to delete
+type ObjectRef struct {
+	Key string `json:"key"`
+
+	// VersionID deletes that version permanently; empty deletes the
+	// current object, leaving a delete marker if versioning is enabled
+	VersionID string `json:"version_id,omitempty"`
+}
+
+// DeleteError reports an object S3 failed to delete
+type DeleteError struct {
+	Key       string `json:"key"`
+	VersionID string `json:"version_id,omitempty"`
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+}
+
+func (e DeleteError) Error() string {
+	if e.VersionID != "" {
+		return fmt.Sprintf("failed to delete %s version %s: %s: %s", e.Key, e.VersionID, e.Code, e.Message)
+	}
+	return fmt.Sprintf("failed to delete %s: %s: %s", e.Key, e.Code, e.Message)
+}
+
+// DeleteFiles deletes keys with DeleteObjects requests of up to 1000 keys
+// each. Keys S3 fails to delete, e.g. for lack of permission, are returned
+// as DeleteErrors without stopping the rest. The error is only set when a
+// whole request fails, in which case the keys of later requests are not
+// deleted. Deleting a key that does not exist succeeds.
+func (c *S3Client) DeleteFiles(ctx context.Context, bucket string, keys []string) ([]DeleteError, error) {
+	objects := make([]ObjectRef, len(keys))
+	for i, key := range keys {
+		objects[i] = ObjectRef{Key: key}
+	}
+	return c.DeleteFileVersions(ctx, bucket, objects)
+}
+
+// DeleteFileVersions deletes objects like DeleteFiles, naming a version of
+// each object to delete where VersionID is set. Several versions of one key,
+// such as the delete markers found by ListDeleteMarkers, may be deleted at
+// once.
+func (c *S3Client) DeleteFileVersions(ctx context.Context, bucket string, objects []ObjectRef) ([]DeleteError, error) {
+	if bucket == "" {
+		return nil, ErrInvalidBucket
+	}
+	identifiers := make([]*s3.ObjectIdentifier, len(objects))
+	for i, obj := range objects {
+		key, err := c.resolveKey(obj.Key)
+		if err != nil {
+			return nil, err
+		}
+		identifiers[i] = &s3.ObjectIdentifier{Key: aws.String(key)}
+		if obj.VersionID != "" {
+			identifiers[i].VersionId = aws.String(obj.VersionID)
+		}
+	}
+
+	failed := []DeleteError{}
+	for start := 0; start < len(identifiers); start += maxDeleteKeys {
+		batch := identifiers[start:min(start+maxDeleteKeys, len(identifiers))]
+		errs, err := c.deleteBatch(ctx, bucket, batch)
+		failed = append(failed, errs...)
+		if err != nil {
+			return failed, err
+		}
+	}
+	return failed, nil
+}
+
+// VersionsDeleteError is returned by DeleteAllVersions when some versions of
+// a key could not be removed. It matches ErrObjectLocked with errors.Is when
+// Object Lock protects any of them.
+type VersionsDeleteError struct {
+	Key    string
+	Failed []DeleteError
+}
+
+func (e *VersionsDeleteError) Error() string {
+	ids := make([]string, len(e.Failed))
+	for i, f := range e.Failed {
+		ids[i] = f.VersionID
+	}
+	return fmt.Sprintf("failed to delete %d versions of %s: %s", len(e.Failed), e.Key, strings.Join(ids, ", "))
+}
+
+func (e *VersionsDeleteError) Unwrap() error {
+	for _, f := range e.Failed {
+		if f.objectLocked() {
+			return ErrObjectLocked
+		}
+	}
+	return nil
+}
+
+// objectLocked reports whether S3 refused the delete because of Object Lock
+// retention or a legal hold. S3 reports these as AccessDenied with a message
+// naming Object Lock.
+func (e DeleteError) objectLocked() bool {
+	return e.Code == "AccessDenied" && strings.Contains(strings.ToLower(e.Message), "object lock")
+}
+
+// DeleteAllVersions permanently deletes a key from a versioned bucket by
+// removing every version and delete marker it has. Versions that cannot be
+// removed are reported by a *VersionsDeleteError after the rest are deleted.
+// Deleting a key with no versions succeeds.
+func (c *S3Client) DeleteAllVersions(ctx context.Context, bucket, key string) error {
+	if bucket == "" {
+		return ErrInvalidBucket
+	}
+	key, err := c.resolveKey(key)
+	if err != nil {
+		return err
+	}
+
+	// Versions are listed in key order, so the versions of key come before
+	// those of longer keys it is a prefix of
+	var versions []*s3.ObjectIdentifier
+	input := &s3.ListObjectVersionsInput{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(key),
+	}
+	err = c.s3Client.ListObjectVersionsPagesWithContext(ctx, input,
+		func(page *s3.ListObjectVersionsOutput, lastPage bool) bool {
+			past := false
+			for _, v := range page.Versions {
+				if aws.StringValue(v.Key) != key {
+					past = true
+					continue
+				}
+				versions = append(versions, &s3.ObjectIdentifier{Key: v.Key, VersionId: v.VersionId})
+			}
+			for _, m := range page.DeleteMarkers {
+				if aws.StringValue(m.Key) != key {
+					past = true
+					continue
+				}
+				versions = append(versions, &s3.ObjectIdentifier{Key: m.Key, VersionId: m.VersionId})
+			}
+			return !past
+		})
+	if err != nil {
+		return listError(err)
+	}
+
+	var failed []DeleteError
+	for start := 0; start < len(versions); start += maxDeleteKeys {
+		errs, err := c.deleteBatch(ctx, bucket, versions[start:min(start+maxDeleteKeys, len(versions))])
+		if err != nil {
+			return err
+		}
+		failed = append(failed, errs...)
+	}
+	if len(failed) > 0 {
+		return &VersionsDeleteError{Key: c.relativeKey(key), Failed: failed}
+	}
+	return nil
+}
+
+// DeletePrefixOptions represents optional parameters for DeletePrefix
+type DeletePrefixOptions struct {
+	// Concurrency is the number of DeleteObjects requests in flight at once
+	// (default 5)
+	Concurrency int
+	// DryRun lists the keys that would be deleted without deleting them
+	DryRun bool
+	// AllowFullBucket permits an empty prefix, deleting every object in the
+	// bucket (or under the client's key prefix)
+	AllowFullBucket bool
+}
+
+// DeletePrefixResult represents the outcome of DeletePrefix
+type DeletePrefixResult struct {
+	// Deleted is the number of objects deleted, or that would be deleted on
+	// a dry run
+	Deleted int64 `json:"deleted"`
+	// Keys are the keys that would be deleted; only set on a dry run
+	Keys []string `json:"keys,omitempty"`
+	// Errors are the objects S3 failed to delete
+	Errors []DeleteError `json:"errors"`
+}
+
+// DeletePrefix deletes every object under prefix, which should end in "/"
+// so "tenants/acme" does not also match "tenants/acme-corp/". The listing is
+// streamed and deleted in batches of 1000 keys as it goes, so memory use
+// does not grow with the number of objects. Objects S3 fails to delete are
+// reported in the result; a failed listing or DeleteObjects request stops
+// the run and is returned along with what was deleted so far.
+func (c *S3Client) DeletePrefix(ctx context.Context, bucket, prefix string, opts *DeletePrefixOptions) (*DeletePrefixResult, error) {
+	if bucket == "" {
+		return nil, ErrInvalidBucket
+	}
+	if opts == nil {
+		opts = &DeletePrefixOptions{}
+	}
+	if opts.Concurrency < 0 {
+		return nil, fmt.Errorf("%w: Concurrency must not be negative", ErrInvalidOptions)
+	}
+	if prefix == "" && !opts.AllowFullBucket {
+		return nil, fmt.Errorf("%w: deleting with an empty prefix requires AllowFullBucket", ErrInvalidOptions)
+	}
+
+	result := &DeletePrefixResult{Errors: []DeleteError{}}
+	if opts.DryRun {
+		result.Keys = []string{}
+		it := c.ListFilesIter(ctx, bucket, prefix, nil)
+		for it.Next() {
+			result.Keys = append(result.Keys, it.File().Key)
+			result.Deleted++
+		}
+		return result, it.Err()
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	jobs := make(chan []*s3.ObjectIdentifier)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range jobs {
+				errs, err := c.deleteBatch(ctx, bucket, batch)
+				mu.Lock()
+				if err != nil {
+					if firstErr == nil {
+						firstErr = err
+					}
+					cancel()
+				} else {
+					result.Deleted += int64(len(batch) - len(errs))
+					result.Errors = append(result.Errors, errs...)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	send := func(batch []*s3.ObjectIdentifier) bool {
+		select {
+		case jobs <- batch:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	batch := make([]*s3.ObjectIdentifier, 0, maxDeleteKeys)
+	it := c.ListFilesIter(ctx, bucket, prefix, nil)
+	for it.Next() {
+		key, err := c.resolveKey(it.File().Key)
+		if err != nil {
+			continue
+		}
+		batch = append(batch, &s3.ObjectIdentifier{Key: aws.String(key)})
+		if len(batch) == maxDeleteKeys {
+			if !send(batch) {
+				break
+			}
+			batch = make([]*s3.ObjectIdentifier, 0, maxDeleteKeys)
+		}
+	}
+	if len(batch) > 0 && it.Err() == nil {
+		send(batch)
+	}
+	close(jobs)
+	wg.Wait()
+
+	// A failed delete cancels the listing, so report its error rather
+	// than the cancellation it caused
+	if firstErr != nil {
+		return result, firstErr
+	}
+	if err := it.Err(); err != nil {
+		return result, err
+	}
+	return result, ctx.Err()
+}
+
+// deleteBatch deletes up to maxDeleteKeys objects with one DeleteObjects
+// request. Quiet mode makes S3 report only the objects it failed to delete.
+func (c *S3Client) deleteBatch(ctx context.Context, bucket string, objects []*s3.ObjectIdentifier) ([]DeleteError, error) {
+	out, err := withRetry(ctx, c.config.MaxRetries, func() (*s3.DeleteObjectsOutput, error) {
+		return c.s3Client.DeleteObjectsWithContext(ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(bucket),
+			Delete: &s3.Delete{Objects: objects, Quiet: aws.Bool(true)},
+		})
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok {
+			switch aerr.Code() {
+			case s3.ErrCodeNoSuchBucket:
+				return nil, ErrInvalidBucket
+			default:
+				return nil, fmt.Errorf("AWS error: %w", aerr)
+			}
+		}
+		return nil, fmt.Errorf("failed to delete files: %w", err)
+	}
+
+	failed := make([]DeleteError, 0, len(out.Errors))
+	for _, e := range out.Errors {
+		failed = append(failed, DeleteError{
+			Key:       c.relativeKey(aws.StringValue(e.Key)),
+			VersionID: aws.StringValue(e.VersionId),
+			Code:      aws.StringValue(e.Code),
+			Message:   aws.StringValue(e.Message),
+		})
+	}
+	return failed, nil
+}
@@ -0,0 +1,173 @@
+package s3lib
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// deleteRequest is the body of a DeleteObjects request
+type deleteRequest struct {
+	Quiet   bool `xml:"Quiet"`
+	Objects []struct {
+		Key       string `xml:"Key"`
+		VersionID string `xml:"VersionId"`
+	} `xml:"Object"`
+}
+
+// TestS3Client_DeleteFiles tests keys are deleted in quiet batches of 1000
+// and per-key failures are collected from every batch
+func TestS3Client_DeleteFiles(t *testing.T) {
+	var batches []deleteRequest
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		var body deleteRequest
+		data, _ := io.ReadAll(req.Body)
+		require.NoError(t, xml.Unmarshal(data, &body))
+		batches = append(batches, body)
+
+		// Fail the first key of every batch
+		result := fmt.Sprintf("<DeleteResult><Error><Key>%s</Key><Code>AccessDenied</Code><Message>Access Denied</Message></Error></DeleteResult>", body.Objects[0].Key)
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: xmlBody(result), Request: req}, nil
+	})
+
+	keys := make([]string, 2500)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("file-%04d", i)
+	}
+	failed, err := client.DeleteFiles(context.Background(), testBucket, keys)
+	require.NoError(t, err)
+
+	require.Len(t, batches, 3)
+	assert.Len(t, batches[0].Objects, 1000)
+	assert.Len(t, batches[2].Objects, 500)
+	assert.True(t, batches[0].Quiet)
+
+	require.Len(t, failed, 3)
+	assert.Equal(t, DeleteError{Key: "file-1000", Code: "AccessDenied", Message: "Access Denied"}, failed[1])
+	assert.True(t, strings.Contains(failed[1].Error(), "file-1000"))
+}
+
+// TestS3Client_DeleteFileVersions tests version IDs are sent per key
+func TestS3Client_DeleteFileVersions(t *testing.T) {
+	var batch deleteRequest
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		data, _ := io.ReadAll(req.Body)
+		require.NoError(t, xml.Unmarshal(data, &batch))
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: xmlBody("<DeleteResult></DeleteResult>"), Request: req}, nil
+	})
+
+	failed, err := client.DeleteFileVersions(context.Background(), testBucket, []ObjectRef{
+		{Key: "a.txt", VersionID: "v1"},
+		{Key: "a.txt", VersionID: "v2"},
+		{Key: "b.txt"},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, failed)
+	require.Len(t, batch.Objects, 3)
+	assert.Equal(t, "v2", batch.Objects[1].VersionID)
+	assert.Empty(t, batch.Objects[2].VersionID)
+}
+
+// TestS3Client_DeleteFilesValidation tests invalid input is rejected before
+// anything is deleted
+func TestS3Client_DeleteFilesValidation(t *testing.T) {
+	client := setupTestClient(t)
+	ctx := context.Background()
+
+	_, err := client.DeleteFiles(ctx, "", []string{"a"})
+	assert.ErrorIs(t, err, ErrInvalidBucket)
+	_, err = client.DeleteFiles(ctx, testBucket, []string{"a", ""})
+	assert.ErrorIs(t, err, ErrInvalidKey)
+
+	failed, err := client.DeleteFiles(ctx, testBucket, nil)
+	require.NoError(t, err)
+	assert.Empty(t, failed)
+}
+
+// TestS3Client_DeletePrefix tests the listing is deleted in batches as it
+// is streamed, and a dry run deletes nothing
+func TestS3Client_DeletePrefix(t *testing.T) {
+	var queries []url.Values
+	list := listServer(2500, &queries)
+	var mu sync.Mutex
+	deleted := 0
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		if req.Method == http.MethodGet {
+			return list(req)
+		}
+		var body deleteRequest
+		data, _ := io.ReadAll(req.Body)
+		require.NoError(t, xml.Unmarshal(data, &body))
+		mu.Lock()
+		deleted += len(body.Objects)
+		mu.Unlock()
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: xmlBody("<DeleteResult></DeleteResult>"), Request: req}, nil
+	})
+	ctx := context.Background()
+
+	t.Run("dry run", func(t *testing.T) {
+		result, err := client.DeletePrefix(ctx, testBucket, "tenants/acme/", &DeletePrefixOptions{DryRun: true})
+		require.NoError(t, err)
+		assert.Equal(t, int64(2500), result.Deleted)
+		assert.Len(t, result.Keys, 2500)
+		assert.Zero(t, deleted)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		result, err := client.DeletePrefix(ctx, testBucket, "tenants/acme/", &DeletePrefixOptions{Concurrency: 2})
+		require.NoError(t, err)
+		assert.Equal(t, int64(2500), result.Deleted)
+		assert.Empty(t, result.Keys)
+		assert.Empty(t, result.Errors)
+		assert.Equal(t, 2500, deleted)
+	})
+
+	t.Run("empty prefix", func(t *testing.T) {
+		_, err := client.DeletePrefix(ctx, testBucket, "", nil)
+		assert.ErrorIs(t, err, ErrInvalidOptions)
+	})
+}
+
+// TestS3Client_DeleteAllVersions tests every version and delete marker of
+// the key, and only that key, is deleted, and locked versions are reported
+func TestS3Client_DeleteAllVersions(t *testing.T) {
+	var batch deleteRequest
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		if req.Method == http.MethodGet {
+			body := "<ListVersionsResult>" +
+				"<Version><Key>a.txt</Key><VersionId>v1</VersionId></Version>" +
+				"<Version><Key>a.txt</Key><VersionId>v2</VersionId></Version>" +
+				"<Version><Key>a.txt.bak</Key><VersionId>v3</VersionId></Version>" +
+				"<DeleteMarker><Key>a.txt</Key><VersionId>v4</VersionId></DeleteMarker>" +
+				"</ListVersionsResult>"
+			return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: xmlBody(body), Request: req}, nil
+		}
+		data, _ := io.ReadAll(req.Body)
+		require.NoError(t, xml.Unmarshal(data, &batch))
+		result := "<DeleteResult><Error><Key>a.txt</Key><VersionId>v1</VersionId><Code>AccessDenied</Code>" +
+			"<Message>Access Denied because object protected by object lock.</Message></Error></DeleteResult>"
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: xmlBody(result), Request: req}, nil
+	})
+
+	err := client.DeleteAllVersions(context.Background(), testBucket, "a.txt")
+	assert.ErrorIs(t, err, ErrObjectLocked)
+	var versionsErr *VersionsDeleteError
+	require.ErrorAs(t, err, &versionsErr)
+	require.Len(t, versionsErr.Failed, 1)
+	assert.Equal(t, "v1", versionsErr.Failed[0].VersionID)
+
+	require.Len(t, batch.Objects, 3)
+	for _, obj := range batch.Objects {
+		assert.Equal(t, "a.txt", obj.Key)
+	}
+	assert.Equal(t, "v4", batch.Objects[2].VersionID)
+}
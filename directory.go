@@ -0,0 +1,188 @@
+package s3lib
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// defaultConcurrency is the number of workers used by batch operations when
+// the caller does not specify one
+const defaultConcurrency = 5
+
+// UploadDirectoryOptions represents optional parameters for directory uploads
+type UploadDirectoryOptions struct {
+	// Concurrency is the number of files uploaded in parallel (default 5)
+	Concurrency int
+	// Include limits the upload to files whose relative path matches one of
+	// these glob patterns. Patterns without a slash match the base name.
+	Include []string
+	// Exclude skips files whose relative path matches one of these patterns
+	Exclude []string
+	// FollowSymlinks uploads the targets of symlinked files. Symlinked
+	// directories are never descended into.
+	FollowSymlinks bool
+	// UploadOptions are applied to every uploaded file
+	UploadOptions *UploadOptions
+}
+
+// DirectoryUploadResult represents the outcome of uploading a single file
+type DirectoryUploadResult struct {
+	LocalPath string `json:"local_path"`
+	Key       string `json:"key"`
+	Location  string `json:"location,omitempty"`
+	Err       error  `json:"-"`
+}
+
+// UploadDirectory walks localDir and uploads every regular file under
+// keyPrefix, preserving relative paths with forward slashes. A failed file
+// does not abort the run; check Err on each result.
+func (c *S3Client) UploadDirectory(ctx context.Context, bucket, keyPrefix, localDir string, opts *UploadDirectoryOptions) ([]DirectoryUploadResult, error) {
+	if bucket == "" {
+		return nil, ErrInvalidBucket
+	}
+	if err := c.checkClientSideKey(); err != nil {
+		return nil, err
+	}
+	if opts == nil {
+		opts = &UploadDirectoryOptions{}
+	}
+	if err := validatePatterns(opts.Include); err != nil {
+		return nil, err
+	}
+	if err := validatePatterns(opts.Exclude); err != nil {
+		return nil, err
+	}
+
+	stat, err := os.Stat(localDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat local directory: %w", err)
+	}
+	if !stat.IsDir() {
+		return nil, fmt.Errorf("local path %s is not a directory", localDir)
+	}
+
+	var results []DirectoryUploadResult
+	err = filepath.WalkDir(localDir, func(p string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			if p == localDir {
+				return walkErr
+			}
+			results = append(results, DirectoryUploadResult{LocalPath: p, Err: walkErr})
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if d.Type()&fs.ModeSymlink != 0 {
+			if !opts.FollowSymlinks {
+				return nil
+			}
+			target, err := os.Stat(p)
+			if err != nil {
+				results = append(results, DirectoryUploadResult{LocalPath: p, Err: err})
+				return nil
+			}
+			if !target.Mode().IsRegular() {
+				return nil
+			}
+		} else if !d.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(localDir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if len(opts.Include) > 0 && !matchesAny(opts.Include, rel) {
+			return nil
+		}
+		if matchesAny(opts.Exclude, rel) {
+			return nil
+		}
+
+		results = append(results, DirectoryUploadResult{
+			LocalPath: p,
+			Key:       joinKey(keyPrefix, rel),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk local directory: %w", err)
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				r := &results[idx]
+				r.Location, r.Err = c.UploadFromFile(ctx, bucket, r.Key, r.LocalPath, opts.UploadOptions)
+			}
+		}()
+	}
+
+	for i := range results {
+		if results[i].Err != nil {
+			continue
+		}
+		if ctx.Err() != nil {
+			results[i].Err = ctx.Err()
+			continue
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results, ctx.Err()
+}
+
+// joinKey joins a key prefix and a relative slash-separated path
+func joinKey(prefix, rel string) string {
+	if prefix == "" {
+		return rel
+	}
+	if strings.HasSuffix(prefix, "/") {
+		return prefix + rel
+	}
+	return prefix + "/" + rel
+}
+
+// validatePatterns reports the first malformed glob pattern
+func validatePatterns(patterns []string) error {
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// matchesAny reports whether rel matches one of the glob patterns. Patterns
+// without a slash are matched against the base name only.
+func matchesAny(patterns []string, rel string) bool {
+	for _, p := range patterns {
+		target := rel
+		if !strings.Contains(p, "/") {
+			target = path.Base(rel)
+		}
+		if ok, _ := path.Match(p, target); ok {
+			return true
+		}
+	}
+	return false
+}
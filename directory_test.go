@@ -0,0 +1,150 @@
+package s3lib
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestTree creates the given relative files under dir
+func writeTestTree(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for rel, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(rel))
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o755))
+		require.NoError(t, os.WriteFile(p, []byte(content), 0o644))
+	}
+}
+
+// TestS3Client_UploadDirectory tests the UploadDirectory function
+func TestS3Client_UploadDirectory(t *testing.T) {
+	var mu sync.Mutex
+	uploaded := map[string]string{}
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		data, _ := io.ReadAll(req.Body)
+		mu.Lock()
+		uploaded[strings.TrimPrefix(req.URL.Path, "/"+testBucket+"/")] = string(data)
+		mu.Unlock()
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: req}, nil
+	})
+
+	dir := t.TempDir()
+	writeTestTree(t, dir, map[string]string{
+		"index.html":          "<html></html>",
+		"css/site.css":        "body{}",
+		"js/app.js":           "console.log(1)",
+		"js/app.js.map":       "{}",
+		"assets/img/logo.svg": "<svg/>",
+	})
+	require.NoError(t, os.Symlink(filepath.Join(dir, "index.html"), filepath.Join(dir, "link.html")))
+
+	tests := []struct {
+		name     string
+		bucket   string
+		opts     *UploadDirectoryOptions
+		wantKeys []string
+		wantErr  bool
+	}{
+		{
+			name:   "All files",
+			bucket: testBucket,
+			opts:   nil,
+			wantKeys: []string{
+				"site/assets/img/logo.svg",
+				"site/css/site.css",
+				"site/index.html",
+				"site/js/app.js",
+				"site/js/app.js.map",
+			},
+		},
+		{
+			name:   "Exclude source maps",
+			bucket: testBucket,
+			opts:   &UploadDirectoryOptions{Exclude: []string{"*.map"}, Concurrency: 2},
+			wantKeys: []string{
+				"site/assets/img/logo.svg",
+				"site/css/site.css",
+				"site/index.html",
+				"site/js/app.js",
+			},
+		},
+		{
+			name:     "Include by directory",
+			bucket:   testBucket,
+			opts:     &UploadDirectoryOptions{Include: []string{"js/*"}},
+			wantKeys: []string{"site/js/app.js", "site/js/app.js.map"},
+		},
+		{
+			name:   "Follow symlinks",
+			bucket: testBucket,
+			opts:   &UploadDirectoryOptions{Include: []string{"*.html"}, FollowSymlinks: true},
+			wantKeys: []string{
+				"site/index.html",
+				"site/link.html",
+			},
+		},
+		{
+			name:    "Invalid pattern",
+			bucket:  testBucket,
+			opts:    &UploadDirectoryOptions{Include: []string{"["}},
+			wantErr: true,
+		},
+		{
+			name:    "Empty bucket",
+			bucket:  "",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uploaded = map[string]string{}
+			ctx := context.Background()
+			results, err := client.UploadDirectory(ctx, tt.bucket, "site", dir, tt.opts)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			var keys []string
+			for _, r := range results {
+				assert.NoError(t, r.Err, r.LocalPath)
+				keys = append(keys, r.Key)
+			}
+			sort.Strings(keys)
+			assert.Equal(t, tt.wantKeys, keys)
+			assert.Len(t, uploaded, len(tt.wantKeys))
+			for _, key := range tt.wantKeys {
+				assert.Contains(t, uploaded, key)
+			}
+			if _, ok := uploaded["site/css/site.css"]; ok {
+				assert.Equal(t, "body{}", uploaded["site/css/site.css"])
+			}
+		})
+	}
+}
+
+// TestJoinKey tests key prefix joining
+func TestJoinKey(t *testing.T) {
+	assert.Equal(t, "a/b.txt", joinKey("", "a/b.txt"))
+	assert.Equal(t, "site/a/b.txt", joinKey("site", "a/b.txt"))
+	assert.Equal(t, "site/a/b.txt", joinKey("site/", "a/b.txt"))
+}
+
+// TestMatchesAny tests glob matching against relative paths
+func TestMatchesAny(t *testing.T) {
+	assert.True(t, matchesAny([]string{"*.css"}, "css/site.css"))
+	assert.True(t, matchesAny([]string{"css/*"}, "css/site.css"))
+	assert.False(t, matchesAny([]string{"js/*"}, "css/site.css"))
+	assert.False(t, matchesAny(nil, "css/site.css"))
+}
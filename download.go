@@ -0,0 +1,528 @@
+package s3lib
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// DownloadStream returns the body of an object without buffering it, along
+// with the object's metadata. The caller must close the returned reader.
+// Cancelling ctx terminates the stream. Objects encrypted with
+// Config.ClientSideKey are authenticated as a whole, so they are read and
+// decrypted before DownloadStream returns.
+func (c *S3Client) DownloadStream(ctx context.Context, bucket, key string) (io.ReadCloser, *FileInfo, error) {
+	return c.DownloadStreamWithOptions(ctx, bucket, key, nil)
+}
+
+// DownloadStreamWithOptions streams an object like DownloadStream, honoring
+// the VersionID, SSECustomerKey, AutoDecompress, VerifyChecksum and
+// conditions of opts. Gzip encoded objects are decompressed as they are read
+// when AutoDecompress is set, in which case FileInfo.Size is the stored size.
+// A checksum mismatch is returned by the final Read.
+// Reads that fail with a transient network error are resumed from the
+// current offset up to Config.MaxRetries times, as long as the object is
+// unchanged; otherwise they fail with ErrObjectModified.
+func (c *S3Client) DownloadStreamWithOptions(ctx context.Context, bucket, key string, opts *DownloadOptions) (io.ReadCloser, *FileInfo, error) {
+	if bucket == "" {
+		return nil, nil, ErrInvalidBucket
+	}
+	key, err := c.resolveKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := opts.validate(); err != nil {
+		return nil, nil, err
+	}
+
+	ifNoneMatch, ifModifiedSince := opts.conditions()
+	out, err := c.s3Client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket:          aws.String(bucket),
+		Key:             aws.String(key),
+		VersionId:       opts.versionID(),
+		IfNoneMatch:     ifNoneMatch,
+		IfModifiedSince: ifModifiedSince,
+		ChecksumMode:    aws.String(s3.ChecksumModeEnabled),
+	}, opts.requestOptions()...)
+	if err != nil {
+		return nil, nil, c.archivedError(ctx, bucket, key, opts, downloadError(err, opts, "failed to download file"))
+	}
+
+	if err := c.checkDownloadSize(aws.Int64Value(out.ContentLength)); err != nil {
+		out.Body.Close()
+		return nil, nil, err
+	}
+
+	rc := c.resumable(ctx, out.Body, &s3.GetObjectInput{
+		Bucket:    aws.String(bucket),
+		Key:       aws.String(key),
+		VersionId: opts.versionID(),
+		IfMatch:   out.ETag,
+	}, opts.requestOptions())
+	if opts != nil && opts.VerifyChecksum {
+		verifier := newChecksumVerifier(out.ChecksumSHA256, out.ChecksumCRC32C, out.ChecksumCRC32, out.ChecksumSHA1,
+			aws.StringValue(out.ETag), etagIsMD5(out.ServerSideEncryption, out.SSECustomerAlgorithm))
+		if verifier != nil {
+			rc = &verifyingBody{rc: rc, v: verifier}
+		}
+	}
+
+	body, err := c.decodeBody(ctx, rc, aws.StringValue(out.ContentEncoding), out.Metadata, opts.decompress())
+	if err != nil {
+		return nil, nil, err
+	}
+	return body, c.getObjectInfo(key, out), nil
+}
+
+// DownloadToWriter streams an object into w with a sequential GetObject and
+// returns the number of bytes written. Transient network failures are
+// resumed as by DownloadStreamWithOptions. Failures before any data is
+// written are reported as by DownloadStream; other failures while copying,
+// whether reading the object or writing to w, wrap ErrDownloadInterrupted.
+func (c *S3Client) DownloadToWriter(ctx context.Context, bucket, key string, w io.Writer) (int64, error) {
+	if w == nil {
+		return 0, ErrNilValue
+	}
+	body, _, err := c.DownloadStream(ctx, bucket, key)
+	if err != nil {
+		return 0, err
+	}
+	defer body.Close()
+
+	n, err := io.Copy(w, body)
+	if err != nil {
+		return n, fmt.Errorf("%w after %d bytes: %w", ErrDownloadInterrupted, n, err)
+	}
+	return n, nil
+}
+
+// objectTooLargeCode is the error code of a download refused for exceeding
+// Config.MaxDownloadSize before its body was read
+const objectTooLargeCode = "ObjectTooLarge"
+
+// checkDownloadSize fails with ErrObjectTooLarge if an object of size bytes
+// exceeds Config.MaxDownloadSize
+func (c *S3Client) checkDownloadSize(size int64) error {
+	if max := c.config.MaxDownloadSize; max > 0 && size > max {
+		return tooLargeError(max)
+	}
+	return nil
+}
+
+// decodeBody wraps an object body so that reads yield the uploaded content.
+// Gzip encoded bodies are decompressed unless decompress is false, and
+// client-side encrypted bodies are read in full and decrypted. Reads fail
+// with ErrObjectTooLarge once the decoded content exceeds
+// Config.MaxDownloadSize. The body is closed if decoding fails.
+func (c *S3Client) decodeBody(ctx context.Context, rc io.ReadCloser, encoding string, metadata map[string]*string, decompress bool) (io.ReadCloser, error) {
+	body := io.ReadCloser(&contextReader{ctx: ctx, rc: rc})
+
+	if encoding == gzipEncoding && decompress {
+		zr, err := gzip.NewReader(body)
+		if err != nil {
+			body.Close()
+			return nil, fmt.Errorf("%w: %w", ErrDecompressionFailed, err)
+		}
+		body = &gzipBody{Reader: zr, body: body}
+	}
+	if max := c.config.MaxDownloadSize; max > 0 {
+		body = &limitedBody{sizeLimitReader: sizeLimitReader{r: body, limit: max}, body: body}
+	}
+
+	if metadataValue(metadata, clientSideEncryptionMetaKey) != "" {
+		data, err := io.ReadAll(body)
+		body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to download file: %w", err)
+		}
+		if data, err = decryptClientSide(c.config.ClientSideKey, data, metadata); err != nil {
+			return nil, err
+		}
+		body = io.NopCloser(bytes.NewReader(data))
+	}
+
+	return body, nil
+}
+
+// DownloadToFile downloads an object to localPath without holding it in
+// memory and returns the number of bytes written
+func (c *S3Client) DownloadToFile(ctx context.Context, bucket, key, localPath string) (int64, error) {
+	return c.DownloadToFileWithOptions(ctx, bucket, key, localPath, nil)
+}
+
+// DownloadToFileWithOptions downloads an object to localPath with options.
+// Plain objects are fetched with the concurrent downloader; gzip encoded and
+// client-side encrypted objects are decoded sequentially. Either way, reads
+// interrupted by transient network failures are resumed from the bytes
+// already received, up to Config.MaxRetries times per stream or part. Data is
+// written to a temporary file in the destination directory, synced and then
+// renamed over localPath, so a failed download never leaves a truncated file
+// behind.
+func (c *S3Client) DownloadToFileWithOptions(ctx context.Context, bucket, key, localPath string, opts *DownloadOptions) (n int64, err error) {
+	if bucket == "" {
+		return 0, ErrInvalidBucket
+	}
+	key, err = c.resolveKey(key)
+	if err != nil {
+		return 0, err
+	}
+	if localPath == "" {
+		return 0, fmt.Errorf("%w: empty local path", ErrInvalidOptions)
+	}
+	if err := opts.validate(); err != nil {
+		return 0, err
+	}
+
+	head, err := c.headForDownload(ctx, bucket, key, opts)
+	if err != nil {
+		return 0, err
+	}
+	if err := c.checkDownloadSize(aws.Int64Value(head.ContentLength)); err != nil {
+		return 0, err
+	}
+
+	dir := filepath.Dir(localPath)
+	if opts != nil && opts.CreateDirs {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return 0, fmt.Errorf("failed to create directory: %w", err)
+		}
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(localPath)+".tmp-*")
+	if err != nil {
+		return 0, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	// IfMatch makes every request fail if the object is replaced mid-download
+	input := &s3.GetObjectInput{
+		Bucket:    aws.String(bucket),
+		Key:       aws.String(key),
+		VersionId: opts.versionID(),
+		IfMatch:   head.ETag,
+	}
+	reqOpts := opts.requestOptions()
+	var progress *progressTracker
+	if opts != nil {
+		progress = newProgressTracker(opts.Progress, aws.Int64Value(head.ContentLength))
+	}
+	encoded := aws.StringValue(head.ContentEncoding) == gzipEncoding && opts.decompress()
+	encrypted := metadataValue(head.Metadata, clientSideEncryptionMetaKey) != ""
+	var verifier *checksumVerifier
+	if opts != nil && opts.VerifyChecksum {
+		verifier = newChecksumVerifier(head.ChecksumSHA256, head.ChecksumCRC32C, head.ChecksumCRC32, head.ChecksumSHA1,
+			aws.StringValue(head.ETag), etagIsMD5(head.ServerSideEncryption, head.SSECustomerAlgorithm))
+	}
+
+	if encoded || encrypted {
+		// err is the named result, so copy failures below reach the cleanup
+		var out *s3.GetObjectOutput
+		if out, err = c.s3Client.GetObjectWithContext(ctx, input, reqOpts...); err != nil {
+			return 0, downloadError(err, opts, "failed to download file")
+		}
+		rc := io.ReadCloser(&progressReader{rc: c.resumable(ctx, out.Body, input, reqOpts), t: progress})
+		if verifier != nil {
+			rc = &verifyingBody{rc: rc, v: verifier}
+		}
+		var body io.ReadCloser
+		if body, err = c.decodeBody(ctx, rc, aws.StringValue(out.ContentEncoding), out.Metadata, opts.decompress()); err != nil {
+			return 0, err
+		}
+		n, err = io.Copy(tmp, body)
+		body.Close()
+	} else {
+		partOpts := reqOpts
+		if c.config.MaxRetries > 0 && aws.StringValue(input.IfMatch) != "" {
+			resumer := &partResumer{c: c, ctx: ctx, reqOpts: reqOpts}
+			partOpts = append(partOpts[:len(partOpts):len(partOpts)], resumer.option)
+		}
+		n, err = c.downloader.DownloadWithContext(ctx, &progressWriterAt{w: tmp, t: progress}, input, func(d *s3manager.Downloader) {
+			d.RequestOptions = append(d.RequestOptions, partOpts...)
+		})
+	}
+	if progressErr := progress.stop(); progressErr != nil {
+		return 0, progressErr
+	}
+	if err != nil {
+		if errors.Is(err, ErrChecksumMismatch) {
+			return 0, err
+		}
+		// The downloader returns the failed part's GetObject error as is
+		return 0, downloadError(err, opts, "failed to download file")
+	}
+	if verifier != nil && !encoded && !encrypted {
+		// The downloader writes parts out of order, so the file is hashed
+		// once it is complete
+		if _, err = tmp.Seek(0, io.SeekStart); err != nil {
+			return 0, fmt.Errorf("failed to verify file: %w", err)
+		}
+		if _, err = io.Copy(verifier, tmp); err != nil {
+			return 0, fmt.Errorf("failed to verify file: %w", err)
+		}
+		if err = verifier.verify(); err != nil {
+			return 0, err
+		}
+	}
+
+	if err = tmp.Chmod(0o644); err != nil {
+		return 0, fmt.Errorf("failed to set file mode: %w", err)
+	}
+	if err = tmp.Sync(); err != nil {
+		return 0, fmt.Errorf("failed to sync file: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return 0, fmt.Errorf("failed to close file: %w", err)
+	}
+	if err = os.Rename(tmp.Name(), localPath); err != nil {
+		return 0, fmt.Errorf("failed to move file into place: %w", err)
+	}
+	return n, nil
+}
+
+// getObjectInfo builds the FileInfo of a GetObject response for key
+func (c *S3Client) getObjectInfo(key string, out *s3.GetObjectOutput) *FileInfo {
+	info := &FileInfo{
+		Key:          c.relativeKey(key),
+		Size:         aws.Int64Value(out.ContentLength),
+		LastModified: aws.TimeValue(out.LastModified),
+		ETag:         aws.StringValue(out.ETag),
+		StorageClass: aws.StringValue(out.StorageClass),
+
+		ServerSideEncryption: aws.StringValue(out.ServerSideEncryption),
+		SSEKMSKeyID:          aws.StringValue(out.SSEKMSKeyId),
+
+		ObjectLockMode:        aws.StringValue(out.ObjectLockMode),
+		ObjectLockRetainUntil: out.ObjectLockRetainUntilDate,
+		ObjectLockLegalHold:   aws.StringValue(out.ObjectLockLegalHoldStatus) == s3.ObjectLockLegalHoldStatusOn,
+
+		ContentEncoding: aws.StringValue(out.ContentEncoding),
+		ContentLanguage: aws.StringValue(out.ContentLanguage),
+
+		WebsiteRedirectLocation: aws.StringValue(out.WebsiteRedirectLocation),
+
+		RequestCharged: requestCharged(out.RequestCharged),
+
+		VersionID: aws.StringValue(out.VersionId),
+	}
+	if expires, err := http.ParseTime(aws.StringValue(out.Expires)); err == nil {
+		info.Expires = &expires
+	}
+	info.ChecksumAlgorithm, info.Checksum = pickChecksum(out.ChecksumSHA256, out.ChecksumCRC32C, out.ChecksumCRC32, out.ChecksumSHA1)
+	return info
+}
+
+// contextReader fails reads once its context is done, so a cancelled
+// download stops even if the transport has buffered data
+type contextReader struct {
+	ctx context.Context
+	rc  io.ReadCloser
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.rc.Read(p)
+}
+
+func (r *contextReader) Close() error {
+	return r.rc.Close()
+}
+
+// resumable returns body wrapped in a resumingBody, or body itself when
+// retries are disabled or the response had no ETag to pin resumed requests to.
+// input is the GetObject request to repeat; its Range is overwritten.
+func (c *S3Client) resumable(ctx context.Context, body io.ReadCloser, input *s3.GetObjectInput, reqOpts []request.Option) io.ReadCloser {
+	if c.config.MaxRetries == 0 || aws.StringValue(input.IfMatch) == "" {
+		return body
+	}
+	return &resumingBody{c: c, ctx: ctx, input: input, reqOpts: reqOpts, rc: body}
+}
+
+// resumingBody reads an object body and, when a read fails with a transient
+// network error, reopens the object at the current offset with a ranged
+// GetObject. IfMatch pins every request to the ETag of the first response,
+// so bytes from two versions of the object are never stitched together.
+type resumingBody struct {
+	c       *S3Client
+	ctx     context.Context
+	input   *s3.GetObjectInput
+	reqOpts []request.Option
+	rc      io.ReadCloser
+	offset  int64  // of the next byte in the object
+	end     string // last byte of the range being read, "" for the whole object
+	retries int
+	giveUp  func(error) // called with the error once resuming fails, if set
+}
+
+func (b *resumingBody) Read(p []byte) (int, error) {
+	n, err := b.rc.Read(p)
+	b.offset += int64(n)
+	if err == nil || err == io.EOF || !isTransientReadError(err) {
+		return n, err
+	}
+	if resumeErr := b.resume(err); resumeErr != nil {
+		if b.giveUp != nil {
+			b.giveUp(resumeErr)
+		}
+		return n, resumeErr
+	}
+	return n, nil
+}
+
+// resume backs off and replaces the failed body with one starting at the
+// current offset. It returns readErr, wrapped with the number of attempts,
+// once Config.MaxRetries resumptions have been made or ctx ends first.
+func (b *resumingBody) resume(readErr error) error {
+	b.rc.Close()
+	for {
+		if b.retries >= b.c.config.MaxRetries {
+			return fmt.Errorf("giving up after %d attempts: %w", b.retries+1, readErr)
+		}
+		delay := retryDelay(b.retries)
+		b.retries++
+		if deadline, ok := b.ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return fmt.Errorf("giving up after %d attempts, context deadline too close to retry: %w", b.retries, readErr)
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-b.ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("giving up after %d attempts: %w: %w", b.retries, b.ctx.Err(), readErr)
+		case <-timer.C:
+		}
+
+		input := *b.input
+		input.Range = aws.String(fmt.Sprintf("bytes=%d-%s", b.offset, b.end))
+		out, err := b.c.s3Client.GetObjectWithContext(b.ctx, &input, b.reqOpts...)
+		if err == nil {
+			b.rc = out.Body
+			return nil
+		}
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == "PreconditionFailed" {
+			return fmt.Errorf("%w: changed after %d bytes were read", ErrObjectModified, b.offset)
+		}
+		if !isRetryable(err) {
+			return fmt.Errorf("failed to resume download: %w", err)
+		}
+		readErr = err
+	}
+}
+
+func (b *resumingBody) Close() error {
+	return b.rc.Close()
+}
+
+// partResumer resumes the part bodies of a concurrent download as
+// resumingBody does for a single stream. The downloader restarts a failed
+// part from its first byte as many times as the SDK's request retries allow,
+// so once a part has given up, further requests of the download fail with
+// the same error and Config.MaxRetries stays the bound.
+type partResumer struct {
+	c       *S3Client
+	ctx     context.Context
+	reqOpts []request.Option // without option, for the resumed requests
+
+	mu     sync.Mutex
+	failed error
+}
+
+// option is the request option installing the resumer on each part request
+func (p *partResumer) option(r *request.Request) {
+	r.Handlers.Validate.PushBack(func(r *request.Request) {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if p.failed != nil {
+			r.Error = p.failed
+		}
+	})
+	r.Handlers.Unmarshal.PushBack(func(r *request.Request) {
+		out, ok := r.Data.(*s3.GetObjectOutput)
+		in, _ := r.Params.(*s3.GetObjectInput)
+		if !ok || in == nil || r.Error != nil {
+			return
+		}
+		var start, end int64
+		if n, _ := fmt.Sscanf(aws.StringValue(in.Range), "bytes=%d-%d", &start, &end); n != 2 {
+			return
+		}
+		input := *in
+		out.Body = &resumingBody{
+			c: p.c, ctx: p.ctx, input: &input, reqOpts: p.reqOpts, rc: out.Body,
+			offset: start, end: strconv.FormatInt(end, 10), giveUp: p.giveUp,
+		}
+	})
+}
+
+// giveUp records the error a part failed with
+func (p *partResumer) giveUp(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.failed == nil {
+		p.failed = err
+	}
+}
+
+// isTransientReadError reports whether a failed body read is a dropped or
+// stalled connection worth resuming rather than a cancellation
+func isTransientReadError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var netErr net.Error
+	return errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.As(err, &netErr) || isRetryable(err)
+}
+
+// limitedBody enforces a size limit on a response body
+type limitedBody struct {
+	sizeLimitReader
+	body io.Closer
+}
+
+func (b *limitedBody) Close() error {
+	return b.body.Close()
+}
+
+// gzipBody decompresses a response body and closes both on Close
+type gzipBody struct {
+	*gzip.Reader
+	body io.Closer
+}
+
+func (b *gzipBody) Read(p []byte) (int, error) {
+	n, err := b.Reader.Read(p)
+	if err != nil && err != io.EOF && isGzipError(err) {
+		err = fmt.Errorf("%w: %w", ErrDecompressionFailed, err)
+	}
+	return n, err
+}
+
+func (b *gzipBody) Close() error {
+	err := b.Reader.Close()
+	if closeErr := b.body.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
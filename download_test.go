@@ -0,0 +1,672 @@
+package s3lib
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestS3Client_DownloadStream tests streaming an object with its metadata
+func TestS3Client_DownloadStream(t *testing.T) {
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header: http.Header{
+				"Content-Length": []string{"13"},
+				"Etag":           []string{`"abc"`},
+			},
+			Body:    io.NopCloser(bytes.NewReader(testFileContent)),
+			Request: req,
+		}, nil
+	})
+
+	body, info, err := client.DownloadStream(context.Background(), testBucket, testFileName)
+	require.NoError(t, err)
+	defer body.Close()
+
+	got, err := io.ReadAll(body)
+	require.NoError(t, err)
+	assert.Equal(t, testFileContent, got)
+	assert.Equal(t, testFileName, info.Key)
+	assert.Equal(t, int64(len(testFileContent)), info.Size)
+	assert.Equal(t, `"abc"`, info.ETag)
+}
+
+// TestS3Client_DownloadStreamDecompress tests gzip encoded objects are
+// decompressed while streaming with AutoDecompress and returned as stored
+// without it
+func TestS3Client_DownloadStreamDecompress(t *testing.T) {
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	_, err := zw.Write(testFileContent)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Encoding": []string{gzipEncoding}},
+			Body:       io.NopCloser(bytes.NewReader(compressed.Bytes())),
+			Request:    req,
+		}, nil
+	})
+
+	body, info, err := client.DownloadStreamWithOptions(context.Background(), testBucket, testFileName, &DownloadOptions{AutoDecompress: true})
+	require.NoError(t, err)
+	defer body.Close()
+
+	got, err := io.ReadAll(body)
+	require.NoError(t, err)
+	assert.Equal(t, testFileContent, got)
+	assert.Equal(t, gzipEncoding, info.ContentEncoding)
+
+	raw, _, err := client.DownloadStream(context.Background(), testBucket, testFileName)
+	require.NoError(t, err)
+	defer raw.Close()
+	got, err = io.ReadAll(raw)
+	require.NoError(t, err)
+	assert.Equal(t, compressed.Bytes(), got)
+}
+
+// TestS3Client_DownloadStreamErrors tests error mapping and validation
+func TestS3Client_DownloadStreamErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		bucket  string
+		key     string
+		code    string
+		status  int
+		wantErr error
+	}{
+		{
+			name:    "Missing object",
+			bucket:  testBucket,
+			key:     testFileName,
+			code:    "NoSuchKey",
+			status:  http.StatusNotFound,
+			wantErr: ErrFileNotFound,
+		},
+		{
+			name:    "Missing bucket",
+			bucket:  testBucket,
+			key:     testFileName,
+			code:    "NoSuchBucket",
+			status:  http.StatusNotFound,
+			wantErr: ErrInvalidBucket,
+		},
+		{
+			name:    "Empty bucket",
+			key:     testFileName,
+			wantErr: ErrInvalidBucket,
+		},
+		{
+			name:    "Empty key",
+			bucket:  testBucket,
+			wantErr: ErrInvalidKey,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+				return fakeErrorResponse(req, tt.status, tt.code), nil
+			})
+
+			body, info, err := client.DownloadStream(context.Background(), tt.bucket, tt.key)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Nil(t, body)
+			assert.Nil(t, info)
+		})
+	}
+}
+
+// TestContextReader tests reads fail once the context is cancelled
+func TestContextReader(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &contextReader{ctx: ctx, rc: io.NopCloser(strings.NewReader("hello world"))}
+
+	buf := make([]byte, 5)
+	n, err := r.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(buf[:n]))
+
+	cancel()
+	_, err = r.Read(buf)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.NoError(t, r.Close())
+}
+
+// objectServer returns a fake transport serving data as a plain object
+func objectServer(data []byte) roundTripFunc {
+	return func(req *http.Request) (*http.Response, error) {
+		body := io.NopCloser(bytes.NewReader(data))
+		if req.Method == http.MethodHead {
+			body = http.NoBody
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header: http.Header{
+				"Content-Length": []string{strconv.Itoa(len(data))},
+				"Etag":           []string{`"abc"`},
+			},
+			ContentLength: int64(len(data)),
+			Body:          body,
+			Request:       req,
+		}, nil
+	}
+}
+
+// TestS3Client_DownloadToFile tests the object is written to localPath and
+// no temporary file is left behind
+func TestS3Client_DownloadToFile(t *testing.T) {
+	client := setupFakeClient(t, objectServer(testFileContent))
+	dir := t.TempDir()
+	localPath := filepath.Join(dir, "out.txt")
+
+	n, err := client.DownloadToFile(context.Background(), testBucket, testFileName, localPath)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(testFileContent)), n)
+
+	got, err := os.ReadFile(localPath)
+	require.NoError(t, err)
+	assert.Equal(t, testFileContent, got)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+// TestS3Client_DownloadToFileCreateDirs tests parent directories are only
+// created when requested
+func TestS3Client_DownloadToFileCreateDirs(t *testing.T) {
+	client := setupFakeClient(t, objectServer(testFileContent))
+	localPath := filepath.Join(t.TempDir(), "a", "b", "out.txt")
+
+	_, err := client.DownloadToFile(context.Background(), testBucket, testFileName, localPath)
+	assert.Error(t, err)
+
+	_, err = client.DownloadToFileWithOptions(context.Background(), testBucket, testFileName, localPath, &DownloadOptions{CreateDirs: true})
+	require.NoError(t, err)
+	assert.FileExists(t, localPath)
+}
+
+// TestS3Client_DownloadToFileNotFound tests a missing object leaves any
+// existing file untouched
+func TestS3Client_DownloadToFileNotFound(t *testing.T) {
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		return fakeErrorResponse(req, http.StatusNotFound, "NotFound"), nil
+	})
+	dir := t.TempDir()
+	localPath := filepath.Join(dir, "out.txt")
+	require.NoError(t, os.WriteFile(localPath, []byte("previous"), 0o644))
+
+	_, err := client.DownloadToFile(context.Background(), testBucket, testFileName, localPath)
+	assert.ErrorIs(t, err, ErrFileNotFound)
+
+	got, err := os.ReadFile(localPath)
+	require.NoError(t, err)
+	assert.Equal(t, "previous", string(got))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+// TestS3Client_DownloadToFileReplaced tests an object replaced between the
+// HeadObject and the GetObject fails with ErrObjectModified
+func TestS3Client_DownloadToFileReplaced(t *testing.T) {
+	tests := []struct {
+		name string
+		opts *DownloadOptions
+	}{
+		{name: "Decompressed", opts: &DownloadOptions{AutoDecompress: true}},
+		{name: "Raw", opts: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+				if req.Method == http.MethodHead {
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Header:     http.Header{"Content-Encoding": []string{gzipEncoding}, "Etag": []string{`"abc"`}},
+						Body:       http.NoBody,
+						Request:    req,
+					}, nil
+				}
+				assert.Equal(t, `"abc"`, req.Header.Get("If-Match"))
+				return fakeErrorResponse(req, http.StatusPreconditionFailed, "PreconditionFailed"), nil
+			})
+			localPath := filepath.Join(t.TempDir(), "out.txt")
+
+			_, err := client.DownloadToFileWithOptions(context.Background(), testBucket, testFileName, localPath, tt.opts)
+			assert.ErrorIs(t, err, ErrObjectModified)
+			assert.NoFileExists(t, localPath)
+		})
+	}
+}
+
+// failingReader returns data and then a read error
+type failingReader struct {
+	data []byte
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+// TestS3Client_DownloadToWriter tests streaming into a writer and the errors
+// reported before and after data is written
+func TestS3Client_DownloadToWriter(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Success", func(t *testing.T) {
+		client := setupFakeClient(t, objectServer(testFileContent))
+		var buf bytes.Buffer
+		n, err := client.DownloadToWriter(ctx, testBucket, testFileName, &buf)
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(testFileContent)), n)
+		assert.Equal(t, testFileContent, buf.Bytes())
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+			return fakeErrorResponse(req, http.StatusNotFound, "NoSuchKey"), nil
+		})
+		n, err := client.DownloadToWriter(ctx, testBucket, testFileName, io.Discard)
+		assert.ErrorIs(t, err, ErrFileNotFound)
+		assert.NotErrorIs(t, err, ErrDownloadInterrupted)
+		assert.Zero(t, n)
+	})
+
+	t.Run("Interrupted", func(t *testing.T) {
+		client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{},
+				Body:       io.NopCloser(&failingReader{data: testFileContent}),
+				Request:    req,
+			}, nil
+		})
+		var buf bytes.Buffer
+		n, err := client.DownloadToWriter(ctx, testBucket, testFileName, &buf)
+		assert.ErrorIs(t, err, ErrDownloadInterrupted)
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+		assert.Equal(t, int64(len(testFileContent)), n)
+	})
+
+	t.Run("Nil writer", func(t *testing.T) {
+		client := setupTestClient(t)
+		_, err := client.DownloadToWriter(ctx, testBucket, testFileName, nil)
+		assert.ErrorIs(t, err, ErrNilValue)
+	})
+}
+
+// TestS3Client_DownloadToWriterResume tests an interrupted body is resumed
+// with a ranged request pinned to the original ETag, and that a replaced
+// object is not stitched together
+func TestS3Client_DownloadToWriterResume(t *testing.T) {
+	ctx := context.Background()
+	half := len(testFileContent) / 2
+
+	serve := func(replaced bool, ranges *[]string) roundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			*ranges = append(*ranges, req.Header.Get("Range"))
+			if req.Header.Get("Range") == "" {
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     http.Header{"Etag": []string{`"abc"`}},
+					Body:       io.NopCloser(&failingReader{data: testFileContent[:half]}),
+					Request:    req,
+				}, nil
+			}
+			assert.Equal(t, `"abc"`, req.Header.Get("If-Match"))
+			if replaced {
+				return fakeErrorResponse(req, http.StatusPreconditionFailed, "PreconditionFailed"), nil
+			}
+			return &http.Response{
+				StatusCode: http.StatusPartialContent,
+				Header:     http.Header{"Etag": []string{`"abc"`}},
+				Body:       io.NopCloser(bytes.NewReader(testFileContent[half:])),
+				Request:    req,
+			}, nil
+		}
+	}
+
+	t.Run("Resumed", func(t *testing.T) {
+		var ranges []string
+		client := setupFakeClient(t, serve(false, &ranges))
+		client.config.MaxRetries = 2
+
+		var buf bytes.Buffer
+		n, err := client.DownloadToWriter(ctx, testBucket, testFileName, &buf)
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(testFileContent)), n)
+		assert.Equal(t, testFileContent, buf.Bytes())
+		assert.Equal(t, []string{"", "bytes=" + strconv.Itoa(half) + "-"}, ranges)
+	})
+
+	t.Run("Object replaced", func(t *testing.T) {
+		var ranges []string
+		client := setupFakeClient(t, serve(true, &ranges))
+		client.config.MaxRetries = 2
+
+		_, err := client.DownloadToWriter(ctx, testBucket, testFileName, io.Discard)
+		assert.ErrorIs(t, err, ErrObjectModified)
+		assert.Len(t, ranges, 2)
+	})
+
+	t.Run("Retries disabled", func(t *testing.T) {
+		var ranges []string
+		client := setupFakeClient(t, serve(false, &ranges))
+
+		_, err := client.DownloadToWriter(ctx, testBucket, testFileName, io.Discard)
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+		assert.Len(t, ranges, 1)
+	})
+}
+
+// TestS3Client_DownloadToFileResume tests the parts of a concurrent download
+// cut off mid-body are resumed from the bytes received, and that a part that
+// keeps failing gives up after Config.MaxRetries resumptions
+func TestS3Client_DownloadToFileResume(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789abcdef"), 40)
+	const partSize = 256
+
+	// serve cuts off the first request of each part, or every request when
+	// always is set, halfway through its range
+	serve := func(always bool, ranges *[]string) roundTripFunc {
+		var mu sync.Mutex
+		cut := map[int]bool{}
+		return func(req *http.Request) (*http.Response, error) {
+			header := http.Header{"Etag": []string{`"abc"`}}
+			if req.Method == http.MethodHead {
+				header.Set("Content-Length", strconv.Itoa(len(data)))
+				return &http.Response{StatusCode: http.StatusOK, Header: header, Body: http.NoBody, Request: req}, nil
+			}
+			assert.Equal(t, `"abc"`, req.Header.Get("If-Match"))
+			var start, end int
+			_, err := fmt.Sscanf(req.Header.Get("Range"), "bytes=%d-%d", &start, &end)
+			require.NoError(t, err)
+			end = min(end, len(data)-1)
+			header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(data)))
+
+			mu.Lock()
+			*ranges = append(*ranges, req.Header.Get("Range"))
+			first := !cut[start/partSize]
+			cut[start/partSize] = true
+			mu.Unlock()
+
+			var body io.Reader = bytes.NewReader(data[start : end+1])
+			if first || always {
+				body = &failingReader{data: data[start : start+(end+1-start)/2]}
+			}
+			return &http.Response{StatusCode: http.StatusPartialContent, Header: header, Body: io.NopCloser(body), Request: req}, nil
+		}
+	}
+
+	t.Run("Resumed", func(t *testing.T) {
+		var ranges []string
+		client := setupFakeClient(t, serve(false, &ranges))
+		client.config.MaxRetries = 2
+		client.downloader.PartSize = partSize
+		localPath := filepath.Join(t.TempDir(), "out.bin")
+
+		n, err := client.DownloadToFile(context.Background(), testBucket, testFileName, localPath)
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(data)), n)
+		got, err := os.ReadFile(localPath)
+		require.NoError(t, err)
+		assert.Equal(t, data, got)
+		// Each of the three parts is requested once and resumed once
+		assert.Len(t, ranges, 6)
+		assert.Contains(t, ranges, "bytes=128-255")
+		assert.Contains(t, ranges, "bytes=576-767")
+	})
+
+	t.Run("Gives up", func(t *testing.T) {
+		var ranges []string
+		client := setupFakeClient(t, serve(true, &ranges))
+		client.config.MaxRetries = 1
+		client.downloader.PartSize = partSize
+		client.downloader.Concurrency = 1
+		localPath := filepath.Join(t.TempDir(), "out.bin")
+
+		_, err := client.DownloadToFile(context.Background(), testBucket, testFileName, localPath)
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+		assert.NoFileExists(t, localPath)
+		assert.Equal(t, []string{"bytes=0-255", "bytes=128-255"}, ranges)
+	})
+}
+
+// TestIsTransientReadError tests which body read failures are resumed
+func TestIsTransientReadError(t *testing.T) {
+	assert.True(t, isTransientReadError(io.ErrUnexpectedEOF))
+	assert.True(t, isTransientReadError(fmt.Errorf("read: %w", syscall.ECONNRESET)))
+	assert.True(t, isTransientReadError(&net.OpError{Op: "read", Err: errors.New("i/o timeout")}))
+	assert.False(t, isTransientReadError(context.Canceled))
+	assert.False(t, isTransientReadError(ErrChecksumMismatch))
+}
+
+// TestS3Client_DownloadFileWithInfo tests the object's details are taken
+// from the download response
+func TestS3Client_DownloadFileWithInfo(t *testing.T) {
+	var calls int32
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&calls, 1)
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header: http.Header{
+				"Content-Length":          []string{strconv.Itoa(len(testFileContent))},
+				"Content-Type":            []string{"text/plain"},
+				"Etag":                    []string{`"abc"`},
+				"Last-Modified":           []string{"Mon, 02 Jan 2006 15:04:05 GMT"},
+				"X-Amz-Version-Id":        []string{"v1"},
+				"X-Amz-Meta-Author":       []string{"alice"},
+				"X-Amz-Meta-S3lib-Sha256": []string{"internal"},
+			},
+			ContentLength: int64(len(testFileContent)),
+			Body:          io.NopCloser(bytes.NewReader(testFileContent)),
+			Request:       req,
+		}, nil
+	})
+
+	result, err := client.DownloadFileWithInfo(context.Background(), testBucket, testFileName)
+	require.NoError(t, err)
+	assert.Equal(t, testFileContent, result.Data)
+	assert.Equal(t, "text/plain", result.ContentType)
+	assert.Equal(t, `"abc"`, result.ETag)
+	assert.Equal(t, time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC), result.LastModified.UTC())
+	assert.Equal(t, "v1", result.VersionID)
+	assert.Equal(t, map[string]string{"author": "alice"}, result.Metadata)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+// TestS3Client_DownloadFileSingleRequest tests a small download costs one
+// API call and errors keep their meaning without a HeadObject
+func TestS3Client_DownloadFileSingleRequest(t *testing.T) {
+	var calls int32
+	serve := objectServer(testFileContent)
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&calls, 1)
+		assert.Equal(t, http.MethodGet, req.Method)
+		return serve(req)
+	})
+
+	result, err := client.DownloadFileResult(context.Background(), testBucket, testFileName, nil)
+	require.NoError(t, err)
+	assert.Equal(t, testFileContent, result.Data)
+	assert.Equal(t, `"abc"`, result.ETag)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	for code, wantErr := range map[string]error{
+		"NoSuchKey":    ErrFileNotFound,
+		"NoSuchBucket": ErrInvalidBucket,
+	} {
+		t.Run(code, func(t *testing.T) {
+			var calls int32
+			client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+				atomic.AddInt32(&calls, 1)
+				return fakeErrorResponse(req, http.StatusNotFound, code), nil
+			})
+
+			_, err := client.DownloadFile(context.Background(), testBucket, testFileName)
+			assert.ErrorIs(t, err, wantErr)
+			assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+		})
+	}
+}
+
+// TestS3Client_DownloadVersion tests VersionID is sent by every read and
+// delete and reported back by GetFileInfo
+func TestS3Client_DownloadVersion(t *testing.T) {
+	var versions []string
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		versions = append(versions, req.URL.Query().Get("versionId"))
+		if req.Method == http.MethodDelete {
+			return &http.Response{StatusCode: http.StatusNoContent, Header: http.Header{}, Body: http.NoBody, Request: req}, nil
+		}
+		resp, err := objectServer(testFileContent)(req)
+		resp.Header.Set("X-Amz-Version-Id", "v1")
+		return resp, err
+	})
+	ctx := context.Background()
+	opts := &DownloadOptions{VersionID: "v1"}
+
+	_, err := client.DownloadFileWithOptions(ctx, testBucket, testFileName, opts)
+	require.NoError(t, err)
+
+	body, info, err := client.DownloadStreamWithOptions(ctx, testBucket, testFileName, opts)
+	require.NoError(t, err)
+	body.Close()
+	assert.Equal(t, "v1", info.VersionID)
+
+	info, err = client.GetFileInfoWithOptions(ctx, testBucket, testFileName, opts)
+	require.NoError(t, err)
+	assert.Equal(t, "v1", info.VersionID)
+
+	require.NoError(t, client.DeleteFileVersion(ctx, testBucket, testFileName, "v1"))
+
+	for _, v := range versions {
+		assert.Equal(t, "v1", v)
+	}
+}
+
+// TestS3Client_DownloadVersionNotFound tests a missing version maps to
+// ErrFileNotFound
+func TestS3Client_DownloadVersionNotFound(t *testing.T) {
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		return fakeErrorResponse(req, http.StatusNotFound, "NoSuchVersion"), nil
+	})
+
+	_, err := client.DownloadFileWithOptions(context.Background(), testBucket, testFileName, &DownloadOptions{VersionID: "missing"})
+	assert.ErrorIs(t, err, ErrFileNotFound)
+}
+
+// TestS3Client_DownloadNotModified tests conditional headers are sent and a
+// 304 response maps to ErrNotModified
+func TestS3Client_DownloadNotModified(t *testing.T) {
+	since := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	var gotETag, gotSince string
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		gotETag = req.Header.Get("If-None-Match")
+		gotSince = req.Header.Get("If-Modified-Since")
+		return &http.Response{StatusCode: http.StatusNotModified, Header: http.Header{}, Body: http.NoBody, Request: req}, nil
+	})
+	ctx := context.Background()
+	opts := &DownloadOptions{IfNoneMatch: "abc", IfModifiedSince: since}
+
+	data, err := client.DownloadFileWithOptions(ctx, testBucket, testFileName, opts)
+	assert.ErrorIs(t, err, ErrNotModified)
+	assert.Nil(t, data)
+	assert.Equal(t, `"abc"`, gotETag)
+	assert.Equal(t, since.Format(http.TimeFormat), gotSince)
+
+	body, _, err := client.DownloadStreamWithOptions(ctx, testBucket, testFileName, opts)
+	assert.ErrorIs(t, err, ErrNotModified)
+	assert.Nil(t, body)
+
+	_, err = client.GetFileInfoWithOptions(ctx, testBucket, testFileName, opts)
+	assert.ErrorIs(t, err, ErrNotModified)
+}
+
+// TestQuoteETag tests ETags are quoted once
+func TestQuoteETag(t *testing.T) {
+	assert.Equal(t, `"abc"`, quoteETag("abc"))
+	assert.Equal(t, `"abc"`, quoteETag(`"abc"`))
+	assert.Equal(t, `W/"abc"`, quoteETag(`W/"abc"`))
+	assert.Equal(t, "*", quoteETag("*"))
+}
+
+// TestS3Client_MaxDownloadSize tests oversized objects are refused up front
+// and streams of unknown length are cut off once they cross the limit
+func TestS3Client_MaxDownloadSize(t *testing.T) {
+	newClient := func(fn roundTripFunc) *S3Client {
+		client := setupFakeClient(t, fn)
+		client.config.MaxDownloadSize = 5
+		return client
+	}
+	ctx := context.Background()
+
+	client := newClient(objectServer(testFileContent))
+	_, err := client.DownloadFile(ctx, testBucket, testFileName)
+	assert.ErrorIs(t, err, ErrObjectTooLarge)
+
+	_, _, err = client.DownloadStream(ctx, testBucket, testFileName)
+	assert.ErrorIs(t, err, ErrObjectTooLarge)
+
+	_, err = client.DownloadToFile(ctx, testBucket, testFileName, filepath.Join(t.TempDir(), "out.txt"))
+	assert.ErrorIs(t, err, ErrObjectTooLarge)
+
+	client = newClient(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode:    http.StatusOK,
+			Header:        http.Header{},
+			ContentLength: -1,
+			Body:          io.NopCloser(bytes.NewReader(testFileContent)),
+			Request:       req,
+		}, nil
+	})
+	body, _, err := client.DownloadStream(ctx, testBucket, testFileName)
+	require.NoError(t, err)
+	defer body.Close()
+	_, err = io.ReadAll(body)
+	assert.ErrorIs(t, err, ErrObjectTooLarge)
+}
+
+// BenchmarkS3Client_DownloadFile measures repeated 1MB downloads through the
+// shared downloader
+func BenchmarkS3Client_DownloadFile(b *testing.B) {
+	data := bytes.Repeat([]byte("b"), 1024*1024)
+	client := setupFakeClient(b, objectServer(data))
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := client.DownloadFile(ctx, testBucket, testFileName); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package s3lib
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// Server-side encryption algorithms accepted by UploadOptions.ServerSideEncryption
+const (
+	SSEAES256 = "AES256"  // SSE-S3, keys managed by S3
+	SSEKMS    = "aws:kms" // SSE-KMS, keys managed by AWS KMS
+)
+
+// validateServerSideEncryption checks the SSE options of an upload
+func validateServerSideEncryption(sse, kmsKeyID string) error {
+	switch sse {
+	case "", SSEAES256, SSEKMS:
+	default:
+		return fmt.Errorf("%w: unsupported server-side encryption %q", ErrInvalidOptions, sse)
+	}
+	if kmsKeyID != "" && sse != SSEKMS {
+		return fmt.Errorf("%w: SSEKMSKeyID requires ServerSideEncryption %q", ErrInvalidOptions, SSEKMS)
+	}
+	return nil
+}
+
+// applyServerSideEncryption sets the SSE parameters on an upload input
+func applyServerSideEncryption(input *s3manager.UploadInput, sse, kmsKeyID string) {
+	if sse != "" {
+		input.ServerSideEncryption = aws.String(sse)
+	}
+	if kmsKeyID != "" {
+		input.SSEKMSKeyId = aws.String(kmsKeyID)
+	}
+}
+
+// SSECustomerKeySize is the length in bytes of an SSE-C key (AES-256)
+const SSECustomerKeySize = 32
+
+// validateSSECustomerKey checks the length of an SSE-C key, if one is given
+func validateSSECustomerKey(key []byte) error {
+	if key != nil && len(key) != SSECustomerKeySize {
+		return fmt.Errorf("%w: SSECustomerKey must be %d bytes, got %d", ErrInvalidOptions, SSECustomerKeySize, len(key))
+	}
+	return nil
+}
+
+// sseCustomerOperations are the requests that must carry the SSE-C headers
+var sseCustomerOperations = map[string]bool{
+	"PutObject":               true,
+	"CreateMultipartUpload":   true,
+	"UploadPart":              true,
+	"CompleteMultipartUpload": true,
+	"GetObject":               true,
+	"HeadObject":              true,
+}
+
+// withSSECustomerKey returns a request option that sets the SSE-C algorithm,
+// base64 encoded key and key MD5 headers on object reads and writes
+func withSSECustomerKey(key []byte) request.Option {
+	encoded := base64.StdEncoding.EncodeToString(key)
+	sum := md5.Sum(key)
+	keyMD5 := base64.StdEncoding.EncodeToString(sum[:])
+
+	return func(r *request.Request) {
+		r.Handlers.Build.PushBack(func(r *request.Request) {
+			if r.Operation == nil || !sseCustomerOperations[r.Operation.Name] {
+				return
+			}
+			r.HTTPRequest.Header.Set("x-amz-server-side-encryption-customer-algorithm", SSEAES256)
+			r.HTTPRequest.Header.Set("x-amz-server-side-encryption-customer-key", encoded)
+			r.HTTPRequest.Header.Set("x-amz-server-side-encryption-customer-key-MD5", keyMD5)
+		})
+	}
+}
+
+// isSSECustomerKeyError reports whether a read failed because the object is
+// encrypted with a customer-provided key that was not supplied. HeadObject
+// responses have no body, so only the bare 400 status identifies the case.
+func isSSECustomerKeyError(aerr awserr.Error) bool {
+	if aerr.Code() == "InvalidRequest" {
+		return true
+	}
+	if reqErr, ok := aerr.(awserr.RequestFailure); ok {
+		return reqErr.StatusCode() == http.StatusBadRequest
+	}
+	return aerr.Code() == "BadRequest"
+}
+
+// ClientSideKeySize is the length in bytes of Config.ClientSideKey (AES-256)
+const ClientSideKeySize = 32
+
+// Object metadata recording client-side encryption
+const (
+	clientSideEncryptionMetaKey = "s3lib-encryption"
+	clientSideNonceMetaKey      = "s3lib-nonce"
+	clientSideAlgorithm         = "AES256-GCM"
+)
+
+// newClientSideCipher returns the AES-256-GCM cipher for key
+func newClientSideCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encryptClientSide seals data with key under a random nonce. It returns the
+// ciphertext and a copy of opts whose metadata records the algorithm and nonce.
+func encryptClientSide(key, data []byte, opts *UploadOptions) ([]byte, *UploadOptions, error) {
+	gcm, err := newClientSideCipher(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, err
+	}
+
+	var encOpts UploadOptions
+	if opts != nil {
+		encOpts = *opts
+	}
+	encOpts.Metadata = make(map[string]string, len(encOpts.Metadata)+2)
+	if opts != nil {
+		for k, v := range opts.Metadata {
+			encOpts.Metadata[k] = v
+		}
+	}
+	encOpts.Metadata[clientSideEncryptionMetaKey] = clientSideAlgorithm
+	encOpts.Metadata[clientSideNonceMetaKey] = base64.StdEncoding.EncodeToString(nonce)
+
+	return gcm.Seal(nil, nonce, data, nil), &encOpts, nil
+}
+
+// checkClientSideKey fails the upload paths that stream their payload while
+// Config.ClientSideKey is set. GCM seals a payload as a whole, so only the
+// in-memory uploads can encrypt it; storing plaintext instead would be silent.
+func (c *S3Client) checkClientSideKey() error {
+	if c.config.ClientSideKey != nil {
+		return fmt.Errorf("%w: streaming uploads cannot be encrypted with Config.ClientSideKey; use UploadFile", ErrInvalidOptions)
+	}
+	return nil
+}
+
+// decryptClientSide opens data if metadata marks it as client-side encrypted
+// and returns it unchanged otherwise
+func decryptClientSide(key, data []byte, metadata map[string]*string) ([]byte, error) {
+	alg := metadataValue(metadata, clientSideEncryptionMetaKey)
+	if alg == "" {
+		return data, nil
+	}
+	if alg != clientSideAlgorithm {
+		return nil, fmt.Errorf("%w: unsupported algorithm %q", ErrDecryptionFailed, alg)
+	}
+	if key == nil {
+		return nil, fmt.Errorf("%w: object is client-side encrypted but Config.ClientSideKey is not set", ErrDecryptionFailed)
+	}
+
+	nonce, err := base64.StdEncoding.DecodeString(metadataValue(metadata, clientSideNonceMetaKey))
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid nonce: %w", ErrDecryptionFailed, err)
+	}
+	gcm, err := newClientSideCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDecryptionFailed, err)
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("%w: invalid nonce length %d", ErrDecryptionFailed, len(nonce))
+	}
+
+	plaintext, err := gcm.Open(nil, nonce, data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDecryptionFailed, err)
+	}
+	return plaintext, nil
+}
+
+// libraryMetaPrefix starts the user metadata keys the library records for
+// its own use
+const libraryMetaPrefix = "s3lib-"
+
+// userMetadata returns the metadata of an S3 response with lowercase keys,
+// as S3 stores them, leaving out the library's own entries
+func userMetadata(metadata map[string]*string) map[string]string {
+	if len(metadata) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(metadata))
+	for k, v := range metadata {
+		k = strings.ToLower(k)
+		if strings.HasPrefix(k, libraryMetaPrefix) {
+			continue
+		}
+		m[k] = aws.StringValue(v)
+	}
+	return m
+}
+
+// metadataValue looks up user metadata by name. S3 returns metadata keys in
+// canonical header form, so the comparison ignores case.
+func metadataValue(metadata map[string]*string, name string) string {
+	for k, v := range metadata {
+		if strings.EqualFold(k, name) {
+			return aws.StringValue(v)
+		}
+	}
+	return ""
+}
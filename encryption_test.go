@@ -0,0 +1,266 @@
+package s3lib
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestS3Client_UploadFileServerSideEncryption tests SSE option validation and
+// the headers sent for each mode
+func TestS3Client_UploadFileServerSideEncryption(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    *UploadOptions
+		wantSSE string
+		wantKey string
+		wantErr error
+	}{
+		{
+			name:    "SSE-S3",
+			opts:    &UploadOptions{ServerSideEncryption: SSEAES256},
+			wantSSE: SSEAES256,
+		},
+		{
+			name:    "SSE-KMS with key",
+			opts:    &UploadOptions{ServerSideEncryption: SSEKMS, SSEKMSKeyID: "arn:aws:kms:us-east-1:123456789012:key/test"},
+			wantSSE: SSEKMS,
+			wantKey: "arn:aws:kms:us-east-1:123456789012:key/test",
+		},
+		{
+			name:    "unsupported algorithm",
+			opts:    &UploadOptions{ServerSideEncryption: "DES"},
+			wantErr: ErrInvalidOptions,
+		},
+		{
+			name:    "KMS key without SSE-KMS",
+			opts:    &UploadOptions{ServerSideEncryption: SSEAES256, SSEKMSKeyID: "key"},
+			wantErr: ErrInvalidOptions,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var header http.Header
+			client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+				header = req.Header.Clone()
+				return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: req}, nil
+			})
+
+			_, err := client.UploadFile(context.Background(), testBucket, testFileName, testFileContent, tt.opts)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantSSE, header.Get("X-Amz-Server-Side-Encryption"))
+			assert.Equal(t, tt.wantKey, header.Get("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id"))
+		})
+	}
+}
+
+// TestS3Client_GetFileInfoServerSideEncryption tests the encryption applied
+// to an object is reported
+func TestS3Client_GetFileInfoServerSideEncryption(t *testing.T) {
+	client := setupFakeClient(t, memoryServer(map[string]*memoryObject{}))
+	ctx := context.Background()
+
+	_, err := client.UploadFile(ctx, testBucket, testFileName, testFileContent, &UploadOptions{ServerSideEncryption: SSEAES256})
+	require.NoError(t, err)
+
+	info, err := client.GetFileInfo(ctx, testBucket, testFileName)
+	require.NoError(t, err)
+	assert.Equal(t, SSEAES256, info.ServerSideEncryption)
+	assert.Empty(t, info.SSEKMSKeyID)
+
+	_, err = client.UploadFile(ctx, testBucket, "kms.txt", testFileContent, &UploadOptions{ServerSideEncryption: SSEKMS, SSEKMSKeyID: "alias/uploads"})
+	require.NoError(t, err)
+
+	info, err = client.GetFileInfo(ctx, testBucket, "kms.txt")
+	require.NoError(t, err)
+	assert.Equal(t, SSEKMS, info.ServerSideEncryption)
+	assert.Equal(t, "alias/uploads", info.SSEKMSKeyID)
+}
+
+// TestS3Client_SSECustomerKey tests the SSE-C headers sent on upload and
+// download and the validation of the key length
+func TestS3Client_SSECustomerKey(t *testing.T) {
+	key := bytes.Repeat([]byte("k"), SSECustomerKeySize)
+	sum := md5.Sum(key)
+	wantKey := base64.StdEncoding.EncodeToString(key)
+	wantMD5 := base64.StdEncoding.EncodeToString(sum[:])
+
+	var mu sync.Mutex
+	var headers []http.Header
+	serve := objectServer(testFileContent)
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		headers = append(headers, req.Header.Clone())
+		mu.Unlock()
+		return serve(req)
+	})
+	ctx := context.Background()
+
+	_, err := client.UploadFile(ctx, testBucket, testFileName, testFileContent, &UploadOptions{SSECustomerKey: key})
+	require.NoError(t, err)
+	data, err := client.DownloadFileWithOptions(ctx, testBucket, testFileName, &DownloadOptions{SSECustomerKey: key})
+	require.NoError(t, err)
+	assert.Equal(t, testFileContent, data)
+
+	require.NotEmpty(t, headers)
+	for _, h := range headers {
+		assert.Equal(t, SSEAES256, h.Get("X-Amz-Server-Side-Encryption-Customer-Algorithm"))
+		assert.Equal(t, wantKey, h.Get("X-Amz-Server-Side-Encryption-Customer-Key"))
+		assert.Equal(t, wantMD5, h.Get("X-Amz-Server-Side-Encryption-Customer-Key-Md5"))
+	}
+
+	_, err = client.UploadFile(ctx, testBucket, testFileName, testFileContent, &UploadOptions{SSECustomerKey: key[:16]})
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+	_, err = client.UploadFile(ctx, testBucket, testFileName, testFileContent, &UploadOptions{SSECustomerKey: key, ServerSideEncryption: SSEAES256})
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+	_, err = client.DownloadFileWithOptions(ctx, testBucket, testFileName, &DownloadOptions{SSECustomerKey: key[:16]})
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+}
+
+// TestS3Client_DownloadFileSSECustomerKeyRequired tests reading an SSE-C
+// object without its key
+func TestS3Client_DownloadFileSSECustomerKeyRequired(t *testing.T) {
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		return fakeErrorResponse(req, http.StatusBadRequest, "BadRequest"), nil
+	})
+
+	_, err := client.DownloadFile(context.Background(), testBucket, testFileName)
+	assert.ErrorIs(t, err, ErrEncryptionKeyRequired)
+}
+
+// TestClientSideEncryption tests the AES-256-GCM round trip and the failure
+// modes of decryption
+func TestClientSideEncryption(t *testing.T) {
+	key := bytes.Repeat([]byte("c"), ClientSideKeySize)
+	opts := &UploadOptions{Metadata: map[string]string{"owner": "tests"}}
+
+	ciphertext, encOpts, err := encryptClientSide(key, testFileContent, opts)
+	require.NoError(t, err)
+	assert.NotEqual(t, testFileContent, ciphertext)
+	assert.Equal(t, "tests", encOpts.Metadata["owner"])
+	assert.Len(t, opts.Metadata, 1, "caller's options must not be modified")
+
+	// S3 returns metadata keys in canonical header form
+	metadata := map[string]*string{}
+	for k, v := range encOpts.Metadata {
+		metadata[http.CanonicalHeaderKey(k)] = aws.String(v)
+	}
+
+	tests := []struct {
+		name     string
+		key      []byte
+		data     []byte
+		metadata map[string]*string
+		want     []byte
+		wantErr  error
+	}{
+		{
+			name:     "round trip",
+			key:      key,
+			data:     ciphertext,
+			metadata: metadata,
+			want:     testFileContent,
+		},
+		{
+			name:     "unencrypted object",
+			key:      key,
+			data:     testFileContent,
+			metadata: map[string]*string{"Owner": aws.String("tests")},
+			want:     testFileContent,
+		},
+		{
+			name:     "wrong key",
+			key:      bytes.Repeat([]byte("x"), ClientSideKeySize),
+			data:     ciphertext,
+			metadata: metadata,
+			wantErr:  ErrDecryptionFailed,
+		},
+		{
+			name:     "missing key",
+			data:     ciphertext,
+			metadata: metadata,
+			wantErr:  ErrDecryptionFailed,
+		},
+		{
+			name:     "tampered ciphertext",
+			key:      key,
+			data:     append([]byte{ciphertext[0] ^ 1}, ciphertext[1:]...),
+			metadata: metadata,
+			wantErr:  ErrDecryptionFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decryptClientSide(tt.key, tt.data, tt.metadata)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// TestS3Client_ClientSideKeyStreaming tests the upload paths that cannot
+// encrypt a streamed payload refuse to run rather than store plaintext
+func TestS3Client_ClientSideKeyStreaming(t *testing.T) {
+	cfg := testConfig
+	cfg.Endpoint = "http://s3.fake.local"
+	cfg.ClientSideKey = bytes.Repeat([]byte("c"), ClientSideKeySize)
+	cfg.HTTPClient = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		t.Errorf("unexpected request %s %s", req.Method, req.URL.Path)
+		return nil, errors.New("unexpected request")
+	})}
+	client, err := NewS3Client(cfg)
+	require.NoError(t, err)
+	ctx := context.Background()
+	dir := t.TempDir()
+	localPath := filepath.Join(dir, "file.txt")
+	require.NoError(t, os.WriteFile(localPath, testFileContent, 0o644))
+
+	_, err = client.UploadStream(ctx, testBucket, testFileName, bytes.NewReader(testFileContent), nil)
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+	_, err = client.UploadFromFile(ctx, testBucket, testFileName, localPath, nil)
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+	_, err = client.UploadDirectory(ctx, testBucket, "dir/", dir, nil)
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+	_, err = client.SyncDirectoryToBucket(ctx, dir, testBucket, "dir/", nil)
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+	_, err = client.NewWriter(ctx, testBucket, testFileName, nil)
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+	_, err = client.StartResumableUpload(ctx, testBucket, testFileName, nil)
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+	_, err = client.ResumeUpload(ctx, &ResumableUploadState{Bucket: testBucket, Key: testFileName, UploadID: "upload", PartSize: MinPartSize}, bytes.NewReader(testFileContent))
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+	_, err = client.UploadAtomic(ctx, testBucket, testFileName, bytes.NewReader(testFileContent), nil)
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+}
+
+// TestUserMetadata tests keys are lowercased and library entries dropped
+func TestUserMetadata(t *testing.T) {
+	got := userMetadata(map[string]*string{
+		"Author":           aws.String("alice"),
+		"S3lib-Encryption": aws.String(clientSideAlgorithm),
+		"S3lib-Nonce":      aws.String("nonce"),
+	})
+	assert.Equal(t, map[string]string{"author": "alice"}, got)
+	assert.Nil(t, userMetadata(nil))
+}
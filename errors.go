@@ -14,4 +14,76 @@ var (
     
     // ErrFileNotFound is returned when the requested file is not found
     ErrFileNotFound = errors.New("file not found")
+    
+    // ErrNilValue is returned when a required value argument is nil
+    ErrNilValue = errors.New("nil value")
+    
+    // ErrJSONEncode is returned when a value cannot be marshaled to JSON
+    ErrJSONEncode = errors.New("failed to encode JSON")
+    
+    // ErrJSONDecode is returned when an object cannot be unmarshaled from JSON
+    ErrJSONDecode = errors.New("failed to decode JSON")
+    
+    // ErrInvalidOptions is returned when operation options are invalid
+    ErrInvalidOptions = errors.New("invalid options")
+    
+    // ErrUploadNotFound is returned when a multipart upload no longer exists
+    ErrUploadNotFound = errors.New("multipart upload not found")
+    
+    // ErrChecksumMismatch is returned when S3 or the library detects corrupted data
+    ErrChecksumMismatch = errors.New("checksum mismatch")
+    
+    // ErrEncryptionKeyRequired is returned when an object needs a customer-provided key to be read
+    ErrEncryptionKeyRequired = errors.New("object is encrypted with a customer-provided key")
+    
+    // ErrDecryptionFailed is returned when a client-side encrypted object cannot be decrypted
+    ErrDecryptionFailed = errors.New("failed to decrypt object")
+    
+    // ErrObjectExists is returned when a conditional write finds the key already exists
+    ErrObjectExists = errors.New("object already exists")
+    
+    // ErrObjectTooLarge is returned when an object exceeds Config.MaxUploadSize or Config.MaxDownloadSize
+    ErrObjectTooLarge = errors.New("object exceeds maximum size")
+    
+    // ErrDownloadInterrupted is returned when a download fails after data has been written
+    ErrDownloadInterrupted = errors.New("download interrupted")
+    
+    // ErrInvalidRange is returned when a requested byte range is invalid or starts past the end of the object
+    ErrInvalidRange = errors.New("invalid byte range")
+    
+    // ErrObjectModified is returned when an object is replaced while it is being read
+    ErrObjectModified = errors.New("object modified during read")
+    
+    // ErrNotModified is returned when a conditional download finds the object unchanged
+    ErrNotModified = errors.New("object not modified")
+    
+    // ErrDecompressionFailed is returned when a gzip encoded object is corrupt
+    ErrDecompressionFailed = errors.New("failed to decompress object")
+    
+    // ErrObjectArchived is returned when an object must be restored from GLACIER or DEEP_ARCHIVE before it can be read
+    ErrObjectArchived = errors.New("object is archived")
+    
+    // ErrInvalidContinuationToken is returned when S3 rejects a listing's continuation token as malformed or expired
+    ErrInvalidContinuationToken = errors.New("invalid continuation token")
+    
+    // ErrAccessDenied is returned when the credentials are not allowed to perform an operation
+    ErrAccessDenied = errors.New("access denied")
+    
+    // ErrObjectLocked is returned when Object Lock retention or a legal hold prevents deleting a version
+    ErrObjectLocked = errors.New("object version is locked")
+    
+    // ErrNotDeleted is returned when undeleting a key whose current version is not a delete marker
+    ErrNotDeleted = errors.New("object is not deleted")
+    
+    // ErrWrongRegion is returned when a bucket is in another region than the client's
+    ErrWrongRegion = errors.New("bucket is in another region")
+    
+    // ErrBucketExists is returned when creating a bucket whose name is taken by another account
+    ErrBucketExists = errors.New("bucket already exists")
+    
+    // ErrBucketNotEmpty is returned when deleting a bucket that still holds objects
+    ErrBucketNotEmpty = errors.New("bucket is not empty")
+    
+    // ErrWaitTimeout is returned when the context ends before a waited-for condition is met
+    ErrWaitTimeout = errors.New("timed out waiting")
 )
\ No newline at end of file
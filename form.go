@@ -0,0 +1,88 @@
+package s3lib
+
+import (
+	"context"
+	"fmt"
+	"mime/multipart"
+	"path"
+	"strings"
+	"unicode"
+)
+
+// maxFilenameLength bounds the length of keys derived from client filenames
+const maxFilenameLength = 255
+
+// UploadFromMultipartFile streams a file received in an HTTP multipart form
+// to the specified bucket without buffering it in memory. If opts does not
+// set a ContentType the part's Content-Type header is used. Config.MaxUploadSize
+// is checked against the declared size before anything is read and against
+// the actual stream while uploading.
+func (c *S3Client) UploadFromMultipartFile(ctx context.Context, bucket, key string, fh *multipart.FileHeader, opts *UploadOptions) (string, error) {
+	if fh == nil {
+		return "", ErrNilValue
+	}
+	if bucket == "" {
+		return "", ErrInvalidBucket
+	}
+	if key == "" {
+		return "", ErrInvalidKey
+	}
+	if max := c.config.MaxUploadSize; max > 0 && fh.Size > max {
+		return "", tooLargeError(max)
+	}
+
+	f, err := fh.Open()
+	if err != nil {
+		return "", fmt.Errorf("failed to open form file: %w", err)
+	}
+	defer f.Close()
+
+	uploadOpts := UploadOptions{}
+	if opts != nil {
+		uploadOpts = *opts
+	}
+	// Browsers send application/octet-stream for unknown types, which is no
+	// better than detecting the type from the key or content
+	if ct := fh.Header.Get("Content-Type"); uploadOpts.ContentType == "" && ct != "" && ct != "application/octet-stream" {
+		uploadOpts.ContentType = ct
+	}
+
+	result, err := c.uploadSeeker(ctx, bucket, key, f, fh.Size, uploadOpts)
+	if err != nil {
+		return "", err
+	}
+	return result.Location, nil
+}
+
+// SanitizeFilename turns a client-provided filename into a string that is
+// safe to use as (part of) an object key. Directory components, including
+// Windows ones, are dropped and anything other than letters, digits, '.',
+// '-' and '_' is replaced with '_'. Leading dots are removed so the result
+// is never hidden, "." or "..". An empty result becomes "file".
+func SanitizeFilename(name string) string {
+	name = path.Base(strings.ReplaceAll(name, `\`, "/"))
+
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)), r == '.', r == '-', r == '_':
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+
+	safe := strings.TrimLeft(b.String(), ".")
+	if len(safe) > maxFilenameLength {
+		// Keep the extension when truncating
+		ext := path.Ext(safe)
+		if len(ext) > 16 {
+			ext = ""
+		}
+		safe = safe[:maxFilenameLength-len(ext)] + ext
+	}
+	if safe == "" {
+		return "file"
+	}
+	return safe
+}
@@ -0,0 +1,101 @@
+package s3lib
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFormFile builds a multipart form with one file part and returns its header
+func newFormFile(t *testing.T, filename, contentType string, content []byte) *multipart.FileHeader {
+	t.Helper()
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Disposition", `form-data; name="file"; filename="`+filename+`"`)
+	h.Set("Content-Type", contentType)
+	part, err := w.CreatePart(h)
+	require.NoError(t, err)
+	_, err = part.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	form, err := multipart.NewReader(&body, w.Boundary()).ReadForm(1 << 20)
+	require.NoError(t, err)
+	t.Cleanup(func() { form.RemoveAll() })
+	return form.File["file"][0]
+}
+
+// TestS3Client_UploadFromMultipartFile tests a form file is uploaded with the
+// part's content type
+func TestS3Client_UploadFromMultipartFile(t *testing.T) {
+	objects := map[string]*memoryObject{}
+	client := setupFakeClient(t, memoryServer(objects))
+	ctx := context.Background()
+	fh := newFormFile(t, "report.csv", "text/csv", testFileContent)
+
+	location, err := client.UploadFromMultipartFile(ctx, testBucket, "uploads/"+SanitizeFilename(fh.Filename), fh, nil)
+	require.NoError(t, err)
+	assert.NotEmpty(t, location)
+
+	info, err := client.GetFileInfo(ctx, testBucket, "uploads/report.csv")
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(testFileContent)), info.Size)
+	require.Contains(t, objects, "uploads/report.csv")
+	assert.Equal(t, testFileContent, objects["uploads/report.csv"].data)
+	assert.Equal(t, "text/csv", objects["uploads/report.csv"].header.Get("Content-Type"))
+}
+
+// TestS3Client_UploadFromMultipartFileTooLarge tests the declared size is
+// checked before any request
+func TestS3Client_UploadFromMultipartFileTooLarge(t *testing.T) {
+	var requests int
+	cfg := testConfig
+	cfg.Endpoint = "http://s3.fake.local"
+	cfg.MaxUploadSize = 4
+	cfg.HTTPClient = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requests++
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: req}, nil
+	})}
+	client, err := NewS3Client(cfg)
+	require.NoError(t, err)
+
+	_, err = client.UploadFromMultipartFile(context.Background(), testBucket, testFileName, newFormFile(t, "a.txt", "text/plain", testFileContent), nil)
+	assert.ErrorIs(t, err, ErrObjectTooLarge)
+	_, err = client.UploadFromMultipartFile(context.Background(), testBucket, testFileName, nil, nil)
+	assert.ErrorIs(t, err, ErrNilValue)
+	assert.Zero(t, requests)
+}
+
+// TestSanitizeFilename tests client filenames are reduced to safe key parts
+func TestSanitizeFilename(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		want     string
+	}{
+		{name: "Plain", filename: "report-2024_v1.pdf", want: "report-2024_v1.pdf"},
+		{name: "Unix path", filename: "../../etc/passwd", want: "passwd"},
+		{name: "Windows path", filename: `C:\Users\me\photo.jpg`, want: "photo.jpg"},
+		{name: "Spaces and symbols", filename: "my file (1)&.txt", want: "my_file__1__.txt"},
+		{name: "Non-ASCII", filename: "résumé.doc", want: "r_sum_.doc"},
+		{name: "Hidden file", filename: ".env", want: "env"},
+		{name: "Dot dot", filename: "..", want: "file"},
+		{name: "Empty", filename: "", want: "file"},
+		{name: "Long name keeps extension", filename: strings.Repeat("a", 300) + ".png", want: strings.Repeat("a", 251) + ".png"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, SanitizeFilename(tt.filename))
+		})
+	}
+}
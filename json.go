@@ -0,0 +1,64 @@
+package s3lib
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// UploadJSON marshals v and uploads it with Content-Type application/json.
+// Marshaling failures wrap ErrJSONEncode so they can be told apart from S3 errors.
+func (c *S3Client) UploadJSON(ctx context.Context, bucket, key string, v interface{}, opts *UploadOptions) (string, error) {
+	if isNilValue(v) {
+		return "", ErrNilValue
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrJSONEncode, err)
+	}
+
+	uploadOpts := UploadOptions{}
+	if opts != nil {
+		uploadOpts = *opts
+	}
+	uploadOpts.ContentType = "application/json"
+
+	return c.UploadFile(ctx, bucket, key, data, &uploadOpts)
+}
+
+// DownloadJSON downloads an object and unmarshals it into v, which must be a
+// non-nil pointer. Gzip encoded documents are decompressed first.
+// Unmarshaling failures wrap ErrJSONDecode.
+func (c *S3Client) DownloadJSON(ctx context.Context, bucket, key string, v interface{}) error {
+	if isNilValue(v) {
+		return ErrNilValue
+	}
+	if reflect.ValueOf(v).Kind() != reflect.Ptr {
+		return fmt.Errorf("%w: destination must be a pointer, got %T", ErrJSONDecode, v)
+	}
+
+	data, err := c.DownloadFileWithOptions(ctx, bucket, key, &DownloadOptions{AutoDecompress: true})
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%w: %w", ErrJSONDecode, err)
+	}
+	return nil
+}
+
+// isNilValue reports whether v is nil or a typed nil pointer, map, or slice
+func isNilValue(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+package s3lib
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testDocument struct {
+	Name  string   `json:"name"`
+	Count int      `json:"count"`
+	Tags  []string `json:"tags"`
+}
+
+// TestS3Client_UploadJSON tests the UploadJSON function
+func TestS3Client_UploadJSON(t *testing.T) {
+	objects := map[string]*memoryObject{}
+	client := setupFakeClient(t, memoryServer(objects))
+
+	var nilDoc *testDocument
+
+	tests := []struct {
+		name      string
+		value     interface{}
+		wantErr   bool
+		wantErrIs error
+	}{
+		{
+			name:    "Valid document",
+			value:   testDocument{Name: "report", Count: 3, Tags: []string{"a"}},
+			wantErr: false,
+		},
+		{
+			name:      "Nil value",
+			value:     nil,
+			wantErr:   true,
+			wantErrIs: ErrNilValue,
+		},
+		{
+			name:      "Typed nil pointer",
+			value:     nilDoc,
+			wantErr:   true,
+			wantErrIs: ErrNilValue,
+		},
+		{
+			name:      "Unmarshalable value",
+			value:     map[string]interface{}{"ch": make(chan int)},
+			wantErr:   true,
+			wantErrIs: ErrJSONEncode,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			location, err := client.UploadJSON(ctx, testBucket, "test-doc.json", tt.value, nil)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.ErrorIs(t, err, tt.wantErrIs)
+				assert.Empty(t, location)
+			} else {
+				assert.NoError(t, err)
+				assert.NotEmpty(t, location)
+				require.Contains(t, objects, "test-doc.json")
+				assert.JSONEq(t, `{"name":"report","count":3,"tags":["a"]}`, string(objects["test-doc.json"].data))
+				assert.Equal(t, "application/json", objects["test-doc.json"].header.Get("Content-Type"))
+			}
+		})
+	}
+}
+
+// TestS3Client_DownloadJSON tests the DownloadJSON function
+func TestS3Client_DownloadJSON(t *testing.T) {
+	client := setupFakeClient(t, memoryServer(map[string]*memoryObject{}))
+
+	ctx := context.Background()
+	want := testDocument{Name: "report", Count: 3, Tags: []string{"a", "b"}}
+	_, err := client.UploadJSON(ctx, testBucket, "test-doc.json", want, nil)
+	require.NoError(t, err)
+	_, err = client.UploadFile(ctx, testBucket, "test-not-json.txt", testFileContent, nil)
+	require.NoError(t, err)
+
+	info, err := client.GetFileInfo(ctx, testBucket, "test-doc.json")
+	require.NoError(t, err)
+	assert.NotNil(t, info)
+
+	t.Run("Valid document", func(t *testing.T) {
+		var got testDocument
+		require.NoError(t, client.DownloadJSON(ctx, testBucket, "test-doc.json", &got))
+		assert.Equal(t, want, got)
+	})
+
+	t.Run("Invalid JSON", func(t *testing.T) {
+		var got testDocument
+		err := client.DownloadJSON(ctx, testBucket, "test-not-json.txt", &got)
+		assert.ErrorIs(t, err, ErrJSONDecode)
+	})
+
+	t.Run("Missing object", func(t *testing.T) {
+		var got testDocument
+		err := client.DownloadJSON(ctx, testBucket, "nonexistent.json", &got)
+		assert.ErrorIs(t, err, ErrFileNotFound)
+	})
+
+	t.Run("Nil destination", func(t *testing.T) {
+		assert.ErrorIs(t, client.DownloadJSON(ctx, testBucket, "test-doc.json", nil), ErrNilValue)
+	})
+
+	t.Run("Non-pointer destination", func(t *testing.T) {
+		assert.ErrorIs(t, client.DownloadJSON(ctx, testBucket, "test-doc.json", testDocument{}), ErrJSONDecode)
+	})
+}
@@ -0,0 +1,94 @@
+package s3lib
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// timestampKeyLayout is RFC 3339 with a fixed nine digit fraction, so keys
+// sort lexicographically in time order (time.RFC3339Nano trims zeros)
+const timestampKeyLayout = "2006-01-02T15:04:05.000000000Z07:00"
+
+// maxKeyStrategyAttempts bounds how often a generated key is retried after
+// colliding with an existing object
+const maxKeyStrategyAttempts = 3
+
+// KeyStrategy generates the object key for an upload from its payload. Set
+// UploadOptions.KeyStrategy and pass an empty key to UploadFileResult, e.g.
+//
+//	opts := &s3lib.UploadOptions{KeyStrategy: func([]byte) string {
+//		return s3lib.NewUUIDKey("avatars/", ".png")
+//	}}
+type KeyStrategy func(data []byte) string
+
+// NewUUIDKey returns prefix followed by a random (version 4) UUID and ext.
+// A missing leading dot is added to ext.
+func NewUUIDKey(prefix, ext string) string {
+	var u [16]byte
+	if _, err := rand.Read(u[:]); err != nil {
+		panic(fmt.Sprintf("s3lib: failed to read random bytes: %v", err))
+	}
+	u[6] = u[6]&0x0f | 0x40 // version 4
+	u[8] = u[8]&0x3f | 0x80 // RFC 4122 variant
+
+	return fmt.Sprintf("%s%x-%x-%x-%x-%x%s", prefix, u[0:4], u[4:6], u[6:8], u[8:10], u[10:16], extension(ext))
+}
+
+// NewTimestampedKey returns prefix followed by the current UTC time and name,
+// e.g. "logs/2024-05-01T12:00:00.123456789Z-app.log". Keys under the same
+// prefix sort in creation order.
+func NewTimestampedKey(prefix, name string) string {
+	key := prefix + time.Now().UTC().Format(timestampKeyLayout)
+	if name != "" {
+		key += "-" + name
+	}
+	return key
+}
+
+// NewContentHashKey returns prefix followed by the hex SHA-256 of data, so
+// identical payloads map to the same key
+func NewContentHashKey(prefix string, data []byte) string {
+	sum := sha256.Sum256(data)
+	return prefix + hex.EncodeToString(sum[:])
+}
+
+// extension returns ext with a leading dot, or "" if ext is empty
+func extension(ext string) string {
+	if ext == "" || strings.HasPrefix(ext, ".") {
+		return ext
+	}
+	return "." + ext
+}
+
+// uploadGeneratedKey implements UploadOptions.KeyStrategy. The object is
+// only created if the generated key is free; on a collision a new key is
+// generated, unless the strategy is deterministic and returns the same one.
+func (c *S3Client) uploadGeneratedKey(ctx context.Context, bucket string, data []byte, opts *UploadOptions) (*UploadResult, error) {
+	generated := *opts
+	generated.KeyStrategy = nil
+	generated.IfNoneMatch = true
+
+	var previous string
+	for attempt := 0; attempt < maxKeyStrategyAttempts; attempt++ {
+		key := opts.KeyStrategy(data)
+		if key == "" {
+			return nil, fmt.Errorf("%w: KeyStrategy returned an empty key", ErrInvalidKey)
+		}
+		if key == previous {
+			break
+		}
+		previous = key
+
+		result, err := c.uploadBytes(ctx, bucket, key, data, &generated)
+		if !errors.Is(err, ErrObjectExists) {
+			return result, err
+		}
+	}
+	return nil, fmt.Errorf("%w: no free key generated for %s", ErrObjectExists, previous)
+}
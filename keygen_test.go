@@ -0,0 +1,79 @@
+package s3lib
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestNewUUIDKey tests the key layout and UUID version bits
+func TestNewUUIDKey(t *testing.T) {
+	uuidKey := regexp.MustCompile(`^avatars/[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}\.png$`)
+
+	for _, ext := range []string{".png", "png"} {
+		key := NewUUIDKey("avatars/", ext)
+		assert.Regexp(t, uuidKey, key)
+	}
+	assert.NotEqual(t, NewUUIDKey("", ""), NewUUIDKey("", ""))
+}
+
+// TestNewTimestampedKey tests keys sort in creation order
+func TestNewTimestampedKey(t *testing.T) {
+	first := NewTimestampedKey("logs/", "app.log")
+	second := NewTimestampedKey("logs/", "app.log")
+
+	assert.Regexp(t, `^logs/\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}\.\d{9}Z-app\.log$`, first)
+	assert.LessOrEqual(t, first, second)
+	assert.False(t, strings.HasSuffix(NewTimestampedKey("logs/", ""), "-"))
+}
+
+// TestNewContentHashKey tests the key is the SHA-256 of the payload
+func TestNewContentHashKey(t *testing.T) {
+	assert.Equal(t, "blobs/2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", NewContentHashKey("blobs/", []byte("hello")))
+}
+
+// TestS3Client_UploadFileKeyStrategy tests a colliding generated key is
+// replaced and the chosen key is returned
+func TestS3Client_UploadFileKeyStrategy(t *testing.T) {
+	var puts int
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		puts++
+		assert.Equal(t, "*", req.Header.Get("If-None-Match"))
+		if puts == 1 {
+			return fakeErrorResponse(req, http.StatusPreconditionFailed, "PreconditionFailed"), nil
+		}
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: req}, nil
+	})
+
+	keys := []string{"taken.txt", "free.txt"}
+	opts := &UploadOptions{KeyStrategy: func([]byte) string {
+		key := keys[0]
+		keys = keys[1:]
+		return key
+	}}
+
+	result, err := client.UploadFileResult(context.Background(), testBucket, "", testFileContent, opts)
+	require.NoError(t, err)
+	assert.Equal(t, "free.txt", result.Key)
+	assert.Equal(t, 2, puts)
+}
+
+// TestS3Client_UploadFileKeyStrategyDeterministic tests a strategy that keeps
+// returning a taken key fails with ErrObjectExists
+func TestS3Client_UploadFileKeyStrategyDeterministic(t *testing.T) {
+	var puts int
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		puts++
+		return fakeErrorResponse(req, http.StatusPreconditionFailed, "PreconditionFailed"), nil
+	})
+
+	opts := &UploadOptions{KeyStrategy: func(data []byte) string { return NewContentHashKey("blobs/", data) }}
+	_, err := client.UploadFileResult(context.Background(), testBucket, "", testFileContent, opts)
+	assert.ErrorIs(t, err, ErrObjectExists)
+	assert.Equal(t, 1, puts)
+}
@@ -0,0 +1,63 @@
+package s3lib
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxKeyLength is the longest object key S3 accepts, in bytes
+const MaxKeyLength = 1024
+
+// validateKey rejects keys S3 refuses or that create objects which are hard
+// to list or delete
+func validateKey(key string) error {
+	if key == "" {
+		return ErrInvalidKey
+	}
+	if len(key) > MaxKeyLength {
+		return fmt.Errorf("%w: %q is %d bytes, limit is %d", ErrInvalidKey, key, len(key), MaxKeyLength)
+	}
+	if i := strings.IndexFunc(key, unicode.IsControl); i >= 0 {
+		r, _ := utf8.DecodeRuneInString(key[i:])
+		return fmt.Errorf("%w: %q contains control character %U at byte %d", ErrInvalidKey, key, r, i)
+	}
+	return nil
+}
+
+// normalizeKey converts Windows path separators to forward slashes and
+// strips leading slashes
+func normalizeKey(key string) string {
+	return strings.TrimLeft(strings.ReplaceAll(key, `\`, "/"), "/")
+}
+
+// resolveKey applies Config.NormalizeKeys and Config.KeyPrefix and
+// validates the resulting object key
+func (c *S3Client) resolveKey(key string) (string, error) {
+	if c.config.NormalizeKeys {
+		key = normalizeKey(key)
+	}
+	if key == "" {
+		return "", ErrInvalidKey
+	}
+	key = c.config.KeyPrefix + key
+	if err := validateKey(key); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// relativeKey strips Config.KeyPrefix from an object key returned by S3
+func (c *S3Client) relativeKey(key string) string {
+	return strings.TrimPrefix(key, c.config.KeyPrefix)
+}
+
+// WithPrefix returns a client that shares c's connections and settings but
+// nests every key under prefix, appended to any existing Config.KeyPrefix.
+// It is a cheap way to give each tenant of a shared bucket its own view.
+func (c *S3Client) WithPrefix(prefix string) *S3Client {
+	scoped := *c
+	scoped.config.KeyPrefix = c.config.KeyPrefix + prefix
+	return &scoped
+}
@@ -0,0 +1,131 @@
+package s3lib
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestValidateKey tests keys S3 would refuse or mangle are rejected
+func TestValidateKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		wantErr bool
+	}{
+		{name: "Simple key", key: "reports/2024/summary.csv"},
+		{name: "Unicode key", key: "données/été.txt"},
+		{name: "Maximum length", key: strings.Repeat("k", MaxKeyLength)},
+		{name: "Empty key", key: "", wantErr: true},
+		{name: "Too long", key: strings.Repeat("k", MaxKeyLength+1), wantErr: true},
+		{name: "Newline", key: "logs/\napp.log", wantErr: true},
+		{name: "NUL byte", key: "logs/app\x00.log", wantErr: true},
+		{name: "DEL", key: "logs/app\x7f.log", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateKey(tt.key)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidKey)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+// TestS3Client_ResolveKey tests Config.NormalizeKeys and Config.KeyPrefix
+func TestS3Client_ResolveKey(t *testing.T) {
+	tests := []struct {
+		name      string
+		normalize bool
+		prefix    string
+		key       string
+		want      string
+		wantErr   bool
+	}{
+		{name: "Unchanged without normalization", key: `/dir\file.txt`, want: `/dir\file.txt`},
+		{name: "Leading slashes", normalize: true, key: "//dir/file.txt", want: "dir/file.txt"},
+		{name: "Backslashes", normalize: true, key: `dir\sub\file.txt`, want: "dir/sub/file.txt"},
+		{name: "Only slashes", normalize: true, key: "///", wantErr: true},
+		{name: "Control character", normalize: true, key: "dir/\tfile", wantErr: true},
+		{name: "Key prefix", prefix: "tenants/42/", key: "file.txt", want: "tenants/42/file.txt"},
+		{name: "Key prefix after normalization", normalize: true, prefix: "tenants/42/", key: "/file.txt", want: "tenants/42/file.txt"},
+		{name: "Empty key with prefix", prefix: "tenants/42/", key: "", wantErr: true},
+		{name: "Prefixed key too long", prefix: "tenants/42/", key: strings.Repeat("a", MaxKeyLength-5), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig
+			cfg.NormalizeKeys = tt.normalize
+			cfg.KeyPrefix = tt.prefix
+			client, err := NewS3Client(cfg)
+			require.NoError(t, err)
+
+			got, err := client.resolveKey(tt.key)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidKey)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// TestS3Client_InvalidKeyRejected tests keys are checked before any request
+func TestS3Client_InvalidKeyRejected(t *testing.T) {
+	client := setupTestClient(t)
+	ctx := context.Background()
+	key := "bad\nkey"
+
+	_, err := client.UploadFile(ctx, testBucket, key, testFileContent, nil)
+	assert.ErrorIs(t, err, ErrInvalidKey)
+	assert.Contains(t, err.Error(), `"bad\nkey"`)
+	_, err = client.DownloadFile(ctx, testBucket, key)
+	assert.ErrorIs(t, err, ErrInvalidKey)
+	assert.ErrorIs(t, client.DeleteFile(ctx, testBucket, key), ErrInvalidKey)
+}
+
+// TestS3Client_WithPrefix tests scoped clients nest prefixes and return
+// relative keys
+func TestS3Client_WithPrefix(t *testing.T) {
+	cfg := testConfig
+	cfg.KeyPrefix = "tenants/"
+	client, err := NewS3Client(cfg)
+	require.NoError(t, err)
+
+	scoped := client.WithPrefix("42/")
+	got, err := scoped.resolveKey("file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "tenants/42/file.txt", got)
+	assert.Equal(t, "file.txt", scoped.relativeKey(got))
+	assert.Equal(t, "tenants/", client.config.KeyPrefix)
+
+	cfg.KeyPrefix = "bad\nprefix"
+	_, err = NewS3Client(cfg)
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+}
+
+// TestS3Client_ListFilesKeyPrefix tests listings are confined to the prefix
+// and return relative keys
+func TestS3Client_ListFilesKeyPrefix(t *testing.T) {
+	var listPrefix string
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		listPrefix = req.URL.Query().Get("prefix")
+		body := "<ListBucketResult><Contents><Key>tenants/42/logs/a.log</Key><Size>3</Size></Contents></ListBucketResult>"
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: xmlBody(body), Request: req}, nil
+	}).WithPrefix("tenants/42/")
+
+	files, err := client.ListFiles(context.Background(), testBucket, "logs/")
+	require.NoError(t, err)
+	assert.Equal(t, "tenants/42/logs/", listPrefix)
+	require.Len(t, files, 1)
+	assert.Equal(t, "logs/a.log", files[0].Key)
+}
@@ -0,0 +1,304 @@
+package s3lib
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// maxLifecycleRules is the number of rules S3 allows in a bucket's
+// lifecycle configuration
+const maxLifecycleRules = 1000
+
+// LifecycleRule expires or transitions the objects of a bucket that match
+// its filter. Zero day counts leave the action out.
+type LifecycleRule struct {
+	// ID names the rule (up to 255 characters); S3 generates one if empty
+	ID string `json:"id,omitempty"`
+	// Disabled keeps the rule in the configuration without applying it
+	Disabled bool `json:"disabled,omitempty"`
+
+	// Prefix and Tags select the objects the rule applies to: those under
+	// Prefix carrying every one of Tags. Neither selects the whole bucket.
+	Prefix string            `json:"prefix,omitempty"`
+	Tags   map[string]string `json:"tags,omitempty"`
+
+	// ExpirationDays deletes objects this many days after they are created,
+	// or adds a delete marker on versioned buckets
+	ExpirationDays int `json:"expiration_days,omitempty"`
+	// NoncurrentVersionExpirationDays permanently deletes versions this many
+	// days after they stop being current
+	NoncurrentVersionExpirationDays int `json:"noncurrent_version_expiration_days,omitempty"`
+	// AbortIncompleteMultipartUploadDays aborts multipart uploads this many
+	// days after they start. S3 does not allow it on rules with Tags.
+	AbortIncompleteMultipartUploadDays int `json:"abort_incomplete_multipart_upload_days,omitempty"`
+	// Transitions move objects to cheaper storage classes as they age
+	Transitions []LifecycleTransition `json:"transitions,omitempty"`
+}
+
+// LifecycleTransition moves objects to StorageClass Days after they are
+// created
+type LifecycleTransition struct {
+	Days         int          `json:"days"`
+	StorageClass StorageClass `json:"storage_class"`
+}
+
+// LifecycleOptions represents optional parameters for
+// SetBucketLifecycleWithOptions
+type LifecycleOptions struct {
+	// DeleteIfEmpty removes the bucket's lifecycle configuration when no
+	// rules are given, rather than rejecting the empty rule set
+	DeleteIfEmpty bool
+}
+
+// validate checks the rule against S3's constraints, so obviously invalid
+// rules fail before a request is made
+func (r *LifecycleRule) validate() error {
+	name := r.ID
+	if name == "" {
+		name = fmt.Sprintf("rule with prefix %q", r.Prefix)
+	}
+	if len(r.ID) > 255 {
+		return fmt.Errorf("%w: lifecycle rule ID is longer than 255 characters", ErrInvalidOptions)
+	}
+	if r.ExpirationDays < 0 || r.NoncurrentVersionExpirationDays < 0 || r.AbortIncompleteMultipartUploadDays < 0 {
+		return fmt.Errorf("%w: %s: days must not be negative", ErrInvalidOptions, name)
+	}
+	if r.ExpirationDays == 0 && r.NoncurrentVersionExpirationDays == 0 &&
+		r.AbortIncompleteMultipartUploadDays == 0 && len(r.Transitions) == 0 {
+		return fmt.Errorf("%w: %s has no action", ErrInvalidOptions, name)
+	}
+	if r.AbortIncompleteMultipartUploadDays > 0 && len(r.Tags) > 0 {
+		return fmt.Errorf("%w: %s: aborting multipart uploads cannot be combined with a tag filter", ErrInvalidOptions, name)
+	}
+
+	classes := make(map[StorageClass]bool, len(r.Transitions))
+	for _, t := range r.Transitions {
+		if t.Days < 0 {
+			return fmt.Errorf("%w: %s: days must not be negative", ErrInvalidOptions, name)
+		}
+		switch t.StorageClass {
+		case StorageClassStandardIA, StorageClassOneZoneIA:
+			// S3 only moves objects to the infrequent access classes once
+			// they are 30 days old
+			if t.Days < 30 {
+				return fmt.Errorf("%w: %s: transition to %s must be after at least 30 days", ErrInvalidOptions, name, t.StorageClass)
+			}
+		case StorageClassIntelligentTiering, StorageClassGlacier, StorageClassGlacierIR, StorageClassDeepArchive:
+		default:
+			return fmt.Errorf("%w: %s: objects cannot transition to storage class %q", ErrInvalidOptions, name, t.StorageClass)
+		}
+		if classes[t.StorageClass] {
+			return fmt.Errorf("%w: %s transitions to %s more than once", ErrInvalidOptions, name, t.StorageClass)
+		}
+		classes[t.StorageClass] = true
+		if r.ExpirationDays > 0 && t.Days >= r.ExpirationDays {
+			return fmt.Errorf("%w: %s transitions to %s after %d days but expires after %d", ErrInvalidOptions, name, t.StorageClass, t.Days, r.ExpirationDays)
+		}
+	}
+	return nil
+}
+
+// input builds the SDK's form of the rule
+func (r *LifecycleRule) input() *s3.LifecycleRule {
+	rule := &s3.LifecycleRule{
+		Status: aws.String(s3.ExpirationStatusEnabled),
+		Filter: &s3.LifecycleRuleFilter{},
+	}
+	if r.Disabled {
+		rule.Status = aws.String(s3.ExpirationStatusDisabled)
+	}
+	if r.ID != "" {
+		rule.ID = aws.String(r.ID)
+	}
+
+	// A filter holds a single condition unless they are combined with And
+	keys := make([]string, 0, len(r.Tags))
+	for k := range r.Tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	tags := make([]*s3.Tag, 0, len(keys))
+	for _, k := range keys {
+		tags = append(tags, &s3.Tag{Key: aws.String(k), Value: aws.String(r.Tags[k])})
+	}
+	switch {
+	case len(tags) == 0:
+		rule.Filter.Prefix = aws.String(r.Prefix)
+	case len(tags) == 1 && r.Prefix == "":
+		rule.Filter.Tag = tags[0]
+	default:
+		rule.Filter.And = &s3.LifecycleRuleAndOperator{Tags: tags}
+		if r.Prefix != "" {
+			rule.Filter.And.Prefix = aws.String(r.Prefix)
+		}
+	}
+
+	if r.ExpirationDays > 0 {
+		rule.Expiration = &s3.LifecycleExpiration{Days: aws.Int64(int64(r.ExpirationDays))}
+	}
+	if r.NoncurrentVersionExpirationDays > 0 {
+		rule.NoncurrentVersionExpiration = &s3.NoncurrentVersionExpiration{
+			NoncurrentDays: aws.Int64(int64(r.NoncurrentVersionExpirationDays)),
+		}
+	}
+	if r.AbortIncompleteMultipartUploadDays > 0 {
+		rule.AbortIncompleteMultipartUpload = &s3.AbortIncompleteMultipartUpload{
+			DaysAfterInitiation: aws.Int64(int64(r.AbortIncompleteMultipartUploadDays)),
+		}
+	}
+	for _, t := range r.Transitions {
+		rule.Transitions = append(rule.Transitions, &s3.Transition{
+			Days:         aws.Int64(int64(t.Days)),
+			StorageClass: aws.String(string(t.StorageClass)),
+		})
+	}
+	return rule
+}
+
+// lifecycleRule converts a rule returned by S3. Rules using the legacy
+// top-level prefix, or conditions the library does not model such as object
+// size, keep only the parts LifecycleRule can represent.
+func lifecycleRule(in *s3.LifecycleRule) LifecycleRule {
+	rule := LifecycleRule{
+		ID:       aws.StringValue(in.ID),
+		Disabled: aws.StringValue(in.Status) != s3.ExpirationStatusEnabled,
+		Prefix:   aws.StringValue(in.Prefix),
+	}
+	addTag := func(tag *s3.Tag) {
+		if rule.Tags == nil {
+			rule.Tags = make(map[string]string)
+		}
+		rule.Tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+	if f := in.Filter; f != nil {
+		switch {
+		case f.And != nil:
+			rule.Prefix = aws.StringValue(f.And.Prefix)
+			for _, tag := range f.And.Tags {
+				addTag(tag)
+			}
+		case f.Tag != nil:
+			addTag(f.Tag)
+		case f.Prefix != nil:
+			rule.Prefix = aws.StringValue(f.Prefix)
+		}
+	}
+	if in.Expiration != nil {
+		rule.ExpirationDays = int(aws.Int64Value(in.Expiration.Days))
+	}
+	if in.NoncurrentVersionExpiration != nil {
+		rule.NoncurrentVersionExpirationDays = int(aws.Int64Value(in.NoncurrentVersionExpiration.NoncurrentDays))
+	}
+	if in.AbortIncompleteMultipartUpload != nil {
+		rule.AbortIncompleteMultipartUploadDays = int(aws.Int64Value(in.AbortIncompleteMultipartUpload.DaysAfterInitiation))
+	}
+	for _, t := range in.Transitions {
+		rule.Transitions = append(rule.Transitions, LifecycleTransition{
+			Days:         int(aws.Int64Value(t.Days)),
+			StorageClass: StorageClass(aws.StringValue(t.StorageClass)),
+		})
+	}
+	return rule
+}
+
+// SetBucketLifecycle replaces the lifecycle configuration of bucket with
+// rules. The rules are validated before the request is made. An empty rule
+// set is rejected; use SetBucketLifecycleWithOptions with DeleteIfEmpty to
+// remove the configuration.
+func (c *S3Client) SetBucketLifecycle(ctx context.Context, bucket string, rules []LifecycleRule) error {
+	return c.SetBucketLifecycleWithOptions(ctx, bucket, rules, nil)
+}
+
+// SetBucketLifecycleWithOptions replaces the lifecycle configuration of
+// bucket with rules, removing it when rules is empty and opts.DeleteIfEmpty
+// is set
+func (c *S3Client) SetBucketLifecycleWithOptions(ctx context.Context, bucket string, rules []LifecycleRule, opts *LifecycleOptions) error {
+	if bucket == "" {
+		return ErrInvalidBucket
+	}
+	if len(rules) == 0 {
+		if opts == nil || !opts.DeleteIfEmpty {
+			return fmt.Errorf("%w: no lifecycle rules; set DeleteIfEmpty to remove the configuration", ErrInvalidOptions)
+		}
+		_, err := c.s3Client.DeleteBucketLifecycleWithContext(ctx, &s3.DeleteBucketLifecycleInput{
+			Bucket: aws.String(bucket),
+		})
+		if err != nil {
+			return lifecycleError(err)
+		}
+		return nil
+	}
+	if len(rules) > maxLifecycleRules {
+		return fmt.Errorf("%w: at most %d lifecycle rules are allowed", ErrInvalidOptions, maxLifecycleRules)
+	}
+
+	ids := make(map[string]bool, len(rules))
+	config := &s3.BucketLifecycleConfiguration{}
+	for i := range rules {
+		if err := rules[i].validate(); err != nil {
+			return err
+		}
+		if id := rules[i].ID; id != "" {
+			if ids[id] {
+				return fmt.Errorf("%w: duplicate lifecycle rule ID %q", ErrInvalidOptions, id)
+			}
+			ids[id] = true
+		}
+		config.Rules = append(config.Rules, rules[i].input())
+	}
+
+	_, err := c.s3Client.PutBucketLifecycleConfigurationWithContext(ctx, &s3.PutBucketLifecycleConfigurationInput{
+		Bucket:                 aws.String(bucket),
+		LifecycleConfiguration: config,
+	})
+	if err != nil {
+		return lifecycleError(err)
+	}
+	return nil
+}
+
+// GetBucketLifecycle returns the lifecycle rules of bucket, which are empty
+// when it has no lifecycle configuration
+func (c *S3Client) GetBucketLifecycle(ctx context.Context, bucket string) ([]LifecycleRule, error) {
+	if bucket == "" {
+		return nil, ErrInvalidBucket
+	}
+
+	out, err := c.s3Client.GetBucketLifecycleConfigurationWithContext(ctx, &s3.GetBucketLifecycleConfigurationInput{
+		Bucket: aws.String(bucket),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == "NoSuchLifecycleConfiguration" {
+			return []LifecycleRule{}, nil
+		}
+		return nil, lifecycleError(err)
+	}
+	rules := make([]LifecycleRule, 0, len(out.Rules))
+	for _, r := range out.Rules {
+		rules = append(rules, lifecycleRule(r))
+	}
+	return rules, nil
+}
+
+// lifecycleError maps a failed lifecycle request to the library's error
+// values
+func lifecycleError(err error) error {
+	if aerr, ok := err.(awserr.Error); ok {
+		switch aerr.Code() {
+		case s3.ErrCodeNoSuchBucket:
+			return ErrInvalidBucket
+		case "AccessDenied":
+			return fmt.Errorf("%w: %w", ErrAccessDenied, aerr)
+		case "InvalidRequest", "MalformedXML", "InvalidArgument":
+			return fmt.Errorf("%w: %w", ErrInvalidOptions, aerr)
+		default:
+			return fmt.Errorf("AWS error: %w", aerr)
+		}
+	}
+	return fmt.Errorf("failed to access bucket lifecycle: %w", err)
+}
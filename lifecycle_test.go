@@ -0,0 +1,161 @@
+package s3lib
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLifecycleRuleValidate tests obviously invalid rules are rejected
+func TestLifecycleRuleValidate(t *testing.T) {
+	valid := LifecycleRule{
+		Prefix:         "logs/",
+		ExpirationDays: 365,
+		Transitions: []LifecycleTransition{
+			{Days: 30, StorageClass: StorageClassStandardIA},
+			{Days: 90, StorageClass: StorageClassGlacier},
+		},
+	}
+	require.NoError(t, valid.validate())
+
+	tests := map[string]LifecycleRule{
+		"no action":               {Prefix: "logs/"},
+		"negative days":           {ExpirationDays: -1},
+		"transition after expiry": {ExpirationDays: 60, Transitions: []LifecycleTransition{{Days: 90, StorageClass: StorageClassGlacier}}},
+		"early IA transition":     {Transitions: []LifecycleTransition{{Days: 7, StorageClass: StorageClassStandardIA}}},
+		"standard target":         {Transitions: []LifecycleTransition{{Days: 30, StorageClass: StorageClassStandard}}},
+		"repeated class":          {Transitions: []LifecycleTransition{{Days: 30, StorageClass: StorageClassGlacier}, {Days: 60, StorageClass: StorageClassGlacier}}},
+		"abort with tags":         {Tags: map[string]string{"tier": "tmp"}, AbortIncompleteMultipartUploadDays: 7},
+	}
+	for name, rule := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.ErrorIs(t, rule.validate(), ErrInvalidOptions)
+		})
+	}
+}
+
+// lifecycleRequest is the body of a PutBucketLifecycleConfiguration request.
+// The SDK does not marshal elements in a fixed order, so it is decoded
+// rather than compared as text.
+type lifecycleRequest struct {
+	Rules []struct {
+		ID     string `xml:"ID"`
+		Status string `xml:"Status"`
+		Filter struct {
+			Prefix string `xml:"Prefix"`
+			And    struct {
+				Prefix string `xml:"Prefix"`
+				Tags   []struct {
+					Key   string `xml:"Key"`
+					Value string `xml:"Value"`
+				} `xml:"Tag"`
+			} `xml:"And"`
+		} `xml:"Filter"`
+		ExpirationDays int `xml:"Expiration>Days"`
+		Transitions    []struct {
+			Days         int    `xml:"Days"`
+			StorageClass string `xml:"StorageClass"`
+		} `xml:"Transition"`
+		NoncurrentDays      int `xml:"NoncurrentVersionExpiration>NoncurrentDays"`
+		DaysAfterInitiation int `xml:"AbortIncompleteMultipartUpload>DaysAfterInitiation"`
+	} `xml:"Rule"`
+}
+
+// TestS3Client_SetBucketLifecycle tests rules are sent with the matching
+// filter and an empty rule set only deletes the configuration when asked
+func TestS3Client_SetBucketLifecycle(t *testing.T) {
+	var method, body string
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		method = req.Method
+		if req.Body != nil {
+			data, _ := io.ReadAll(req.Body)
+			body = string(data)
+		}
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: req}, nil
+	})
+	ctx := context.Background()
+
+	err := client.SetBucketLifecycle(ctx, testBucket, []LifecycleRule{
+		{ID: "logs", Prefix: "logs/", ExpirationDays: 90, Transitions: []LifecycleTransition{{Days: 30, StorageClass: StorageClassGlacierIR}}},
+		{ID: "tmp", Prefix: "tmp/", Tags: map[string]string{"tier": "scratch"}, NoncurrentVersionExpirationDays: 7},
+		{ID: "uploads", AbortIncompleteMultipartUploadDays: 3, Disabled: true},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, http.MethodPut, method)
+	var sent lifecycleRequest
+	require.NoError(t, xml.Unmarshal([]byte(body), &sent))
+	require.Len(t, sent.Rules, 3)
+
+	logs := sent.Rules[0]
+	assert.Equal(t, "logs", logs.ID)
+	assert.Equal(t, "Enabled", logs.Status)
+	assert.Equal(t, "logs/", logs.Filter.Prefix)
+	assert.Equal(t, 90, logs.ExpirationDays)
+	require.Len(t, logs.Transitions, 1)
+	assert.Equal(t, 30, logs.Transitions[0].Days)
+	assert.Equal(t, "GLACIER_IR", logs.Transitions[0].StorageClass)
+
+	tmp := sent.Rules[1]
+	assert.Equal(t, "tmp/", tmp.Filter.And.Prefix)
+	require.Len(t, tmp.Filter.And.Tags, 1)
+	assert.Equal(t, "tier", tmp.Filter.And.Tags[0].Key)
+	assert.Equal(t, "scratch", tmp.Filter.And.Tags[0].Value)
+	assert.Equal(t, 7, tmp.NoncurrentDays)
+
+	uploads := sent.Rules[2]
+	assert.Equal(t, "Disabled", uploads.Status)
+	assert.Equal(t, 3, uploads.DaysAfterInitiation)
+
+	method = ""
+	err = client.SetBucketLifecycle(ctx, testBucket, nil)
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+	assert.Empty(t, method)
+
+	require.NoError(t, client.SetBucketLifecycleWithOptions(ctx, testBucket, nil, &LifecycleOptions{DeleteIfEmpty: true}))
+	assert.Equal(t, http.MethodDelete, method)
+
+	err = client.SetBucketLifecycle(ctx, testBucket, []LifecycleRule{{ID: "a", ExpirationDays: 1}, {ID: "a", ExpirationDays: 2}})
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+}
+
+// TestS3Client_GetBucketLifecycle tests rules are converted back and a
+// bucket without a configuration has none
+func TestS3Client_GetBucketLifecycle(t *testing.T) {
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path == "/bare" {
+			return fakeErrorResponse(req, http.StatusNotFound, "NoSuchLifecycleConfiguration"), nil
+		}
+		body := "<LifecycleConfiguration>" +
+			"<Rule><ID>logs</ID><Status>Enabled</Status><Filter><And><Prefix>logs/</Prefix>" +
+			"<Tag><Key>env</Key><Value>prod</Value></Tag><Tag><Key>team</Key><Value>ops</Value></Tag></And></Filter>" +
+			"<Transition><Days>30</Days><StorageClass>STANDARD_IA</StorageClass></Transition>" +
+			"<Expiration><Days>365</Days></Expiration></Rule>" +
+			"<Rule><ID>uploads</ID><Status>Disabled</Status><Filter><Prefix></Prefix></Filter>" +
+			"<AbortIncompleteMultipartUpload><DaysAfterInitiation>7</DaysAfterInitiation></AbortIncompleteMultipartUpload></Rule>" +
+			"</LifecycleConfiguration>"
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: xmlBody(body), Request: req}, nil
+	})
+	ctx := context.Background()
+
+	rules, err := client.GetBucketLifecycle(ctx, testBucket)
+	require.NoError(t, err)
+	assert.Equal(t, []LifecycleRule{
+		{
+			ID:             "logs",
+			Prefix:         "logs/",
+			Tags:           map[string]string{"env": "prod", "team": "ops"},
+			ExpirationDays: 365,
+			Transitions:    []LifecycleTransition{{Days: 30, StorageClass: StorageClassStandardIA}},
+		},
+		{ID: "uploads", Disabled: true, AbortIncompleteMultipartUploadDays: 7},
+	}, rules)
+
+	rules, err = client.GetBucketLifecycle(ctx, "bare")
+	require.NoError(t, err)
+	assert.Empty(t, rules)
+}
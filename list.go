@@ -0,0 +1,559 @@
+package s3lib
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// maxListKeys is the most keys S3 returns in one ListObjectsV2 page
+const maxListKeys = 1000
+
+// ListOptions controls how much of a bucket ListFilesWithOptions walks and
+// which page ListFilesPage returns
+type ListOptions struct {
+	// MaxKeys stops the listing after this many files in total instead of
+	// paging to the end of the bucket. Pages of up to MaxKeys, capped at
+	// S3's limit of 1000, are requested. 0 lists every file, or a page of
+	// up to 1000 with ListFilesPage.
+	MaxKeys int64
+
+	// StartAfter lists only keys that sort after it. Like other keys it is
+	// relative to Config.KeyPrefix.
+	StartAfter string
+
+	// ContinuationToken resumes a listing from the NextToken of an earlier
+	// ListFilesPage call
+	ContinuationToken string
+
+	// Delimiter rolls up keys containing it after the prefix into folders,
+	// which ListDirectoryWithOptions and ListFilesPage return, so only the
+	// immediate files are listed. ListDirectory uses "/".
+	Delimiter string
+
+	// SkipPlaceholders leaves out objects whose key equals the listed
+	// prefix, such as the empty folder placeholders created by the S3
+	// console
+	SkipPlaceholders bool
+
+	// Suffix, Glob and Regex keep only the files whose keys match every
+	// filter set. They are applied to each page as it arrives, and Glob and
+	// Regex match the part of the key after the listed prefix. Glob uses
+	// path.Match syntax, so "*.parquet" does not match in subfolders; its
+	// leading literal part, such as "2024-" in "2024-*.csv", narrows the
+	// prefix requested from S3. MaxKeys counts matching files.
+	Suffix string
+	Glob   string
+	Regex  string
+
+	// ModifiedBefore and ModifiedAfter keep only files last modified
+	// strictly before and after the given times, when set
+	ModifiedBefore time.Time
+	ModifiedAfter  time.Time
+
+	// MinSize and MaxSize keep only files of at least and at most the given
+	// number of bytes. A MaxSize of 0 sets no upper limit.
+	MinSize int64
+	MaxSize int64
+
+	// SortBy and SortOrder sort the files returned, by key in S3's order
+	// when SortBy is empty. ListFilesWithOptions and ListDirectory sort the
+	// whole listing, or, with MaxKeys set, the first MaxKeys files in key
+	// order, so the result is not the newest or largest MaxKeys files of the
+	// bucket. ListFilesPage sorts each page on its own. ListFilesIter cannot
+	// sort and rejects these options.
+	SortBy    SortField
+	SortOrder SortOrder
+
+	// FetchOwner and FetchRestoreStatus ask S3 to return each file's owner
+	// and the restore status of archived files, which are otherwise left
+	// empty
+	FetchOwner         bool
+	FetchRestoreStatus bool
+}
+
+// ListPage is one page of a listing
+type ListPage struct {
+	// Files are the files on the page
+	Files []FileInfo `json:"files"`
+
+	// NextToken fetches the next page when passed as
+	// ListOptions.ContinuationToken. It is empty on the last page. On
+	// backends listed with ListObjects, see Config.ForceListObjectsV1, it is
+	// the last key of the page.
+	NextToken string `json:"next_token,omitempty"`
+
+	// Folders are the prefixes rolled up by ListOptions.Delimiter, each
+	// ending in the delimiter
+	Folders []string `json:"folders,omitempty"`
+
+	// IsTruncated is set when more files follow this page
+	IsTruncated bool `json:"is_truncated"`
+}
+
+// DirectoryListing is one level of a bucket viewed as a folder tree
+type DirectoryListing struct {
+	// Files are the objects directly under the prefix
+	Files []FileInfo `json:"files"`
+
+	// Folders are the prefixes of the next level, each ending in the
+	// delimiter, such as "photos/2024/" when listing "photos/"
+	Folders []string `json:"folders"`
+}
+
+func (o *ListOptions) validate() error {
+	if o == nil {
+		return nil
+	}
+	if o.MaxKeys < 0 {
+		return fmt.Errorf("%w: MaxKeys must not be negative", ErrInvalidOptions)
+	}
+	if _, err := path.Match(o.Glob, ""); err != nil {
+		return fmt.Errorf("%w: invalid Glob %q", ErrInvalidOptions, o.Glob)
+	}
+	if _, err := regexp.Compile(o.Regex); err != nil {
+		return fmt.Errorf("%w: invalid Regex: %w", ErrInvalidOptions, err)
+	}
+	if o.MinSize < 0 || o.MaxSize < 0 {
+		return fmt.Errorf("%w: MinSize and MaxSize must not be negative", ErrInvalidOptions)
+	}
+	if o.MaxSize > 0 && o.MinSize > o.MaxSize {
+		return fmt.Errorf("%w: MinSize exceeds MaxSize", ErrInvalidOptions)
+	}
+	if !o.ModifiedBefore.IsZero() && !o.ModifiedAfter.IsZero() && !o.ModifiedAfter.Before(o.ModifiedBefore) {
+		return fmt.Errorf("%w: ModifiedAfter must be before ModifiedBefore", ErrInvalidOptions)
+	}
+	return validateSort(o.SortBy, o.SortOrder)
+}
+
+// sort sorts listed files as the options ask
+func (o *ListOptions) sort(files []FileInfo) {
+	if o != nil {
+		sortFiles(files, o.SortBy, o.SortOrder)
+	}
+}
+
+// ListFilesWithOptions lists files like ListFiles, limited and positioned
+// by opts. A nil opts lists every file under prefix.
+func (c *S3Client) ListFilesWithOptions(ctx context.Context, bucket, prefix string, opts *ListOptions) ([]FileInfo, error) {
+	if bucket == "" {
+		return nil, ErrInvalidBucket
+	}
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	input := c.listInput(bucket, prefix, opts)
+	filter := c.newListFilter(prefix, opts)
+	var limit int64
+	if opts != nil {
+		limit = opts.MaxKeys
+	}
+
+	files := []FileInfo{}
+	err := c.listObjectsPages(ctx, input,
+		func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+			for _, obj := range page.Contents {
+				if limit > 0 && int64(len(files)) >= limit {
+					return false
+				}
+				if !filter.keep(obj) {
+					continue
+				}
+				files = append(files, c.listedFile(obj))
+			}
+			return limit <= 0 || int64(len(files)) < limit
+		})
+	if err != nil {
+		return nil, listError(err)
+	}
+
+	opts.sort(files)
+	return files, nil
+}
+
+// ListFilesPage lists a single page of files with one ListObjectsV2
+// request, for callers paginating a listing themselves. Start with a nil
+// opts, or an empty ContinuationToken, and pass each page's NextToken to get
+// the next one. A token S3 rejects as malformed or expired returns
+// ErrInvalidContinuationToken.
+func (c *S3Client) ListFilesPage(ctx context.Context, bucket, prefix string, opts *ListOptions) (*ListPage, error) {
+	if bucket == "" {
+		return nil, ErrInvalidBucket
+	}
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	input := c.listInput(bucket, prefix, opts)
+	filter := c.newListFilter(prefix, opts)
+	out, err := c.listObjects(ctx, input)
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == "InvalidArgument" && input.ContinuationToken != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidContinuationToken, aerr.Message())
+		}
+		return nil, listError(err)
+	}
+
+	page := &ListPage{
+		Files:       make([]FileInfo, 0, len(out.Contents)),
+		IsTruncated: aws.BoolValue(out.IsTruncated),
+	}
+	for _, obj := range out.Contents {
+		if filter.keep(obj) {
+			page.Files = append(page.Files, c.listedFile(obj))
+		}
+	}
+	for _, p := range out.CommonPrefixes {
+		page.Folders = append(page.Folders, c.relativeKey(aws.StringValue(p.Prefix)))
+	}
+	if page.IsTruncated {
+		page.NextToken = aws.StringValue(out.NextContinuationToken)
+	}
+	opts.sort(page.Files)
+	return page, nil
+}
+
+// FileIterator walks a listing one file at a time, requesting each page of
+// up to 1000 files only once the previous one is used up, so listing very
+// large buckets takes constant memory. Use it like bufio.Scanner:
+//
+//	it := client.ListFilesIter(ctx, bucket, prefix, nil)
+//	for it.Next() {
+//		file := it.File()
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+//
+// Breaking out of the loop sends no further requests. Iterators returned by
+// ListFilesParallel must be closed instead.
+type FileIterator struct {
+	c      *S3Client
+	ctx    context.Context
+	input  *s3.ListObjectsV2Input
+	filter *listFilter
+	limit  int64
+
+	page  []*s3.Object
+	file  FileInfo
+	count int64
+	done  bool
+	err   error
+
+	parallel *parallelListing // set by ListFilesParallel
+}
+
+// ListFilesIter returns an iterator over the files under prefix, limited
+// and positioned by opts like ListFilesWithOptions. Cancelling ctx ends the
+// iteration with ctx.Err().
+func (c *S3Client) ListFilesIter(ctx context.Context, bucket, prefix string, opts *ListOptions) *FileIterator {
+	it := &FileIterator{c: c, ctx: ctx}
+	if bucket == "" {
+		it.err = ErrInvalidBucket
+		return it
+	}
+	if err := opts.validate(); err != nil {
+		it.err = err
+		return it
+	}
+	if opts != nil && opts.SortBy != "" {
+		it.err = fmt.Errorf("%w: an iterator cannot sort its files", ErrInvalidOptions)
+		return it
+	}
+
+	it.input = c.listInput(bucket, prefix, opts)
+	it.filter = c.newListFilter(prefix, opts)
+	if opts != nil {
+		it.limit = opts.MaxKeys
+	}
+	return it
+}
+
+// Next advances to the next file, fetching the next page if needed. It
+// returns false at the end of the listing or on an error, which Err reports.
+func (it *FileIterator) Next() bool {
+	for it.err == nil {
+		if err := it.ctx.Err(); err != nil {
+			it.err = err
+			return false
+		}
+		if it.parallel != nil {
+			return it.nextParallel()
+		}
+		if it.limit > 0 && it.count >= it.limit {
+			return false
+		}
+		if len(it.page) == 0 {
+			if it.done || !it.fetch() {
+				return false
+			}
+			continue
+		}
+
+		obj := it.page[0]
+		it.page = it.page[1:]
+		if !it.filter.keep(obj) {
+			continue
+		}
+		it.file = it.c.listedFile(obj)
+		it.count++
+		return true
+	}
+	return false
+}
+
+// File returns the file Next advanced to
+func (it *FileIterator) File() FileInfo {
+	return it.file
+}
+
+// Err returns the error that ended the iteration, if any
+func (it *FileIterator) Err() error {
+	return it.err
+}
+
+// Close stops the iteration. Later calls to Next return false. Close is
+// only needed to stop a parallel listing early, but is safe to call on any
+// iterator, and more than once.
+func (it *FileIterator) Close() error {
+	it.done = true
+	it.page = nil
+	if it.parallel != nil {
+		it.parallel.cancel()
+		it.parallel.current = len(it.parallel.shards)
+		it.parallel.files = nil
+	}
+	return nil
+}
+
+// fetch requests the next page of the listing
+func (it *FileIterator) fetch() bool {
+	out, err := it.c.listObjects(it.ctx, it.input)
+	if err != nil {
+		if ctxErr := it.ctx.Err(); ctxErr != nil {
+			it.err = ctxErr
+		} else {
+			it.err = listError(err)
+		}
+		return false
+	}
+
+	it.page = out.Contents
+	if aws.BoolValue(out.IsTruncated) && out.NextContinuationToken != nil {
+		it.input.ContinuationToken = out.NextContinuationToken
+	} else {
+		it.done = true
+	}
+	return true
+}
+
+// ListDirectory lists one level of a bucket as a folder tree: the files
+// directly under prefix, which should end in "/", and the folders below it.
+// Folder placeholder objects are left out.
+func (c *S3Client) ListDirectory(ctx context.Context, bucket, prefix string) (*DirectoryListing, error) {
+	return c.ListDirectoryWithOptions(ctx, bucket, prefix, &ListOptions{Delimiter: "/", SkipPlaceholders: true})
+}
+
+// ListDirectoryWithOptions lists one level of a bucket like ListDirectory,
+// splitting keys on opts.Delimiter, or "/" if it is empty. MaxKeys bounds
+// the files and folders returned together, as S3 counts them.
+func (c *S3Client) ListDirectoryWithOptions(ctx context.Context, bucket, prefix string, opts *ListOptions) (*DirectoryListing, error) {
+	if bucket == "" {
+		return nil, ErrInvalidBucket
+	}
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	input := c.listInput(bucket, prefix, opts)
+	filter := c.newListFilter(prefix, opts)
+	if input.Delimiter == nil {
+		input.Delimiter = aws.String("/")
+	}
+	var limit int64
+	if opts != nil {
+		limit = opts.MaxKeys
+	}
+
+	listing := &DirectoryListing{Files: []FileInfo{}, Folders: []string{}}
+	full := func() bool {
+		return limit > 0 && int64(len(listing.Files)+len(listing.Folders)) >= limit
+	}
+	err := c.listObjectsPages(ctx, input,
+		func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+			for _, obj := range page.Contents {
+				if full() {
+					return false
+				}
+				if filter.keep(obj) {
+					listing.Files = append(listing.Files, c.listedFile(obj))
+				}
+			}
+			for _, p := range page.CommonPrefixes {
+				if full() {
+					return false
+				}
+				listing.Folders = append(listing.Folders, c.relativeKey(aws.StringValue(p.Prefix)))
+			}
+			return !full()
+		})
+	if err != nil {
+		return nil, listError(err)
+	}
+
+	opts.sort(listing.Files)
+	return listing, nil
+}
+
+// listFilter selects which listed objects are returned
+type listFilter struct {
+	prefix           string // full listed prefix
+	skipPlaceholders bool
+	suffix           string
+	glob             string
+	re               *regexp.Regexp
+	modifiedBefore   time.Time
+	modifiedAfter    time.Time
+	minSize          int64
+	maxSize          int64
+}
+
+// newListFilter returns the filter for a listing of prefix with validated
+// opts
+func (c *S3Client) newListFilter(prefix string, opts *ListOptions) *listFilter {
+	f := &listFilter{prefix: c.config.KeyPrefix + prefix}
+	if opts == nil {
+		return f
+	}
+	f.skipPlaceholders = opts.SkipPlaceholders
+	f.suffix = opts.Suffix
+	f.glob = opts.Glob
+	if opts.Regex != "" {
+		f.re = regexp.MustCompile(opts.Regex)
+	}
+	f.modifiedBefore = opts.ModifiedBefore
+	f.modifiedAfter = opts.ModifiedAfter
+	f.minSize = opts.MinSize
+	f.maxSize = opts.MaxSize
+	return f
+}
+
+// keep reports whether obj passes the filter. Folder placeholders are
+// objects whose key is the listed prefix itself.
+func (f *listFilter) keep(obj *s3.Object) bool {
+	key := aws.StringValue(obj.Key)
+	if f.skipPlaceholders && f.prefix != "" && key == f.prefix {
+		return false
+	}
+	if !strings.HasSuffix(key, f.suffix) {
+		return false
+	}
+	if size := aws.Int64Value(obj.Size); size < f.minSize || (f.maxSize > 0 && size > f.maxSize) {
+		return false
+	}
+	modified := aws.TimeValue(obj.LastModified)
+	if !f.modifiedBefore.IsZero() && !modified.Before(f.modifiedBefore) {
+		return false
+	}
+	if !f.modifiedAfter.IsZero() && !modified.After(f.modifiedAfter) {
+		return false
+	}
+	rel := strings.TrimPrefix(key, f.prefix)
+	if f.glob != "" {
+		if ok, _ := path.Match(f.glob, rel); !ok {
+			return false
+		}
+	}
+	return f.re == nil || f.re.MatchString(rel)
+}
+
+// globLiteralPrefix returns the part of a glob before its first special
+// character
+func globLiteralPrefix(glob string) string {
+	if i := strings.IndexAny(glob, `*?[\`); i >= 0 {
+		return glob[:i]
+	}
+	return glob
+}
+
+// listInput builds the ListObjectsV2 request for a listing under prefix
+func (c *S3Client) listInput(bucket, prefix string, opts *ListOptions) *s3.ListObjectsV2Input {
+	input := &s3.ListObjectsV2Input{Bucket: aws.String(bucket)}
+	prefix = c.config.KeyPrefix + prefix
+	if opts != nil {
+		prefix += globLiteralPrefix(opts.Glob)
+	}
+	if prefix != "" {
+		input.Prefix = aws.String(prefix)
+	}
+	if opts == nil {
+		return input
+	}
+	if opts.MaxKeys > 0 {
+		input.MaxKeys = aws.Int64(min(opts.MaxKeys, maxListKeys))
+	}
+	if opts.StartAfter != "" {
+		input.StartAfter = aws.String(c.config.KeyPrefix + opts.StartAfter)
+	}
+	if opts.ContinuationToken != "" {
+		input.ContinuationToken = aws.String(opts.ContinuationToken)
+	}
+	if opts.Delimiter != "" {
+		input.Delimiter = aws.String(opts.Delimiter)
+	}
+	if opts.FetchOwner {
+		input.FetchOwner = aws.Bool(true)
+	}
+	if opts.FetchRestoreStatus {
+		input.OptionalObjectAttributes = aws.StringSlice([]string{s3.OptionalObjectAttributesRestoreStatus})
+	}
+	return input
+}
+
+// listedFile converts a listed object, returning its key relative to
+// Config.KeyPrefix. Fields the backend does not return are left zero.
+func (c *S3Client) listedFile(obj *s3.Object) FileInfo {
+	info := FileInfo{
+		Key:          c.relativeKey(aws.StringValue(obj.Key)),
+		Size:         aws.Int64Value(obj.Size),
+		LastModified: aws.TimeValue(obj.LastModified),
+		ETag:         aws.StringValue(obj.ETag),
+		StorageClass: aws.StringValue(obj.StorageClass),
+	}
+	if len(obj.ChecksumAlgorithm) > 0 {
+		info.ChecksumAlgorithm = ChecksumAlgorithm(aws.StringValue(obj.ChecksumAlgorithm[0]))
+	}
+	if obj.Owner != nil {
+		info.Owner = aws.StringValue(obj.Owner.ID)
+		info.OwnerDisplayName = aws.StringValue(obj.Owner.DisplayName)
+	}
+	if obj.RestoreStatus != nil {
+		info.RestoreStatus = &RestoreStatus{
+			InProgress: aws.BoolValue(obj.RestoreStatus.IsRestoreInProgress),
+			ExpiryDate: obj.RestoreStatus.RestoreExpiryDate,
+		}
+	}
+	return info
+}
+
+// listError maps a ListObjectsV2 failure to the library's errors
+func listError(err error) error {
+	if aerr, ok := err.(awserr.Error); ok {
+		switch aerr.Code() {
+		case s3.ErrCodeNoSuchBucket:
+			return ErrInvalidBucket
+		default:
+			return fmt.Errorf("AWS error: %w", aerr)
+		}
+	}
+	return fmt.Errorf("failed to list objects: %w", err)
+}
@@ -0,0 +1,439 @@
+package s3lib
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// listServer fakes a bucket of n objects served in pages of at most
+// max-keys, recording the query of each request
+func listServer(n int, queries *[]url.Values) roundTripFunc {
+	return func(req *http.Request) (*http.Response, error) {
+		query := req.URL.Query()
+		*queries = append(*queries, query)
+
+		start := 0
+		if token := query.Get("continuation-token"); token != "" {
+			fmt.Sscanf(token, "page-%d", &start)
+		}
+		pageSize := 1000
+		if max := query.Get("max-keys"); max != "" {
+			fmt.Sscanf(max, "%d", &pageSize)
+		}
+		end := min(start+pageSize, n)
+
+		var body strings.Builder
+		body.WriteString("<ListBucketResult>")
+		for i := start; i < end; i++ {
+			fmt.Fprintf(&body, "<Contents><Key>file-%04d</Key><Size>1</Size></Contents>", i)
+		}
+		if end < n {
+			fmt.Fprintf(&body, "<IsTruncated>true</IsTruncated><NextContinuationToken>page-%d</NextContinuationToken>", end)
+		}
+		body.WriteString("</ListBucketResult>")
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: xmlBody(body.String()), Request: req}, nil
+	}
+}
+
+// TestS3Client_ListFilesWithOptions tests MaxKeys stops the listing early
+// and the position options are passed to S3
+func TestS3Client_ListFilesWithOptions(t *testing.T) {
+	var queries []url.Values
+	client := setupFakeClient(t, listServer(2500, &queries))
+	ctx := context.Background()
+
+	t.Run("MaxKeys within one page", func(t *testing.T) {
+		queries = nil
+		files, err := client.ListFilesWithOptions(ctx, testBucket, "", &ListOptions{MaxKeys: 100})
+		require.NoError(t, err)
+		assert.Len(t, files, 100)
+		require.Len(t, queries, 1)
+		assert.Equal(t, "100", queries[0].Get("max-keys"))
+	})
+
+	t.Run("MaxKeys across pages", func(t *testing.T) {
+		queries = nil
+		files, err := client.ListFilesWithOptions(ctx, testBucket, "", &ListOptions{MaxKeys: 1500})
+		require.NoError(t, err)
+		require.Len(t, files, 1500)
+		assert.Equal(t, "file-1499", files[1499].Key)
+		assert.Len(t, queries, 2)
+	})
+
+	t.Run("Position", func(t *testing.T) {
+		queries = nil
+		files, err := client.ListFilesWithOptions(ctx, testBucket, "", &ListOptions{
+			StartAfter:        "file-0001",
+			ContinuationToken: "page-2000",
+		})
+		require.NoError(t, err)
+		assert.Len(t, files, 500)
+		require.Len(t, queries, 1)
+		assert.Equal(t, "file-0001", queries[0].Get("start-after"))
+		assert.Equal(t, "page-2000", queries[0].Get("continuation-token"))
+	})
+
+	t.Run("Glob", func(t *testing.T) {
+		queries = nil
+		files, err := client.ListFilesWithOptions(ctx, testBucket, "", &ListOptions{Glob: "file-00*", MaxKeys: 2000})
+		require.NoError(t, err)
+		assert.Len(t, files, 100)
+		assert.Equal(t, "file-00", queries[0].Get("prefix"))
+	})
+
+	t.Run("Nil options", func(t *testing.T) {
+		queries = nil
+		files, err := client.ListFiles(ctx, testBucket, "")
+		require.NoError(t, err)
+		assert.Len(t, files, 2500)
+		assert.Empty(t, queries[0].Get("max-keys"))
+	})
+}
+
+// TestS3Client_ListFilesPage tests following NextToken visits every file
+// with one request per page
+func TestS3Client_ListFilesPage(t *testing.T) {
+	var queries []url.Values
+	client := setupFakeClient(t, listServer(250, &queries))
+	ctx := context.Background()
+
+	var keys []string
+	opts := &ListOptions{MaxKeys: 100}
+	for pages := 1; ; pages++ {
+		page, err := client.ListFilesPage(ctx, testBucket, "", opts)
+		require.NoError(t, err)
+		require.Len(t, queries, pages)
+		for _, f := range page.Files {
+			keys = append(keys, f.Key)
+		}
+		if !page.IsTruncated {
+			assert.Empty(t, page.NextToken)
+			break
+		}
+		require.NotEmpty(t, page.NextToken)
+		opts.ContinuationToken = page.NextToken
+	}
+	assert.Len(t, keys, 250)
+	assert.Equal(t, "file-0249", keys[len(keys)-1])
+}
+
+// TestS3Client_ListFilesPageInvalidToken tests a rejected continuation token
+// is reported as ErrInvalidContinuationToken
+func TestS3Client_ListFilesPageInvalidToken(t *testing.T) {
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		return fakeErrorResponse(req, http.StatusBadRequest, "InvalidArgument"), nil
+	})
+
+	_, err := client.ListFilesPage(context.Background(), testBucket, "", &ListOptions{ContinuationToken: "expired"})
+	assert.ErrorIs(t, err, ErrInvalidContinuationToken)
+}
+
+// TestS3Client_ListFilesIter tests pages are fetched lazily, stopping early
+// sends no further requests and cancellation ends the iteration
+func TestS3Client_ListFilesIter(t *testing.T) {
+	var queries []url.Values
+	client := setupFakeClient(t, listServer(2500, &queries))
+
+	t.Run("All", func(t *testing.T) {
+		queries = nil
+		it := client.ListFilesIter(context.Background(), testBucket, "", nil)
+		var n int
+		for it.Next() {
+			assert.Equal(t, fmt.Sprintf("file-%04d", n), it.File().Key)
+			n++
+		}
+		require.NoError(t, it.Err())
+		assert.Equal(t, 2500, n)
+		assert.Len(t, queries, 3)
+	})
+
+	t.Run("Break", func(t *testing.T) {
+		queries = nil
+		it := client.ListFilesIter(context.Background(), testBucket, "", nil)
+		for n := 0; it.Next() && n < 1000; n++ {
+		}
+		require.NoError(t, it.Err())
+		assert.Len(t, queries, 2, "only the page holding the last file read is fetched")
+	})
+
+	t.Run("Cancel", func(t *testing.T) {
+		queries = nil
+		ctx, cancel := context.WithCancel(context.Background())
+		it := client.ListFilesIter(ctx, testBucket, "", nil)
+		require.True(t, it.Next())
+		cancel()
+		assert.False(t, it.Next())
+		assert.ErrorIs(t, it.Err(), context.Canceled)
+		assert.Len(t, queries, 1)
+	})
+}
+
+// TestS3Client_ListDirectory tests files and folders are returned for one
+// level, relative to Config.KeyPrefix, without the folder placeholder
+func TestS3Client_ListDirectory(t *testing.T) {
+	var query url.Values
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		query = req.URL.Query()
+		body := "<ListBucketResult>" +
+			"<Contents><Key>tenant/photos/</Key><Size>0</Size></Contents>" +
+			"<Contents><Key>tenant/photos/cover.jpg</Key><Size>3</Size></Contents>" +
+			"<CommonPrefixes><Prefix>tenant/photos/2023/</Prefix></CommonPrefixes>" +
+			"<CommonPrefixes><Prefix>tenant/photos/2024/</Prefix></CommonPrefixes>" +
+			"</ListBucketResult>"
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: xmlBody(body), Request: req}, nil
+	}).WithPrefix("tenant/")
+	ctx := context.Background()
+
+	listing, err := client.ListDirectory(ctx, testBucket, "photos/")
+	require.NoError(t, err)
+	assert.Equal(t, "/", query.Get("delimiter"))
+	assert.Equal(t, "tenant/photos/", query.Get("prefix"))
+	require.Len(t, listing.Files, 1)
+	assert.Equal(t, "photos/cover.jpg", listing.Files[0].Key)
+	assert.Equal(t, []string{"photos/2023/", "photos/2024/"}, listing.Folders)
+
+	listing, err = client.ListDirectoryWithOptions(ctx, testBucket, "photos/", &ListOptions{Delimiter: "|"})
+	require.NoError(t, err)
+	assert.Equal(t, "|", query.Get("delimiter"))
+	assert.Len(t, listing.Files, 2, "placeholders are kept unless skipped")
+}
+
+// TestS3Client_ListFilesAttributes tests the owner and restore status are
+// requested only when asked for, and the attributes returned are reported
+func TestS3Client_ListFilesAttributes(t *testing.T) {
+	var req *http.Request
+	client := setupFakeClient(t, func(r *http.Request) (*http.Response, error) {
+		req = r
+		body := "<ListBucketResult>" +
+			"<Contents><Key>archived.bin</Key><Size>3</Size><StorageClass>GLACIER</StorageClass>" +
+			"<ChecksumAlgorithm>CRC32C</ChecksumAlgorithm>" +
+			"<Owner><ID>abc123</ID><DisplayName>alice</DisplayName></Owner>" +
+			"<RestoreStatus><IsRestoreInProgress>false</IsRestoreInProgress>" +
+			"<RestoreExpiryDate>2012-12-21T00:00:00.000Z</RestoreExpiryDate></RestoreStatus></Contents>" +
+			"<Contents><Key>plain.txt</Key><Size>3</Size></Contents>" +
+			"</ListBucketResult>"
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: xmlBody(body), Request: r}, nil
+	})
+	ctx := context.Background()
+
+	_, err := client.ListFiles(ctx, testBucket, "")
+	require.NoError(t, err)
+	assert.False(t, req.URL.Query().Has("fetch-owner"))
+	assert.Empty(t, req.Header.Get("X-Amz-Optional-Object-Attributes"))
+
+	files, err := client.ListFilesWithOptions(ctx, testBucket, "", &ListOptions{FetchOwner: true, FetchRestoreStatus: true})
+	require.NoError(t, err)
+	assert.Equal(t, "true", req.URL.Query().Get("fetch-owner"))
+	assert.Equal(t, "RestoreStatus", req.Header.Get("X-Amz-Optional-Object-Attributes"))
+
+	require.Len(t, files, 2)
+	assert.Equal(t, ChecksumCRC32C, files[0].ChecksumAlgorithm)
+	assert.Equal(t, "abc123", files[0].Owner)
+	assert.Equal(t, "alice", files[0].OwnerDisplayName)
+	require.NotNil(t, files[0].RestoreStatus)
+	assert.False(t, files[0].RestoreStatus.InProgress)
+	require.NotNil(t, files[0].RestoreStatus.ExpiryDate)
+	assert.True(t, files[0].RestoreStatus.ExpiryDate.Equal(time.Date(2012, 12, 21, 0, 0, 0, 0, time.UTC)))
+
+	assert.Empty(t, files[1].Owner)
+	assert.Nil(t, files[1].RestoreStatus)
+}
+
+// TestListFilter tests the filters are combined and Glob and Regex match
+// below the listed prefix
+func TestListFilter(t *testing.T) {
+	client := setupTestClient(t)
+	tests := []struct {
+		name string
+		opts *ListOptions
+		keys map[string]bool
+	}{
+		{
+			name: "No filters",
+			keys: map[string]bool{"data/": true, "data/a.parquet": true},
+		},
+		{
+			name: "Placeholders",
+			opts: &ListOptions{SkipPlaceholders: true},
+			keys: map[string]bool{"data/": false, "data/a.parquet": true},
+		},
+		{
+			name: "Suffix",
+			opts: &ListOptions{Suffix: ".parquet"},
+			keys: map[string]bool{"data/a.parquet": true, "data/a.csv": false},
+		},
+		{
+			name: "Glob",
+			opts: &ListOptions{Glob: "*.parquet"},
+			keys: map[string]bool{"data/a.parquet": true, "data/sub/a.parquet": false, "data/a.csv": false},
+		},
+		{
+			name: "Regex",
+			opts: &ListOptions{Regex: `^\d{4}/`},
+			keys: map[string]bool{"data/2024/a.csv": true, "data/x2024/a.csv": false},
+		},
+		{
+			name: "Combined",
+			opts: &ListOptions{Glob: "2024-*", Suffix: ".csv", Regex: "-0[1-6]-"},
+			keys: map[string]bool{"data/2024-03-01.csv": true, "data/2024-09-01.csv": false, "data/2024-03-01.json": false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, tt.opts.validate())
+			filter := client.newListFilter("data/", tt.opts)
+			for key, want := range tt.keys {
+				assert.Equal(t, want, filter.keep(&s3.Object{Key: aws.String(key)}), key)
+			}
+		})
+	}
+}
+
+// TestListFilterAttributes tests the modification time and size filters
+// compose with each other and with key filters
+func TestListFilterAttributes(t *testing.T) {
+	client := setupTestClient(t)
+	now := time.Now()
+	object := func(key string, age time.Duration, size int64) *s3.Object {
+		return &s3.Object{Key: aws.String(key), LastModified: aws.Time(now.Add(-age)), Size: aws.Int64(size)}
+	}
+	day := 24 * time.Hour
+
+	tests := []struct {
+		name string
+		opts *ListOptions
+		objs map[*s3.Object]bool
+	}{
+		{
+			name: "Older than 30 days",
+			opts: &ListOptions{ModifiedBefore: now.Add(-30 * day)},
+			objs: map[*s3.Object]bool{object("a", 31*day, 1): true, object("b", 29*day, 1): false},
+		},
+		{
+			name: "Modified window",
+			opts: &ListOptions{ModifiedAfter: now.Add(-7 * day), ModifiedBefore: now.Add(-day)},
+			objs: map[*s3.Object]bool{object("a", 3*day, 1): true, object("b", 8*day, 1): false, object("c", 0, 1): false},
+		},
+		{
+			name: "Larger than 1GB",
+			opts: &ListOptions{MinSize: 1 << 30},
+			objs: map[*s3.Object]bool{object("a", 0, 1<<30): true, object("b", 0, 1<<30-1): false},
+		},
+		{
+			name: "Size range with suffix",
+			opts: &ListOptions{MinSize: 10, MaxSize: 100, Suffix: ".log"},
+			objs: map[*s3.Object]bool{object("a.log", 0, 50): true, object("a.txt", 0, 50): false, object("b.log", 0, 101): false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, tt.opts.validate())
+			filter := client.newListFilter("", tt.opts)
+			for obj, want := range tt.objs {
+				assert.Equal(t, want, filter.keep(obj), aws.StringValue(obj.Key))
+			}
+		})
+	}
+}
+
+// TestGlobLiteralPrefix tests the prefix a glob narrows the listing to
+func TestGlobLiteralPrefix(t *testing.T) {
+	assert.Equal(t, "", globLiteralPrefix(""))
+	assert.Equal(t, "", globLiteralPrefix("*.parquet"))
+	assert.Equal(t, "2024-", globLiteralPrefix("2024-*.csv"))
+	assert.Equal(t, "logs/app", globLiteralPrefix("logs/app[0-9].log"))
+	assert.Equal(t, "exact.txt", globLiteralPrefix("exact.txt"))
+}
+
+// TestS3Client_ListEmpty tests empty listings return empty slices, which
+// encode as JSON arrays rather than null
+func TestS3Client_ListEmpty(t *testing.T) {
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		body := "<ListBucketResult></ListBucketResult>"
+		switch {
+		case strings.Trim(req.URL.Path, "/") == "":
+			body = "<ListAllMyBucketsResult></ListAllMyBucketsResult>"
+		case req.URL.Query().Has("uploads"):
+			body = "<ListMultipartUploadsResult></ListMultipartUploadsResult>"
+		}
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: xmlBody(body), Request: req}, nil
+	})
+	ctx := context.Background()
+	assertEmptyArray := func(t *testing.T, v interface{}) {
+		t.Helper()
+		data, err := json.Marshal(v)
+		require.NoError(t, err)
+		assert.Equal(t, "[]", string(data))
+	}
+
+	files, err := client.ListFiles(ctx, testBucket, "empty/")
+	require.NoError(t, err)
+	assert.NotNil(t, files)
+	assertEmptyArray(t, files)
+
+	files, err = client.ListFilesWithOptions(ctx, testBucket, "empty/", &ListOptions{MaxKeys: 10, SortBy: SortBySize})
+	require.NoError(t, err)
+	assertEmptyArray(t, files)
+
+	page, err := client.ListFilesPage(ctx, testBucket, "empty/", nil)
+	require.NoError(t, err)
+	assertEmptyArray(t, page.Files)
+
+	listing, err := client.ListDirectory(ctx, testBucket, "empty/")
+	require.NoError(t, err)
+	assertEmptyArray(t, listing.Files)
+	assertEmptyArray(t, listing.Folders)
+
+	buckets, err := client.ListBuckets(ctx)
+	require.NoError(t, err)
+	assertEmptyArray(t, buckets)
+
+	uploads, err := client.ListIncompleteUploads(ctx, testBucket, "empty/")
+	require.NoError(t, err)
+	assertEmptyArray(t, uploads)
+}
+
+// TestS3Client_ListFilesWithOptionsValidation tests invalid options are
+// rejected before anything is sent
+func TestS3Client_ListFilesWithOptionsValidation(t *testing.T) {
+	client := setupTestClient(t)
+	_, err := client.ListFilesWithOptions(context.Background(), testBucket, "", &ListOptions{MaxKeys: -1})
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+	_, err = client.ListFilesPage(context.Background(), testBucket, "", &ListOptions{MaxKeys: -1})
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+	_, err = client.ListFilesPage(context.Background(), "", "", nil)
+	assert.ErrorIs(t, err, ErrInvalidBucket)
+	_, err = client.ListDirectory(context.Background(), "", "")
+	assert.ErrorIs(t, err, ErrInvalidBucket)
+	_, err = client.ListFilesWithOptions(context.Background(), testBucket, "", &ListOptions{Glob: "[a-"})
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+	_, err = client.ListFilesWithOptions(context.Background(), testBucket, "", &ListOptions{Regex: "("})
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+	_, err = client.ListFilesWithOptions(context.Background(), testBucket, "", &ListOptions{MinSize: 10, MaxSize: 5})
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+	_, err = client.ListFilesWithOptions(context.Background(), testBucket, "", &ListOptions{ModifiedBefore: time.Now().Add(-time.Hour), ModifiedAfter: time.Now()})
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+
+	it := client.ListFilesIter(context.Background(), "", "", nil)
+	assert.False(t, it.Next())
+	assert.ErrorIs(t, it.Err(), ErrInvalidBucket)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	it = client.ListFilesIter(ctx, testBucket, "", nil)
+	assert.False(t, it.Next())
+	assert.ErrorIs(t, it.Err(), context.Canceled)
+}
@@ -0,0 +1,76 @@
+package s3lib
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// SortField is the FileInfo field listings are sorted by
+type SortField string
+
+// Fields listings can be sorted by. Ties keep S3's key order.
+const (
+	SortByKey          SortField = "key"
+	SortByLastModified SortField = "last_modified"
+	SortBySize         SortField = "size"
+)
+
+// SortOrder is the direction listings are sorted in
+type SortOrder string
+
+// Sort orders. The zero value sorts ascending.
+const (
+	SortAsc  SortOrder = "asc"
+	SortDesc SortOrder = "desc"
+)
+
+// validateSort checks the sort options of a listing
+func validateSort(by SortField, order SortOrder) error {
+	switch by {
+	case "", SortByKey, SortByLastModified, SortBySize:
+	default:
+		return fmt.Errorf("%w: unsupported SortBy %q", ErrInvalidOptions, by)
+	}
+	switch order {
+	case "", SortAsc, SortDesc:
+	default:
+		return fmt.Errorf("%w: unsupported SortOrder %q", ErrInvalidOptions, order)
+	}
+	return nil
+}
+
+// sortFiles stably sorts files in place by field in order. Files without a
+// sort field are left in S3's order, which is ascending by key.
+func sortFiles(files []FileInfo, by SortField, order SortOrder) {
+	if by == "" || (by == SortByKey && order != SortDesc) {
+		return
+	}
+
+	var compare func(a, b *FileInfo) int
+	switch by {
+	case SortByKey:
+		compare = func(a, b *FileInfo) int { return strings.Compare(a.Key, b.Key) }
+	case SortByLastModified:
+		compare = func(a, b *FileInfo) int { return a.LastModified.Compare(b.LastModified) }
+	case SortBySize:
+		compare = func(a, b *FileInfo) int { return cmp.Compare(a.Size, b.Size) }
+	}
+	if order == SortDesc {
+		asc := compare
+		compare = func(a, b *FileInfo) int { return asc(b, a) }
+	}
+
+	// FileInfo is large, so sort indexes and move each file once
+	perm := make([]int, len(files))
+	for i := range perm {
+		perm[i] = i
+	}
+	slices.SortStableFunc(perm, func(i, j int) int { return compare(&files[i], &files[j]) })
+	sorted := make([]FileInfo, len(files))
+	for i, j := range perm {
+		sorted[i] = files[j]
+	}
+	copy(files, sorted)
+}
@@ -0,0 +1,80 @@
+package s3lib
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSortFiles tests each field and order, and that ties keep key order
+func TestSortFiles(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	listed := []FileInfo{
+		{Key: "a", Size: 20, LastModified: base.Add(2 * time.Hour)},
+		{Key: "b", Size: 10, LastModified: base},
+		{Key: "c", Size: 20, LastModified: base.Add(time.Hour)},
+		{Key: "d", Size: 30, LastModified: base},
+	}
+
+	tests := []struct {
+		by    SortField
+		order SortOrder
+		want  []string
+	}{
+		{"", "", []string{"a", "b", "c", "d"}},
+		{SortByKey, SortDesc, []string{"d", "c", "b", "a"}},
+		{SortByLastModified, SortAsc, []string{"b", "d", "c", "a"}},
+		{SortByLastModified, SortDesc, []string{"a", "c", "b", "d"}},
+		{SortBySize, "", []string{"b", "a", "c", "d"}},
+		{SortBySize, SortDesc, []string{"d", "a", "c", "b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s %s", tt.by, tt.order), func(t *testing.T) {
+			files := append([]FileInfo(nil), listed...)
+			sortFiles(files, tt.by, tt.order)
+			var keys []string
+			for _, f := range files {
+				keys = append(keys, f.Key)
+			}
+			assert.Equal(t, tt.want, keys)
+		})
+	}
+}
+
+// TestValidateSort tests unknown sort fields and orders are rejected
+func TestValidateSort(t *testing.T) {
+	assert.NoError(t, validateSort("", ""))
+	assert.NoError(t, validateSort(SortBySize, SortDesc))
+	assert.ErrorIs(t, validateSort("owner", ""), ErrInvalidOptions)
+	assert.ErrorIs(t, validateSort(SortByKey, "up"), ErrInvalidOptions)
+
+	it := setupTestClient(t).ListFilesIter(context.Background(), testBucket, "", &ListOptions{SortBy: SortBySize})
+	assert.False(t, it.Next())
+	assert.ErrorIs(t, it.Err(), ErrInvalidOptions)
+}
+
+// BenchmarkSortFiles measures sorting a listing of 1M files newest first
+func BenchmarkSortFiles(b *testing.B) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	rng := rand.New(rand.NewSource(1))
+	listed := make([]FileInfo, 1_000_000)
+	for i := range listed {
+		listed[i] = FileInfo{
+			Key:          fmt.Sprintf("logs/%08d.log", i),
+			Size:         rng.Int63n(1 << 30),
+			LastModified: base.Add(time.Duration(rng.Int63n(int64(365 * 24 * time.Hour)))),
+		}
+	}
+	files := make([]FileInfo, len(listed))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		copy(files, listed)
+		sortFiles(files, SortByLastModified, SortDesc)
+	}
+}
@@ -0,0 +1,103 @@
+package s3lib
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// listObjects requests one page of a listing. It uses ListObjectsV2 unless
+// the backend does not support it, in which case the page is requested with
+// ListObjects and converted, so callers see the same output either way. A
+// V1 page's continuation token is the marker to resume after.
+func (c *S3Client) listObjects(ctx context.Context, input *s3.ListObjectsV2Input) (*s3.ListObjectsV2Output, error) {
+	if c.listV1 == nil || !c.listV1.Load() {
+		out, err := c.s3Client.ListObjectsV2WithContext(ctx, input)
+		if err == nil || !isListV2Unsupported(err) || c.listV1 == nil {
+			return out, err
+		}
+		if c.debugMode {
+			fmt.Println("ListObjectsV2 is not supported, falling back to ListObjects")
+		}
+		c.listV1.Store(true)
+	}
+
+	v1 := &s3.ListObjectsInput{
+		Bucket:                   input.Bucket,
+		Prefix:                   input.Prefix,
+		Delimiter:                input.Delimiter,
+		MaxKeys:                  input.MaxKeys,
+		Marker:                   input.StartAfter,
+		EncodingType:             input.EncodingType,
+		OptionalObjectAttributes: input.OptionalObjectAttributes,
+	}
+	if input.ContinuationToken != nil {
+		v1.Marker = input.ContinuationToken
+	}
+	out, err := c.s3Client.ListObjectsWithContext(ctx, v1)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &s3.ListObjectsV2Output{
+		Contents:       out.Contents,
+		CommonPrefixes: out.CommonPrefixes,
+		IsTruncated:    out.IsTruncated,
+	}
+	if aws.BoolValue(out.IsTruncated) {
+		// NextMarker is only returned with a delimiter; otherwise listing
+		// resumes after the last key
+		next := aws.StringValue(out.NextMarker)
+		if next == "" {
+			next = lastListed(out.Contents, out.CommonPrefixes)
+		}
+		page.NextContinuationToken = aws.String(next)
+	}
+	return page, nil
+}
+
+// listObjectsPages calls fn with each page of a listing until it returns
+// false or the last page has been seen, like ListObjectsV2PagesWithContext
+func (c *S3Client) listObjectsPages(ctx context.Context, input *s3.ListObjectsV2Input, fn func(*s3.ListObjectsV2Output, bool) bool) error {
+	page := *input
+	for {
+		out, err := c.listObjects(ctx, &page)
+		if err != nil {
+			return err
+		}
+		last := !aws.BoolValue(out.IsTruncated) || aws.StringValue(out.NextContinuationToken) == ""
+		if !fn(out, last) || last {
+			return nil
+		}
+		page.ContinuationToken = out.NextContinuationToken
+	}
+}
+
+// isListV2Unsupported reports whether a ListObjectsV2 failure means the
+// backend does not implement it
+func isListV2Unsupported(err error) bool {
+	if aerr, ok := err.(awserr.Error); ok {
+		switch aerr.Code() {
+		case "NotImplemented", "InvalidRequest":
+			return true
+		}
+	}
+	return false
+}
+
+// lastListed returns the key or common prefix of a page that sorts last
+func lastListed(contents []*s3.Object, prefixes []*s3.CommonPrefix) string {
+	var last string
+	if n := len(contents); n > 0 {
+		last = aws.StringValue(contents[n-1].Key)
+	}
+	if n := len(prefixes); n > 0 {
+		if p := aws.StringValue(prefixes[n-1].Prefix); p > last {
+			last = p
+		}
+	}
+	return last
+}
@@ -0,0 +1,95 @@
+package s3lib
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// v1ListServer fakes a backend that only implements ListObjects, serving
+// keys in pages of pageSize with marker based pagination
+func v1ListServer(keys []string, pageSize int, v2Requests *int) roundTripFunc {
+	return func(req *http.Request) (*http.Response, error) {
+		query := req.URL.Query()
+		if query.Get("list-type") == "2" {
+			*v2Requests++
+			return fakeErrorResponse(req, http.StatusNotImplemented, "NotImplemented"), nil
+		}
+
+		var body strings.Builder
+		body.WriteString("<ListBucketResult>")
+		n := 0
+		for _, key := range keys {
+			if key <= query.Get("marker") {
+				continue
+			}
+			if n == pageSize {
+				body.WriteString("<IsTruncated>true</IsTruncated>")
+				break
+			}
+			fmt.Fprintf(&body, "<Contents><Key>%s</Key><Size>1</Size><ETag>&quot;abc&quot;</ETag></Contents>", key)
+			n++
+		}
+		body.WriteString("</ListBucketResult>")
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: xmlBody(body.String()), Request: req}, nil
+	}
+}
+
+// TestS3Client_ListFilesV1Fallback tests listings fall back to ListObjects
+// once, paginate by marker and produce the same FileInfo
+func TestS3Client_ListFilesV1Fallback(t *testing.T) {
+	keys := []string{"a", "b", "c", "d", "e"}
+	var v2Requests int
+	client := setupFakeClient(t, v1ListServer(keys, 2, &v2Requests))
+	ctx := context.Background()
+
+	files, err := client.ListFiles(ctx, testBucket, "")
+	require.NoError(t, err)
+	require.Len(t, files, 5)
+	assert.Equal(t, FileInfo{Key: "e", Size: 1, ETag: `"abc"`}, files[4])
+
+	page, err := client.ListFilesPage(ctx, testBucket, "", &ListOptions{MaxKeys: 2})
+	require.NoError(t, err)
+	assert.True(t, page.IsTruncated)
+	assert.Equal(t, "b", page.NextToken)
+	page, err = client.ListFilesPage(ctx, testBucket, "", &ListOptions{MaxKeys: 2, ContinuationToken: page.NextToken})
+	require.NoError(t, err)
+	assert.Equal(t, "c", page.Files[0].Key)
+
+	assert.Equal(t, 1, v2Requests, "ListObjectsV2 is only probed once")
+}
+
+// TestS3Client_ListFilesForceV1 tests Config.ForceListObjectsV1 skips the
+// ListObjectsV2 probe
+func TestS3Client_ListFilesForceV1(t *testing.T) {
+	var v2Requests int
+	cfg := testConfig
+	cfg.Endpoint = "http://s3.fake.local"
+	cfg.ForceListObjectsV1 = true
+	cfg.HTTPClient = &http.Client{Transport: v1ListServer([]string{"a"}, 10, &v2Requests)}
+	client, err := NewS3Client(cfg)
+	require.NoError(t, err)
+
+	files, err := client.WithPrefix("").ListFiles(context.Background(), testBucket, "")
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+	assert.Zero(t, v2Requests)
+}
+
+// TestLastListed tests the marker a truncated ListObjects page resumes after
+func TestLastListed(t *testing.T) {
+	objects := []*s3.Object{{Key: aws.String("a/1")}, {Key: aws.String("c")}}
+	prefixes := []*s3.CommonPrefix{{Prefix: aws.String("b/")}, {Prefix: aws.String("d/")}}
+
+	assert.Equal(t, "", lastListed(nil, nil))
+	assert.Equal(t, "c", lastListed(objects, nil))
+	assert.Equal(t, "d/", lastListed(objects, prefixes))
+	assert.Equal(t, "c", lastListed(objects, prefixes[:1]))
+}
@@ -0,0 +1,33 @@
+package s3lib
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// bucketURL returns the base URL of bucket, the same way the SDK addresses
+// it: path style under Config.Endpoint (which NewS3Client forces to path
+// style), otherwise virtual-hosted style on AWS. Bucket names containing dots
+// fall back to path style because they do not match the wildcard TLS
+// certificate.
+func (c *S3Client) bucketURL(bucket string) string {
+	if c.config.Endpoint != "" {
+		endpoint := strings.TrimSuffix(c.config.Endpoint, "/")
+		// The SDK defaults to HTTPS for endpoints given without a scheme
+		if !strings.Contains(endpoint, "://") {
+			endpoint = "https://" + endpoint
+		}
+		return endpoint + "/" + bucket
+	}
+	if strings.Contains(bucket, ".") {
+		return fmt.Sprintf("https://s3.%s.amazonaws.com/%s", c.config.Region, bucket)
+	}
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com", bucket, c.config.Region)
+}
+
+// objectURL returns the URL of key in bucket, with the key escaped, as
+// reported in the Location of upload results
+func (c *S3Client) objectURL(bucket, key string) string {
+	return c.bucketURL(bucket) + "/" + (&url.URL{Path: key}).EscapedPath()
+}
@@ -0,0 +1,71 @@
+package s3lib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestS3Client_objectURL tests upload locations for AWS and custom endpoints
+func TestS3Client_objectURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		endpoint string
+		bucket   string
+		key      string
+		want     string
+	}{
+		{
+			name:   "AWS default",
+			bucket: "my-bucket",
+			key:    "dir/file.txt",
+			want:   "https://my-bucket.s3.us-east-1.amazonaws.com/dir/file.txt",
+		},
+		{
+			name:   "AWS bucket with dots",
+			bucket: "assets.example.com",
+			key:    "file.txt",
+			want:   "https://s3.us-east-1.amazonaws.com/assets.example.com/file.txt",
+		},
+		{
+			name:     "Custom endpoint path style",
+			endpoint: "http://localhost:9000/",
+			bucket:   "my-bucket",
+			key:      "dir/file.txt",
+			want:     "http://localhost:9000/my-bucket/dir/file.txt",
+		},
+		{
+			name:     "Custom endpoint without scheme",
+			endpoint: "minio.internal:9000",
+			bucket:   "my-bucket",
+			key:      "file.txt",
+			want:     "https://minio.internal:9000/my-bucket/file.txt",
+		},
+		{
+			name:   "Key with spaces",
+			bucket: "my-bucket",
+			key:    "my docs/annual report.pdf",
+			want:   "https://my-bucket.s3.us-east-1.amazonaws.com/my%20docs/annual%20report.pdf",
+		},
+		{
+			name:     "Key with unicode and reserved characters",
+			endpoint: "http://localhost:4566",
+			bucket:   "my-bucket",
+			key:      "données/été?#.txt",
+			want:     "http://localhost:4566/my-bucket/donn%C3%A9es/%C3%A9t%C3%A9%3F%23.txt",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig
+			cfg.Region = "us-east-1"
+			cfg.Endpoint = tt.endpoint
+			client, err := NewS3Client(cfg)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.want, client.objectURL(tt.bucket, tt.key))
+		})
+	}
+}
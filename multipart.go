@@ -0,0 +1,442 @@
+package s3lib
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// ResumableUploadState is the persistable state of a resumable multipart
+// upload. It is updated in place as parts complete, so it can be marshaled
+// to JSON after a failure and passed back to ResumeUpload later. Key is the
+// full object key, including any Config.KeyPrefix.
+type ResumableUploadState struct {
+	Bucket   string         `json:"bucket"`
+	Key      string         `json:"key"`
+	UploadID string         `json:"upload_id"`
+	PartSize int64          `json:"part_size"`
+	Parts    []UploadedPart `json:"parts"`
+
+	// ChecksumAlgorithm is the UploadOptions.ChecksumAlgorithm the upload was
+	// started with; every part is sent and completed with this checksum
+	ChecksumAlgorithm ChecksumAlgorithm `json:"checksum_algorithm,omitempty"`
+
+	// RequestPayer is set when the upload was started acknowledging
+	// Requester Pays, which every later request must acknowledge too
+	RequestPayer bool `json:"request_payer,omitempty"`
+}
+
+// UploadedPart describes a part that has been stored by S3
+type UploadedPart struct {
+	PartNumber int64  `json:"part_number"`
+	ETag       string `json:"etag"`
+	Size       int64  `json:"size"`
+
+	// Checksum is the part's checksum under the upload's ChecksumAlgorithm
+	Checksum string `json:"checksum,omitempty"`
+
+	// LastModified is when the part was uploaded, as reported by
+	// GetMultipartUploadParts
+	LastModified *time.Time `json:"last_modified,omitempty"`
+}
+
+// StartResumableUpload creates a multipart upload and returns its state.
+// The part size is taken from opts, then Config.UploadPartSize, then 5MB.
+// The checksum algorithm and Requester Pays setting are kept in the state
+// for ResumeUpload. SSECustomerKey and Compress are not supported: the key
+// would have to be persisted with the state, and parts are read from the
+// source as is.
+func (c *S3Client) StartResumableUpload(ctx context.Context, bucket, key string, opts *UploadOptions) (*ResumableUploadState, error) {
+	if err := c.checkClientSideKey(); err != nil {
+		return nil, err
+	}
+	if opts != nil && (opts.SSECustomerKey != nil || opts.Compress) {
+		return nil, fmt.Errorf("%w: SSECustomerKey and Compress are not supported by resumable uploads", ErrInvalidOptions)
+	}
+	key, err := c.resolveKey(key)
+	if err != nil {
+		return nil, err
+	}
+	input, err := newUploadInput(bucket, key, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	partSize := c.config.UploadPartSize
+	if opts != nil && opts.PartSize != 0 {
+		partSize = opts.PartSize
+	}
+	if partSize == 0 {
+		partSize = MinPartSize
+	}
+
+	result, err := c.s3Client.CreateMultipartUploadWithContext(ctx, createMultipartUploadInput(input))
+	if err != nil {
+		return nil, multipartError(err, "failed to create multipart upload")
+	}
+
+	state := &ResumableUploadState{
+		Bucket:       bucket,
+		Key:          key,
+		UploadID:     aws.StringValue(result.UploadId),
+		PartSize:     partSize,
+		Parts:        []UploadedPart{},
+		RequestPayer: requestPayerEnabled(ctx, c.config.RequestPayer),
+	}
+	if opts != nil {
+		state.ChecksumAlgorithm = opts.ChecksumAlgorithm
+	}
+	return state, nil
+}
+
+// ResumeUpload uploads every part of r that is missing from state and then
+// completes the upload, returning the object location. r must contain the
+// same data on every attempt; parts already recorded in state are skipped.
+// Parts are sent with the checksum algorithm and Requester Pays setting the
+// upload was started with.
+func (c *S3Client) ResumeUpload(ctx context.Context, state *ResumableUploadState, r io.ReadSeeker) (string, error) {
+	if state == nil || r == nil {
+		return "", ErrNilValue
+	}
+	if err := c.checkClientSideKey(); err != nil {
+		return "", err
+	}
+	if state.UploadID == "" || state.PartSize < MinPartSize {
+		return "", fmt.Errorf("%w: incomplete resumable upload state", ErrInvalidOptions)
+	}
+	if !state.ChecksumAlgorithm.valid() {
+		return "", fmt.Errorf("%w: unsupported checksum algorithm %q", ErrInvalidOptions, state.ChecksumAlgorithm)
+	}
+	if state.RequestPayer {
+		ctx = WithRequestPayer(ctx, true)
+	}
+
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return "", fmt.Errorf("failed to determine upload size: %w", err)
+	}
+	numParts := (size + state.PartSize - 1) / state.PartSize
+	if numParts == 0 {
+		numParts = 1
+	}
+	if numParts > s3manager.MaxUploadParts {
+		return "", fmt.Errorf("%w: %d bytes needs more than %d parts of %d bytes", ErrInvalidOptions, size, s3manager.MaxUploadParts, state.PartSize)
+	}
+
+	done := make(map[int64]bool, len(state.Parts))
+	for _, p := range state.Parts {
+		if p.Size != partLength(size, state.PartSize, p.PartNumber) {
+			return "", fmt.Errorf("part %d was uploaded with %d bytes but the source now has %d", p.PartNumber, p.Size, partLength(size, state.PartSize, p.PartNumber))
+		}
+		done[p.PartNumber] = true
+	}
+
+	buf := make([]byte, state.PartSize)
+	for partNumber := int64(1); partNumber <= numParts; partNumber++ {
+		if done[partNumber] {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+
+		if _, err := r.Seek((partNumber-1)*state.PartSize, io.SeekStart); err != nil {
+			return "", fmt.Errorf("failed to seek to part %d: %w", partNumber, err)
+		}
+		n, err := io.ReadFull(r, buf[:partLength(size, state.PartSize, partNumber)])
+		if err != nil {
+			return "", fmt.Errorf("failed to read part %d: %w", partNumber, err)
+		}
+
+		reqOpts := c.bandwidthOptions(nil)
+		part := UploadedPart{PartNumber: partNumber, Size: int64(n)}
+		if state.ChecksumAlgorithm != ChecksumNone {
+			reqOpts = append(reqOpts, withChecksum(state.ChecksumAlgorithm))
+			part.Checksum = state.ChecksumAlgorithm.Checksum(buf[:n])
+		}
+		result, err := c.s3Client.UploadPartWithContext(ctx, &s3.UploadPartInput{
+			Bucket:        aws.String(state.Bucket),
+			Key:           aws.String(state.Key),
+			UploadId:      aws.String(state.UploadID),
+			PartNumber:    aws.Int64(partNumber),
+			Body:          bytes.NewReader(buf[:n]),
+			ContentLength: aws.Int64(int64(n)),
+		}, reqOpts...)
+		if err != nil {
+			return "", multipartError(err, fmt.Sprintf("failed to upload part %d", partNumber))
+		}
+
+		part.ETag = aws.StringValue(result.ETag)
+		state.Parts = append(state.Parts, part)
+	}
+
+	sort.Slice(state.Parts, func(i, j int) bool {
+		return state.Parts[i].PartNumber < state.Parts[j].PartNumber
+	})
+	completed := make([]*s3.CompletedPart, 0, len(state.Parts))
+	for _, p := range state.Parts {
+		completed = append(completed, p.completedPart(state.ChecksumAlgorithm))
+	}
+
+	_, err = c.s3Client.CompleteMultipartUploadWithContext(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(state.Bucket),
+		Key:             aws.String(state.Key),
+		UploadId:        aws.String(state.UploadID),
+		MultipartUpload: &s3.CompletedMultipartUpload{Parts: completed},
+	})
+	if err != nil {
+		return "", multipartError(err, "failed to complete multipart upload")
+	}
+
+	return c.objectURL(state.Bucket, state.Key), nil
+}
+
+// AbortResumableUpload aborts the upload described by state and discards
+// every part stored so far
+func (c *S3Client) AbortResumableUpload(ctx context.Context, state *ResumableUploadState) error {
+	if state == nil {
+		return ErrNilValue
+	}
+	if state.RequestPayer {
+		ctx = WithRequestPayer(ctx, true)
+	}
+	return c.abortMultipartUpload(ctx, state.Bucket, state.Key, state.UploadID)
+}
+
+// completedPart returns the CompleteMultipartUpload entry for p. An upload
+// started with a checksum algorithm must list the checksum of every part.
+func (p UploadedPart) completedPart(alg ChecksumAlgorithm) *s3.CompletedPart {
+	part := &s3.CompletedPart{
+		ETag:       aws.String(p.ETag),
+		PartNumber: aws.Int64(p.PartNumber),
+	}
+	if p.Checksum == "" {
+		return part
+	}
+	switch alg {
+	case ChecksumCRC32:
+		part.ChecksumCRC32 = aws.String(p.Checksum)
+	case ChecksumCRC32C:
+		part.ChecksumCRC32C = aws.String(p.Checksum)
+	case ChecksumSHA1:
+		part.ChecksumSHA1 = aws.String(p.Checksum)
+	case ChecksumSHA256:
+		part.ChecksumSHA256 = aws.String(p.Checksum)
+	}
+	return part
+}
+
+// abortMultipartUpload aborts a multipart upload and discards its parts
+func (c *S3Client) abortMultipartUpload(ctx context.Context, bucket, key, uploadID string) error {
+	_, err := c.s3Client.AbortMultipartUploadWithContext(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(bucket),
+		Key:      aws.String(key),
+		UploadId: aws.String(uploadID),
+	})
+	if err != nil {
+		return multipartError(err, "failed to abort multipart upload")
+	}
+	return nil
+}
+
+// partLength returns the size of the given 1-based part of an object
+func partLength(size, partSize, partNumber int64) int64 {
+	remaining := size - (partNumber-1)*partSize
+	if remaining > partSize {
+		return partSize
+	}
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// multipartError maps a multipart API failure to the library's error values
+func multipartError(err error, msg string) error {
+	if aerr, ok := err.(awserr.Error); ok {
+		switch aerr.Code() {
+		case s3.ErrCodeNoSuchUpload:
+			return ErrUploadNotFound
+		case s3.ErrCodeNoSuchBucket:
+			return ErrInvalidBucket
+		default:
+			return fmt.Errorf("AWS error: %w", aerr)
+		}
+	}
+	return fmt.Errorf("%s: %w", msg, err)
+}
+
+// createMultipartUploadInput converts an uploader input into the equivalent
+// CreateMultipartUpload input
+func createMultipartUploadInput(in *s3manager.UploadInput) *s3.CreateMultipartUploadInput {
+	return &s3.CreateMultipartUploadInput{
+		ACL:                       in.ACL,
+		Bucket:                    in.Bucket,
+		BucketKeyEnabled:          in.BucketKeyEnabled,
+		CacheControl:              in.CacheControl,
+		ChecksumAlgorithm:         in.ChecksumAlgorithm,
+		ContentDisposition:        in.ContentDisposition,
+		ContentEncoding:           in.ContentEncoding,
+		ContentLanguage:           in.ContentLanguage,
+		ContentType:               in.ContentType,
+		ExpectedBucketOwner:       in.ExpectedBucketOwner,
+		Expires:                   in.Expires,
+		Key:                       in.Key,
+		Metadata:                  in.Metadata,
+		ObjectLockLegalHoldStatus: in.ObjectLockLegalHoldStatus,
+		ObjectLockMode:            in.ObjectLockMode,
+		ObjectLockRetainUntilDate: in.ObjectLockRetainUntilDate,
+		RequestPayer:              in.RequestPayer,
+		SSECustomerAlgorithm:      in.SSECustomerAlgorithm,
+		SSECustomerKey:            in.SSECustomerKey,
+		SSECustomerKeyMD5:         in.SSECustomerKeyMD5,
+		SSEKMSEncryptionContext:   in.SSEKMSEncryptionContext,
+		SSEKMSKeyId:               in.SSEKMSKeyId,
+		ServerSideEncryption:      in.ServerSideEncryption,
+		StorageClass:              in.StorageClass,
+		Tagging:                   in.Tagging,
+		WebsiteRedirectLocation:   in.WebsiteRedirectLocation,
+	}
+}
+
+// IncompleteUpload describes a multipart upload that was started but never
+// completed or aborted
+type IncompleteUpload struct {
+	Key          string    `json:"key"`
+	UploadID     string    `json:"upload_id"`
+	Initiated    time.Time `json:"initiated"`
+	StorageClass string    `json:"storage_class"`
+}
+
+// ListIncompleteUploads lists all in-progress multipart uploads in the bucket
+// with an optional key prefix. Keys are relative to Config.KeyPrefix.
+func (c *S3Client) ListIncompleteUploads(ctx context.Context, bucket, prefix string) ([]IncompleteUpload, error) {
+	if bucket == "" {
+		return nil, ErrInvalidBucket
+	}
+
+	input := &s3.ListMultipartUploadsInput{
+		Bucket: aws.String(bucket),
+	}
+	if prefix = c.config.KeyPrefix + prefix; prefix != "" {
+		input.Prefix = aws.String(prefix)
+	}
+
+	uploads := []IncompleteUpload{}
+	err := c.s3Client.ListMultipartUploadsPagesWithContext(ctx, input,
+		func(page *s3.ListMultipartUploadsOutput, lastPage bool) bool {
+			for _, u := range page.Uploads {
+				uploads = append(uploads, IncompleteUpload{
+					Key:          c.relativeKey(aws.StringValue(u.Key)),
+					UploadID:     aws.StringValue(u.UploadId),
+					Initiated:    aws.TimeValue(u.Initiated),
+					StorageClass: aws.StringValue(u.StorageClass),
+				})
+			}
+			return true
+		})
+	if err != nil {
+		return nil, multipartError(err, "failed to list multipart uploads")
+	}
+
+	return uploads, nil
+}
+
+// MultipartUploadParts describes an in-progress multipart upload and the
+// parts S3 has stored so far
+type MultipartUploadParts struct {
+	Key          string `json:"key"`
+	UploadID     string `json:"upload_id"`
+	StorageClass string `json:"storage_class"`
+
+	// Initiator is the ID or ARN of the principal that created the upload
+	Initiator string `json:"initiator,omitempty"`
+
+	// AbortDate is when a bucket lifecycle rule will abort the upload
+	AbortDate *time.Time `json:"abort_date,omitempty"`
+
+	Parts []UploadedPart `json:"parts"`
+}
+
+// GetMultipartUploadParts lists every part stored for an in-progress
+// multipart upload, for inspecting uploads that stalled. It returns
+// ErrUploadNotFound once the upload has been completed or aborted.
+func (c *S3Client) GetMultipartUploadParts(ctx context.Context, bucket, key, uploadID string) (*MultipartUploadParts, error) {
+	if bucket == "" {
+		return nil, ErrInvalidBucket
+	}
+	key, err := c.resolveKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if uploadID == "" {
+		return nil, fmt.Errorf("%w: empty upload ID", ErrInvalidOptions)
+	}
+
+	upload := &MultipartUploadParts{
+		Key:      c.relativeKey(key),
+		UploadID: uploadID,
+		Parts:    []UploadedPart{},
+	}
+	err = c.s3Client.ListPartsPagesWithContext(ctx, &s3.ListPartsInput{
+		Bucket:   aws.String(bucket),
+		Key:      aws.String(key),
+		UploadId: aws.String(uploadID),
+	}, func(page *s3.ListPartsOutput, lastPage bool) bool {
+		upload.StorageClass = aws.StringValue(page.StorageClass)
+		upload.AbortDate = page.AbortDate
+		if page.Initiator != nil {
+			upload.Initiator = aws.StringValue(page.Initiator.ID)
+		}
+		for _, p := range page.Parts {
+			upload.Parts = append(upload.Parts, UploadedPart{
+				PartNumber:   aws.Int64Value(p.PartNumber),
+				ETag:         aws.StringValue(p.ETag),
+				Size:         aws.Int64Value(p.Size),
+				LastModified: p.LastModified,
+			})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, multipartError(err, "failed to list parts")
+	}
+
+	return upload, nil
+}
+
+// AbortIncompleteUploads aborts every in-progress multipart upload under
+// prefix that was initiated more than olderThan ago and returns how many
+// were removed. Uploads that vanish concurrently are not counted as errors.
+func (c *S3Client) AbortIncompleteUploads(ctx context.Context, bucket, prefix string, olderThan time.Duration) (int, error) {
+	uploads, err := c.ListIncompleteUploads(ctx, bucket, prefix)
+	if err != nil {
+		return 0, err
+	}
+
+	cutoff := time.Now().Add(-olderThan)
+	aborted := 0
+	for _, u := range uploads {
+		if !u.Initiated.Before(cutoff) {
+			continue
+		}
+		if err := c.abortMultipartUpload(ctx, bucket, c.config.KeyPrefix+u.Key, u.UploadID); err != nil {
+			if errors.Is(err, ErrUploadNotFound) {
+				continue
+			}
+			return aborted, fmt.Errorf("failed to abort upload %s for key %s: %w", u.UploadID, u.Key, err)
+		}
+		aborted++
+	}
+
+	return aborted, nil
+}
@@ -0,0 +1,250 @@
+package s3lib
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestS3Client_ResumeUpload simulates the connection dropping while the
+// second part is sent and resumes the upload from a persisted state
+func TestS3Client_ResumeUpload(t *testing.T) {
+	objects := map[string]*memoryObject{}
+	server := memoryServer(objects)
+	var down atomic.Bool
+	var sent []string
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		if partNumber := req.URL.Query().Get("partNumber"); partNumber != "" {
+			sent = append(sent, partNumber)
+			if partNumber == "2" && down.Load() {
+				return nil, errors.New("connection reset by peer")
+			}
+		}
+		return server(req)
+	})
+	ctx := context.Background()
+
+	key := "test-resumable.bin"
+	content := bytes.Repeat([]byte("0123456789abcdef"), (3*int(MinPartSize)+1024)/16)
+
+	state, err := client.StartResumableUpload(ctx, testBucket, key, &UploadOptions{ContentType: "application/octet-stream"})
+	require.NoError(t, err)
+	require.NotEmpty(t, state.UploadID)
+
+	down.Store(true)
+	_, err = client.ResumeUpload(ctx, state, bytes.NewReader(content))
+	require.Error(t, err)
+	require.Len(t, state.Parts, 1)
+	assert.NotContains(t, objects, key)
+
+	// Persist and restore the state as a separate process would
+	data, err := json.Marshal(state)
+	require.NoError(t, err)
+	var restored ResumableUploadState
+	require.NoError(t, json.Unmarshal(data, &restored))
+
+	down.Store(false)
+	sent = nil
+	location, err := client.ResumeUpload(ctx, &restored, bytes.NewReader(content))
+	require.NoError(t, err)
+	assert.NotEmpty(t, location)
+	assert.Len(t, restored.Parts, 4)
+	assert.Equal(t, []string{"2", "3", "4"}, sent)
+
+	require.Contains(t, objects, key)
+	assert.True(t, bytes.Equal(content, objects[key].data), "assembled object differs from the source")
+	assert.Equal(t, "application/octet-stream", objects[key].header.Get("Content-Type"))
+	assert.True(t, strings.HasSuffix(objects[key].etag, `-4"`))
+}
+
+// TestS3Client_AbortResumableUpload tests aborting a resumable upload
+func TestS3Client_AbortResumableUpload(t *testing.T) {
+	objects := map[string]*memoryObject{}
+	client := setupFakeClient(t, memoryServer(objects))
+	ctx := context.Background()
+
+	state, err := client.StartResumableUpload(ctx, testBucket, "test-abort.bin", nil)
+	require.NoError(t, err)
+
+	require.NoError(t, client.AbortResumableUpload(ctx, state))
+
+	_, err = client.ResumeUpload(ctx, state, bytes.NewReader(testFileContent))
+	assert.ErrorIs(t, err, ErrUploadNotFound)
+	assert.Empty(t, objects)
+}
+
+// TestS3Client_StartResumableUpload tests argument validation
+func TestS3Client_StartResumableUpload(t *testing.T) {
+	client := setupTestClient(t)
+	ctx := context.Background()
+
+	_, err := client.StartResumableUpload(ctx, "", "key", nil)
+	assert.ErrorIs(t, err, ErrInvalidBucket)
+
+	_, err = client.StartResumableUpload(ctx, testBucket, "", nil)
+	assert.ErrorIs(t, err, ErrInvalidKey)
+
+	_, err = client.StartResumableUpload(ctx, testBucket, "key", &UploadOptions{PartSize: 1024})
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+
+	_, err = client.ResumeUpload(ctx, nil, bytes.NewReader(nil))
+	assert.ErrorIs(t, err, ErrNilValue)
+}
+
+// TestS3Client_ResumeUploadState tests the checksum algorithm and Requester
+// Pays setting survive a persisted state and apply to every later request
+func TestS3Client_ResumeUploadState(t *testing.T) {
+	content := bytes.Repeat([]byte("x"), int(MinPartSize)+10)
+	var mu sync.Mutex
+	var partChecksums, payers []string
+	var completeBody string
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		payers = append(payers, req.Header.Get("X-Amz-Request-Payer"))
+		resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: req}
+		query := req.URL.Query()
+		switch {
+		case query.Has("uploads"):
+			resp.Body = xmlBody("<InitiateMultipartUploadResult><UploadId>upload-1</UploadId></InitiateMultipartUploadResult>")
+		case query.Has("partNumber"):
+			partChecksums = append(partChecksums, req.Header.Get("X-Amz-Checksum-Sha256"))
+			resp.Header.Set("Etag", `"part-`+query.Get("partNumber")+`"`)
+		default:
+			data, _ := io.ReadAll(req.Body)
+			completeBody = string(data)
+			resp.Body = xmlBody("<CompleteMultipartUploadResult><ETag>\"done-2\"</ETag></CompleteMultipartUploadResult>")
+		}
+		return resp, nil
+	})
+
+	state, err := client.StartResumableUpload(WithRequestPayer(context.Background(), true), testBucket, testFileName, &UploadOptions{ChecksumAlgorithm: ChecksumSHA256})
+	require.NoError(t, err)
+	data, err := json.Marshal(state)
+	require.NoError(t, err)
+	var restored ResumableUploadState
+	require.NoError(t, json.Unmarshal(data, &restored))
+	assert.Equal(t, ChecksumSHA256, restored.ChecksumAlgorithm)
+	assert.True(t, restored.RequestPayer)
+
+	_, err = client.ResumeUpload(context.Background(), &restored, bytes.NewReader(content))
+	require.NoError(t, err)
+
+	first := ChecksumSHA256.Checksum(content[:MinPartSize])
+	last := ChecksumSHA256.Checksum(content[MinPartSize:])
+	assert.ElementsMatch(t, []string{first, last}, partChecksums)
+	assert.Contains(t, completeBody, "<ChecksumSHA256>"+first+"</ChecksumSHA256>")
+	assert.Contains(t, completeBody, "<ChecksumSHA256>"+last+"</ChecksumSHA256>")
+	require.Len(t, payers, 4)
+	for _, payer := range payers {
+		assert.Equal(t, "requester", payer)
+	}
+
+	_, err = client.StartResumableUpload(context.Background(), testBucket, testFileName, &UploadOptions{SSECustomerKey: bytes.Repeat([]byte("k"), SSECustomerKeySize)})
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+}
+
+// TestPartLength tests part boundary calculation
+func TestPartLength(t *testing.T) {
+	assert.Equal(t, int64(10), partLength(25, 10, 1))
+	assert.Equal(t, int64(10), partLength(25, 10, 2))
+	assert.Equal(t, int64(5), partLength(25, 10, 3))
+	assert.Equal(t, int64(0), partLength(25, 10, 4))
+	assert.Equal(t, int64(0), partLength(0, 10, 1))
+}
+
+// TestS3Client_IncompleteUploads tests listing and aborting orphaned uploads
+func TestS3Client_IncompleteUploads(t *testing.T) {
+	client := setupFakeClient(t, memoryServer(map[string]*memoryObject{}))
+	ctx := context.Background()
+
+	prefix := "test-orphans/"
+	for _, key := range []string{prefix + "a.bin", prefix + "b.bin", "other/c.bin"} {
+		_, err := client.StartResumableUpload(ctx, testBucket, key, nil)
+		require.NoError(t, err)
+	}
+
+	uploads, err := client.ListIncompleteUploads(ctx, testBucket, prefix)
+	require.NoError(t, err)
+	require.Len(t, uploads, 2)
+	assert.Equal(t, prefix+"a.bin", uploads[0].Key)
+	assert.Equal(t, prefix+"b.bin", uploads[1].Key)
+	for _, u := range uploads {
+		assert.NotEmpty(t, u.UploadID)
+		assert.False(t, u.Initiated.IsZero())
+	}
+
+	// Nothing is older than a day, so nothing should be aborted
+	aborted, err := client.AbortIncompleteUploads(ctx, testBucket, prefix, 24*time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, 0, aborted)
+
+	aborted, err = client.AbortIncompleteUploads(ctx, testBucket, prefix, 0)
+	require.NoError(t, err)
+	assert.Equal(t, len(uploads), aborted)
+
+	uploads, err = client.ListIncompleteUploads(ctx, testBucket, prefix)
+	require.NoError(t, err)
+	assert.Empty(t, uploads)
+
+	uploads, err = client.ListIncompleteUploads(ctx, testBucket, "")
+	require.NoError(t, err)
+	require.Len(t, uploads, 1)
+	assert.Equal(t, "other/c.bin", uploads[0].Key)
+
+	_, err = client.ListIncompleteUploads(ctx, "", prefix)
+	assert.ErrorIs(t, err, ErrInvalidBucket)
+}
+
+// TestS3Client_GetMultipartUploadParts tests the parts of an in-progress
+// upload are listed and a finished upload is reported as not found
+func TestS3Client_GetMultipartUploadParts(t *testing.T) {
+	client := setupFakeClient(t, memoryServer(map[string]*memoryObject{}))
+	ctx := context.Background()
+
+	key := "test-parts/stalled.bin"
+	state, err := client.StartResumableUpload(ctx, testBucket, key, nil)
+	require.NoError(t, err)
+
+	upload, err := client.GetMultipartUploadParts(ctx, testBucket, key, state.UploadID)
+	require.NoError(t, err)
+	assert.Equal(t, key, upload.Key)
+	assert.NotNil(t, upload.Parts)
+	assert.Empty(t, upload.Parts)
+
+	_, err = client.s3Client.UploadPartWithContext(ctx, &s3.UploadPartInput{
+		Bucket:     aws.String(testBucket),
+		Key:        aws.String(key),
+		UploadId:   aws.String(state.UploadID),
+		PartNumber: aws.Int64(1),
+		Body:       bytes.NewReader(testFileContent),
+	})
+	require.NoError(t, err)
+
+	upload, err = client.GetMultipartUploadParts(ctx, testBucket, key, state.UploadID)
+	require.NoError(t, err)
+	require.Len(t, upload.Parts, 1)
+	assert.Equal(t, int64(1), upload.Parts[0].PartNumber)
+	assert.Equal(t, int64(len(testFileContent)), upload.Parts[0].Size)
+	assert.Equal(t, md5ETag(testFileContent), upload.Parts[0].ETag)
+
+	require.NoError(t, client.AbortResumableUpload(ctx, state))
+	_, err = client.GetMultipartUploadParts(ctx, testBucket, key, state.UploadID)
+	assert.ErrorIs(t, err, ErrUploadNotFound)
+
+	_, err = client.GetMultipartUploadParts(ctx, testBucket, key, "")
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+}
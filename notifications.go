@@ -0,0 +1,224 @@
+package s3lib
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// NotificationEvent is a type of bucket event S3 can publish
+type NotificationEvent string
+
+// Common notification events. A "*" event covers every event of its group.
+const (
+	EventObjectCreated                        NotificationEvent = "s3:ObjectCreated:*"
+	EventObjectCreatedPut                     NotificationEvent = "s3:ObjectCreated:Put"
+	EventObjectCreatedPost                    NotificationEvent = "s3:ObjectCreated:Post"
+	EventObjectCreatedCopy                    NotificationEvent = "s3:ObjectCreated:Copy"
+	EventObjectCreatedCompleteMultipartUpload NotificationEvent = "s3:ObjectCreated:CompleteMultipartUpload"
+	EventObjectRemoved                        NotificationEvent = "s3:ObjectRemoved:*"
+	EventObjectRemovedDelete                  NotificationEvent = "s3:ObjectRemoved:Delete"
+	EventObjectRemovedDeleteMarkerCreated     NotificationEvent = "s3:ObjectRemoved:DeleteMarkerCreated"
+	EventObjectRestore                        NotificationEvent = "s3:ObjectRestore:*"
+	EventObjectRestoreCompleted               NotificationEvent = "s3:ObjectRestore:Completed"
+	EventObjectTagging                        NotificationEvent = "s3:ObjectTagging:*"
+	EventLifecycleExpiration                  NotificationEvent = "s3:LifecycleExpiration:*"
+	EventReplication                          NotificationEvent = "s3:Replication:*"
+)
+
+// NotificationTarget sends the Events of objects matching Prefix and Suffix
+// to exactly one of an SQS queue, an SNS topic or a Lambda function
+type NotificationTarget struct {
+	// ID names the target; S3 generates one if empty
+	ID string `json:"id,omitempty"`
+
+	QueueARN  string `json:"queue_arn,omitempty"`
+	TopicARN  string `json:"topic_arn,omitempty"`
+	LambdaARN string `json:"lambda_arn,omitempty"`
+
+	Events []NotificationEvent `json:"events"`
+	// Prefix and Suffix limit the events to keys starting and ending with
+	// them, like "uploads/" and ".jpg"
+	Prefix string `json:"prefix,omitempty"`
+	Suffix string `json:"suffix,omitempty"`
+}
+
+// NotificationConfig is the complete event notification configuration of a
+// bucket
+type NotificationConfig struct {
+	Targets []NotificationTarget `json:"targets"`
+	// EventBridge also sends every event to Amazon EventBridge
+	EventBridge bool `json:"event_bridge,omitempty"`
+}
+
+// validate checks the target is complete
+func (t *NotificationTarget) validate() error {
+	arns := 0
+	for _, arn := range []string{t.QueueARN, t.TopicARN, t.LambdaARN} {
+		if arn == "" {
+			continue
+		}
+		if !strings.HasPrefix(arn, "arn:") {
+			return fmt.Errorf("%w: %q is not an ARN", ErrInvalidOptions, arn)
+		}
+		arns++
+	}
+	if arns != 1 {
+		return fmt.Errorf("%w: a notification target needs exactly one of QueueARN, TopicARN or LambdaARN", ErrInvalidOptions)
+	}
+	if len(t.Events) == 0 {
+		return fmt.Errorf("%w: a notification target needs at least one event", ErrInvalidOptions)
+	}
+	for _, event := range t.Events {
+		if !strings.HasPrefix(string(event), "s3:") {
+			return fmt.Errorf("%w: unknown notification event %q", ErrInvalidOptions, event)
+		}
+	}
+	return nil
+}
+
+// filter builds the SDK's key filter of the target, or nil if it has none
+func (t *NotificationTarget) filter() *s3.NotificationConfigurationFilter {
+	var rules []*s3.FilterRule
+	if t.Prefix != "" {
+		rules = append(rules, &s3.FilterRule{Name: aws.String(s3.FilterRuleNamePrefix), Value: aws.String(t.Prefix)})
+	}
+	if t.Suffix != "" {
+		rules = append(rules, &s3.FilterRule{Name: aws.String(s3.FilterRuleNameSuffix), Value: aws.String(t.Suffix)})
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+	return &s3.NotificationConfigurationFilter{Key: &s3.KeyFilter{FilterRules: rules}}
+}
+
+// notificationTarget converts the parts of a target returned by S3 that
+// are common to every kind of destination
+func notificationTarget(id *string, events []*string, filter *s3.NotificationConfigurationFilter) NotificationTarget {
+	target := NotificationTarget{ID: aws.StringValue(id)}
+	for _, event := range events {
+		target.Events = append(target.Events, NotificationEvent(aws.StringValue(event)))
+	}
+	if filter != nil && filter.Key != nil {
+		// S3 returns the rule names capitalized
+		for _, rule := range filter.Key.FilterRules {
+			switch strings.ToLower(aws.StringValue(rule.Name)) {
+			case s3.FilterRuleNamePrefix:
+				target.Prefix = aws.StringValue(rule.Value)
+			case s3.FilterRuleNameSuffix:
+				target.Suffix = aws.StringValue(rule.Value)
+			}
+		}
+	}
+	return target
+}
+
+// SetBucketNotifications replaces the whole event notification
+// configuration of bucket with cfg in a single request, so setting the same
+// configuration again changes nothing and targets left out are removed. An
+// empty cfg turns notifications off. S3 checks that it may publish to every
+// destination and fails with ErrInvalidOptions if it may not.
+func (c *S3Client) SetBucketNotifications(ctx context.Context, bucket string, cfg NotificationConfig) error {
+	if bucket == "" {
+		return ErrInvalidBucket
+	}
+
+	config := &s3.NotificationConfiguration{}
+	for i := range cfg.Targets {
+		t := &cfg.Targets[i]
+		if err := t.validate(); err != nil {
+			return err
+		}
+		var id *string
+		if t.ID != "" {
+			id = aws.String(t.ID)
+		}
+		events := make([]*string, 0, len(t.Events))
+		for _, event := range t.Events {
+			events = append(events, aws.String(string(event)))
+		}
+		switch {
+		case t.QueueARN != "":
+			config.QueueConfigurations = append(config.QueueConfigurations, &s3.QueueConfiguration{
+				Id: id, QueueArn: aws.String(t.QueueARN), Events: events, Filter: t.filter(),
+			})
+		case t.TopicARN != "":
+			config.TopicConfigurations = append(config.TopicConfigurations, &s3.TopicConfiguration{
+				Id: id, TopicArn: aws.String(t.TopicARN), Events: events, Filter: t.filter(),
+			})
+		default:
+			config.LambdaFunctionConfigurations = append(config.LambdaFunctionConfigurations, &s3.LambdaFunctionConfiguration{
+				Id: id, LambdaFunctionArn: aws.String(t.LambdaARN), Events: events, Filter: t.filter(),
+			})
+		}
+	}
+	if cfg.EventBridge {
+		config.EventBridgeConfiguration = &s3.EventBridgeConfiguration{}
+	}
+
+	_, err := c.s3Client.PutBucketNotificationConfigurationWithContext(ctx, &s3.PutBucketNotificationConfigurationInput{
+		Bucket:                    aws.String(bucket),
+		NotificationConfiguration: config,
+	})
+	if err != nil {
+		return notificationError(err)
+	}
+	return nil
+}
+
+// GetBucketNotifications returns the event notification configuration of
+// bucket, with queue targets first, then topics, then Lambda functions
+func (c *S3Client) GetBucketNotifications(ctx context.Context, bucket string) (*NotificationConfig, error) {
+	if bucket == "" {
+		return nil, ErrInvalidBucket
+	}
+
+	out, err := c.s3Client.GetBucketNotificationConfigurationWithContext(ctx, &s3.GetBucketNotificationConfigurationRequest{
+		Bucket: aws.String(bucket),
+	})
+	if err != nil {
+		return nil, notificationError(err)
+	}
+	cfg := &NotificationConfig{
+		Targets:     []NotificationTarget{},
+		EventBridge: out.EventBridgeConfiguration != nil,
+	}
+	for _, q := range out.QueueConfigurations {
+		target := notificationTarget(q.Id, q.Events, q.Filter)
+		target.QueueARN = aws.StringValue(q.QueueArn)
+		cfg.Targets = append(cfg.Targets, target)
+	}
+	for _, t := range out.TopicConfigurations {
+		target := notificationTarget(t.Id, t.Events, t.Filter)
+		target.TopicARN = aws.StringValue(t.TopicArn)
+		cfg.Targets = append(cfg.Targets, target)
+	}
+	for _, l := range out.LambdaFunctionConfigurations {
+		target := notificationTarget(l.Id, l.Events, l.Filter)
+		target.LambdaARN = aws.StringValue(l.LambdaFunctionArn)
+		cfg.Targets = append(cfg.Targets, target)
+	}
+	return cfg, nil
+}
+
+// notificationError maps a failed notification configuration request to
+// the library's error values
+func notificationError(err error) error {
+	if aerr, ok := err.(awserr.Error); ok {
+		switch aerr.Code() {
+		case s3.ErrCodeNoSuchBucket:
+			return ErrInvalidBucket
+		case "AccessDenied":
+			return fmt.Errorf("%w: %w", ErrAccessDenied, aerr)
+		case "InvalidArgument", "MalformedXML":
+			return fmt.Errorf("%w: %w", ErrInvalidOptions, aerr)
+		default:
+			return fmt.Errorf("AWS error: %w", aerr)
+		}
+	}
+	return fmt.Errorf("failed to access bucket notifications: %w", err)
+}
@@ -0,0 +1,84 @@
+package s3lib
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestNotificationTargetValidate tests incomplete targets are rejected
+func TestNotificationTargetValidate(t *testing.T) {
+	queue := "arn:aws:sqs:us-west-2:111122223333:uploads"
+	valid := NotificationTarget{QueueARN: queue, Events: []NotificationEvent{EventObjectCreated}}
+	require.NoError(t, valid.validate())
+
+	tests := map[string]NotificationTarget{
+		"no destination":   {Events: []NotificationEvent{EventObjectCreated}},
+		"two destinations": {QueueARN: queue, TopicARN: "arn:aws:sns:us-west-2:111122223333:uploads", Events: []NotificationEvent{EventObjectCreated}},
+		"not an ARN":       {QueueARN: "uploads", Events: []NotificationEvent{EventObjectCreated}},
+		"no events":        {QueueARN: queue},
+		"unknown event":    {QueueARN: queue, Events: []NotificationEvent{"ObjectCreated"}},
+	}
+	for name, target := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.ErrorIs(t, target.validate(), ErrInvalidOptions)
+		})
+	}
+}
+
+// TestS3Client_BucketNotifications tests the configuration set is read back
+// and an empty one turns notifications off
+func TestS3Client_BucketNotifications(t *testing.T) {
+	stored := "<NotificationConfiguration></NotificationConfiguration>"
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: req}
+		switch req.Method {
+		case http.MethodPut:
+			data, _ := io.ReadAll(req.Body)
+			stored = string(data)
+		case http.MethodGet:
+			resp.Body = xmlBody(stored)
+		}
+		return resp, nil
+	})
+	ctx := context.Background()
+
+	cfg, err := client.GetBucketNotifications(ctx, testBucket)
+	require.NoError(t, err)
+	assert.Equal(t, &NotificationConfig{Targets: []NotificationTarget{}}, cfg)
+
+	want := &NotificationConfig{Targets: []NotificationTarget{
+		{
+			ID:       "images",
+			QueueARN: "arn:aws:sqs:us-west-2:111122223333:uploads",
+			Events:   []NotificationEvent{EventObjectCreated},
+			Prefix:   "uploads/",
+			Suffix:   ".jpg",
+		},
+		{
+			ID:       "deletes",
+			TopicARN: "arn:aws:sns:us-west-2:111122223333:audit",
+			Events:   []NotificationEvent{EventObjectRemoved, EventLifecycleExpiration},
+		},
+		{
+			ID:        "thumbnails",
+			LambdaARN: "arn:aws:lambda:us-west-2:111122223333:function:thumbnail",
+			Events:    []NotificationEvent{EventObjectCreatedPut},
+			Suffix:    ".png",
+		},
+	}}
+	require.NoError(t, client.SetBucketNotifications(ctx, testBucket, *want))
+	assert.Contains(t, stored, "<Queue>arn:aws:sqs:us-west-2:111122223333:uploads</Queue>")
+	cfg, err = client.GetBucketNotifications(ctx, testBucket)
+	require.NoError(t, err)
+	assert.Equal(t, want, cfg)
+
+	require.NoError(t, client.SetBucketNotifications(ctx, testBucket, NotificationConfig{}))
+	cfg, err = client.GetBucketNotifications(ctx, testBucket)
+	require.NoError(t, err)
+	assert.Empty(t, cfg.Targets)
+}
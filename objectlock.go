@@ -0,0 +1,48 @@
+package s3lib
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// Object Lock retention modes accepted by UploadOptions.ObjectLockMode
+const (
+	ObjectLockGovernance = s3.ObjectLockModeGovernance
+	ObjectLockCompliance = s3.ObjectLockModeCompliance
+)
+
+// validateObjectLock checks the Object Lock options of an upload
+func validateObjectLock(mode string, retainUntil time.Time) error {
+	switch mode {
+	case "":
+		if !retainUntil.IsZero() {
+			return fmt.Errorf("%w: ObjectLockRetainUntil requires ObjectLockMode", ErrInvalidOptions)
+		}
+		return nil
+	case ObjectLockGovernance, ObjectLockCompliance:
+	default:
+		return fmt.Errorf("%w: unsupported object lock mode %q", ErrInvalidOptions, mode)
+	}
+	if retainUntil.IsZero() {
+		return fmt.Errorf("%w: ObjectLockMode %s requires ObjectLockRetainUntil", ErrInvalidOptions, mode)
+	}
+	if !retainUntil.After(time.Now()) {
+		return fmt.Errorf("%w: ObjectLockRetainUntil %s is not in the future", ErrInvalidOptions, retainUntil.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// applyObjectLock sets the Object Lock parameters on an upload input
+func applyObjectLock(input *s3manager.UploadInput, mode string, retainUntil time.Time, legalHold bool) {
+	if mode != "" {
+		input.ObjectLockMode = aws.String(mode)
+		input.ObjectLockRetainUntilDate = aws.Time(retainUntil)
+	}
+	if legalHold {
+		input.ObjectLockLegalHoldStatus = aws.String(s3.ObjectLockLegalHoldStatusOn)
+	}
+}
@@ -0,0 +1,91 @@
+package s3lib
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestValidateObjectLock tests mode and retain-until date validation
+func TestValidateObjectLock(t *testing.T) {
+	future := time.Now().Add(24 * time.Hour)
+
+	tests := []struct {
+		name        string
+		mode        string
+		retainUntil time.Time
+		wantErr     bool
+	}{
+		{
+			name: "No lock",
+		},
+		{
+			name:        "Governance",
+			mode:        ObjectLockGovernance,
+			retainUntil: future,
+		},
+		{
+			name:        "Compliance",
+			mode:        ObjectLockCompliance,
+			retainUntil: future,
+		},
+		{
+			name:    "Mode without date",
+			mode:    ObjectLockGovernance,
+			wantErr: true,
+		},
+		{
+			name:        "Date without mode",
+			retainUntil: future,
+			wantErr:     true,
+		},
+		{
+			name:        "Date in the past",
+			mode:        ObjectLockCompliance,
+			retainUntil: time.Now().Add(-time.Hour),
+			wantErr:     true,
+		},
+		{
+			name:        "Unknown mode",
+			mode:        "FOREVER",
+			retainUntil: future,
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateObjectLock(tt.mode, tt.retainUntil)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidOptions)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+// TestS3Client_UploadFileObjectLock tests the lock headers sent on upload
+func TestS3Client_UploadFileObjectLock(t *testing.T) {
+	var header http.Header
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		header = req.Header.Clone()
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: req}, nil
+	})
+
+	retainUntil := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+	_, err := client.UploadFile(context.Background(), testBucket, testFileName, testFileContent, &UploadOptions{
+		ObjectLockMode:        ObjectLockGovernance,
+		ObjectLockRetainUntil: retainUntil,
+		ObjectLockLegalHold:   true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ObjectLockGovernance, header.Get("X-Amz-Object-Lock-Mode"))
+	assert.Equal(t, retainUntil.Format(time.RFC3339), header.Get("X-Amz-Object-Lock-Retain-Until-Date"))
+	assert.Equal(t, "ON", header.Get("X-Amz-Object-Lock-Legal-Hold"))
+	assert.NotEmpty(t, header.Get("Content-MD5"), "Object Lock writes require an integrity header")
+}
@@ -0,0 +1,254 @@
+package s3lib
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// Shard sets for ParallelListOptions.Shards
+const (
+	ShardsHex    = "0123456789abcdef"
+	ShardsBase36 = "0123456789abcdefghijklmnopqrstuvwxyz"
+)
+
+// DefaultListConcurrency is the number of shards ListFilesParallel lists at
+// once by default
+const DefaultListConcurrency = 8
+
+// ParallelListOptions controls how ListFilesParallel shards a listing
+type ParallelListOptions struct {
+	// ListOptions filters the files as for ListFilesIter. MaxKeys,
+	// StartAfter, ContinuationToken, Delimiter and sorting are not supported.
+	ListOptions
+
+	// Shards are the characters splitting the keyspace, in ascending order.
+	// Each shard lists the keys whose first character after the prefix
+	// sorts from one shard character up to the next, so keys starting with
+	// other characters are still listed, by the neighbouring shards. Pick a
+	// set matching how keys start; ShardsBase36 is used by default.
+	Shards string
+
+	// Concurrency is the number of shards listed at once,
+	// DefaultListConcurrency if 0
+	Concurrency int
+
+	// Ordered returns files in key order across shards. Shards cover
+	// consecutive key ranges, so their files are returned one shard after
+	// the other; shards ahead of the one being read buffer only a few pages,
+	// which limits how far listing runs ahead of the caller.
+	Ordered bool
+}
+
+func (o *ParallelListOptions) validate() error {
+	if o == nil {
+		return nil
+	}
+	if err := o.ListOptions.validate(); err != nil {
+		return err
+	}
+	if o.MaxKeys != 0 || o.StartAfter != "" || o.ContinuationToken != "" || o.Delimiter != "" || o.SortBy != "" {
+		return fmt.Errorf("%w: MaxKeys, StartAfter, ContinuationToken, Delimiter and SortBy are not supported by parallel listings", ErrInvalidOptions)
+	}
+	if o.Concurrency < 0 {
+		return fmt.Errorf("%w: Concurrency must not be negative", ErrInvalidOptions)
+	}
+	shards := []rune(o.Shards)
+	for i := 1; i < len(shards); i++ {
+		if string(shards[i-1]) >= string(shards[i]) {
+			return fmt.Errorf("%w: Shards must be in ascending order", ErrInvalidOptions)
+		}
+	}
+	return nil
+}
+
+// listBatch is a page of files listed by a shard, or the error that ended it
+type listBatch struct {
+	files []FileInfo
+	err   error
+}
+
+// parallelListing feeds a FileIterator from concurrently listed shards.
+// Unordered listings merge every shard into one channel; ordered ones have
+// a channel per shard, read in key order.
+type parallelListing struct {
+	cancel  context.CancelFunc
+	shards  []chan listBatch
+	current int
+	files   []FileInfo
+}
+
+// ListFilesParallel lists the files under prefix like ListFilesIter,
+// splitting the keyspace into shards listed concurrently. Files within a
+// shard are returned in key order, but shards are interleaved unless
+// opts.Ordered is set. Call Close on the iterator when stopping before the
+// end so the remaining shards stop listing.
+func (c *S3Client) ListFilesParallel(ctx context.Context, bucket, prefix string, opts *ParallelListOptions) *FileIterator {
+	it := &FileIterator{c: c, ctx: ctx}
+	if bucket == "" {
+		it.err = ErrInvalidBucket
+		return it
+	}
+	if err := opts.validate(); err != nil {
+		it.err = err
+		return it
+	}
+	if opts == nil {
+		opts = &ParallelListOptions{}
+	}
+	shardSet := opts.Shards
+	if shardSet == "" {
+		shardSet = ShardsBase36
+	}
+	concurrency := opts.Concurrency
+	if concurrency == 0 {
+		concurrency = DefaultListConcurrency
+	}
+
+	input := c.listInput(bucket, prefix, &opts.ListOptions)
+	filter := c.newListFilter(prefix, &opts.ListOptions)
+
+	// Shard i lists the keys after boundary i up to and including boundary
+	// i+1. Listings start strictly after a key, so a key equal to a boundary
+	// belongs to the shard before it.
+	base := aws.StringValue(input.Prefix)
+	var boundaries []string
+	for i, r := range []rune(shardSet) {
+		if i > 0 {
+			boundaries = append(boundaries, base+string(r))
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	p := &parallelListing{cancel: cancel}
+	shards := len(boundaries) + 1
+	if opts.Ordered {
+		for i := 0; i < shards; i++ {
+			p.shards = append(p.shards, make(chan listBatch, 2))
+		}
+	} else {
+		p.shards = []chan listBatch{make(chan listBatch, concurrency)}
+	}
+	it.parallel = p
+
+	go func() {
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, concurrency)
+		// Shards are started in key order, so the one an ordered listing is
+		// reading always holds a slot
+		started := 0
+	dispatch:
+		for ; started < shards; started++ {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				break dispatch
+			}
+
+			out := p.shards[0]
+			if opts.Ordered {
+				out = p.shards[started]
+			}
+			var startAfter, end string
+			if started > 0 {
+				startAfter = boundaries[started-1]
+			}
+			if started < len(boundaries) {
+				end = boundaries[started]
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+				c.listShard(ctx, input, filter, startAfter, end, out)
+				if opts.Ordered {
+					close(out)
+				}
+			}()
+		}
+		wg.Wait()
+
+		if !opts.Ordered {
+			close(p.shards[0])
+			return
+		}
+		for ; started < shards; started++ {
+			close(p.shards[started])
+		}
+	}()
+	return it
+}
+
+// listShard lists the keys after startAfter up to and including end, either
+// of which may be empty for no bound, sending each page of files to out
+func (c *S3Client) listShard(ctx context.Context, template *s3.ListObjectsV2Input, filter *listFilter, startAfter, end string, out chan<- listBatch) {
+	input := *template
+	if startAfter != "" {
+		input.StartAfter = aws.String(startAfter)
+	}
+
+	err := c.listObjectsPages(ctx, &input,
+		func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+			more := true
+			files := make([]FileInfo, 0, len(page.Contents))
+			for _, obj := range page.Contents {
+				if end != "" && aws.StringValue(obj.Key) > end {
+					more = false
+					break
+				}
+				if filter.keep(obj) {
+					files = append(files, c.listedFile(obj))
+				}
+			}
+			if len(files) > 0 {
+				select {
+				case out <- listBatch{files: files}:
+				case <-ctx.Done():
+					return false
+				}
+			}
+			return more
+		})
+	if err != nil && ctx.Err() == nil {
+		select {
+		case out <- listBatch{err: listError(err)}:
+		case <-ctx.Done():
+		}
+	}
+}
+
+// nextParallel advances a parallel listing to its next file
+func (it *FileIterator) nextParallel() bool {
+	p := it.parallel
+	for len(p.files) == 0 {
+		if p.current == len(p.shards) {
+			p.cancel()
+			return false
+		}
+		select {
+		case batch, ok := <-p.shards[p.current]:
+			if !ok {
+				p.current++
+				continue
+			}
+			if batch.err != nil {
+				it.err = batch.err
+				p.cancel()
+				return false
+			}
+			p.files = batch.files
+		case <-it.ctx.Done():
+			it.err = it.ctx.Err()
+			p.cancel()
+			return false
+		}
+	}
+
+	it.file = p.files[0]
+	p.files = p.files[1:]
+	it.count++
+	return true
+}
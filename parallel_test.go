@@ -0,0 +1,128 @@
+package s3lib
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// keyListServer fakes a bucket holding keys, honoring prefix and start-after
+// and serving pages of pageSize keys
+func keyListServer(keys []string, pageSize int, requests *int32) roundTripFunc {
+	sorted := append([]string(nil), keys...)
+	sort.Strings(sorted)
+	return func(req *http.Request) (*http.Response, error) {
+		atomic.AddInt32(requests, 1)
+		query := req.URL.Query()
+		after := query.Get("start-after")
+		if token := query.Get("continuation-token"); token != "" {
+			after = token
+		}
+
+		var body strings.Builder
+		body.WriteString("<ListBucketResult>")
+		n := 0
+		for _, key := range sorted {
+			if !strings.HasPrefix(key, query.Get("prefix")) || key <= after {
+				continue
+			}
+			if n == pageSize {
+				fmt.Fprintf(&body, "<IsTruncated>true</IsTruncated><NextContinuationToken>%s</NextContinuationToken>", after)
+				break
+			}
+			fmt.Fprintf(&body, "<Contents><Key>%s</Key><Size>1</Size></Contents>", key)
+			after = key
+			n++
+		}
+		body.WriteString("</ListBucketResult>")
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: xmlBody(body.String()), Request: req}, nil
+	}
+}
+
+// TestS3Client_ListFilesParallel tests every key is listed exactly once,
+// including keys outside the shard set and keys equal to a boundary
+func TestS3Client_ListFilesParallel(t *testing.T) {
+	keys := []string{"data/", "data/-dash", "data/0", "data/00", "data/1", "data/5x", "data/9", "data/a", "data/f/", "data/g", "data/z", "data/~", "other"}
+	for i := 1; i < 200; i++ {
+		keys = append(keys, "data/"+strconv.FormatInt(int64(i*7919), 16))
+	}
+	var want []string
+	for _, key := range keys {
+		if strings.HasPrefix(key, "data/") {
+			want = append(want, key)
+		}
+	}
+	sort.Strings(want)
+
+	var requests int32
+	client := setupFakeClient(t, keyListServer(keys, 5, &requests))
+	collect := func(opts *ParallelListOptions) []string {
+		it := client.ListFilesParallel(context.Background(), testBucket, "data/", opts)
+		defer it.Close()
+		var got []string
+		for it.Next() {
+			got = append(got, it.File().Key)
+		}
+		require.NoError(t, it.Err())
+		return got
+	}
+
+	t.Run("Unordered", func(t *testing.T) {
+		got := collect(&ParallelListOptions{Shards: ShardsHex, Concurrency: 4})
+		sort.Strings(got)
+		assert.Equal(t, want, got)
+	})
+
+	t.Run("Ordered", func(t *testing.T) {
+		assert.Equal(t, want, collect(&ParallelListOptions{Shards: ShardsHex, Concurrency: 2, Ordered: true}))
+		assert.Equal(t, want, collect(&ParallelListOptions{Ordered: true}))
+	})
+
+	t.Run("Filtered", func(t *testing.T) {
+		got := collect(&ParallelListOptions{ListOptions: ListOptions{Suffix: "0"}})
+		for _, key := range got {
+			assert.True(t, strings.HasSuffix(key, "0"), key)
+		}
+		assert.NotEmpty(t, got)
+	})
+
+	t.Run("Close", func(t *testing.T) {
+		it := client.ListFilesParallel(context.Background(), testBucket, "data/", &ParallelListOptions{Ordered: true})
+		require.True(t, it.Next())
+		require.NoError(t, it.Close())
+		assert.False(t, it.Next())
+		assert.NoError(t, it.Err())
+	})
+}
+
+// TestS3Client_ListFilesParallelCancel tests cancelling the context ends a
+// parallel listing with the context's error
+func TestS3Client_ListFilesParallelCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	it := setupTestClient(t).ListFilesParallel(ctx, testBucket, "", nil)
+	defer it.Close()
+	for it.Next() {
+	}
+	assert.ErrorIs(t, it.Err(), context.Canceled)
+}
+
+// TestParallelListOptionsValidate tests unsupported and invalid options are
+// rejected
+func TestParallelListOptionsValidate(t *testing.T) {
+	assert.NoError(t, (*ParallelListOptions)(nil).validate())
+	assert.NoError(t, (&ParallelListOptions{Shards: ShardsBase36, Ordered: true}).validate())
+	assert.ErrorIs(t, (&ParallelListOptions{Shards: "ba"}).validate(), ErrInvalidOptions)
+	assert.ErrorIs(t, (&ParallelListOptions{Concurrency: -1}).validate(), ErrInvalidOptions)
+	assert.ErrorIs(t, (&ParallelListOptions{ListOptions: ListOptions{MaxKeys: 10}}).validate(), ErrInvalidOptions)
+	assert.ErrorIs(t, (&ParallelListOptions{ListOptions: ListOptions{SortBy: SortBySize}}).validate(), ErrInvalidOptions)
+}
@@ -0,0 +1,137 @@
+package s3lib
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// PolicyPrincipal is who a bucket policy statement applies to. Set AWS to
+// an account, user or role ARN, such as a CloudFront origin access
+// identity's "arn:aws:iam::cloudfront:user/CloudFront Origin Access Identity
+// E2QWRUHAPOMQZL", or Service to a service principal like
+// "cloudfront.amazonaws.com".
+type PolicyPrincipal struct {
+	AWS     string `json:"AWS,omitempty"`
+	Service string `json:"Service,omitempty"`
+}
+
+// policyDocument is the JSON form of a bucket policy built by the library
+type policyDocument struct {
+	Version   string            `json:"Version"`
+	Statement []policyStatement `json:"Statement"`
+}
+
+type policyStatement struct {
+	Sid       string          `json:"Sid,omitempty"`
+	Effect    string          `json:"Effect"`
+	Principal PolicyPrincipal `json:"Principal"`
+	Action    string          `json:"Action"`
+	Resource  string          `json:"Resource"`
+}
+
+// ReadAccessPolicy returns a bucket policy allowing principal to
+// s3:GetObject the objects of bucket under prefix, or all of them if prefix
+// is empty. The result can be passed to SetBucketPolicy; it replaces any
+// policy the bucket already has.
+func ReadAccessPolicy(bucket, prefix string, principal PolicyPrincipal) (string, error) {
+	if bucket == "" {
+		return "", ErrInvalidBucket
+	}
+	if (principal.AWS == "") == (principal.Service == "") {
+		return "", fmt.Errorf("%w: the principal needs exactly one of AWS or Service", ErrInvalidOptions)
+	}
+	doc := policyDocument{
+		Version: "2012-10-17",
+		Statement: []policyStatement{{
+			Sid:       "AllowRead",
+			Effect:    "Allow",
+			Principal: principal,
+			Action:    "s3:GetObject",
+			Resource:  "arn:aws:s3:::" + bucket + "/" + prefix + "*",
+		}},
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrJSONEncode, err)
+	}
+	return string(data), nil
+}
+
+// SetBucketPolicy replaces the policy of bucket with policyJSON, which is
+// checked to be JSON before the request is made. S3 rejects policies it
+// cannot parse, or that grant actions to unknown principals, with
+// ErrInvalidOptions.
+func (c *S3Client) SetBucketPolicy(ctx context.Context, bucket, policyJSON string) error {
+	if bucket == "" {
+		return ErrInvalidBucket
+	}
+	if !json.Valid([]byte(policyJSON)) {
+		return fmt.Errorf("%w: bucket policy is not valid JSON", ErrInvalidOptions)
+	}
+
+	_, err := c.s3Client.PutBucketPolicyWithContext(ctx, &s3.PutBucketPolicyInput{
+		Bucket: aws.String(bucket),
+		Policy: aws.String(policyJSON),
+	})
+	if err != nil {
+		return policyError(err)
+	}
+	return nil
+}
+
+// GetBucketPolicy returns the policy of bucket as JSON, or an empty string
+// if it has none
+func (c *S3Client) GetBucketPolicy(ctx context.Context, bucket string) (string, error) {
+	if bucket == "" {
+		return "", ErrInvalidBucket
+	}
+
+	out, err := c.s3Client.GetBucketPolicyWithContext(ctx, &s3.GetBucketPolicyInput{
+		Bucket: aws.String(bucket),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == "NoSuchBucketPolicy" {
+			return "", nil
+		}
+		return "", policyError(err)
+	}
+	return aws.StringValue(out.Policy), nil
+}
+
+// DeleteBucketPolicy removes the policy of bucket. Removing one that does
+// not exist succeeds.
+func (c *S3Client) DeleteBucketPolicy(ctx context.Context, bucket string) error {
+	if bucket == "" {
+		return ErrInvalidBucket
+	}
+	_, err := c.s3Client.DeleteBucketPolicyWithContext(ctx, &s3.DeleteBucketPolicyInput{
+		Bucket: aws.String(bucket),
+	})
+	if err != nil {
+		return policyError(err)
+	}
+	return nil
+}
+
+// policyError maps a failed bucket policy request to the library's error
+// values
+func policyError(err error) error {
+	if aerr, ok := err.(awserr.Error); ok {
+		switch aerr.Code() {
+		case s3.ErrCodeNoSuchBucket:
+			return ErrInvalidBucket
+		case "AccessDenied":
+			return fmt.Errorf("%w: %w", ErrAccessDenied, aerr)
+		case "MalformedPolicy":
+			return fmt.Errorf("%w: %w", ErrInvalidOptions, aerr)
+		default:
+			return fmt.Errorf("AWS error: %w", aerr)
+		}
+	}
+	return fmt.Errorf("failed to access bucket policy: %w", err)
+}
@@ -0,0 +1,80 @@
+package s3lib
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestReadAccessPolicy tests the policy grants GetObject under the prefix
+// to exactly one principal
+func TestReadAccessPolicy(t *testing.T) {
+	oai := "arn:aws:iam::cloudfront:user/CloudFront Origin Access Identity E2QWRUHAPOMQZL"
+	policy, err := ReadAccessPolicy("media", "public/", PolicyPrincipal{AWS: oai})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"Version": "2012-10-17",
+		"Statement": [{
+			"Sid": "AllowRead",
+			"Effect": "Allow",
+			"Principal": {"AWS": "`+oai+`"},
+			"Action": "s3:GetObject",
+			"Resource": "arn:aws:s3:::media/public/*"
+		}]
+	}`, policy)
+
+	_, err = ReadAccessPolicy("media", "", PolicyPrincipal{})
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+	_, err = ReadAccessPolicy("media", "", PolicyPrincipal{AWS: oai, Service: "cloudfront.amazonaws.com"})
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+}
+
+// TestS3Client_BucketPolicy tests a policy set is read back, a bucket
+// without one reads as empty, and invalid JSON is never sent
+func TestS3Client_BucketPolicy(t *testing.T) {
+	var stored string
+	var requests int
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		requests++
+		resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: req}
+		switch req.Method {
+		case http.MethodPut:
+			data, _ := io.ReadAll(req.Body)
+			stored = string(data)
+		case http.MethodGet:
+			if stored == "" {
+				return fakeErrorResponse(req, http.StatusNotFound, "NoSuchBucketPolicy"), nil
+			}
+			resp.Body = xmlBody(stored)
+		case http.MethodDelete:
+			stored = ""
+			resp.StatusCode = http.StatusNoContent
+		}
+		return resp, nil
+	})
+	ctx := context.Background()
+
+	policy, err := client.GetBucketPolicy(ctx, testBucket)
+	require.NoError(t, err)
+	assert.Empty(t, policy)
+
+	want, err := ReadAccessPolicy(testBucket, "", PolicyPrincipal{Service: "cloudfront.amazonaws.com"})
+	require.NoError(t, err)
+	require.NoError(t, client.SetBucketPolicy(ctx, testBucket, want))
+	policy, err = client.GetBucketPolicy(ctx, testBucket)
+	require.NoError(t, err)
+	assert.JSONEq(t, want, policy)
+
+	require.NoError(t, client.DeleteBucketPolicy(ctx, testBucket))
+	policy, err = client.GetBucketPolicy(ctx, testBucket)
+	require.NoError(t, err)
+	assert.Empty(t, policy)
+
+	sent := requests
+	assert.ErrorIs(t, client.SetBucketPolicy(ctx, testBucket, `{"Version":`), ErrInvalidOptions)
+	assert.Equal(t, sent, requests)
+}
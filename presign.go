@@ -0,0 +1,370 @@
+package s3lib
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// postPolicyAlgorithm is the signing algorithm of SigV4 POST policies
+const postPolicyAlgorithm = "AWS4-HMAC-SHA256"
+
+// PostPolicyConditions restricts what a browser may upload with a presigned
+// POST policy. Zero values add no condition.
+type PostPolicyConditions struct {
+	// ExactKey requires the uploaded key to equal the keyPrefix argument
+	// instead of merely starting with it
+	ExactKey bool
+
+	// MinContentLength and MaxContentLength bound the upload size in bytes.
+	// A range condition is added when MaxContentLength is set.
+	MinContentLength int64
+	MaxContentLength int64
+
+	// ContentType requires an exact Content-Type form field and
+	// ContentTypePrefix one starting with the prefix (e.g. "image/")
+	ContentType       string
+	ContentTypePrefix string
+}
+
+// validate rejects negative and contradictory conditions
+func (p PostPolicyConditions) validate() error {
+	if p.MinContentLength < 0 || p.MaxContentLength < 0 {
+		return fmt.Errorf("%w: content length bounds must not be negative", ErrInvalidOptions)
+	}
+	if p.MinContentLength > 0 && p.MaxContentLength == 0 {
+		return fmt.Errorf("%w: MinContentLength requires MaxContentLength", ErrInvalidOptions)
+	}
+	if p.MaxContentLength > 0 && p.MinContentLength > p.MaxContentLength {
+		return fmt.Errorf("%w: MinContentLength %d exceeds MaxContentLength %d", ErrInvalidOptions, p.MinContentLength, p.MaxContentLength)
+	}
+	if p.ContentType != "" && p.ContentTypePrefix != "" && !strings.HasPrefix(p.ContentType, p.ContentTypePrefix) {
+		return fmt.Errorf("%w: ContentType %q does not match ContentTypePrefix %q", ErrInvalidOptions, p.ContentType, p.ContentTypePrefix)
+	}
+	return nil
+}
+
+// PresignPostPolicy creates a SigV4 signed POST policy for browser uploads
+// with multipart/form-data. The returned fields must be sent as form fields
+// ahead of the file field. Unless conditions.ExactKey is set, any key starting
+// with keyPrefix is accepted and the "key" field defaults to
+// keyPrefix + "${filename}", which S3 replaces with the uploaded file name.
+func (c *S3Client) PresignPostPolicy(ctx context.Context, bucket, keyPrefix string, expiry time.Duration, conditions PostPolicyConditions) (*PreSignedPostResponse, error) {
+	if bucket == "" {
+		return nil, ErrInvalidBucket
+	}
+	if conditions.ExactKey {
+		key, err := c.resolveKey(keyPrefix)
+		if err != nil {
+			return nil, err
+		}
+		keyPrefix = key
+	} else {
+		keyPrefix = c.config.KeyPrefix + keyPrefix
+	}
+	if expiry <= 0 {
+		return nil, fmt.Errorf("%w: expiry must be positive", ErrInvalidOptions)
+	}
+	if err := conditions.validate(); err != nil {
+		return nil, err
+	}
+
+	creds, err := c.session.Config.Credentials.GetWithContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credentials: %w", err)
+	}
+
+	now := time.Now().UTC()
+	date := now.Format("20060102")
+	amzDate := now.Format("20060102T150405Z")
+	credential := fmt.Sprintf("%s/%s/%s/s3/aws4_request", creds.AccessKeyID, date, c.config.Region)
+
+	fields := map[string]string{
+		"x-amz-algorithm":  postPolicyAlgorithm,
+		"x-amz-credential": credential,
+		"x-amz-date":       amzDate,
+	}
+	policyConditions := []interface{}{
+		map[string]string{"bucket": bucket},
+		map[string]string{"x-amz-algorithm": postPolicyAlgorithm},
+		map[string]string{"x-amz-credential": credential},
+		map[string]string{"x-amz-date": amzDate},
+	}
+	if creds.SessionToken != "" {
+		fields["x-amz-security-token"] = creds.SessionToken
+		policyConditions = append(policyConditions, map[string]string{"x-amz-security-token": creds.SessionToken})
+	}
+
+	if conditions.ExactKey {
+		fields["key"] = keyPrefix
+		policyConditions = append(policyConditions, map[string]string{"key": keyPrefix})
+	} else {
+		fields["key"] = keyPrefix + "${filename}"
+		policyConditions = append(policyConditions, []interface{}{"starts-with", "$key", keyPrefix})
+	}
+	if conditions.MaxContentLength > 0 {
+		policyConditions = append(policyConditions, []interface{}{"content-length-range", conditions.MinContentLength, conditions.MaxContentLength})
+	}
+	if conditions.ContentType != "" {
+		fields["Content-Type"] = conditions.ContentType
+		policyConditions = append(policyConditions, map[string]string{"Content-Type": conditions.ContentType})
+	}
+	if conditions.ContentTypePrefix != "" {
+		policyConditions = append(policyConditions, []interface{}{"starts-with", "$Content-Type", conditions.ContentTypePrefix})
+	}
+
+	policyJSON, err := json.Marshal(map[string]interface{}{
+		"expiration": now.Add(expiry).Format("2006-01-02T15:04:05.000Z"),
+		"conditions": policyConditions,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal policy: %w", err)
+	}
+	policy := base64.StdEncoding.EncodeToString(policyJSON)
+
+	fields["policy"] = policy
+	fields["x-amz-signature"] = signPostPolicy(creds.SecretAccessKey, date, c.config.Region, policy)
+
+	return &PreSignedPostResponse{
+		URL:    c.bucketURL(bucket),
+		Fields: fields,
+	}, nil
+}
+
+// signPostPolicy returns the hex SigV4 signature of a base64 encoded policy
+func signPostPolicy(secretKey, date, region, policy string) string {
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, policy))
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data under key
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// maxPresignExpiry is the longest validity SigV4 allows for presigned URLs
+const maxPresignExpiry = 7 * 24 * time.Hour
+
+// PresignDownload returns a URL that GETs the object, or the version named
+// by opts.VersionID, until expiry elapses. A zero expiry uses
+// Config.Duration; AWS rejects anything beyond 7 days. The Response* options
+// are signed into the URL. SSECustomerKey cannot be carried in a URL and is
+// rejected; the other download options do not apply.
+func (c *S3Client) PresignDownload(ctx context.Context, bucket, key string, expiry time.Duration, opts *DownloadOptions) (*PreSignedURLResponse, error) {
+	if bucket == "" {
+		return nil, ErrInvalidBucket
+	}
+	key, err := c.resolveKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if expiry == 0 {
+		expiry = c.config.Duration
+	}
+	if expiry <= 0 || expiry > maxPresignExpiry {
+		return nil, fmt.Errorf("%w: expiry must be positive and at most %s", ErrInvalidOptions, maxPresignExpiry)
+	}
+	if opts != nil && opts.SSECustomerKey != nil {
+		return nil, fmt.Errorf("%w: SSECustomerKey cannot be used with presigned URLs", ErrInvalidOptions)
+	}
+
+	input := &s3.GetObjectInput{
+		Bucket:    aws.String(bucket),
+		Key:       aws.String(key),
+		VersionId: opts.versionID(),
+	}
+	if opts != nil {
+		if opts.ResponseContentType != "" {
+			input.ResponseContentType = aws.String(opts.ResponseContentType)
+		}
+		if opts.ResponseContentDisposition != "" {
+			input.ResponseContentDisposition = aws.String(opts.ResponseContentDisposition)
+		}
+		if opts.ResponseCacheControl != "" {
+			input.ResponseCacheControl = aws.String(opts.ResponseCacheControl)
+		}
+	}
+	req, _ := c.s3Client.GetObjectRequest(input)
+	req.SetContext(ctx)
+
+	url, err := req.Presign(expiry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to presign download: %w", err)
+	}
+
+	return &PreSignedURLResponse{
+		URL:     url,
+		Expires: time.Now().Add(expiry),
+	}, nil
+}
+
+// PresignedMultipartUpload identifies a multipart upload whose parts are
+// sent directly by a browser using URLs from PresignPart. Key is the full
+// object key, including any Config.KeyPrefix.
+type PresignedMultipartUpload struct {
+	Bucket   string `json:"bucket"`
+	Key      string `json:"key"`
+	UploadID string `json:"upload_id"`
+}
+
+// CompletedPart is a part uploaded with a presigned URL, as reported back by
+// the browser. ETag is the ETag response header of the part upload.
+type CompletedPart struct {
+	PartNumber int64  `json:"part_number"`
+	ETag       string `json:"etag"`
+}
+
+// CreatePresignedMultipart starts a multipart upload for browser clients.
+// Options computed per request by the library (ComputeMD5, ChecksumAlgorithm,
+// Compress, SSECustomerKey) cannot be applied to browser requests and are
+// rejected.
+func (c *S3Client) CreatePresignedMultipart(ctx context.Context, bucket, key string, opts *UploadOptions) (*PresignedMultipartUpload, error) {
+	if opts != nil && (opts.ComputeMD5 || opts.ChecksumAlgorithm != ChecksumNone || opts.Compress || opts.SSECustomerKey != nil) {
+		return nil, fmt.Errorf("%w: ComputeMD5, ChecksumAlgorithm, Compress and SSECustomerKey are not supported for presigned uploads", ErrInvalidOptions)
+	}
+	key, err := c.resolveKey(key)
+	if err != nil {
+		return nil, err
+	}
+	input, err := newUploadInput(bucket, key, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := c.s3Client.CreateMultipartUploadWithContext(ctx, createMultipartUploadInput(input))
+	if err != nil {
+		return nil, multipartError(err, "failed to create multipart upload")
+	}
+
+	return &PresignedMultipartUpload{
+		Bucket:   bucket,
+		Key:      key,
+		UploadID: aws.StringValue(result.UploadId),
+	}, nil
+}
+
+// PresignPart returns a URL the browser uses to PUT one part of a presigned
+// multipart upload. Parts other than the last must be at least 5MB.
+func (c *S3Client) PresignPart(ctx context.Context, upload *PresignedMultipartUpload, partNumber int64, expiry time.Duration) (*PreSignedURLResponse, error) {
+	if err := upload.validate(); err != nil {
+		return nil, err
+	}
+	if partNumber < 1 || partNumber > s3manager.MaxUploadParts {
+		return nil, fmt.Errorf("%w: part number %d is outside 1-%d", ErrInvalidOptions, partNumber, s3manager.MaxUploadParts)
+	}
+	if expiry <= 0 || expiry > maxPresignExpiry {
+		return nil, fmt.Errorf("%w: expiry must be positive and at most %s", ErrInvalidOptions, maxPresignExpiry)
+	}
+
+	req, _ := c.s3Client.UploadPartRequest(&s3.UploadPartInput{
+		Bucket:     aws.String(upload.Bucket),
+		Key:        aws.String(upload.Key),
+		UploadId:   aws.String(upload.UploadID),
+		PartNumber: aws.Int64(partNumber),
+	})
+	req.SetContext(ctx)
+
+	url, err := req.Presign(expiry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to presign part: %w", err)
+	}
+
+	return &PreSignedURLResponse{
+		URL:     url,
+		Expires: time.Now().Add(expiry),
+	}, nil
+}
+
+// CompletePresignedMultipart assembles the parts reported by the browser
+// into the final object
+func (c *S3Client) CompletePresignedMultipart(ctx context.Context, upload *PresignedMultipartUpload, parts []CompletedPart) (*UploadResult, error) {
+	if err := upload.validate(); err != nil {
+		return nil, err
+	}
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("%w: no parts to complete", ErrInvalidOptions)
+	}
+
+	sorted := make([]CompletedPart, len(parts))
+	copy(sorted, parts)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].PartNumber < sorted[j].PartNumber })
+
+	completed := make([]*s3.CompletedPart, 0, len(sorted))
+	for i, part := range sorted {
+		if part.PartNumber < 1 || part.PartNumber > s3manager.MaxUploadParts {
+			return nil, fmt.Errorf("%w: part number %d is outside 1-%d", ErrInvalidOptions, part.PartNumber, s3manager.MaxUploadParts)
+		}
+		if i > 0 && part.PartNumber == sorted[i-1].PartNumber {
+			return nil, fmt.Errorf("%w: part %d listed twice", ErrInvalidOptions, part.PartNumber)
+		}
+		if part.ETag == "" {
+			return nil, fmt.Errorf("%w: part %d has no ETag", ErrInvalidOptions, part.PartNumber)
+		}
+		completed = append(completed, &s3.CompletedPart{
+			ETag:       aws.String(part.ETag),
+			PartNumber: aws.Int64(part.PartNumber),
+		})
+	}
+
+	out, err := c.s3Client.CompleteMultipartUploadWithContext(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(upload.Bucket),
+		Key:             aws.String(upload.Key),
+		UploadId:        aws.String(upload.UploadID),
+		MultipartUpload: &s3.CompletedMultipartUpload{Parts: completed},
+	})
+	if err != nil {
+		return nil, multipartError(err, "failed to complete multipart upload")
+	}
+
+	result := &UploadResult{
+		Key:        c.relativeKey(upload.Key),
+		Location:   c.objectURL(upload.Bucket, upload.Key),
+		ETag:       aws.StringValue(out.ETag),
+		VersionID:  aws.StringValue(out.VersionId),
+		Expiration: aws.StringValue(out.Expiration),
+	}
+	result.setChecksum(out.ChecksumSHA256, out.ChecksumCRC32C, out.ChecksumCRC32, out.ChecksumSHA1)
+	return result, nil
+}
+
+// AbortPresignedMultipart aborts a presigned multipart upload, e.g. when the
+// browser session is abandoned, and deletes the parts uploaded so far
+func (c *S3Client) AbortPresignedMultipart(ctx context.Context, upload *PresignedMultipartUpload) error {
+	if err := upload.validate(); err != nil {
+		return err
+	}
+	return c.abortMultipartUpload(ctx, upload.Bucket, upload.Key, upload.UploadID)
+}
+
+// validate checks the upload identifies a multipart upload
+func (u *PresignedMultipartUpload) validate() error {
+	if u == nil {
+		return ErrNilValue
+	}
+	if u.Bucket == "" {
+		return ErrInvalidBucket
+	}
+	if u.Key == "" {
+		return ErrInvalidKey
+	}
+	if u.UploadID == "" {
+		return ErrUploadNotFound
+	}
+	return nil
+}
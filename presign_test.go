@@ -0,0 +1,353 @@
+package s3lib
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPostPolicyConditions_validate tests contradictory conditions are rejected
+func TestPostPolicyConditions_validate(t *testing.T) {
+	tests := []struct {
+		name       string
+		conditions PostPolicyConditions
+		wantErr    bool
+	}{
+		{
+			name: "No conditions",
+		},
+		{
+			name:       "Length range",
+			conditions: PostPolicyConditions{MinContentLength: 1, MaxContentLength: 10 << 20},
+		},
+		{
+			name:       "Matching content type and prefix",
+			conditions: PostPolicyConditions{ContentType: "image/png", ContentTypePrefix: "image/"},
+		},
+		{
+			name:       "Inverted length range",
+			conditions: PostPolicyConditions{MinContentLength: 100, MaxContentLength: 10},
+			wantErr:    true,
+		},
+		{
+			name:       "Negative length",
+			conditions: PostPolicyConditions{MaxContentLength: -1},
+			wantErr:    true,
+		},
+		{
+			name:       "Minimum without maximum",
+			conditions: PostPolicyConditions{MinContentLength: 1},
+			wantErr:    true,
+		},
+		{
+			name:       "Content type outside prefix",
+			conditions: PostPolicyConditions{ContentType: "text/html", ContentTypePrefix: "image/"},
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.conditions.validate()
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidOptions)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+// TestS3Client_PresignPostPolicy tests the form fields and signed policy
+func TestS3Client_PresignPostPolicy(t *testing.T) {
+	client := setupTestClient(t)
+	ctx := context.Background()
+
+	resp, err := client.PresignPostPolicy(ctx, testBucket, "uploads/", 15*time.Minute, PostPolicyConditions{
+		MaxContentLength:  5 << 20,
+		ContentTypePrefix: "image/",
+	})
+	require.NoError(t, err)
+	assert.Contains(t, resp.URL, testBucket)
+	assert.Equal(t, "uploads/${filename}", resp.Fields["key"])
+	assert.Equal(t, postPolicyAlgorithm, resp.Fields["x-amz-algorithm"])
+	assert.True(t, strings.HasPrefix(resp.Fields["x-amz-credential"], testConfig.AccessKey+"/"))
+
+	policyJSON, err := base64.StdEncoding.DecodeString(resp.Fields["policy"])
+	require.NoError(t, err)
+	var policy struct {
+		Expiration string            `json:"expiration"`
+		Conditions []json.RawMessage `json:"conditions"`
+	}
+	require.NoError(t, json.Unmarshal(policyJSON, &policy))
+	assert.NotEmpty(t, policy.Expiration)
+	assert.Contains(t, string(policyJSON), `["starts-with","$key","uploads/"]`)
+	assert.Contains(t, string(policyJSON), `["content-length-range",0,5242880]`)
+	assert.Contains(t, string(policyJSON), `["starts-with","$Content-Type","image/"]`)
+
+	date := resp.Fields["x-amz-date"][:8]
+	assert.Equal(t, signPostPolicy(testConfig.SecretKey, date, testConfig.Region, resp.Fields["policy"]), resp.Fields["x-amz-signature"])
+
+	exact, err := client.PresignPostPolicy(ctx, testBucket, "avatars/42.png", time.Minute, PostPolicyConditions{ExactKey: true})
+	require.NoError(t, err)
+	assert.Equal(t, "avatars/42.png", exact.Fields["key"])
+
+	_, err = client.PresignPostPolicy(ctx, "", "uploads/", time.Minute, PostPolicyConditions{})
+	assert.ErrorIs(t, err, ErrInvalidBucket)
+	_, err = client.PresignPostPolicy(ctx, testBucket, "uploads/", 0, PostPolicyConditions{})
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+}
+
+// TestS3Client_PresignedMultipart tests the browser multipart flow end to end
+func TestS3Client_PresignedMultipart(t *testing.T) {
+	objects := map[string]*memoryObject{}
+	server := memoryServer(objects)
+	client := setupFakeClient(t, server)
+	ctx := context.Background()
+
+	key := "test-presigned-multipart.bin"
+	upload, err := client.CreatePresignedMultipart(ctx, testBucket, key, &UploadOptions{ContentType: "application/octet-stream"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, upload.UploadID)
+
+	// The browser PUTs each part to its URL and reports the ETag back
+	browser := &http.Client{Transport: server}
+	var parts []CompletedPart
+	for i, body := range []string{"first part,", "second part"} {
+		partNumber := int64(i + 1)
+		presigned, err := client.PresignPart(ctx, upload, partNumber, 15*time.Minute)
+		require.NoError(t, err)
+		assert.Contains(t, presigned.URL, fmt.Sprintf("partNumber=%d", partNumber))
+		assert.Contains(t, presigned.URL, "uploadId="+upload.UploadID)
+
+		req, err := http.NewRequest(http.MethodPut, presigned.URL, strings.NewReader(body))
+		require.NoError(t, err)
+		resp, err := browser.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		parts = append(parts, CompletedPart{PartNumber: partNumber, ETag: resp.Header.Get("ETag")})
+	}
+
+	// Parts may be reported in any order
+	parts[0], parts[1] = parts[1], parts[0]
+	result, err := client.CompletePresignedMultipart(ctx, upload, parts)
+	require.NoError(t, err)
+	assert.True(t, strings.HasSuffix(result.ETag, `-2"`))
+	require.Contains(t, objects, key)
+	assert.Equal(t, "first part,second part", string(objects[key].data))
+	assert.Equal(t, "application/octet-stream", objects[key].header.Get("Content-Type"))
+
+	abandoned, err := client.CreatePresignedMultipart(ctx, testBucket, "abandoned.bin", nil)
+	require.NoError(t, err)
+	require.NoError(t, client.AbortPresignedMultipart(ctx, abandoned))
+	uploads, err := client.ListIncompleteUploads(ctx, testBucket, "")
+	require.NoError(t, err)
+	assert.Empty(t, uploads)
+}
+
+// TestS3Client_CreatePresignedMultipartOptions tests options that need the
+// request body are rejected
+func TestS3Client_CreatePresignedMultipartOptions(t *testing.T) {
+	client := setupTestClient(t)
+
+	for name, opts := range map[string]*UploadOptions{
+		"ComputeMD5":        {ComputeMD5: true},
+		"ChecksumAlgorithm": {ChecksumAlgorithm: ChecksumSHA256},
+		"Compress":          {Compress: true},
+		"SSECustomerKey":    {SSECustomerKey: make([]byte, SSECustomerKeySize)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := client.CreatePresignedMultipart(context.Background(), testBucket, testFileName, opts)
+			assert.ErrorIs(t, err, ErrInvalidOptions)
+		})
+	}
+}
+
+// TestS3Client_PresignPartValidation tests invalid part requests are rejected
+// before signing
+func TestS3Client_PresignPartValidation(t *testing.T) {
+	client := setupTestClient(t)
+	upload := &PresignedMultipartUpload{Bucket: testBucket, Key: testFileName, UploadID: "upload-id"}
+
+	tests := []struct {
+		name       string
+		upload     *PresignedMultipartUpload
+		partNumber int64
+		expiry     time.Duration
+		wantErr    error
+	}{
+		{
+			name:       "Nil upload",
+			partNumber: 1,
+			expiry:     time.Minute,
+			wantErr:    ErrNilValue,
+		},
+		{
+			name:       "Missing upload ID",
+			upload:     &PresignedMultipartUpload{Bucket: testBucket, Key: testFileName},
+			partNumber: 1,
+			expiry:     time.Minute,
+			wantErr:    ErrUploadNotFound,
+		},
+		{
+			name:       "Part number zero",
+			upload:     upload,
+			partNumber: 0,
+			expiry:     time.Minute,
+			wantErr:    ErrInvalidOptions,
+		},
+		{
+			name:       "Part number too large",
+			upload:     upload,
+			partNumber: 10001,
+			expiry:     time.Minute,
+			wantErr:    ErrInvalidOptions,
+		},
+		{
+			name:       "Expiry too long",
+			upload:     upload,
+			partNumber: 1,
+			expiry:     8 * 24 * time.Hour,
+			wantErr:    ErrInvalidOptions,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.PresignPart(context.Background(), tt.upload, tt.partNumber, tt.expiry)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+// TestS3Client_CompletePresignedMultipartValidation tests malformed part
+// lists are rejected before contacting S3
+func TestS3Client_CompletePresignedMultipartValidation(t *testing.T) {
+	client := setupTestClient(t)
+	upload := &PresignedMultipartUpload{Bucket: testBucket, Key: testFileName, UploadID: "upload-id"}
+
+	tests := []struct {
+		name  string
+		parts []CompletedPart
+	}{
+		{
+			name: "No parts",
+		},
+		{
+			name:  "Duplicate part",
+			parts: []CompletedPart{{PartNumber: 2, ETag: "b"}, {PartNumber: 1, ETag: "a"}, {PartNumber: 2, ETag: "c"}},
+		},
+		{
+			name:  "Missing ETag",
+			parts: []CompletedPart{{PartNumber: 1}},
+		},
+		{
+			name:  "Part number out of range",
+			parts: []CompletedPart{{PartNumber: 0, ETag: "a"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.CompletePresignedMultipart(context.Background(), upload, tt.parts)
+			assert.ErrorIs(t, err, ErrInvalidOptions)
+		})
+	}
+}
+
+// TestS3Client_PresignDownload tests the version ID is signed into the URL
+// and SSE-C keys are rejected
+func TestS3Client_PresignDownload(t *testing.T) {
+	client := setupTestClient(t)
+	ctx := context.Background()
+
+	resp, err := client.PresignDownload(ctx, testBucket, testFileName, time.Minute, &DownloadOptions{VersionID: "v1"})
+	require.NoError(t, err)
+	assert.Contains(t, resp.URL, "versionId=v1")
+
+	_, err = client.PresignDownload(ctx, testBucket, testFileName, time.Minute, &DownloadOptions{SSECustomerKey: make([]byte, SSECustomerKeySize)})
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+
+	_, err = client.PresignDownload(ctx, testBucket, testFileName, maxPresignExpiry+time.Second, nil)
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+}
+
+// TestS3Client_PresignDownloadExpiry tests a zero expiry falls back to
+// Config.Duration and is rejected when that is unset too
+func TestS3Client_PresignDownloadExpiry(t *testing.T) {
+	client := setupTestClient(t)
+	ctx := context.Background()
+
+	resp, err := client.PresignDownload(ctx, testBucket, testFileName, 0, nil)
+	require.NoError(t, err)
+	assert.Contains(t, resp.URL, "X-Amz-Expires=300")
+	assert.WithinDuration(t, time.Now().Add(testConfig.Duration), resp.Expires, time.Minute)
+
+	cfg := testConfig
+	cfg.Duration = 0
+	noDefault, err := NewS3Client(cfg)
+	require.NoError(t, err)
+	_, err = noDefault.PresignDownload(ctx, testBucket, testFileName, 0, nil)
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+}
+
+// TestS3Client_PresignDownloadResponseHeaders tests response header
+// overrides are signed into the URL
+func TestS3Client_PresignDownloadResponseHeaders(t *testing.T) {
+	client := setupTestClient(t)
+
+	resp, err := client.PresignDownload(context.Background(), testBucket, testFileName, time.Minute, &DownloadOptions{
+		ResponseContentType:        "text/plain",
+		ResponseContentDisposition: `attachment; filename="hello.txt"`,
+		ResponseCacheControl:       "no-cache",
+	})
+	require.NoError(t, err)
+
+	u, err := url.Parse(resp.URL)
+	require.NoError(t, err)
+	query := u.Query()
+	assert.Equal(t, "text/plain", query.Get("response-content-type"))
+	assert.Equal(t, `attachment; filename="hello.txt"`, query.Get("response-content-disposition"))
+	assert.Equal(t, "no-cache", query.Get("response-cache-control"))
+}
+
+// TestS3Client_PresignDownloadLocalStack fetches a presigned URL with a plain
+// HTTP client. Set LOCALSTACK_ENDPOINT (e.g. http://localhost:4566) to run it.
+func TestS3Client_PresignDownloadLocalStack(t *testing.T) {
+	endpoint := os.Getenv("LOCALSTACK_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("LOCALSTACK_ENDPOINT not set")
+	}
+	cfg := testConfig
+	cfg.Endpoint = endpoint
+	client, err := NewS3Client(cfg)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	_, err = client.UploadFile(ctx, testBucket, testFileName, testFileContent, nil)
+	require.NoError(t, err)
+
+	resp, err := client.PresignDownload(ctx, testBucket, testFileName, 0, &DownloadOptions{ResponseContentType: "text/plain"})
+	require.NoError(t, err)
+
+	httpResp, err := http.Get(resp.URL)
+	require.NoError(t, err)
+	defer httpResp.Body.Close()
+	body, err := io.ReadAll(httpResp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+	assert.Equal(t, "text/plain", httpResp.Header.Get("Content-Type"))
+	assert.Equal(t, testFileContent, body)
+}
@@ -0,0 +1,115 @@
+package s3lib
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// progressTracker reports transferred bytes to a progress callback. Calls
+// are serialized, none are made after stop, and a panicking callback is
+// recovered and remembered so the transfer can fail with it. A nil tracker
+// ignores every call.
+type progressTracker struct {
+	fn func(done, total int64)
+
+	mu      sync.Mutex
+	total   int64
+	done    int64
+	stopped bool
+	err     error
+}
+
+// newProgressTracker returns a tracker for fn, or nil if fn is nil
+func newProgressTracker(fn func(done, total int64), total int64) *progressTracker {
+	if fn == nil {
+		return nil
+	}
+	return &progressTracker{fn: fn, total: total}
+}
+
+// setTotal sets the total reported to the callback once it becomes known
+func (t *progressTracker) setTotal(total int64) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.total = total
+}
+
+// add records n more bytes and invokes the callback
+func (t *progressTracker) add(n int) {
+	if t == nil || n <= 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.stopped || t.err != nil {
+		return
+	}
+	t.done += int64(n)
+	defer func() {
+		if r := recover(); r != nil {
+			t.err = fmt.Errorf("progress callback panicked: %v", r)
+		}
+	}()
+	t.fn(t.done, t.total)
+}
+
+// failed returns the error of a panicking callback, if any
+func (t *progressTracker) failed() error {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.err
+}
+
+// stop prevents further callbacks and returns the error of a panicking
+// callback, if any
+func (t *progressTracker) stop() error {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stopped = true
+	return t.err
+}
+
+// progressWriterAt reports bytes written through an io.WriterAt
+type progressWriterAt struct {
+	w io.WriterAt
+	t *progressTracker
+}
+
+func (w *progressWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	if err := w.t.failed(); err != nil {
+		return 0, err
+	}
+	n, err := w.w.WriteAt(p, off)
+	w.t.add(n)
+	return n, err
+}
+
+// progressReader reports bytes read from an io.ReadCloser
+type progressReader struct {
+	rc io.ReadCloser
+	t  *progressTracker
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	if err := r.t.failed(); err != nil {
+		return 0, err
+	}
+	n, err := r.rc.Read(p)
+	r.t.add(n)
+	return n, err
+}
+
+func (r *progressReader) Close() error {
+	return r.rc.Close()
+}
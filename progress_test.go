@@ -0,0 +1,69 @@
+package s3lib
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestProgressTracker tests callbacks, panics and stopping
+func TestProgressTracker(t *testing.T) {
+	assert.Nil(t, newProgressTracker(nil, 10))
+
+	var calls [][2]int64
+	tracker := newProgressTracker(func(done, total int64) {
+		calls = append(calls, [2]int64{done, total})
+		if done == 8 {
+			panic("boom")
+		}
+	}, 10)
+
+	tracker.add(3)
+	tracker.add(0)
+	tracker.add(5)
+	assert.ErrorContains(t, tracker.failed(), "boom")
+	tracker.add(2)
+	assert.Equal(t, [][2]int64{{3, 10}, {8, 10}}, calls)
+	assert.Error(t, tracker.stop())
+
+	tracker = newProgressTracker(func(done, total int64) {
+		calls = append(calls, [2]int64{done, total})
+	}, 10)
+	require.NoError(t, tracker.stop())
+	tracker.add(1)
+	assert.Len(t, calls, 2)
+}
+
+// TestS3Client_DownloadProgress tests progress is reported for DownloadFile
+// and DownloadToFile and that a panicking callback fails the download
+func TestS3Client_DownloadProgress(t *testing.T) {
+	client := setupFakeClient(t, objectServer(testFileContent))
+	ctx := context.Background()
+	size := int64(len(testFileContent))
+
+	var last, total int64
+	progress := func(downloaded, t int64) {
+		last, total = downloaded, t
+	}
+
+	_, err := client.DownloadFileWithOptions(ctx, testBucket, testFileName, &DownloadOptions{Progress: progress})
+	require.NoError(t, err)
+	assert.Equal(t, size, last)
+	assert.Equal(t, size, total)
+
+	last, total = 0, 0
+	localPath := filepath.Join(t.TempDir(), "out.txt")
+	_, err = client.DownloadToFileWithOptions(ctx, testBucket, testFileName, localPath, &DownloadOptions{Progress: progress})
+	require.NoError(t, err)
+	assert.Equal(t, size, last)
+	assert.Equal(t, size, total)
+
+	_, err = client.DownloadToFileWithOptions(ctx, testBucket, testFileName, localPath+".2", &DownloadOptions{
+		Progress: func(downloaded, total int64) { panic("boom") },
+	})
+	assert.ErrorContains(t, err, "boom")
+	assert.NoFileExists(t, localPath+".2")
+}
@@ -0,0 +1,142 @@
+package s3lib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// RangeResult is a segment of an object returned by DownloadRangeResult
+type RangeResult struct {
+	Data []byte `json:"-"`
+
+	// Offset is the position of Data within the object
+	Offset int64 `json:"offset"`
+
+	// TotalSize is the size of the whole object, or -1 if S3 did not report it
+	TotalSize int64 `json:"total_size"`
+}
+
+// DownloadRange downloads length bytes of an object starting at offset.
+// A length of -1 reads to the end of the object. See DownloadRangeResult.
+func (c *S3Client) DownloadRange(ctx context.Context, bucket, key string, offset, length int64) ([]byte, error) {
+	result, err := c.DownloadRangeResult(ctx, bucket, key, offset, length)
+	if err != nil {
+		return nil, err
+	}
+	return result.Data, nil
+}
+
+// DownloadRangeResult downloads a byte range like DownloadRange and also
+// reports the total size of the object, so callers can page through it.
+// Ranges running past the end of the object are truncated; a range starting
+// past the end returns ErrInvalidRange, while one starting exactly at the end
+// or with zero length returns no data. Stored bytes are returned as is, so
+// ranges of gzip encoded or client-side encrypted objects are not decoded.
+// Ranges returning more than Config.MaxDownloadSize bytes fail with
+// ErrObjectTooLarge.
+func (c *S3Client) DownloadRangeResult(ctx context.Context, bucket, key string, offset, length int64) (*RangeResult, error) {
+	if bucket == "" {
+		return nil, ErrInvalidBucket
+	}
+	key, err := c.resolveKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if offset < 0 || length < -1 {
+		return nil, fmt.Errorf("%w: offset %d, length %d", ErrInvalidRange, offset, length)
+	}
+	if length == 0 {
+		return c.emptyRange(ctx, bucket, key, offset)
+	}
+
+	byteRange := fmt.Sprintf("bytes=%d-", offset)
+	if length > 0 {
+		byteRange += strconv.FormatInt(offset+length-1, 10)
+	}
+	out, err := c.s3Client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+		Range:  aws.String(byteRange),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok {
+			switch aerr.Code() {
+			case s3.ErrCodeNoSuchKey, "NotFound":
+				return nil, ErrFileNotFound
+			case s3.ErrCodeNoSuchBucket:
+				return nil, ErrInvalidBucket
+			case "InvalidRange":
+				// S3 rejects any range starting at the end, including
+				// every range of an empty object
+				return c.emptyRange(ctx, bucket, key, offset)
+			default:
+				return nil, fmt.Errorf("AWS error: %w", aerr)
+			}
+		}
+		return nil, fmt.Errorf("failed to download range: %w", err)
+	}
+	defer out.Body.Close()
+
+	// Refuse oversized ranges before any of the body is buffered
+	if out.ContentLength != nil {
+		if err := c.checkDownloadSize(*out.ContentLength); err != nil {
+			return nil, err
+		}
+	}
+	body := io.Reader(&contextReader{ctx: ctx, rc: out.Body})
+	if max := c.config.MaxDownloadSize; max > 0 {
+		body = &sizeLimitReader{r: body, limit: max}
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		if errors.Is(err, ErrObjectTooLarge) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to download range: %w", err)
+	}
+
+	total := int64(-1)
+	if out.ContentRange != nil {
+		total = contentRangeSize(*out.ContentRange)
+	} else if out.ContentLength != nil {
+		// The whole object was returned
+		total = *out.ContentLength
+	}
+	return &RangeResult{Data: data, Offset: offset, TotalSize: total}, nil
+}
+
+// emptyRange returns an empty result at offset, or ErrInvalidRange if offset
+// is past the end of the object
+func (c *S3Client) emptyRange(ctx context.Context, bucket, key string, offset int64) (*RangeResult, error) {
+	head, err := c.headForDownload(ctx, bucket, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	size := aws.Int64Value(head.ContentLength)
+	if offset > size {
+		return nil, fmt.Errorf("%w: offset %d is past the end of the %d byte object", ErrInvalidRange, offset, size)
+	}
+	return &RangeResult{Data: []byte{}, Offset: offset, TotalSize: size}, nil
+}
+
+// contentRangeSize returns the complete length from a Content-Range header
+// such as "bytes 0-99/1234", or -1 if it is missing or unknown
+func contentRangeSize(contentRange string) int64 {
+	i := strings.LastIndexByte(contentRange, '/')
+	if i < 0 {
+		return -1
+	}
+	size, err := strconv.ParseInt(contentRange[i+1:], 10, 64)
+	if err != nil {
+		return -1
+	}
+	return size
+}
@@ -0,0 +1,167 @@
+package s3lib
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestS3Client_DownloadRange tests the Range header sent and the total size
+// parsed from Content-Range
+func TestS3Client_DownloadRange(t *testing.T) {
+	tests := []struct {
+		name      string
+		offset    int64
+		length    int64
+		wantRange string
+	}{
+		{
+			name:      "Bounded range",
+			offset:    7,
+			length:    5,
+			wantRange: "bytes=7-11",
+		},
+		{
+			name:      "To end of object",
+			offset:    7,
+			length:    -1,
+			wantRange: "bytes=7-",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotRange string
+			client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+				gotRange = req.Header.Get("Range")
+				return &http.Response{
+					StatusCode: http.StatusPartialContent,
+					Header: http.Header{
+						"Content-Range":  []string{"bytes 7-11/13"},
+						"Content-Length": []string{"5"},
+					},
+					Body:    io.NopCloser(strings.NewReader("World")),
+					Request: req,
+				}, nil
+			})
+
+			result, err := client.DownloadRangeResult(context.Background(), testBucket, testFileName, tt.offset, tt.length)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantRange, gotRange)
+			assert.Equal(t, "World", string(result.Data))
+			assert.Equal(t, int64(13), result.TotalSize)
+			assert.Equal(t, tt.offset, result.Offset)
+		})
+	}
+}
+
+// TestS3Client_DownloadRangeMaxDownloadSize tests ranges returning more than
+// Config.MaxDownloadSize are refused whether or not their length is reported
+func TestS3Client_DownloadRangeMaxDownloadSize(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  http.Header
+		length  int64
+		wantErr bool
+	}{
+		{name: "Reported length", header: http.Header{"Content-Length": []string{"13"}}, length: -1, wantErr: true},
+		{name: "Unreported length", header: http.Header{}, length: -1, wantErr: true},
+		{name: "Within limit", header: http.Header{"Content-Length": []string{"5"}}, length: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := &countingReader{r: strings.NewReader(string(testFileContent))}
+			client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+				var rc io.Reader = body
+				if tt.length > 0 {
+					rc = io.LimitReader(body, tt.length)
+				}
+				return &http.Response{StatusCode: http.StatusPartialContent, Header: tt.header, Body: io.NopCloser(rc), Request: req}, nil
+			})
+			client.config.MaxDownloadSize = 5
+
+			data, err := client.DownloadRange(context.Background(), testBucket, testFileName, 0, tt.length)
+			if !tt.wantErr {
+				require.NoError(t, err)
+				assert.Equal(t, testFileContent[:5], data)
+				return
+			}
+			assert.ErrorIs(t, err, ErrObjectTooLarge)
+			assert.Nil(t, data)
+			if tt.header.Get("Content-Length") != "" {
+				assert.Zero(t, body.n, "body read before the size was checked")
+			}
+		})
+	}
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// TestS3Client_DownloadRangeEmpty tests ranges at and past the end of an object
+func TestS3Client_DownloadRangeEmpty(t *testing.T) {
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		if req.Method == http.MethodHead {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Length": []string{"13"}},
+				Body:       http.NoBody,
+				Request:    req,
+			}, nil
+		}
+		return fakeErrorResponse(req, http.StatusRequestedRangeNotSatisfiable, "InvalidRange"), nil
+	})
+	ctx := context.Background()
+
+	data, err := client.DownloadRange(ctx, testBucket, testFileName, 13, -1)
+	require.NoError(t, err)
+	assert.Empty(t, data)
+
+	data, err = client.DownloadRange(ctx, testBucket, testFileName, 5, 0)
+	require.NoError(t, err)
+	assert.Empty(t, data)
+
+	_, err = client.DownloadRange(ctx, testBucket, testFileName, 20, 10)
+	assert.ErrorIs(t, err, ErrInvalidRange)
+}
+
+// TestS3Client_DownloadRangeValidation tests invalid arguments are rejected
+// before any request is made
+func TestS3Client_DownloadRangeValidation(t *testing.T) {
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		t.Fatalf("unexpected request %s %s", req.Method, req.URL)
+		return nil, nil
+	})
+	ctx := context.Background()
+
+	_, err := client.DownloadRange(ctx, testBucket, testFileName, -1, 10)
+	assert.ErrorIs(t, err, ErrInvalidRange)
+
+	_, err = client.DownloadRange(ctx, testBucket, testFileName, 0, -2)
+	assert.ErrorIs(t, err, ErrInvalidRange)
+
+	_, err = client.DownloadRange(ctx, "", testFileName, 0, 10)
+	assert.ErrorIs(t, err, ErrInvalidBucket)
+}
+
+// TestContentRangeSize tests parsing the complete length of Content-Range
+func TestContentRangeSize(t *testing.T) {
+	assert.Equal(t, int64(1234), contentRangeSize("bytes 0-99/1234"))
+	assert.Equal(t, int64(-1), contentRangeSize("bytes 0-99/*"))
+	assert.Equal(t, int64(-1), contentRangeSize(""))
+}
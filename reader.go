@@ -0,0 +1,346 @@
+package s3lib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// DefaultReadAheadSize is the read-ahead buffer size used by NewReader
+const DefaultReadAheadSize = 1024 * 1024 // 1MB
+
+// ReaderOptions configures an ObjectReader
+type ReaderOptions struct {
+	// ReadAheadSize is the least number of bytes a sequential read fetches,
+	// and the chunk size when prefetching (default DefaultReadAheadSize)
+	ReadAheadSize int
+
+	// Prefetch is the number of chunks fetched concurrently ahead of the
+	// chunk being read, so sequential reads rarely wait for a round trip.
+	// At most Prefetch+1 chunks are held in memory. Seeking outside the
+	// chunks in flight cancels them and restarts the pipeline at the new
+	// position. Zero disables prefetching.
+	Prefetch int
+}
+
+// ObjectReader provides random access to an object through ranged GetObject
+// requests. It implements io.ReadSeekCloser and io.ReaderAt. Read and Seek
+// share a position and must not be called concurrently; ReadAt may be called
+// from any number of goroutines. Stored bytes are returned as is, so gzip
+// encoded and client-side encrypted objects are not decoded.
+type ObjectReader struct {
+	client   *S3Client
+	ctx      context.Context
+	bucket   string
+	key      string
+	etag     string
+	size     int64
+	bufSize  int
+	prefetch int
+
+	mu     sync.Mutex
+	pos    int64
+	buf    []byte // read-ahead data starting at bufOff
+	bufOff int64
+	closed bool
+	queue  []*prefetchChunk // chunks in flight, in object order
+	next   int64            // offset of the next chunk to prefetch
+}
+
+// prefetchChunk is a range of the object fetched ahead of the reader
+type prefetchChunk struct {
+	off    int64
+	data   []byte
+	n      int   // bytes fetched, set before done is closed
+	err    error // fetch error, set before done is closed
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewReader returns a reader over an object with a DefaultReadAheadSize
+// read-ahead buffer. See NewReaderSize.
+func (c *S3Client) NewReader(ctx context.Context, bucket, key string) (*ObjectReader, error) {
+	return c.NewReaderSize(ctx, bucket, key, DefaultReadAheadSize)
+}
+
+// NewReaderSize returns a reader over an object whose sequential reads fetch
+// at least bufSize bytes per request. See NewReaderWithOptions.
+func (c *S3Client) NewReaderSize(ctx context.Context, bucket, key string, bufSize int) (*ObjectReader, error) {
+	if bufSize <= 0 {
+		return nil, fmt.Errorf("%w: read-ahead size must be positive", ErrInvalidOptions)
+	}
+	return c.NewReaderWithOptions(ctx, bucket, key, &ReaderOptions{ReadAheadSize: bufSize})
+}
+
+// NewReaderWithOptions returns a reader over an object configured by opts.
+// The object's size and ETag are taken from an initial HeadObject; if the
+// object is replaced while being read, later reads fail rather than mixing
+// data from both versions.
+func (c *S3Client) NewReaderWithOptions(ctx context.Context, bucket, key string, opts *ReaderOptions) (*ObjectReader, error) {
+	if bucket == "" {
+		return nil, ErrInvalidBucket
+	}
+	key, err := c.resolveKey(key)
+	if err != nil {
+		return nil, err
+	}
+	bufSize, prefetch := DefaultReadAheadSize, 0
+	if opts != nil {
+		if opts.ReadAheadSize < 0 || opts.Prefetch < 0 {
+			return nil, fmt.Errorf("%w: ReadAheadSize and Prefetch must not be negative", ErrInvalidOptions)
+		}
+		if opts.ReadAheadSize > 0 {
+			bufSize = opts.ReadAheadSize
+		}
+		prefetch = opts.Prefetch
+	}
+
+	head, err := c.headForDownload(ctx, bucket, key, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ObjectReader{
+		client:   c,
+		ctx:      ctx,
+		bucket:   bucket,
+		key:      key,
+		etag:     aws.StringValue(head.ETag),
+		size:     aws.Int64Value(head.ContentLength),
+		bufSize:  bufSize,
+		prefetch: prefetch,
+	}, nil
+}
+
+// Size returns the size of the object in bytes
+func (r *ObjectReader) Size() int64 {
+	return r.size
+}
+
+// Read implements io.Reader
+func (r *ObjectReader) Read(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return 0, os.ErrClosed
+	}
+	if r.pos >= r.size {
+		return 0, io.EOF
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	switch {
+	case r.pos >= r.bufOff && r.pos < r.bufOff+int64(len(r.buf)):
+		// Served from the read-ahead buffer
+	case r.prefetch > 0:
+		if err := r.nextChunk(); err != nil {
+			return 0, err
+		}
+	case len(p) >= r.bufSize:
+		// Large reads bypass the buffer
+		n, err := r.fetch(r.ctx, p, r.pos)
+		r.pos += int64(n)
+		if err == io.EOF && n > 0 {
+			err = nil
+		}
+		return n, err
+	default:
+		if cap(r.buf) < r.bufSize {
+			r.buf = make([]byte, r.bufSize)
+		}
+		n, err := r.fetch(r.ctx, r.buf[:r.bufSize], r.pos)
+		if err != nil && err != io.EOF {
+			r.buf = r.buf[:0]
+			return 0, err
+		}
+		r.buf = r.buf[:n]
+		r.bufOff = r.pos
+	}
+
+	n := copy(p, r.buf[r.pos-r.bufOff:])
+	r.pos += int64(n)
+	return n, nil
+}
+
+// ReadAt implements io.ReaderAt. It does not use or change the read
+// position and is safe for concurrent use.
+func (r *ObjectReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("%w: negative offset %d", ErrInvalidRange, off)
+	}
+
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return 0, os.ErrClosed
+	}
+	// Serve from the read-ahead buffer when it holds the whole range
+	if off >= r.bufOff && off+int64(len(p)) <= r.bufOff+int64(len(r.buf)) {
+		n := copy(p, r.buf[off-r.bufOff:])
+		r.mu.Unlock()
+		return n, nil
+	}
+	r.mu.Unlock()
+
+	if off >= r.size {
+		return 0, io.EOF
+	}
+	return r.fetch(r.ctx, p, off)
+}
+
+// Seek implements io.Seeker. Seeking past the end is allowed; reads there
+// return io.EOF.
+func (r *ObjectReader) Seek(offset int64, whence int) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return 0, os.ErrClosed
+	}
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.pos
+	case io.SeekEnd:
+		offset += r.size
+	default:
+		return 0, fmt.Errorf("%w: invalid whence %d", ErrInvalidOptions, whence)
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("%w: negative position %d", ErrInvalidRange, offset)
+	}
+	r.pos = offset
+	return offset, nil
+}
+
+// Close releases the read-ahead buffer and cancels prefetches in flight.
+// Later calls fail with os.ErrClosed.
+func (r *ObjectReader) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.closed = true
+	r.buf = nil
+	r.dropChunks(len(r.queue))
+	return nil
+}
+
+// nextChunk makes the prefetched chunk holding the read position the
+// read-ahead buffer, restarting the pipeline at the position if no chunk in
+// flight holds it, and keeps Prefetch chunks in flight behind it
+func (r *ObjectReader) nextChunk() error {
+	drop := 0
+	for drop < len(r.queue) {
+		c := r.queue[drop]
+		if r.pos >= c.off && r.pos < c.off+int64(len(c.data)) {
+			break
+		}
+		drop++
+	}
+	r.dropChunks(drop)
+	if len(r.queue) == 0 {
+		r.next = r.pos
+		r.fillQueue(1)
+	}
+
+	c := r.queue[0]
+	r.queue = r.queue[1:]
+	r.fillQueue(r.prefetch)
+	<-c.done
+	c.cancel()
+
+	if c.err != nil || c.n == 0 {
+		// Restart from the read position on the next Read
+		r.dropChunks(len(r.queue))
+		if c.err == nil || c.err == io.EOF {
+			return fmt.Errorf("failed to read range: %w", io.ErrUnexpectedEOF)
+		}
+		return c.err
+	}
+	r.buf = c.data[:c.n]
+	r.bufOff = c.off
+	return nil
+}
+
+// fillQueue starts fetching chunks after the last one in flight until n are
+// in flight or the end of the object is reached
+func (r *ObjectReader) fillQueue(n int) {
+	for len(r.queue) < n && r.next < r.size {
+		ctx, cancel := context.WithCancel(r.ctx)
+		c := &prefetchChunk{
+			off:    r.next,
+			data:   make([]byte, min(int64(r.bufSize), r.size-r.next)),
+			cancel: cancel,
+			done:   make(chan struct{}),
+		}
+		go func() {
+			defer close(c.done)
+			c.n, c.err = r.fetch(ctx, c.data, c.off)
+		}()
+		r.queue = append(r.queue, c)
+		r.next += int64(len(c.data))
+	}
+}
+
+// dropChunks cancels the first n chunks in flight and removes them
+func (r *ObjectReader) dropChunks(n int) {
+	for _, c := range r.queue[:n] {
+		c.cancel()
+	}
+	r.queue = r.queue[n:]
+}
+
+// fetch fills p with the object's bytes starting at off, clamped to the end
+// of the object, and returns io.EOF if p could not be filled
+func (r *ObjectReader) fetch(ctx context.Context, p []byte, off int64) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	want := min(int64(len(p)), r.size-off)
+	if want <= 0 {
+		return 0, io.EOF
+	}
+
+	out, err := r.client.s3Client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket:  aws.String(r.bucket),
+		Key:     aws.String(r.key),
+		Range:   aws.String(fmt.Sprintf("bytes=%d-%d", off, off+want-1)),
+		IfMatch: aws.String(r.etag),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok {
+			switch aerr.Code() {
+			case s3.ErrCodeNoSuchKey, "NotFound":
+				return 0, ErrFileNotFound
+			case "PreconditionFailed":
+				return 0, ErrObjectModified
+			default:
+				return 0, fmt.Errorf("AWS error: %w", aerr)
+			}
+		}
+		return 0, fmt.Errorf("failed to read range: %w", err)
+	}
+	defer out.Body.Close()
+
+	n, err := io.ReadFull(&contextReader{ctx: ctx, rc: out.Body}, p[:want])
+	if err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+			return n, fmt.Errorf("failed to read range: %w", io.ErrUnexpectedEOF)
+		}
+		return n, fmt.Errorf("failed to read range: %w", err)
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
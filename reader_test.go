@@ -0,0 +1,160 @@
+package s3lib
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rangeServer returns a fake transport serving byte ranges of data and
+// counting GetObject requests
+func rangeServer(data []byte, gets *int32) roundTripFunc {
+	return func(req *http.Request) (*http.Response, error) {
+		header := http.Header{"Etag": []string{`"abc"`}}
+		if req.Method == http.MethodHead {
+			header.Set("Content-Length", strconv.Itoa(len(data)))
+			return &http.Response{StatusCode: http.StatusOK, Header: header, Body: http.NoBody, Request: req}, nil
+		}
+
+		atomic.AddInt32(gets, 1)
+		var start, end int
+		if _, err := fmt.Sscanf(req.Header.Get("Range"), "bytes=%d-%d", &start, &end); err != nil {
+			return fakeErrorResponse(req, http.StatusBadRequest, "InvalidRange"), nil
+		}
+		end = min(end, len(data)-1)
+		header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(data)))
+		return &http.Response{
+			StatusCode: http.StatusPartialContent,
+			Header:     header,
+			Body:       io.NopCloser(bytes.NewReader(data[start : end+1])),
+			Request:    req,
+		}, nil
+	}
+}
+
+// TestS3Client_NewReader tests the reader against the io.Reader, io.Seeker
+// and io.ReaderAt contracts
+func TestS3Client_NewReader(t *testing.T) {
+	var gets int32
+	client := setupFakeClient(t, rangeServer(testFileContent, &gets))
+
+	r, err := client.NewReaderSize(context.Background(), testBucket, testFileName, 4)
+	require.NoError(t, err)
+	defer r.Close()
+
+	assert.Equal(t, int64(len(testFileContent)), r.Size())
+	assert.NoError(t, iotest.TestReader(r, testFileContent))
+}
+
+// TestS3Client_NewReaderReadAhead tests small sequential reads are served
+// from the read-ahead buffer
+func TestS3Client_NewReaderReadAhead(t *testing.T) {
+	var gets int32
+	client := setupFakeClient(t, rangeServer(testFileContent, &gets))
+
+	r, err := client.NewReader(context.Background(), testBucket, testFileName)
+	require.NoError(t, err)
+	defer r.Close()
+
+	got, err := io.ReadAll(iotest.OneByteReader(r))
+	require.NoError(t, err)
+	assert.Equal(t, testFileContent, got)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&gets))
+}
+
+// TestS3Client_NewReaderPrefetch tests prefetched chunks are delivered in
+// order and that seeking restarts the pipeline
+func TestS3Client_NewReaderPrefetch(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 100)
+	var gets int32
+	client := setupFakeClient(t, rangeServer(data, &gets))
+
+	r, err := client.NewReaderWithOptions(context.Background(), testBucket, testFileName, &ReaderOptions{ReadAheadSize: 64, Prefetch: 3})
+	require.NoError(t, err)
+	defer r.Close()
+
+	got, err := io.ReadAll(iotest.OneByteReader(r))
+	require.NoError(t, err)
+	assert.Equal(t, data, got)
+	assert.Equal(t, int32((len(data)+63)/64), atomic.LoadInt32(&gets))
+
+	_, err = r.Seek(500, io.SeekStart)
+	require.NoError(t, err)
+	buf := make([]byte, 10)
+	_, err = io.ReadFull(r, buf)
+	require.NoError(t, err)
+	assert.Equal(t, data[500:510], buf)
+
+	_, err = r.Seek(100, io.SeekStart)
+	require.NoError(t, err)
+	rest, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, data[100:], rest)
+}
+
+// TestS3Client_NewReaderConcurrentReadAt tests concurrent ReadAt calls
+func TestS3Client_NewReaderConcurrentReadAt(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 100)
+	var gets int32
+	client := setupFakeClient(t, rangeServer(data, &gets))
+
+	r, err := client.NewReaderSize(context.Background(), testBucket, testFileName, 16)
+	require.NoError(t, err)
+	defer r.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(off int64) {
+			defer wg.Done()
+			buf := make([]byte, 50)
+			n, err := r.ReadAt(buf, off)
+			assert.NoError(t, err)
+			assert.Equal(t, data[off:off+int64(n)], buf[:n])
+		}(int64(i * 95))
+	}
+	wg.Wait()
+}
+
+// TestS3Client_NewReaderClosed tests calls after Close fail
+func TestS3Client_NewReaderClosed(t *testing.T) {
+	var gets int32
+	client := setupFakeClient(t, rangeServer(testFileContent, &gets))
+
+	r, err := client.NewReader(context.Background(), testBucket, testFileName)
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+
+	_, err = r.Read(make([]byte, 4))
+	assert.ErrorIs(t, err, os.ErrClosed)
+	_, err = r.ReadAt(make([]byte, 4), 0)
+	assert.ErrorIs(t, err, os.ErrClosed)
+	_, err = r.Seek(0, io.SeekStart)
+	assert.ErrorIs(t, err, os.ErrClosed)
+}
+
+// TestS3Client_NewReaderValidation tests invalid arguments
+func TestS3Client_NewReaderValidation(t *testing.T) {
+	client := setupTestClient(t)
+	ctx := context.Background()
+
+	_, err := client.NewReader(ctx, "", testFileName)
+	assert.ErrorIs(t, err, ErrInvalidBucket)
+
+	_, err = client.NewReaderSize(ctx, testBucket, testFileName, 0)
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+
+	_, err = client.NewReaderWithOptions(ctx, testBucket, testFileName, &ReaderOptions{Prefetch: -1})
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+}
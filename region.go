@@ -0,0 +1,138 @@
+package s3lib
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// regionCache remembers the region of each bucket the client has looked up.
+// It is shared with clients derived by WithPrefix. A nil cache remembers
+// nothing.
+type regionCache struct {
+	mu      sync.Mutex
+	regions map[string]string
+}
+
+func newRegionCache() *regionCache {
+	return &regionCache{regions: make(map[string]string)}
+}
+
+// get returns the cached region of bucket
+func (rc *regionCache) get(bucket string) (string, bool) {
+	if rc == nil {
+		return "", false
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	region, ok := rc.regions[bucket]
+	return region, ok
+}
+
+// set caches the region of bucket
+func (rc *regionCache) set(bucket, region string) {
+	if rc == nil || region == "" {
+		return
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.regions[bucket] = region
+}
+
+// invalidate drops the cached region of bucket
+func (rc *regionCache) invalidate(bucket string) {
+	if rc == nil {
+		return
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	delete(rc.regions, bucket)
+}
+
+// GetBucketRegion returns the region bucket lives in, which may differ from
+// the client's. It is read from the x-amz-bucket-region header S3 returns
+// to HeadBucket, even for redirects and buckets the credentials may not
+// access, so it needs no s3:GetBucketLocation permission; backends that do
+// not send the header are asked with GetBucketLocation instead. The region
+// is cached on the client, and dropped again when a lookup fails.
+func (c *S3Client) GetBucketRegion(ctx context.Context, bucket string) (string, error) {
+	if bucket == "" {
+		return "", ErrInvalidBucket
+	}
+	if region, ok := c.regions.get(bucket); ok {
+		return region, nil
+	}
+	region, err := c.lookupBucketRegion(ctx, bucket)
+	if err != nil {
+		c.regions.invalidate(bucket)
+		return "", err
+	}
+	c.regions.set(bucket, region)
+	return region, nil
+}
+
+// CachedBucketRegion returns the region of bucket if the client has
+// already looked it up, without making a request
+func (c *S3Client) CachedBucketRegion(bucket string) (string, bool) {
+	return c.regions.get(bucket)
+}
+
+// InvalidateBucketRegion drops the cached region of bucket, so the next
+// GetBucketRegion looks it up again. Call it when a request to the bucket
+// fails in a way that suggests it has moved, such as ErrWrongRegion.
+func (c *S3Client) InvalidateBucketRegion(bucket string) {
+	c.regions.invalidate(bucket)
+}
+
+// lookupBucketRegion asks S3 for the region of bucket
+func (c *S3Client) lookupBucketRegion(ctx context.Context, bucket string) (string, error) {
+	var region string
+	_, err := c.s3Client.HeadBucketWithContext(ctx, &s3.HeadBucketInput{
+		Bucket: aws.String(bucket),
+	}, captureBucketRegion(&region))
+	if region != "" {
+		return region, nil
+	}
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok {
+			switch aerr.Code() {
+			case "NotFound", s3.ErrCodeNoSuchBucket:
+				return "", fmt.Errorf("%w: %s does not exist", ErrInvalidBucket, bucket)
+			case "Forbidden", "AccessDenied":
+				return "", fmt.Errorf("%w: %w", ErrAccessDenied, aerr)
+			default:
+				return "", fmt.Errorf("AWS error: %w", aerr)
+			}
+		}
+		return "", fmt.Errorf("failed to get bucket region: %w", err)
+	}
+
+	out, err := c.s3Client.GetBucketLocationWithContext(ctx, &s3.GetBucketLocationInput{
+		Bucket: aws.String(bucket),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok {
+			switch aerr.Code() {
+			case "AccessDenied":
+				return "", fmt.Errorf("%w: cannot get location of %s", ErrAccessDenied, bucket)
+			default:
+				return "", fmt.Errorf("AWS error: %w", aerr)
+			}
+		}
+		return "", fmt.Errorf("failed to get bucket region: %w", err)
+	}
+	// GetBucketLocation reports us-east-1 as no constraint and eu-west-1 by
+	// its legacy name
+	switch constraint := aws.StringValue(out.LocationConstraint); constraint {
+	case "":
+		return "us-east-1", nil
+	case "EU":
+		return "eu-west-1", nil
+	default:
+		return constraint, nil
+	}
+}
@@ -0,0 +1,63 @@
+package s3lib
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestS3Client_GetBucketRegion tests the region is read from HeadBucket,
+// even on a redirect, and cached until invalidated
+func TestS3Client_GetBucketRegion(t *testing.T) {
+	var heads int
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		switch {
+		case req.URL.Path == "/missing":
+			return fakeErrorResponse(req, http.StatusNotFound, "NotFound"), nil
+		case req.URL.Path == "/minio" && req.Method == http.MethodGet:
+			body := `<LocationConstraint xmlns="http://s3.amazonaws.com/doc/2006-03-01/"></LocationConstraint>`
+			return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: xmlBody(body), Request: req}, nil
+		case req.URL.Path == "/minio":
+			return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: req}, nil
+		}
+		heads++
+		resp := fakeErrorResponse(req, http.StatusMovedPermanently, "PermanentRedirect")
+		resp.Header.Set("x-amz-bucket-region", "eu-central-1")
+		return resp, nil
+	})
+	ctx := context.Background()
+
+	region, err := client.GetBucketRegion(ctx, testBucket)
+	require.NoError(t, err)
+	assert.Equal(t, "eu-central-1", region)
+	_, err = client.GetBucketRegion(ctx, testBucket)
+	require.NoError(t, err)
+	assert.Equal(t, 1, heads)
+
+	cached, ok := client.WithPrefix("tenant/").CachedBucketRegion(testBucket)
+	assert.True(t, ok)
+	assert.Equal(t, "eu-central-1", cached)
+
+	client.InvalidateBucketRegion(testBucket)
+	_, ok = client.CachedBucketRegion(testBucket)
+	assert.False(t, ok)
+	_, err = client.GetBucketRegion(ctx, testBucket)
+	require.NoError(t, err)
+	assert.Equal(t, 2, heads)
+
+	t.Run("location fallback", func(t *testing.T) {
+		region, err := client.GetBucketRegion(ctx, "minio")
+		require.NoError(t, err)
+		assert.Equal(t, "us-east-1", region)
+	})
+
+	t.Run("missing bucket", func(t *testing.T) {
+		_, err := client.GetBucketRegion(ctx, "missing")
+		assert.ErrorIs(t, err, ErrInvalidBucket)
+		_, ok := client.CachedBucketRegion("missing")
+		assert.False(t, ok)
+	})
+}
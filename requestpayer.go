@@ -0,0 +1,50 @@
+package s3lib
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// requestPayerHeader acknowledges that the caller pays for requests to a
+// Requester Pays bucket
+const requestPayerHeader = "x-amz-request-payer"
+
+// requestPayerKey is the context key set by WithRequestPayer
+type requestPayerKey struct{}
+
+// WithRequestPayer returns a context that overrides Config.RequestPayer for
+// every operation called with it, e.g. to read one public dataset in a
+// Requester Pays bucket without enabling it for the whole client
+func WithRequestPayer(ctx context.Context, enabled bool) context.Context {
+	return context.WithValue(ctx, requestPayerKey{}, enabled)
+}
+
+// requestPayerHandler returns a Build handler that sets the request payer
+// header when the request's context or, failing that, the client default
+// enables it. It runs for every request, including multipart parts and
+// ranged downloads issued by the uploader and downloader.
+func requestPayerHandler(enabledByDefault bool) func(*request.Request) {
+	return func(r *request.Request) {
+		if requestPayerEnabled(r.Context(), enabledByDefault) {
+			r.HTTPRequest.Header.Set(requestPayerHeader, s3.RequestPayerRequester)
+		}
+	}
+}
+
+// requestPayerEnabled reports whether requests made with ctx acknowledge
+// Requester Pays, given the client default
+func requestPayerEnabled(ctx context.Context, enabledByDefault bool) bool {
+	if v, ok := ctx.Value(requestPayerKey{}).(bool); ok {
+		return v
+	}
+	return enabledByDefault
+}
+
+// requestCharged reports whether a response's x-amz-request-charged value
+// says the requester was billed
+func requestCharged(v *string) bool {
+	return aws.StringValue(v) == s3.RequestChargedRequester
+}
@@ -0,0 +1,60 @@
+package s3lib
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestS3Client_RequestPayer tests the header is sent by every operation when
+// enabled in the config and that a context override turns it off
+func TestS3Client_RequestPayer(t *testing.T) {
+	var mu sync.Mutex
+	payers := map[string]string{}
+	cfg := testConfig
+	cfg.Endpoint = "http://s3.fake.local"
+	cfg.RequestPayer = true
+	cfg.HTTPClient = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		payers[req.Method] = req.Header.Get("x-amz-request-payer")
+		mu.Unlock()
+		header := http.Header{}
+		header.Set("X-Amz-Request-Charged", "requester")
+		if req.Method == http.MethodGet && req.URL.Query().Has("list-type") {
+			return &http.Response{StatusCode: http.StatusOK, Header: header, Body: xmlBody("<ListBucketResult></ListBucketResult>"), Request: req}, nil
+		}
+		return &http.Response{StatusCode: http.StatusOK, Header: header, Body: http.NoBody, Request: req}, nil
+	})}
+	client, err := NewS3Client(cfg)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	_, err = client.UploadFile(ctx, testBucket, testFileName, testFileContent, nil)
+	require.NoError(t, err)
+	info, err := client.GetFileInfo(ctx, testBucket, testFileName)
+	require.NoError(t, err)
+	assert.True(t, info.RequestCharged)
+	_, err = client.ListFiles(ctx, testBucket, "")
+	require.NoError(t, err)
+	require.NoError(t, client.DeleteFile(ctx, testBucket, testFileName))
+
+	for _, method := range []string{http.MethodPut, http.MethodHead, http.MethodGet, http.MethodDelete} {
+		assert.Equal(t, "requester", payers[method], method)
+	}
+
+	_, err = client.GetFileInfo(WithRequestPayer(ctx, false), testBucket, testFileName)
+	require.NoError(t, err)
+	assert.Empty(t, payers[http.MethodHead])
+}
+
+// TestRequestCharged tests the x-amz-request-charged values
+func TestRequestCharged(t *testing.T) {
+	assert.True(t, requestCharged(aws.String("requester")))
+	assert.False(t, requestCharged(aws.String("")))
+	assert.False(t, requestCharged(nil))
+}
@@ -0,0 +1,208 @@
+package s3lib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// RestoreTier is the retrieval speed, and price, of restoring an archived
+// object
+type RestoreTier string
+
+// Restore tiers supported by S3. Expedited is not available for
+// DEEP_ARCHIVE.
+const (
+	RestoreTierStandard  RestoreTier = "Standard"
+	RestoreTierBulk      RestoreTier = "Bulk"
+	RestoreTierExpedited RestoreTier = "Expedited"
+)
+
+// RestoreStatus is the state of the restore of an archived object
+type RestoreStatus struct {
+	// InProgress is set while the object is being restored
+	InProgress bool `json:"in_progress"`
+
+	// ExpiryDate is when the restored copy is removed again, once the
+	// restore has completed
+	ExpiryDate *time.Time `json:"expiry_date,omitempty"`
+}
+
+// valid reports whether the tier is one the library knows
+func (t RestoreTier) valid() bool {
+	switch t {
+	case RestoreTierStandard, RestoreTierBulk, RestoreTierExpedited:
+		return true
+	}
+	return false
+}
+
+// RestoreObject starts restoring a GLACIER or DEEP_ARCHIVE object so it can
+// be downloaded for the given number of days. An empty tier uses
+// RestoreTierStandard. Requesting a restore that is already in progress is
+// not an error. Objects that are not archived fail with ErrInvalidOptions.
+func (c *S3Client) RestoreObject(ctx context.Context, bucket, key string, days int, tier RestoreTier) error {
+	if bucket == "" {
+		return ErrInvalidBucket
+	}
+	key, err := c.resolveKey(key)
+	if err != nil {
+		return err
+	}
+	if days < 1 {
+		return fmt.Errorf("%w: restore days must be at least 1", ErrInvalidOptions)
+	}
+	if tier == "" {
+		tier = RestoreTierStandard
+	}
+	if !tier.valid() {
+		return fmt.Errorf("%w: unknown restore tier %q", ErrInvalidOptions, tier)
+	}
+
+	_, err = c.s3Client.RestoreObjectWithContext(ctx, &s3.RestoreObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+		RestoreRequest: &s3.RestoreRequest{
+			Days:                 aws.Int64(int64(days)),
+			GlacierJobParameters: &s3.GlacierJobParameters{Tier: aws.String(string(tier))},
+		},
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok {
+			switch aerr.Code() {
+			case "RestoreAlreadyInProgress":
+				return nil
+			case "ObjectAlreadyInActiveTierError":
+				return fmt.Errorf("%w: object is not archived", ErrInvalidOptions)
+			case s3.ErrCodeNoSuchKey:
+				return ErrFileNotFound
+			case s3.ErrCodeNoSuchBucket:
+				return ErrInvalidBucket
+			default:
+				return fmt.Errorf("AWS error: %w", aerr)
+			}
+		}
+		return fmt.Errorf("failed to restore object: %w", err)
+	}
+	return nil
+}
+
+// GetRestoreStatus reports whether a restore of an archived object is in
+// progress and, once it has completed, when the restored copy expires. Both
+// are zero if no restore has been requested or the restored copy has
+// expired.
+func (c *S3Client) GetRestoreStatus(ctx context.Context, bucket, key string) (ongoing bool, expiry time.Time, err error) {
+	if bucket == "" {
+		return false, time.Time{}, ErrInvalidBucket
+	}
+	key, err = c.resolveKey(key)
+	if err != nil {
+		return false, time.Time{}, err
+	}
+
+	head, err := c.s3Client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return false, time.Time{}, downloadError(err, nil, "failed to get restore status")
+	}
+	return parseRestoreHeader(aws.StringValue(head.Restore))
+}
+
+// WaitForRestore polls GetRestoreStatus every interval until a restore in
+// progress completes and returns when the restored copy expires. It fails
+// with ErrObjectArchived if no restore has been requested, and with the
+// context's error if ctx ends first.
+func (c *S3Client) WaitForRestore(ctx context.Context, bucket, key string, interval time.Duration) (time.Time, error) {
+	if interval <= 0 {
+		return time.Time{}, fmt.Errorf("%w: poll interval must be positive", ErrInvalidOptions)
+	}
+
+	for {
+		ongoing, expiry, err := c.GetRestoreStatus(ctx, bucket, key)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if !ongoing {
+			if expiry.IsZero() {
+				return time.Time{}, fmt.Errorf("%w: no restore has been requested", ErrObjectArchived)
+			}
+			return expiry, nil
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return time.Time{}, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// parseRestoreHeader parses an x-amz-restore header such as
+// `ongoing-request="false", expiry-date="Fri, 21 Dec 2012 00:00:00 GMT"`
+func parseRestoreHeader(header string) (ongoing bool, expiry time.Time, err error) {
+	if header == "" {
+		return false, time.Time{}, nil
+	}
+	ongoing = strings.Contains(header, `ongoing-request="true"`)
+
+	const expiryField = `expiry-date="`
+	i := strings.Index(header, expiryField)
+	if i < 0 {
+		return ongoing, time.Time{}, nil
+	}
+	value := header[i+len(expiryField):]
+	end := strings.IndexByte(value, '"')
+	if end < 0 {
+		return false, time.Time{}, fmt.Errorf("malformed restore header %q", header)
+	}
+	if expiry, err = http.ParseTime(value[:end]); err != nil {
+		return false, time.Time{}, fmt.Errorf("malformed restore expiry %q: %w", value[:end], err)
+	}
+	return ongoing, expiry, nil
+}
+
+// archivedError returns an ErrObjectArchived err with the object's storage
+// class added, which S3 does not report when refusing the download. Other
+// errors are returned unchanged.
+func (c *S3Client) archivedError(ctx context.Context, bucket, key string, opts *DownloadOptions, err error) error {
+	if !errors.Is(err, ErrObjectArchived) {
+		return err
+	}
+	if _, headErr := c.headForDownload(ctx, bucket, key, opts); errors.Is(headErr, ErrObjectArchived) {
+		return headErr
+	}
+	return err
+}
+
+// checkArchived fails with ErrObjectArchived if the object described by head
+// must be restored before it can be downloaded
+func checkArchived(head *s3.HeadObjectOutput) error {
+	class := aws.StringValue(head.StorageClass)
+	archived := class == string(StorageClassGlacier) || class == string(StorageClassDeepArchive) ||
+		aws.StringValue(head.ArchiveStatus) != ""
+	if !archived {
+		return nil
+	}
+	ongoing, expiry, _ := parseRestoreHeader(aws.StringValue(head.Restore))
+	if !ongoing && !expiry.IsZero() {
+		return nil
+	}
+	if aws.StringValue(head.ArchiveStatus) != "" {
+		class = fmt.Sprintf("%s (%s)", class, aws.StringValue(head.ArchiveStatus))
+	}
+	if ongoing {
+		return fmt.Errorf("%w: stored in %s, restore in progress", ErrObjectArchived, class)
+	}
+	return fmt.Errorf("%w: stored in %s, restore it with RestoreObject first", ErrObjectArchived, class)
+}
@@ -0,0 +1,161 @@
+package s3lib
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseRestoreHeader tests the x-amz-restore header formats S3 returns
+func TestParseRestoreHeader(t *testing.T) {
+	tests := []struct {
+		name        string
+		header      string
+		wantOngoing bool
+		wantExpiry  time.Time
+		wantErr     bool
+	}{
+		{
+			name: "No restore",
+		},
+		{
+			name:        "In progress",
+			header:      `ongoing-request="true"`,
+			wantOngoing: true,
+		},
+		{
+			name:       "Completed",
+			header:     `ongoing-request="false", expiry-date="Fri, 21 Dec 2012 00:00:00 GMT"`,
+			wantExpiry: time.Date(2012, 12, 21, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:    "Malformed expiry",
+			header:  `ongoing-request="false", expiry-date="tomorrow"`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ongoing, expiry, err := parseRestoreHeader(tt.header)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantOngoing, ongoing)
+			assert.True(t, tt.wantExpiry.Equal(expiry), "expiry %v", expiry)
+		})
+	}
+}
+
+// TestCheckArchived tests which objects must be restored before a download
+func TestCheckArchived(t *testing.T) {
+	restored := aws.String(`ongoing-request="false", expiry-date="Fri, 21 Dec 2012 00:00:00 GMT"`)
+
+	tests := []struct {
+		name     string
+		head     *s3.HeadObjectOutput
+		wantErr  bool
+		contains string
+	}{
+		{
+			name: "Standard",
+			head: &s3.HeadObjectOutput{StorageClass: aws.String("STANDARD")},
+		},
+		{
+			name: "Glacier Instant Retrieval",
+			head: &s3.HeadObjectOutput{StorageClass: aws.String("GLACIER_IR")},
+		},
+		{
+			name:     "Deep Archive",
+			head:     &s3.HeadObjectOutput{StorageClass: aws.String("DEEP_ARCHIVE")},
+			wantErr:  true,
+			contains: "DEEP_ARCHIVE",
+		},
+		{
+			name:     "Restore in progress",
+			head:     &s3.HeadObjectOutput{StorageClass: aws.String("GLACIER"), Restore: aws.String(`ongoing-request="true"`)},
+			wantErr:  true,
+			contains: "in progress",
+		},
+		{
+			name: "Restored",
+			head: &s3.HeadObjectOutput{StorageClass: aws.String("GLACIER"), Restore: restored},
+		},
+		{
+			name:     "Intelligent-Tiering archive",
+			head:     &s3.HeadObjectOutput{StorageClass: aws.String("INTELLIGENT_TIERING"), ArchiveStatus: aws.String("DEEP_ARCHIVE_ACCESS")},
+			wantErr:  true,
+			contains: "DEEP_ARCHIVE_ACCESS",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkArchived(tt.head)
+			if !tt.wantErr {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, ErrObjectArchived)
+			assert.Contains(t, err.Error(), tt.contains)
+		})
+	}
+}
+
+// TestS3Client_RestoreObject tests the restore request sent to S3 and that
+// an already running restore is not an error
+func TestS3Client_RestoreObject(t *testing.T) {
+	var body string
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		data, _ := io.ReadAll(req.Body)
+		body = string(data)
+		return fakeErrorResponse(req, http.StatusConflict, "RestoreAlreadyInProgress"), nil
+	})
+
+	require.NoError(t, client.RestoreObject(context.Background(), testBucket, testFileName, 3, RestoreTierBulk))
+	assert.Contains(t, body, "<Days>3</Days>")
+	assert.Contains(t, body, "<Tier>Bulk</Tier>")
+}
+
+// TestS3Client_RestoreObjectValidation tests invalid restore requests are
+// rejected before anything is sent
+func TestS3Client_RestoreObjectValidation(t *testing.T) {
+	client := setupTestClient(t)
+	ctx := context.Background()
+
+	assert.ErrorIs(t, client.RestoreObject(ctx, testBucket, testFileName, 0, RestoreTierStandard), ErrInvalidOptions)
+	assert.ErrorIs(t, client.RestoreObject(ctx, testBucket, testFileName, 1, RestoreTier("Fast")), ErrInvalidOptions)
+	assert.ErrorIs(t, client.RestoreObject(ctx, "", testFileName, 1, ""), ErrInvalidBucket)
+
+	_, err := client.WaitForRestore(ctx, testBucket, testFileName, 0)
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+}
+
+// TestS3Client_DownloadFileArchived tests an archived object is reported
+// with its storage class
+func TestS3Client_DownloadFileArchived(t *testing.T) {
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		if req.Method == http.MethodHead {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"X-Amz-Storage-Class": []string{"GLACIER"}},
+				Body:       http.NoBody,
+				Request:    req,
+			}, nil
+		}
+		return fakeErrorResponse(req, http.StatusForbidden, "InvalidObjectState"), nil
+	})
+
+	_, err := client.DownloadFile(context.Background(), testBucket, testFileName)
+	assert.ErrorIs(t, err, ErrObjectArchived)
+	assert.Contains(t, err.Error(), "GLACIER")
+}
@@ -0,0 +1,81 @@
+package s3lib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+// Backoff bounds for Config.MaxRetries. The delay before retry n is drawn
+// from [d/2, d] where d = retryBaseDelay * 2^n, capped at retryMaxDelay.
+const (
+	retryBaseDelay = 100 * time.Millisecond
+	retryMaxDelay  = 20 * time.Second
+)
+
+// isRetryable reports whether err is a throttling, 5xx or transient network
+// failure worth retrying. Errors mapped to library sentinels are final.
+func isRetryable(err error) bool {
+	var aerr awserr.Error
+	if !errors.As(err, &aerr) {
+		return false
+	}
+	if aerr.Code() == request.CanceledErrorCode {
+		return false
+	}
+	if request.IsErrorThrottle(aerr) || request.IsErrorRetryable(aerr) {
+		return true
+	}
+	var reqErr awserr.RequestFailure
+	if errors.As(err, &reqErr) {
+		status := reqErr.StatusCode()
+		return status >= http.StatusInternalServerError || status == http.StatusTooManyRequests
+	}
+	return false
+}
+
+// retryDelay returns the jittered backoff before retry number attempt (0-based)
+func retryDelay(attempt int) time.Duration {
+	d := retryMaxDelay
+	if attempt < 20 {
+		if backoff := retryBaseDelay << uint(attempt); backoff < retryMaxDelay {
+			d = backoff
+		}
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// withRetry calls fn until it succeeds, fails with a non-retryable error,
+// maxRetries retries have been made, or the next backoff would outlast ctx.
+// After retrying, the returned error wraps the last attempt's error and
+// reports the number of attempts.
+func withRetry[T any](ctx context.Context, maxRetries int, fn func() (T, error)) (T, error) {
+	for attempt := 0; ; attempt++ {
+		result, err := fn()
+		if err == nil || attempt >= maxRetries || !isRetryable(err) {
+			if err != nil && attempt > 0 {
+				err = fmt.Errorf("giving up after %d attempts: %w", attempt+1, err)
+			}
+			return result, err
+		}
+
+		delay := retryDelay(attempt)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return result, fmt.Errorf("giving up after %d attempts, context deadline too close to retry: %w", attempt+1, err)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return result, fmt.Errorf("giving up after %d attempts: %w: %w", attempt+1, ctx.Err(), err)
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package s3lib
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// slowDownError builds the error S3 returns when throttling requests
+func slowDownError() error {
+	return awserr.NewRequestFailure(awserr.New("SlowDown", "Please reduce your request rate.", nil), http.StatusServiceUnavailable, "req-id")
+}
+
+// TestWithRetry tests which errors are retried and how attempts are reported
+func TestWithRetry(t *testing.T) {
+	tests := []struct {
+		name         string
+		errs         []error
+		maxRetries   int
+		wantAttempts int
+		wantErr      bool
+	}{
+		{
+			name:         "Success first time",
+			errs:         []error{nil},
+			maxRetries:   3,
+			wantAttempts: 1,
+		},
+		{
+			name:         "Success after throttling",
+			errs:         []error{slowDownError(), slowDownError(), nil},
+			maxRetries:   3,
+			wantAttempts: 3,
+		},
+		{
+			name:         "Retries exhausted",
+			errs:         []error{slowDownError(), slowDownError(), slowDownError()},
+			maxRetries:   2,
+			wantAttempts: 3,
+			wantErr:      true,
+		},
+		{
+			name:         "Not retryable",
+			errs:         []error{ErrInvalidBucket},
+			maxRetries:   3,
+			wantAttempts: 1,
+			wantErr:      true,
+		},
+		{
+			name:         "Retries disabled",
+			errs:         []error{slowDownError()},
+			maxRetries:   0,
+			wantAttempts: 1,
+			wantErr:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			_, err := withRetry(context.Background(), tt.maxRetries, func() (struct{}, error) {
+				err := tt.errs[attempts]
+				attempts++
+				return struct{}{}, err
+			})
+			assert.Equal(t, tt.wantAttempts, attempts)
+			if !tt.wantErr {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, tt.errs[len(tt.errs)-1]) || errors.As(err, new(awserr.Error)))
+			if attempts > 1 {
+				assert.Contains(t, err.Error(), "after 3 attempts")
+			}
+		})
+	}
+}
+
+// TestWithRetryContext tests a cancelled context stops retrying
+func TestWithRetryContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	attempts := 0
+	_, err := withRetry(ctx, 10, func() (struct{}, error) {
+		attempts++
+		return struct{}{}, slowDownError()
+	})
+	require.Error(t, err)
+	assert.Less(t, attempts, 11)
+}
+
+// TestRetryDelay tests the backoff grows exponentially within its bounds
+func TestRetryDelay(t *testing.T) {
+	for attempt := 0; attempt < 30; attempt++ {
+		d := retryDelay(attempt)
+		want := retryMaxDelay
+		if attempt < 20 && retryBaseDelay<<uint(attempt) < retryMaxDelay {
+			want = retryBaseDelay << uint(attempt)
+		}
+		assert.GreaterOrEqual(t, d, want/2)
+		assert.LessOrEqual(t, d, want)
+	}
+}
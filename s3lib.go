@@ -8,11 +8,17 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
@@ -20,11 +26,26 @@ import (
 
 // S3Client represents the S3 client configuration and operations
 type S3Client struct {
-	s3Client  *s3.S3
-	session   *session.Session
-	uploader  *s3manager.Uploader
-	config    Config
-	debugMode bool
+	s3Client   *s3.S3
+	session    *session.Session
+	uploader   *s3manager.Uploader
+	downloader *s3manager.Downloader
+	config     Config
+	debugMode  bool
+
+	// uploadLimiter enforces Config.UploadBandwidthLimit across all uploads
+	uploadLimiter *bandwidthLimiter
+
+	// cache stores downloads in Config.CacheDir, or is nil
+	cache *diskCache
+
+	// listV1 is set once listings use ListObjects instead of ListObjectsV2.
+	// It is shared with clients derived by WithPrefix.
+	listV1 *atomic.Bool
+
+	// regions caches the region of each bucket looked up by
+	// GetBucketRegion. It is shared with clients derived by WithPrefix.
+	regions *regionCache
 }
 
 // FileInfo represents S3 object metadata
@@ -32,8 +53,50 @@ type FileInfo struct {
 	Key          string    `json:"key"`
 	Size         int64     `json:"size"`
 	LastModified time.Time `json:"last_modified"`
-	ETag         string    `json:"etag"`
+	ETag         string    `json:"etag"` // quoted, as S3 returns it
 	StorageClass string    `json:"storage_class"`
+
+	// Checksum is the additional checksum stored with the object, if any.
+	// Multipart objects report a checksum of the part checksums.
+	Checksum          string            `json:"checksum,omitempty"`
+	ChecksumAlgorithm ChecksumAlgorithm `json:"checksum_algorithm,omitempty"`
+
+	// ServerSideEncryption is the SSE algorithm applied to the object and
+	// SSEKMSKeyID the ARN of the KMS key when it is SSEKMS
+	ServerSideEncryption string `json:"server_side_encryption,omitempty"`
+	SSEKMSKeyID          string `json:"sse_kms_key_id,omitempty"`
+
+	// Object Lock status of the object
+	ObjectLockMode        string     `json:"object_lock_mode,omitempty"`
+	ObjectLockRetainUntil *time.Time `json:"object_lock_retain_until,omitempty"`
+	ObjectLockLegalHold   bool       `json:"object_lock_legal_hold,omitempty"`
+
+	// HTTP headers stored with the object
+	ContentEncoding string     `json:"content_encoding,omitempty"`
+	ContentLanguage string     `json:"content_language,omitempty"`
+	Expires         *time.Time `json:"expires,omitempty"`
+
+	// WebsiteRedirectLocation is where static website hosting redirects
+	// requests for this object
+	WebsiteRedirectLocation string `json:"website_redirect_location,omitempty"`
+
+	// RequestCharged is set when the request was billed to the requester
+	// of a Requester Pays bucket
+	RequestCharged bool `json:"request_charged,omitempty"`
+
+	// VersionID is the version that was read, in a versioned bucket
+	VersionID string `json:"version_id,omitempty"`
+
+	// Owner is the canonical user ID of the object's owner and
+	// OwnerDisplayName their name, where the backend returns them. Only
+	// listings with ListOptions.FetchOwner report them.
+	Owner            string `json:"owner,omitempty"`
+	OwnerDisplayName string `json:"owner_display_name,omitempty"`
+
+	// RestoreStatus reports the restore of an archived object, or is nil
+	// if it has not been restored. Listings report it only with
+	// ListOptions.FetchRestoreStatus.
+	RestoreStatus *RestoreStatus `json:"restore_status,omitempty"`
 }
 
 // UploadOptions represents optional parameters for upload operations
@@ -42,8 +105,158 @@ type UploadOptions struct {
 	ContentDisposition string
 	CacheControl       string
 	Metadata           map[string]string
-	StorageClass       string
-	ACL                string
+	StorageClass       StorageClass
+	ACL                ACL
+	ContentEncoding    string    // Optional: e.g. "gzip" or "br" for pre-compressed payloads
+	ContentLanguage    string    // Optional: e.g. "en-US"
+	Expires            time.Time // Optional: when caches should treat the object as stale
+
+	// AllowUnknownValues skips the check that StorageClass and ACL are values
+	// the library knows, for classes or ACLs added to S3 after its release
+	AllowUnknownValues bool
+
+	// DisableContentTypeDetection keeps S3's default content type when
+	// ContentType is empty instead of detecting it from the key's extension
+	// or the leading bytes of the payload
+	DisableContentTypeDetection bool
+
+	// Compress gzips the payload while it is uploaded and sets
+	// Content-Encoding: gzip. The key is left unchanged.
+	Compress bool
+
+	// IfNoneMatch only creates the object if the key does not exist yet;
+	// otherwise the upload fails with ErrObjectExists
+	IfNoneMatch bool
+
+	// PartSize and Concurrency override Config.UploadPartSize and
+	// Config.UploadConcurrency for a single multipart upload
+	PartSize    int64
+	Concurrency int
+
+	// ComputeMD5 sends a Content-MD5 header with the payload (or with each
+	// part of a multipart upload) so S3 rejects corrupted transfers
+	ComputeMD5 bool
+
+	// ChecksumAlgorithm computes an additional checksum of the payload (or of
+	// each part) that S3 verifies and stores with the object
+	ChecksumAlgorithm ChecksumAlgorithm
+
+	// ServerSideEncryption requests SSE-S3 (SSEAES256) or SSE-KMS (SSEKMS).
+	// SSEKMSKeyID selects the KMS key; the account default is used if empty.
+	ServerSideEncryption string
+	SSEKMSKeyID          string
+
+	// SSECustomerKey encrypts the object with a customer-provided 32-byte
+	// key (SSE-C). The same key must be supplied to download it.
+	SSECustomerKey []byte
+
+	// Tags are stored as object tags (at most MaxObjectTags per object)
+	Tags map[string]string
+
+	// ObjectLockMode (ObjectLockGovernance or ObjectLockCompliance) retains
+	// the object until ObjectLockRetainUntil; both must be set together.
+	// ObjectLockLegalHold places a legal hold on the object. The bucket must
+	// have Object Lock enabled.
+	ObjectLockMode        string
+	ObjectLockRetainUntil time.Time
+	ObjectLockLegalHold   bool
+
+	// WebsiteRedirectLocation makes static website hosting redirect requests
+	// for the object to a path in the bucket ("/other.html") or to an
+	// absolute http(s) URL
+	WebsiteRedirectLocation string
+
+	// BandwidthLimit caps this upload at the given bytes per second across
+	// all of its concurrent parts, replacing Config.UploadBandwidthLimit
+	BandwidthLimit int64
+
+	// SkipIfUnchanged skips the upload when the existing object already has
+	// the same content, reporting UploadResult.Skipped. The payload's SHA-256
+	// is stored in the object metadata so later uploads can be compared.
+	SkipIfUnchanged bool
+
+	// KeyStrategy generates the key when an empty key is passed to
+	// UploadFile or UploadFileResult. The upload only succeeds if the key is
+	// free (as with IfNoneMatch), and a colliding key is regenerated.
+	KeyStrategy KeyStrategy
+
+	limiter *bandwidthLimiter // shared by the uploads of a sync
+}
+
+// DownloadResult is a downloaded object and the details S3 reported with it
+type DownloadResult struct {
+	Data         []byte    `json:"-"`
+	ContentType  string    `json:"content_type,omitempty"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified time.Time `json:"last_modified"`
+	VersionID    string    `json:"version_id,omitempty"`
+
+	// Metadata is the object's user metadata with lowercase keys, without
+	// the entries the library records for its own use
+	Metadata map[string]string `json:"metadata,omitempty"`
+
+	// RequestCharged is set when the download was billed to the requester
+	// of a Requester Pays bucket
+	RequestCharged bool `json:"request_charged,omitempty"`
+
+	// Decompressed is set when the object was stored gzip encoded and Data
+	// holds the decompressed content
+	Decompressed bool `json:"decompressed,omitempty"`
+
+	// Cached is set when Data was read from Config.CacheDir after S3
+	// confirmed the cached copy is current
+	Cached bool `json:"cached,omitempty"`
+
+	encrypted bool // client-side encrypted, so never cached
+}
+
+// DownloadOptions represents optional parameters for download operations
+type DownloadOptions struct {
+	// SSECustomerKey is the 32-byte key the object was uploaded with (SSE-C)
+	SSECustomerKey []byte
+
+	// AutoDecompress decompresses objects stored with Content-Encoding: gzip
+	// and returns their decompressed content. It is off by default, so the
+	// stored bytes are returned as they were before compression support.
+	AutoDecompress bool
+
+	// CreateDirs creates missing parent directories of the destination
+	// when downloading to a local file
+	CreateDirs bool
+
+	// Progress, if set, is called as data arrives with the number of stored
+	// bytes downloaded so far and the stored size of the object. Calls are
+	// serialized and none are made after the download returns. A panic in
+	// Progress fails the download with an error.
+	Progress func(downloaded, total int64)
+
+	// VersionID reads a specific version of the object in a versioned
+	// bucket instead of the current one
+	VersionID string
+
+	// VerifyChecksum hashes the downloaded bytes and fails with
+	// ErrChecksumMismatch unless they match the SHA-256, CRC32C, CRC32 or
+	// SHA-1 checksum stored with the object, or its ETag when that is an
+	// MD5. Multipart objects without a full object checksum are not
+	// verified. DownloadFile needs an extra HeadObject to read the checksum.
+	VerifyChecksum bool
+
+	// IfNoneMatch and IfModifiedSince make the download conditional: if the
+	// object's ETag equals IfNoneMatch, or it has not changed since
+	// IfModifiedSince, the download fails with ErrNotModified and no data is
+	// transferred. IfNoneMatch accepts FileInfo.ETag as is, quoted or not.
+	IfNoneMatch     string
+	IfModifiedSince time.Time
+
+	// ResponseContentType, ResponseContentDisposition and
+	// ResponseCacheControl override those headers in the response to a
+	// PresignDownload URL, e.g. to force a browser to save the object under
+	// a given file name. They are ignored by the other download methods.
+	ResponseContentType        string
+	ResponseContentDisposition string
+	ResponseCacheControl       string
+
+	limiter *bandwidthLimiter // paces the response body; set by syncs
 }
 
 // NewS3Client creates a new S3 client instance
@@ -61,184 +274,817 @@ func NewS3Client(cfg Config) (*S3Client, error) {
 		awsCfg.Endpoint = aws.String(cfg.Endpoint)
 		awsCfg.S3ForcePathStyle = aws.Bool(true)
 	}
+	if cfg.HTTPClient != nil {
+		awsCfg.HTTPClient = cfg.HTTPClient
+	}
 
 	sess, err := session.NewSession(awsCfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create session: %w", err)
 	}
+	// Registered on the session so the uploader and downloader clients
+	// derived from it send the header too
+	sess.Handlers.Build.PushBack(requestPayerHandler(cfg.RequestPayer))
 
 	s3Client := s3.New(sess)
-	uploader := s3manager.NewUploader(sess)
+	uploader := s3manager.NewUploader(sess, func(u *s3manager.Uploader) {
+		if cfg.UploadPartSize > 0 {
+			u.PartSize = cfg.UploadPartSize
+		}
+		if cfg.UploadConcurrency > 0 {
+			u.Concurrency = cfg.UploadConcurrency
+		}
+	})
+
+	downloader := s3manager.NewDownloaderWithClient(s3Client, func(d *s3manager.Downloader) {
+		if cfg.DownloadPartSize > 0 {
+			d.PartSize = cfg.DownloadPartSize
+		}
+		if cfg.DownloadConcurrency > 0 {
+			d.Concurrency = cfg.DownloadConcurrency
+		}
+	})
+
+	listV1 := new(atomic.Bool)
+	listV1.Store(cfg.ForceListObjectsV1)
+
+	var cache *diskCache
+	if cfg.CacheDir != "" {
+		if cache, err = newDiskCache(cfg.CacheDir, cfg.CacheMaxSize); err != nil {
+			return nil, err
+		}
+	}
 
 	return &S3Client{
-		s3Client:  s3Client,
-		session:   sess,
-		uploader:  uploader,
-		config:    cfg,
-		debugMode: cfg.Debug,
+		s3Client:      s3Client,
+		session:       sess,
+		uploader:      uploader,
+		downloader:    downloader,
+		config:        cfg,
+		debugMode:     cfg.Debug,
+		uploadLimiter: newBandwidthLimiter(cfg.UploadBandwidthLimit),
+		cache:         cache,
+		listV1:        listV1,
+		regions:       newRegionCache(),
 	}, nil
 }
 
-// ListFiles lists all files in the specified bucket with optional prefix
+// ListFiles lists all files in the specified bucket with optional prefix.
+// With Config.KeyPrefix set only keys under it are listed, and they are
+// returned relative to it.
 func (c *S3Client) ListFiles(ctx context.Context, bucket, prefix string) ([]FileInfo, error) {
-	if bucket == "" {
-		return nil, ErrInvalidBucket
+	return c.ListFilesWithOptions(ctx, bucket, prefix, nil)
+}
+
+// UploadFile uploads a file to the specified bucket with options.
+// Payloads smaller than Config.SinglePartThreshold are sent with a single
+// PutObject request; larger ones go through the multipart uploader.
+func (c *S3Client) UploadFile(ctx context.Context, bucket, filename string, data []byte, opts *UploadOptions) (string, error) {
+	result, err := c.uploadBytes(ctx, bucket, filename, data, opts)
+	if err != nil {
+		return "", err
 	}
+	return result.Location, nil
+}
 
-	input := &s3.ListObjectsV2Input{
-		Bucket: aws.String(bucket),
+// UploadFileResult uploads a file like UploadFile and returns the full
+// result, including the ETag, version ID and checksum assigned by S3. If key
+// is empty and UploadOptions.KeyStrategy is set, the key is generated and
+// returned in the result.
+func (c *S3Client) UploadFileResult(ctx context.Context, bucket, key string, data []byte, opts *UploadOptions) (*UploadResult, error) {
+	return c.uploadBytes(ctx, bucket, key, data, opts)
+}
+
+// uploadBytes uploads an in-memory payload and returns the full result.
+// With Config.ClientSideKey set the payload is encrypted before upload.
+func (c *S3Client) uploadBytes(ctx context.Context, bucket, key string, data []byte, opts *UploadOptions) (*UploadResult, error) {
+	if key == "" && opts != nil && opts.KeyStrategy != nil {
+		return c.uploadGeneratedKey(ctx, bucket, data, opts)
 	}
-	if prefix != "" {
-		input.Prefix = aws.String(prefix)
-	}
-
-	var files []FileInfo
-	err := c.s3Client.ListObjectsV2PagesWithContext(ctx, input,
-		func(page *s3.ListObjectsV2Output, lastPage bool) bool {
-			for _, obj := range page.Contents {
-				files = append(files, FileInfo{
-					Key:          aws.StringValue(obj.Key),
-					Size:         aws.Int64Value(obj.Size),
-					LastModified: aws.TimeValue(obj.LastModified),
-					ETag:         aws.StringValue(obj.ETag),
-					StorageClass: aws.StringValue(obj.StorageClass),
-				})
-			}
-			return true
-		})
+	key, err := c.resolveKey(key)
+	if err != nil {
+		return nil, err
+	}
+	transformed := c.config.ClientSideKey != nil || (opts != nil && opts.Compress)
+	skipped, opts, err := c.skipUnchanged(ctx, bucket, key, bytes.NewReader(data), int64(len(data)), transformed, opts)
+	if err != nil || skipped != nil {
+		return skipped, err
+	}
+	opts, err = c.ensureAbsent(ctx, bucket, key, opts)
+	if err != nil {
+		return nil, err
+	}
+	opts = withDetectedContentType(opts, key, data)
+	if c.config.ClientSideKey != nil {
+		if data, opts, err = encryptClientSide(c.config.ClientSideKey, data, opts); err != nil {
+			return nil, fmt.Errorf("failed to encrypt payload: %w", err)
+		}
+	}
+
+	// Compressed payloads are streamed so no second in-memory copy is made
+	compress := opts != nil && opts.Compress
+	if max := c.config.MaxUploadSize; max > 0 && !compress && int64(len(data)) > max {
+		return nil, tooLargeError(max)
+	}
+
+	return withRetry(ctx, c.config.MaxRetries, func() (*UploadResult, error) {
+		if int64(len(data)) >= c.config.singlePartThreshold() || compress {
+			return c.streamUploadResult(ctx, bucket, key, bytes.NewReader(data), opts)
+		}
+		return c.putObject(ctx, bucket, key, data, opts)
+	})
+}
 
+// putObject uploads a payload below the single part threshold with one
+// PutObject request
+func (c *S3Client) putObject(ctx context.Context, bucket, key string, data []byte, opts *UploadOptions) (*UploadResult, error) {
+	input, err := newUploadInput(bucket, key, opts)
 	if err != nil {
-		if aerr, ok := err.(awserr.Error); ok {
-			switch aerr.Code() {
-			case s3.ErrCodeNoSuchBucket:
-				return nil, ErrInvalidBucket
-			default:
-				return nil, fmt.Errorf("AWS error: %w", aerr)
-			}
+		return nil, err
+	}
+
+	req, out := c.s3Client.PutObjectRequest(putObjectInput(input, bytes.NewReader(data)))
+	req.SetContext(ctx)
+	req.ApplyOptions(opts.requestOptions()...)
+	req.ApplyOptions(c.bandwidthOptions(opts)...)
+	if err := req.Send(); err != nil {
+		return nil, uploadError(err)
+	}
+
+	result := &UploadResult{
+		Key:        c.relativeKey(key),
+		Location:   c.objectURL(bucket, key),
+		ETag:       aws.StringValue(out.ETag),
+		VersionID:  aws.StringValue(out.VersionId),
+		Expiration: aws.StringValue(out.Expiration),
+	}
+	result.setChecksum(out.ChecksumSHA256, out.ChecksumCRC32C, out.ChecksumCRC32, out.ChecksumSHA1)
+	return result, nil
+}
+
+// UploadStream uploads the contents of r to the specified bucket with options.
+// The reader is consumed part by part by the multipart uploader, so memory use
+// stays bounded regardless of object size and the length need not be known.
+func (c *S3Client) UploadStream(ctx context.Context, bucket, key string, r io.Reader, opts *UploadOptions) (string, error) {
+	result, err := c.uploadReader(ctx, bucket, key, r, opts)
+	if err != nil {
+		return "", err
+	}
+	return result.Location, nil
+}
+
+// uploadReader streams r through the multipart uploader with optional
+// per-call uploader overrides and returns the full result
+func (c *S3Client) uploadReader(ctx context.Context, bucket, key string, r io.Reader, opts *UploadOptions, uploaderOpts ...func(*s3manager.Uploader)) (*UploadResult, error) {
+	if err := c.checkClientSideKey(); err != nil {
+		return nil, err
+	}
+	key, err := c.resolveKey(key)
+	if err != nil {
+		return nil, err
+	}
+	opts, err = c.ensureAbsent(ctx, bucket, key, opts)
+	if err != nil {
+		return nil, err
+	}
+	return c.streamUploadResult(ctx, bucket, key, r, opts, uploaderOpts...)
+}
+
+// streamUpload is uploadReader for a key that has already been resolved
+func (c *S3Client) streamUpload(ctx context.Context, bucket, key string, r io.Reader, opts *UploadOptions, uploaderOpts ...func(*s3manager.Uploader)) (*UploadResult, error) {
+	input, err := newUploadInput(bucket, key, opts)
+	if err != nil {
+		return nil, err
+	}
+	if input.ContentType == nil && (opts == nil || !opts.DisableContentTypeDetection) {
+		head, body, err := peekReader(r, sniffLen)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read upload body: %w", err)
+		}
+		input.ContentType = aws.String(detectContentType(key, head))
+		r = body
+	}
+	if opts != nil && opts.Compress {
+		zr := gzipReader(r)
+		defer zr.Close()
+		r = zr
+	}
+	var limited *sizeLimitReader
+	if c.config.MaxUploadSize > 0 {
+		if r, limited, err = limitUploadSize(r, c.config.MaxUploadSize); err != nil {
+			return nil, err
 		}
-		return nil, fmt.Errorf("failed to list objects: %w", err)
 	}
+	input.Body = r
 
-	return files, nil
+	if opts != nil {
+		uploaderOpts = append([]func(*s3manager.Uploader){opts.uploaderOverrides}, uploaderOpts...)
+	}
+	// UploadOutput does not carry the lifecycle expiration, so read it from
+	// the response that created the object
+	var expiration string
+	reqOpts := append(c.bandwidthOptions(opts), captureExpiration(&expiration))
+	uploaderOpts = append(uploaderOpts, func(u *s3manager.Uploader) {
+		u.RequestOptions = append(u.RequestOptions[:len(u.RequestOptions):len(u.RequestOptions)], reqOpts...)
+	})
+
+	out, err := c.uploader.UploadWithContext(ctx, input, uploaderOpts...)
+	if err != nil {
+		// The uploader has already aborted any parts sent before the limit
+		if limited != nil && limited.exceeded {
+			return nil, tooLargeError(limited.limit)
+		}
+		return nil, uploadError(err)
+	}
+
+	result := &UploadResult{
+		Key:        c.relativeKey(key),
+		Location:   c.objectURL(bucket, key),
+		ETag:       aws.StringValue(out.ETag),
+		VersionID:  aws.StringValue(out.VersionID),
+		Expiration: expiration,
+	}
+	return result, nil
 }
 
-// UploadFile uploads a file to the specified bucket with options
-func (c *S3Client) UploadFile(ctx context.Context, bucket, filename string, data []byte, opts *UploadOptions) (string, error) {
+// streamUploadResult is streamUpload for callers that return the result.
+// UploadOutput does not carry the checksum headers, so when a checksum was
+// requested the one S3 stored is read back from the new object.
+func (c *S3Client) streamUploadResult(ctx context.Context, bucket, key string, r io.Reader, opts *UploadOptions, uploaderOpts ...func(*s3manager.Uploader)) (*UploadResult, error) {
+	result, err := c.streamUpload(ctx, bucket, key, r, opts, uploaderOpts...)
+	if err != nil || opts == nil || opts.ChecksumAlgorithm == ChecksumNone {
+		return result, err
+	}
+
+	input := &s3.HeadObjectInput{
+		Bucket:       aws.String(bucket),
+		Key:          aws.String(key),
+		ChecksumMode: aws.String(s3.ChecksumModeEnabled),
+	}
+	if result.VersionID != "" {
+		input.VersionId = aws.String(result.VersionID)
+	}
+	var reqOpts []request.Option
+	if opts.SSECustomerKey != nil {
+		reqOpts = append(reqOpts, withSSECustomerKey(opts.SSECustomerKey))
+	}
+	head, err := c.s3Client.HeadObjectWithContext(ctx, input, reqOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read uploaded checksum: %w", uploadError(err))
+	}
+	result.setChecksum(head.ChecksumSHA256, head.ChecksumCRC32C, head.ChecksumCRC32, head.ChecksumSHA1)
+	return result, nil
+}
+
+// newUploadInput validates the upload arguments and builds the uploader
+// input shared by the single request and multipart upload paths
+func newUploadInput(bucket, key string, opts *UploadOptions) (*s3manager.UploadInput, error) {
 	if bucket == "" {
-		return "", ErrInvalidBucket
+		return nil, ErrInvalidBucket
+	}
+	if err := validateKey(key); err != nil {
+		return nil, err
 	}
-	if filename == "" {
-		return "", ErrInvalidKey
+	if err := opts.validate(); err != nil {
+		return nil, err
 	}
 
 	input := &s3manager.UploadInput{
 		Bucket: aws.String(bucket),
-		Key:    aws.String(filename),
-		Body:   bytes.NewReader(data),
+		Key:    aws.String(key),
 	}
+	applyUploadOptions(input, opts)
+	return input, nil
+}
 
-	if opts != nil {
-		if opts.ContentType != "" {
-			input.ContentType = aws.String(opts.ContentType)
-		}
-		if opts.ContentDisposition != "" {
-			input.ContentDisposition = aws.String(opts.ContentDisposition)
-		}
-		if opts.CacheControl != "" {
-			input.CacheControl = aws.String(opts.CacheControl)
-		}
-		if opts.Metadata != nil {
-			input.Metadata = aws.StringMap(opts.Metadata)
-		}
-		if opts.StorageClass != "" {
-			input.StorageClass = aws.String(opts.StorageClass)
-		}
-		if opts.ACL != "" {
-			input.ACL = aws.String(opts.ACL)
+// validate checks the upload options before any request is made
+func (o *UploadOptions) validate() error {
+	if o == nil {
+		return nil
+	}
+	if o.PartSize != 0 && o.PartSize < MinPartSize {
+		return fmt.Errorf("%w: part size %d is below the S3 minimum part size of %d bytes", ErrInvalidOptions, o.PartSize, MinPartSize)
+	}
+	if o.BandwidthLimit < 0 {
+		return fmt.Errorf("%w: BandwidthLimit must not be negative", ErrInvalidOptions)
+	}
+	if err := validateStorageClassAndACL(o.StorageClass, o.ACL, o.AllowUnknownValues); err != nil {
+		return err
+	}
+	if o.Compress && o.ContentEncoding != "" && o.ContentEncoding != gzipEncoding {
+		return fmt.Errorf("%w: Compress conflicts with ContentEncoding %q", ErrInvalidOptions, o.ContentEncoding)
+	}
+	if !o.ChecksumAlgorithm.valid() {
+		return fmt.Errorf("%w: unsupported checksum algorithm %q", ErrInvalidOptions, o.ChecksumAlgorithm)
+	}
+	if err := validateServerSideEncryption(o.ServerSideEncryption, o.SSEKMSKeyID); err != nil {
+		return err
+	}
+	if err := validateSSECustomerKey(o.SSECustomerKey); err != nil {
+		return err
+	}
+	if o.SSECustomerKey != nil && o.ServerSideEncryption != "" {
+		return fmt.Errorf("%w: SSECustomerKey cannot be combined with ServerSideEncryption", ErrInvalidOptions)
+	}
+	if err := validateTags(o.Tags); err != nil {
+		return err
+	}
+	if err := validateObjectLock(o.ObjectLockMode, o.ObjectLockRetainUntil); err != nil {
+		return err
+	}
+	if err := validateWebsiteRedirectLocation(o.WebsiteRedirectLocation); err != nil {
+		return err
+	}
+	return nil
+}
+
+// requestOptions returns the per-request handlers needed by these options.
+// They are applied to PutObject requests and to every uploader request.
+func (o *UploadOptions) requestOptions() []request.Option {
+	if o == nil {
+		return nil
+	}
+	var reqOpts []request.Option
+	// S3 requires an integrity header on writes that set Object Lock
+	locked := o.ObjectLockMode != "" || o.ObjectLockLegalHold
+	if o.ComputeMD5 || (locked && o.ChecksumAlgorithm == ChecksumNone) {
+		reqOpts = append(reqOpts, withContentMD5)
+	}
+	if o.ChecksumAlgorithm != ChecksumNone {
+		reqOpts = append(reqOpts, withChecksum(o.ChecksumAlgorithm))
+	}
+	if o.SSECustomerKey != nil {
+		reqOpts = append(reqOpts, withSSECustomerKey(o.SSECustomerKey))
+	}
+	if o.IfNoneMatch {
+		reqOpts = append(reqOpts, withIfNoneMatch)
+	}
+	return reqOpts
+}
+
+// uploaderOverrides applies the per-call multipart tuning to an uploader
+func (o *UploadOptions) uploaderOverrides(u *s3manager.Uploader) {
+	if o.PartSize > 0 {
+		u.PartSize = o.PartSize
+	}
+	if o.Concurrency > 0 {
+		u.Concurrency = o.Concurrency
+	}
+	if reqOpts := o.requestOptions(); len(reqOpts) > 0 {
+		u.RequestOptions = append(u.RequestOptions[:len(u.RequestOptions):len(u.RequestOptions)], reqOpts...)
+	}
+}
+
+// captureExpiration returns a request option that stores the x-amz-expiration
+// header of the PutObject or CompleteMultipartUpload response in dst
+func captureExpiration(dst *string) request.Option {
+	return func(r *request.Request) {
+		r.Handlers.Complete.PushBack(func(r *request.Request) {
+			if r.Operation == nil || r.HTTPResponse == nil {
+				return
+			}
+			if r.Operation.Name == "PutObject" || r.Operation.Name == "CompleteMultipartUpload" {
+				*dst = r.HTTPResponse.Header.Get("x-amz-expiration")
+			}
+		})
+	}
+}
+
+// uploadError maps an upload failure to the library's error values
+func uploadError(err error) error {
+	// Multipart failures wrap the error of the part that failed
+	if mf, ok := err.(s3manager.MultiUploadFailure); ok {
+		if orig, ok := mf.OrigErr().(awserr.Error); ok {
+			err = orig
 		}
 	}
 
-	result, err := c.uploader.UploadWithContext(ctx, input)
-	if err != nil {
-		if aerr, ok := err.(awserr.Error); ok {
-			switch aerr.Code() {
-			case s3.ErrCodeNoSuchBucket:
-				return "", ErrInvalidBucket
-			default:
-				return "", fmt.Errorf("AWS error: %w", aerr)
+	if aerr, ok := err.(awserr.Error); ok {
+		switch aerr.Code() {
+		case s3.ErrCodeNoSuchBucket:
+			return ErrInvalidBucket
+		case "BadDigest":
+			return fmt.Errorf("%w: %w", ErrChecksumMismatch, aerr)
+		case "PreconditionFailed":
+			return ErrObjectExists
+		case "ReadRequestBody":
+			// The uploader could not read the body; keep the reader's error,
+			// such as a checksum mismatch of a streamed copy, matchable
+			if orig := aerr.OrigErr(); orig != nil {
+				return fmt.Errorf("failed to read upload body: %w", orig)
 			}
+			return fmt.Errorf("AWS error: %w", aerr)
+		default:
+			return fmt.Errorf("AWS error: %w", aerr)
 		}
-		return "", fmt.Errorf("failed to upload file: %w", err)
 	}
+	return fmt.Errorf("failed to upload file: %w", err)
+}
 
-	return result.Location, nil
+// putObjectInput converts an uploader input into the equivalent PutObject
+// input so both upload paths honor UploadOptions identically
+func putObjectInput(in *s3manager.UploadInput, body io.ReadSeeker) *s3.PutObjectInput {
+	return &s3.PutObjectInput{
+		ACL:                       in.ACL,
+		Body:                      body,
+		Bucket:                    in.Bucket,
+		BucketKeyEnabled:          in.BucketKeyEnabled,
+		CacheControl:              in.CacheControl,
+		ChecksumAlgorithm:         in.ChecksumAlgorithm,
+		ChecksumCRC32:             in.ChecksumCRC32,
+		ChecksumCRC32C:            in.ChecksumCRC32C,
+		ChecksumSHA1:              in.ChecksumSHA1,
+		ChecksumSHA256:            in.ChecksumSHA256,
+		ContentDisposition:        in.ContentDisposition,
+		ContentEncoding:           in.ContentEncoding,
+		ContentLanguage:           in.ContentLanguage,
+		ContentMD5:                in.ContentMD5,
+		ContentType:               in.ContentType,
+		ExpectedBucketOwner:       in.ExpectedBucketOwner,
+		Expires:                   in.Expires,
+		GrantFullControl:          in.GrantFullControl,
+		GrantRead:                 in.GrantRead,
+		GrantReadACP:              in.GrantReadACP,
+		GrantWriteACP:             in.GrantWriteACP,
+		Key:                       in.Key,
+		Metadata:                  in.Metadata,
+		ObjectLockLegalHoldStatus: in.ObjectLockLegalHoldStatus,
+		ObjectLockMode:            in.ObjectLockMode,
+		ObjectLockRetainUntilDate: in.ObjectLockRetainUntilDate,
+		RequestPayer:              in.RequestPayer,
+		SSECustomerAlgorithm:      in.SSECustomerAlgorithm,
+		SSECustomerKey:            in.SSECustomerKey,
+		SSECustomerKeyMD5:         in.SSECustomerKeyMD5,
+		SSEKMSEncryptionContext:   in.SSEKMSEncryptionContext,
+		SSEKMSKeyId:               in.SSEKMSKeyId,
+		ServerSideEncryption:      in.ServerSideEncryption,
+		StorageClass:              in.StorageClass,
+		Tagging:                   in.Tagging,
+		WebsiteRedirectLocation:   in.WebsiteRedirectLocation,
+	}
+}
+
+// applyUploadOptions copies the optional upload parameters onto input
+func applyUploadOptions(input *s3manager.UploadInput, opts *UploadOptions) {
+	if opts == nil {
+		return
+	}
+	if opts.ContentType != "" {
+		input.ContentType = aws.String(opts.ContentType)
+	}
+	if opts.ContentDisposition != "" {
+		input.ContentDisposition = aws.String(opts.ContentDisposition)
+	}
+	if opts.CacheControl != "" {
+		input.CacheControl = aws.String(opts.CacheControl)
+	}
+	if opts.Metadata != nil {
+		input.Metadata = aws.StringMap(opts.Metadata)
+	}
+	if opts.StorageClass != "" {
+		input.StorageClass = aws.String(string(opts.StorageClass))
+	}
+	if opts.ACL != "" {
+		input.ACL = aws.String(string(opts.ACL))
+	}
+	if opts.ContentEncoding != "" {
+		input.ContentEncoding = aws.String(opts.ContentEncoding)
+	}
+	if opts.Compress {
+		input.ContentEncoding = aws.String(gzipEncoding)
+	}
+	if opts.ContentLanguage != "" {
+		input.ContentLanguage = aws.String(opts.ContentLanguage)
+	}
+	if !opts.Expires.IsZero() {
+		input.Expires = aws.Time(opts.Expires)
+	}
+	if opts.ChecksumAlgorithm != ChecksumNone {
+		input.ChecksumAlgorithm = aws.String(string(opts.ChecksumAlgorithm))
+	}
+	applyServerSideEncryption(input, opts.ServerSideEncryption, opts.SSEKMSKeyID)
+	if len(opts.Tags) > 0 {
+		input.Tagging = aws.String(encodeTags(opts.Tags))
+	}
+	applyObjectLock(input, opts.ObjectLockMode, opts.ObjectLockRetainUntil, opts.ObjectLockLegalHold)
+	if opts.WebsiteRedirectLocation != "" {
+		input.WebsiteRedirectLocation = aws.String(opts.WebsiteRedirectLocation)
+	}
 }
 
-// DownloadFile downloads a file from the specified bucket
+// DownloadFile downloads a file from the specified bucket. Objects stored
+// with Content-Encoding: gzip are returned as stored; see
+// DownloadOptions.AutoDecompress.
 func (c *S3Client) DownloadFile(ctx context.Context, bucket, key string) ([]byte, error) {
+	return c.DownloadFileWithOptions(ctx, bucket, key, nil)
+}
+
+// DownloadFileWithOptions downloads a file from the specified bucket with options
+func (c *S3Client) DownloadFileWithOptions(ctx context.Context, bucket, key string, opts *DownloadOptions) ([]byte, error) {
+	result, err := c.DownloadFileResult(ctx, bucket, key, opts)
+	if err != nil {
+		return nil, err
+	}
+	return result.Data, nil
+}
+
+// DownloadFileWithInfo downloads a file and returns it with its content
+// type, user metadata, ETag, modification time and version ID. The details
+// come from the same response as the data, so they always describe it.
+func (c *S3Client) DownloadFileWithInfo(ctx context.Context, bucket, key string) (*DownloadResult, error) {
+	return c.DownloadFileResult(ctx, bucket, key, nil)
+}
+
+// DownloadFileResult downloads a file like DownloadFileWithOptions and
+// returns it with the details of DownloadFileWithInfo and whether the
+// requester was charged. With Config.CacheDir set, a cached copy is
+// revalidated with If-None-Match and served from disk if S3 answers 304
+// Not Modified; downloads that are versioned, conditional, raw or use
+// SSECustomerKey bypass the cache.
+func (c *S3Client) DownloadFileResult(ctx context.Context, bucket, key string, opts *DownloadOptions) (*DownloadResult, error) {
 	if bucket == "" {
 		return nil, ErrInvalidBucket
 	}
-	if key == "" {
-		return nil, ErrInvalidKey
+	key, err := c.resolveKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := opts.validate(); err != nil {
+		return nil, err
 	}
 
-	// First check if the object exists
-	_, err := c.s3Client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
-		Bucket: aws.String(bucket),
-		Key:    aws.String(key),
-	})
-	if err != nil {
-		if aerr, ok := err.(awserr.Error); ok {
-			switch aerr.Code() {
-			case "NotFound":
-				return nil, ErrFileNotFound
-			case s3.ErrCodeNoSuchBucket:
-				return nil, ErrInvalidBucket
-			default:
-				return nil, fmt.Errorf("AWS error: %w", aerr)
+	if c.cache != nil && opts.cacheable() {
+		return c.cachedDownload(ctx, bucket, key, opts, func(opts *DownloadOptions) (*DownloadResult, error) {
+			return c.downloadFileResult(ctx, bucket, key, opts)
+		})
+	}
+	return c.downloadFileResult(ctx, bucket, key, opts)
+}
+
+// downloadFileResult implements DownloadFileResult for a full object key
+// without consulting the cache
+func (c *S3Client) downloadFileResult(ctx context.Context, bucket, key string, opts *DownloadOptions) (*DownloadResult, error) {
+	// The object's metadata is taken from the downloader's first response,
+	// so a download costs no more requests than its ranged GETs
+	var (
+		err      error
+		first    *s3.GetObjectOutput
+		once     sync.Once
+		progress *progressTracker
+		buf      = newDownloadBuffer()
+	)
+	defer buf.release()
+	if opts != nil {
+		progress = newProgressTracker(opts.Progress, -1)
+	}
+	captureFirst := func(r *request.Request) {
+		r.Handlers.Unmarshal.PushBack(func(r *request.Request) {
+			out, ok := r.Data.(*s3.GetObjectOutput)
+			if !ok || r.Error != nil {
+				return
 			}
-		}
-		return nil, fmt.Errorf("failed to get object info: %w", err)
+			once.Do(func() {
+				first = out
+				total := aws.Int64Value(out.ContentLength)
+				if out.ContentRange != nil {
+					total = contentRangeSize(*out.ContentRange)
+				}
+				progress.setTotal(total)
+				// Refuse oversized objects before any of the body is buffered
+				if err := c.checkDownloadSize(total); err != nil {
+					out.Body.Close()
+					r.Error = awserr.New(objectTooLargeCode, fmt.Sprintf("limit is %d bytes", c.config.MaxDownloadSize), nil)
+					return
+				}
+				buf.reserve(total)
+			})
+		})
 	}
+	reqOpts := append(opts.requestOptions(), captureFirst)
 
-	// Download the object
-	buf := aws.NewWriteAtBuffer([]byte{})
-	downloader := s3manager.NewDownloader(c.session)
+	ifNoneMatch, ifModifiedSince := opts.conditions()
+	input := &s3.GetObjectInput{
+		Bucket:          aws.String(bucket),
+		Key:             aws.String(key),
+		VersionId:       opts.versionID(),
+		IfNoneMatch:     ifNoneMatch,
+		IfModifiedSince: ifModifiedSince,
+	}
 
-	_, err = downloader.DownloadWithContext(ctx, buf,
-		&s3.GetObjectInput{
-			Bucket: aws.String(bucket),
-			Key:    aws.String(key),
+	// Ranged GETs do not return the object's checksum, so it is read first
+	// and IfMatch ties the download to the object it describes
+	var verifier *checksumVerifier
+	if opts != nil && opts.VerifyChecksum {
+		head, err := c.headForDownload(ctx, bucket, key, opts)
+		if err != nil {
+			return nil, err
+		}
+		verifier = newChecksumVerifier(head.ChecksumSHA256, head.ChecksumCRC32C, head.ChecksumCRC32, head.ChecksumSHA1,
+			aws.StringValue(head.ETag), etagIsMD5(head.ServerSideEncryption, head.SSECustomerAlgorithm))
+		input.IfMatch = head.ETag
+	}
+
+	_, err = c.downloader.DownloadWithContext(ctx, &progressWriterAt{w: buf, t: progress},
+		input, func(d *s3manager.Downloader) {
+			d.RequestOptions = append(d.RequestOptions, reqOpts...)
 		})
+	if progressErr := progress.stop(); progressErr != nil {
+		return nil, progressErr
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to download file: %w", err)
+		return nil, c.archivedError(ctx, bucket, key, opts, downloadError(err, opts, "failed to download file"))
+	}
+	if first == nil {
+		return nil, fmt.Errorf("failed to download file: no response received")
 	}
 
-	return buf.Bytes(), nil
+	data := buf.bytes()
+	if verifier != nil {
+		verifier.Write(data)
+		if err := verifier.verify(); err != nil {
+			return nil, err
+		}
+	}
+	decompressed := aws.StringValue(first.ContentEncoding) == gzipEncoding && opts.decompress()
+	if decompressed {
+		if data, err = gunzipLimit(data, c.config.MaxDownloadSize); err != nil {
+			return nil, err
+		}
+	}
+	encrypted := metadataValue(first.Metadata, clientSideEncryptionMetaKey) != ""
+	if data, err = decryptClientSide(c.config.ClientSideKey, data, first.Metadata); err != nil {
+		return nil, err
+	}
+	if !decompressed && !encrypted {
+		// data is still the pooled buffer, which is released on return
+		data = append(make([]byte, 0, len(data)), data...)
+	}
+
+	return &DownloadResult{
+		Data:           data,
+		ContentType:    aws.StringValue(first.ContentType),
+		ETag:           aws.StringValue(first.ETag),
+		LastModified:   aws.TimeValue(first.LastModified),
+		VersionID:      aws.StringValue(first.VersionId),
+		Metadata:       userMetadata(first.Metadata),
+		encrypted:      encrypted,
+		RequestCharged: requestCharged(first.RequestCharged),
+		Decompressed:   decompressed,
+	}, nil
+}
+
+// headForDownload fetches the metadata of an object about to be downloaded
+// and maps the failures that mean it cannot be
+func (c *S3Client) headForDownload(ctx context.Context, bucket, key string, opts *DownloadOptions) (*s3.HeadObjectOutput, error) {
+	ifNoneMatch, ifModifiedSince := opts.conditions()
+	head, err := c.s3Client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket:          aws.String(bucket),
+		Key:             aws.String(key),
+		VersionId:       opts.versionID(),
+		IfNoneMatch:     ifNoneMatch,
+		IfModifiedSince: ifModifiedSince,
+		ChecksumMode:    aws.String(s3.ChecksumModeEnabled),
+	}, opts.requestOptions()...)
+	if err != nil {
+		return nil, downloadError(err, opts, "failed to get object info")
+	}
+	if err := checkArchived(head); err != nil {
+		return nil, err
+	}
+	return head, nil
+}
+
+// downloadError maps a failed HeadObject or GetObject of a download to the
+// library's error values
+func downloadError(err error, opts *DownloadOptions, msg string) error {
+	if aerr, ok := err.(awserr.Error); ok {
+		switch {
+		case aerr.Code() == "NotFound" || aerr.Code() == s3.ErrCodeNoSuchKey || aerr.Code() == "NoSuchVersion":
+			return ErrFileNotFound
+		case aerr.Code() == "NotModified":
+			return ErrNotModified
+		case aerr.Code() == "PreconditionFailed":
+			// Only sent when a read is pinned to an ETag with IfMatch
+			return fmt.Errorf("%w: %w", ErrObjectModified, aerr)
+		case aerr.Code() == "InvalidObjectState":
+			return fmt.Errorf("%w: %s", ErrObjectArchived, aerr.Message())
+		case aerr.Code() == objectTooLargeCode:
+			return fmt.Errorf("%w: %s", ErrObjectTooLarge, aerr.Message())
+		case opts != nil && opts.VersionID != "" && aerr.Code() == "InvalidArgument":
+			// S3 rejects malformed version IDs rather than reporting them missing
+			return fmt.Errorf("%w: invalid version ID %q", ErrFileNotFound, opts.VersionID)
+		case aerr.Code() == s3.ErrCodeNoSuchBucket:
+			return ErrInvalidBucket
+		case (opts == nil || opts.SSECustomerKey == nil) && isSSECustomerKeyError(aerr):
+			return fmt.Errorf("%w: supply DownloadOptions.SSECustomerKey: %w", ErrEncryptionKeyRequired, aerr)
+		default:
+			return fmt.Errorf("AWS error: %w", aerr)
+		}
+	}
+	return fmt.Errorf("%s: %w", msg, err)
+}
+
+// decompress reports whether gzip encoded objects are to be decompressed
+func (o *DownloadOptions) decompress() bool {
+	return o != nil && o.AutoDecompress
+}
+
+// validate checks the download options before any request is made
+func (o *DownloadOptions) validate() error {
+	if o == nil {
+		return nil
+	}
+	return validateSSECustomerKey(o.SSECustomerKey)
+}
+
+// conditions returns the If-None-Match and If-Modified-Since values to send,
+// or nil for those not set
+func (o *DownloadOptions) conditions() (ifNoneMatch *string, ifModifiedSince *time.Time) {
+	if o == nil {
+		return nil, nil
+	}
+	if o.IfNoneMatch != "" {
+		ifNoneMatch = aws.String(quoteETag(o.IfNoneMatch))
+	}
+	if !o.IfModifiedSince.IsZero() {
+		ifModifiedSince = aws.Time(o.IfModifiedSince)
+	}
+	return ifNoneMatch, ifModifiedSince
+}
+
+// quoteETag returns etag in the quoted form S3 compares against. Weak and
+// wildcard values are returned unchanged.
+func quoteETag(etag string) string {
+	if etag == "*" || strings.HasPrefix(etag, `"`) || strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return `"` + etag + `"`
+}
+
+// versionID returns the version to request, or nil for the current one
+func (o *DownloadOptions) versionID() *string {
+	if o == nil || o.VersionID == "" {
+		return nil
+	}
+	return aws.String(o.VersionID)
+}
+
+// requestOptions returns the per-request handlers needed by these options
+func (o *DownloadOptions) requestOptions() []request.Option {
+	if o == nil {
+		return nil
+	}
+	var reqOpts []request.Option
+	if o.SSECustomerKey != nil {
+		reqOpts = append(reqOpts, withSSECustomerKey(o.SSECustomerKey))
+	}
+	if o.limiter != nil {
+		reqOpts = append(reqOpts, withDownloadBandwidthLimit(o.limiter))
+	}
+	return reqOpts
 }
 
 // DeleteFile deletes a file from the specified bucket
 func (c *S3Client) DeleteFile(ctx context.Context, bucket, key string) error {
+	return c.DeleteFileVersion(ctx, bucket, key, "")
+}
+
+// DeleteFileVersion permanently deletes one version of an object in a
+// versioned bucket. An empty versionID deletes the current object, leaving a
+// delete marker if versioning is enabled. A version protected by Object Lock
+// returns ErrObjectLocked.
+func (c *S3Client) DeleteFileVersion(ctx context.Context, bucket, key, versionID string) error {
 	if bucket == "" {
 		return ErrInvalidBucket
 	}
-	if key == "" {
-		return ErrInvalidKey
+	key, err := c.resolveKey(key)
+	if err != nil {
+		return err
+	}
+	var version *string
+	if versionID != "" {
+		version = aws.String(versionID)
 	}
 
-	_, err := c.s3Client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
-		Bucket: aws.String(bucket),
-		Key:    aws.String(key),
+	_, err = withRetry(ctx, c.config.MaxRetries, func() (*s3.DeleteObjectOutput, error) {
+		return c.s3Client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+			Bucket:    aws.String(bucket),
+			Key:       aws.String(key),
+			VersionId: version,
+		})
 	})
 	if err != nil {
 		if aerr, ok := err.(awserr.Error); ok {
 			switch aerr.Code() {
 			case s3.ErrCodeNoSuchBucket:
 				return ErrInvalidBucket
-			default:
-				return fmt.Errorf("AWS error: %w", aerr)
+			case "NoSuchVersion", "InvalidArgument":
+				return ErrFileNotFound
+			}
+			if (DeleteError{Code: aerr.Code(), Message: aerr.Message()}).objectLocked() {
+				return fmt.Errorf("%w: %s version %s", ErrObjectLocked, key, versionID)
 			}
+			return fmt.Errorf("AWS error: %w", aerr)
 		}
 		return fmt.Errorf("failed to delete file: %w", err)
 	}
@@ -248,24 +1094,44 @@ func (c *S3Client) DeleteFile(ctx context.Context, bucket, key string) error {
 
 // GetFileInfo gets metadata for a specific file
 func (c *S3Client) GetFileInfo(ctx context.Context, bucket, key string) (*FileInfo, error) {
+	return c.GetFileInfoWithOptions(ctx, bucket, key, nil)
+}
+
+// GetFileInfoWithOptions gets metadata for a specific file, honoring the
+// VersionID, SSECustomerKey and conditions of opts
+func (c *S3Client) GetFileInfoWithOptions(ctx context.Context, bucket, key string, opts *DownloadOptions) (*FileInfo, error) {
 	if bucket == "" {
 		return nil, ErrInvalidBucket
 	}
-	if key == "" {
-		return nil, ErrInvalidKey
+	key, err := c.resolveKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := opts.validate(); err != nil {
+		return nil, err
 	}
 
+	ifNoneMatch, ifModifiedSince := opts.conditions()
 	result, err := c.s3Client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
-		Bucket: aws.String(bucket),
-		Key:    aws.String(key),
-	})
+		Bucket:          aws.String(bucket),
+		Key:             aws.String(key),
+		VersionId:       opts.versionID(),
+		IfNoneMatch:     ifNoneMatch,
+		IfModifiedSince: ifModifiedSince,
+		ChecksumMode:    aws.String(s3.ChecksumModeEnabled),
+	}, opts.requestOptions()...)
 	if err != nil {
 		if aerr, ok := err.(awserr.Error); ok {
-			switch aerr.Code() {
-			case "NotFound":
+			switch {
+			case aerr.Code() == "NotFound":
 				return nil, ErrFileNotFound
-			case s3.ErrCodeNoSuchBucket:
+			case aerr.Code() == "NotModified":
+				return nil, ErrNotModified
+			case aerr.Code() == s3.ErrCodeNoSuchBucket:
 				return nil, ErrInvalidBucket
+			case opts != nil && opts.VersionID != "" && aerr.Code() == "BadRequest":
+				// HeadObject reports a malformed version ID as a bare 400
+				return nil, fmt.Errorf("%w: invalid version ID %q", ErrFileNotFound, opts.VersionID)
 			default:
 				return nil, fmt.Errorf("AWS error: %w", aerr)
 			}
@@ -273,13 +1139,77 @@ func (c *S3Client) GetFileInfo(ctx context.Context, bucket, key string) (*FileIn
 		return nil, fmt.Errorf("failed to get file info: %w", err)
 	}
 
-	return &FileInfo{
-		Key:          key,
+	info := &FileInfo{
+		Key:          c.relativeKey(key),
 		Size:         aws.Int64Value(result.ContentLength),
 		LastModified: aws.TimeValue(result.LastModified),
 		ETag:         aws.StringValue(result.ETag),
 		StorageClass: aws.StringValue(result.StorageClass),
-	}, nil
+
+		ServerSideEncryption: aws.StringValue(result.ServerSideEncryption),
+		SSEKMSKeyID:          aws.StringValue(result.SSEKMSKeyId),
+
+		ObjectLockMode:        aws.StringValue(result.ObjectLockMode),
+		ObjectLockRetainUntil: result.ObjectLockRetainUntilDate,
+		ObjectLockLegalHold:   aws.StringValue(result.ObjectLockLegalHoldStatus) == s3.ObjectLockLegalHoldStatusOn,
+
+		ContentEncoding: aws.StringValue(result.ContentEncoding),
+		ContentLanguage: aws.StringValue(result.ContentLanguage),
+
+		WebsiteRedirectLocation: aws.StringValue(result.WebsiteRedirectLocation),
+
+		RequestCharged: requestCharged(result.RequestCharged),
+
+		VersionID: aws.StringValue(result.VersionId),
+	}
+	// Expires is returned as an HTTP date; an unparseable value is ignored
+	if expires, err := http.ParseTime(aws.StringValue(result.Expires)); err == nil {
+		info.Expires = &expires
+	}
+	info.ChecksumAlgorithm, info.Checksum = pickChecksum(result.ChecksumSHA256, result.ChecksumCRC32C, result.ChecksumCRC32, result.ChecksumSHA1)
+	if ongoing, expiry, err := parseRestoreHeader(aws.StringValue(result.Restore)); err == nil && (ongoing || !expiry.IsZero()) {
+		info.RestoreStatus = &RestoreStatus{InProgress: ongoing}
+		if !expiry.IsZero() {
+			info.RestoreStatus.ExpiryDate = &expiry
+		}
+	}
+	return info, nil
+}
+
+// FileExists reports whether an object exists at key. A missing object, or
+// one whose current version is a delete marker, gives (false, nil); any
+// other failure is returned. In particular S3 answers 403 rather than 404
+// when the credentials may not read the key or list the bucket, so the
+// object may well exist and FileExists returns ErrAccessDenied.
+func (c *S3Client) FileExists(ctx context.Context, bucket, key string) (bool, error) {
+	if bucket == "" {
+		return false, ErrInvalidBucket
+	}
+	key, err := c.resolveKey(key)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = c.s3Client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok {
+			switch aerr.Code() {
+			case "NotFound":
+				return false, nil
+			case s3.ErrCodeNoSuchBucket:
+				return false, ErrInvalidBucket
+			case "Forbidden":
+				return false, fmt.Errorf("%w: %w", ErrAccessDenied, aerr)
+			default:
+				return false, fmt.Errorf("AWS error: %w", aerr)
+			}
+		}
+		return false, fmt.Errorf("failed to check file: %w", err)
+	}
+	return true, nil
 }
 
 // Close closes the S3 client and cleans up resources
@@ -299,10 +1229,13 @@ func (c *S3Client) Close() error {
 		c.s3Client = nil
 	}
 
-	// Clean up the uploader
+	// Clean up the uploader and downloader
 	if c.uploader != nil {
 		c.uploader = nil
 	}
+	if c.downloader != nil {
+		c.downloader = nil
+	}
 
 	// Log cleanup if debug mode is enabled
 	if c.debugMode {
@@ -329,12 +1262,12 @@ func (c *S3Client) GeneratePresignedURL(ctx context.Context, bucket, key string,
 	if bucket == "" {
 		return nil, ErrInvalidBucket
 	}
-	if key == "" {
-		return nil, ErrInvalidKey
+	key, err := c.resolveKey(key)
+	if err != nil {
+		return nil, err
 	}
 
 	var url string
-	var err error
 
 	switch operation {
 	case "upload":
@@ -370,8 +1303,9 @@ func (c *S3Client) GeneratePresignedPost(ctx context.Context, bucket, key string
 	if bucket == "" {
 		return nil, ErrInvalidBucket
 	}
-	if key == "" {
-		return nil, ErrInvalidKey
+	key, err := c.resolveKey(key)
+	if err != nil {
+		return nil, err
 	}
 
 	// Create policy
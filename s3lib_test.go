@@ -1,11 +1,22 @@
 package s3lib
 
 import (
+	"bytes"
 	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
 	"fmt"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
 	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -42,6 +53,225 @@ func setupTestClient(t *testing.T) *S3Client {
 	return client
 }
 
+// roundTripFunc adapts a function into an http.RoundTripper for faking S3
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Helper function to setup a client whose requests are served by fn
+func setupFakeClient(t testing.TB, fn roundTripFunc) *S3Client {
+	cfg := testConfig
+	cfg.Endpoint = "http://s3.fake.local"
+	cfg.HTTPClient = &http.Client{Transport: fn}
+	client, err := NewS3Client(cfg)
+	require.NoError(t, err)
+	return client
+}
+
+// Helper function to build a fake S3 XML error response
+func fakeErrorResponse(req *http.Request, status int, code string) *http.Response {
+	body := fmt.Sprintf("<Error><Code>%s</Code><Message>%s</Message></Error>", code, code)
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{"application/xml"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}
+}
+
+// Helper function to build a fake S3 XML response body
+func xmlBody(body string) io.ReadCloser {
+	return io.NopCloser(strings.NewReader(body))
+}
+
+// memoryObject is an object stored by memoryServer
+type memoryObject struct {
+	data   []byte
+	header http.Header // content and metadata headers it was uploaded with
+	etag   string
+}
+
+// memoryUpload is a multipart upload in progress on memoryServer
+type memoryUpload struct {
+	key       string
+	header    http.Header
+	initiated time.Time
+	parts     map[int][]byte
+}
+
+// Helper function to fake S3 with objects held in memory, keyed without the
+// bucket. PUT stores the body with its content and metadata headers, GET and
+// HEAD serve it with its ETag, and DELETE removes it. Multipart uploads are
+// assembled from their parts on completion, with a multipart ETag, and are
+// listed until then.
+func memoryServer(objects map[string]*memoryObject) roundTripFunc {
+	var mu sync.Mutex
+	uploads := map[string]*memoryUpload{}
+	nextUpload := 0
+	return func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		key := ""
+		if parts := strings.SplitN(strings.TrimPrefix(req.URL.Path, "/"), "/", 2); len(parts) == 2 {
+			key = parts[1]
+		}
+		query := req.URL.Query()
+		resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: req}
+		if req.Body == nil {
+			// The SDK sends empty payloads without a body
+			req.Body = http.NoBody
+		}
+
+		uploadID := query.Get("uploadId")
+		upload := uploads[uploadID]
+		if uploadID != "" && upload == nil {
+			return fakeErrorResponse(req, http.StatusNotFound, "NoSuchUpload"), nil
+		}
+
+		switch {
+		case req.Method == http.MethodPost && query.Has("uploads"):
+			nextUpload++
+			uploadID = fmt.Sprintf("upload-%d", nextUpload)
+			uploads[uploadID] = &memoryUpload{key: key, header: storedHeader(req.Header), initiated: time.Now(), parts: map[int][]byte{}}
+			resp.Body = xmlBody(fmt.Sprintf("<InitiateMultipartUploadResult><Key>%s</Key><UploadId>%s</UploadId></InitiateMultipartUploadResult>", key, uploadID))
+		case req.Method == http.MethodGet && query.Has("uploads"):
+			ids := make([]string, 0, len(uploads))
+			for id, u := range uploads {
+				if strings.HasPrefix(u.key, query.Get("prefix")) {
+					ids = append(ids, id)
+				}
+			}
+			sort.Strings(ids)
+			var list strings.Builder
+			list.WriteString("<ListMultipartUploadsResult>")
+			for _, id := range ids {
+				fmt.Fprintf(&list, "<Upload><Key>%s</Key><UploadId>%s</UploadId><Initiated>%s</Initiated></Upload>",
+					uploads[id].key, id, uploads[id].initiated.UTC().Format(time.RFC3339))
+			}
+			list.WriteString("<IsTruncated>false</IsTruncated></ListMultipartUploadsResult>")
+			resp.Body = xmlBody(list.String())
+		case req.Method == http.MethodGet && upload != nil:
+			numbers := make([]int, 0, len(upload.parts))
+			for n := range upload.parts {
+				numbers = append(numbers, n)
+			}
+			sort.Ints(numbers)
+			var list strings.Builder
+			fmt.Fprintf(&list, "<ListPartsResult><Key>%s</Key><UploadId>%s</UploadId>", upload.key, uploadID)
+			for _, n := range numbers {
+				fmt.Fprintf(&list, "<Part><PartNumber>%d</PartNumber><ETag>%s</ETag><Size>%d</Size></Part>", n, md5ETag(upload.parts[n]), len(upload.parts[n]))
+			}
+			list.WriteString("<IsTruncated>false</IsTruncated></ListPartsResult>")
+			resp.Body = xmlBody(list.String())
+		case req.Method == http.MethodPut && upload != nil:
+			data, err := io.ReadAll(req.Body)
+			if err != nil {
+				return nil, err
+			}
+			partNumber, err := strconv.Atoi(query.Get("partNumber"))
+			if err != nil {
+				return fakeErrorResponse(req, http.StatusBadRequest, "InvalidArgument"), nil
+			}
+			upload.parts[partNumber] = data
+			resp.Header.Set("ETag", md5ETag(data))
+		case req.Method == http.MethodPost && upload != nil:
+			var complete struct {
+				Parts []struct {
+					PartNumber int
+				} `xml:"Part"`
+			}
+			if err := xml.NewDecoder(req.Body).Decode(&complete); err != nil {
+				return fakeErrorResponse(req, http.StatusBadRequest, "MalformedXML"), nil
+			}
+			var data, sums []byte
+			for _, p := range complete.Parts {
+				part, ok := upload.parts[p.PartNumber]
+				if !ok {
+					return fakeErrorResponse(req, http.StatusBadRequest, "InvalidPart"), nil
+				}
+				data = append(data, part...)
+				sum := md5.Sum(part)
+				sums = append(sums, sum[:]...)
+			}
+			sum := md5.Sum(sums)
+			obj := &memoryObject{data: data, header: upload.header, etag: fmt.Sprintf(`"%s-%d"`, hex.EncodeToString(sum[:]), len(complete.Parts))}
+			objects[upload.key] = obj
+			delete(uploads, uploadID)
+			resp.Body = xmlBody(fmt.Sprintf("<CompleteMultipartUploadResult><Key>%s</Key><ETag>%s</ETag></CompleteMultipartUploadResult>", upload.key, obj.etag))
+		case req.Method == http.MethodDelete && upload != nil:
+			delete(uploads, uploadID)
+			resp.StatusCode = http.StatusNoContent
+		case req.Method == http.MethodPut:
+			data, err := io.ReadAll(req.Body)
+			if err != nil {
+				return nil, err
+			}
+			obj := &memoryObject{data: data, header: storedHeader(req.Header), etag: md5ETag(data)}
+			objects[key] = obj
+			resp.Header.Set("ETag", obj.etag)
+		case req.Method == http.MethodGet, req.Method == http.MethodHead:
+			obj, ok := objects[key]
+			if !ok && req.Method == http.MethodHead {
+				resp.StatusCode = http.StatusNotFound
+				return resp, nil
+			}
+			if !ok {
+				return fakeErrorResponse(req, http.StatusNotFound, "NoSuchKey"), nil
+			}
+			for name, values := range obj.header {
+				if isChecksumHeader(name) && req.Header.Get("X-Amz-Checksum-Mode") != "ENABLED" {
+					continue
+				}
+				resp.Header[name] = values
+			}
+			resp.Header.Set("ETag", obj.etag)
+			resp.Header.Set("Content-Length", strconv.Itoa(len(obj.data)))
+			resp.ContentLength = int64(len(obj.data))
+			if req.Method == http.MethodGet {
+				resp.Body = io.NopCloser(bytes.NewReader(obj.data))
+			}
+		case req.Method == http.MethodDelete:
+			delete(objects, key)
+			resp.StatusCode = http.StatusNoContent
+		}
+		return resp, nil
+	}
+}
+
+// storedHeader returns the headers of an upload request that S3 keeps with
+// the object and sends back on reads
+func storedHeader(header http.Header) http.Header {
+	stored := http.Header{}
+	for name, values := range header {
+		switch {
+		case name == "Content-Type", name == "Content-Encoding", name == "Content-Language", name == "Expires",
+			strings.HasPrefix(name, "X-Amz-Meta-"), isChecksumHeader(name),
+			name == "X-Amz-Server-Side-Encryption", name == "X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id",
+			name == "X-Amz-Website-Redirect-Location":
+			stored[name] = values
+		}
+	}
+	return stored
+}
+
+// isChecksumHeader reports whether name is a stored x-amz-checksum value,
+// which S3 only returns to reads that enable the checksum mode
+func isChecksumHeader(name string) bool {
+	switch name {
+	case "X-Amz-Checksum-Crc32", "X-Amz-Checksum-Crc32c", "X-Amz-Checksum-Sha1", "X-Amz-Checksum-Sha256":
+		return true
+	}
+	return false
+}
+
+// md5ETag returns the quoted hex MD5 of data, the ETag of a single part upload
+func md5ETag(data []byte) string {
+	sum := md5.Sum(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
 // TestNewS3Client tests the creation of a new S3 client
 func TestNewS3Client(t *testing.T) {
 	tests := []struct {
@@ -81,6 +311,88 @@ func TestNewS3Client(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "Negative single part threshold",
+			config: Config{
+				Region:              "us-west-2",
+				AccessKey:           "test-key",
+				SecretKey:           "test-secret",
+				SinglePartThreshold: -1,
+			},
+			wantErr: true,
+		},
+		{
+			name: "Part size below S3 minimum",
+			config: Config{
+				Region:         "us-west-2",
+				AccessKey:      "test-key",
+				SecretKey:      "test-secret",
+				UploadPartSize: 1024 * 1024,
+			},
+			wantErr: true,
+		},
+		{
+			name: "With upload tuning",
+			config: Config{
+				Region:            "us-west-2",
+				AccessKey:         "test-key",
+				SecretKey:         "test-secret",
+				UploadPartSize:    64 * 1024 * 1024,
+				UploadConcurrency: 8,
+			},
+			wantErr: false,
+		},
+		{
+			name: "With download tuning",
+			config: Config{
+				Region:              "us-west-2",
+				AccessKey:           "test-key",
+				SecretKey:           "test-secret",
+				DownloadPartSize:    16 * 1024 * 1024,
+				DownloadConcurrency: 10,
+			},
+			wantErr: false,
+		},
+		{
+			name: "Negative download concurrency",
+			config: Config{
+				Region:              "us-west-2",
+				AccessKey:           "test-key",
+				SecretKey:           "test-secret",
+				DownloadConcurrency: -1,
+			},
+			wantErr: true,
+		},
+		{
+			name: "Negative download spill threshold",
+			config: Config{
+				Region:                 "us-west-2",
+				AccessKey:              "test-key",
+				SecretKey:              "test-secret",
+				DownloadSpillThreshold: -1,
+			},
+			wantErr: true,
+		},
+		{
+			name: "Negative cache size",
+			config: Config{
+				Region:       "us-west-2",
+				AccessKey:    "test-key",
+				SecretKey:    "test-secret",
+				CacheMaxSize: -1,
+			},
+			wantErr: true,
+		},
+		{
+			name: "Client-side key of wrong length",
+			config: Config{
+				Region:        "us-west-2",
+				AccessKey:     "test-key",
+				SecretKey:     "test-secret",
+				ClientSideKey: []byte("too-short"),
+			},
+			wantErr: true,
+		},
 		{
 			name: "With endpoint",
 			config: Config{
@@ -223,6 +535,219 @@ func TestS3Client_UploadFile(t *testing.T) {
 	}
 }
 
+// TestS3Client_UploadFileThreshold tests uploads around the single part
+// threshold use a plain PutObject below it and the uploader from it on, which
+// switches to a multipart upload once the payload exceeds one part
+func TestS3Client_UploadFileThreshold(t *testing.T) {
+	var apis []string
+	server := memoryServer(map[string]*memoryObject{})
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		query := req.URL.Query()
+		switch {
+		case req.Method == http.MethodPost && query.Has("uploads"):
+			apis = append(apis, "CreateMultipartUpload")
+		case req.Method == http.MethodPut && !query.Has("uploadId"):
+			if strings.Contains(req.Header.Get("User-Agent"), "S3Manager") {
+				apis = append(apis, "PutObject (uploader)")
+			} else {
+				apis = append(apis, "PutObject")
+			}
+		}
+		return server(req)
+	})
+	client.config.SinglePartThreshold = MinPartSize
+
+	tests := []struct {
+		name      string
+		size      int
+		wantAPI   string
+		multipart bool
+	}{
+		{name: "Below threshold", size: int(MinPartSize) - 1, wantAPI: "PutObject"},
+		{name: "At threshold", size: int(MinPartSize), wantAPI: "PutObject (uploader)"},
+		{name: "Just over threshold", size: int(MinPartSize) + 1, wantAPI: "CreateMultipartUpload", multipart: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apis = nil
+			ctx := context.Background()
+			key := fmt.Sprintf("test-threshold-%d.bin", tt.size)
+			data := bytes.Repeat([]byte("x"), tt.size)
+			location, err := client.UploadFile(ctx, testBucket, key, data, &UploadOptions{
+				ContentType: "application/octet-stream",
+				Metadata:    map[string]string{"size": fmt.Sprint(tt.size)},
+			})
+			require.NoError(t, err)
+			assert.Contains(t, location, key)
+			assert.Equal(t, []string{tt.wantAPI}, apis)
+
+			info, err := client.GetFileInfo(ctx, testBucket, key)
+			require.NoError(t, err)
+			assert.Equal(t, int64(tt.size), info.Size)
+			assert.Equal(t, tt.multipart, strings.Contains(info.ETag, "-"))
+		})
+	}
+}
+
+// TestS3Client_UploadStream tests the UploadStream function
+func TestS3Client_UploadStream(t *testing.T) {
+	objects := map[string]*memoryObject{}
+	client := setupFakeClient(t, memoryServer(objects))
+
+	pattern, err := io.ReadAll(io.LimitReader(&patternReader{}, 1024))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		bucket  string
+		key     string
+		reader  io.Reader
+		opts    *UploadOptions
+		want    []byte
+		wantErr bool
+	}{
+		{
+			name:    "Valid stream",
+			bucket:  testBucket,
+			key:     "test-stream.txt",
+			reader:  bytes.NewReader(testFileContent),
+			opts:    nil,
+			want:    testFileContent,
+			wantErr: false,
+		},
+		{
+			name:   "Unknown length stream",
+			bucket: testBucket,
+			key:    "test-stream-unknown.txt",
+			reader: io.LimitReader(&patternReader{}, 1024),
+			opts: &UploadOptions{
+				ContentType: "text/plain",
+			},
+			want:    pattern,
+			wantErr: false,
+		},
+		{
+			name:    "Empty bucket",
+			bucket:  "",
+			key:     "test-stream.txt",
+			reader:  bytes.NewReader(testFileContent),
+			opts:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "Empty key",
+			bucket:  testBucket,
+			key:     "",
+			reader:  bytes.NewReader(testFileContent),
+			opts:    nil,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			location, err := client.UploadStream(ctx, tt.bucket, tt.key, tt.reader, tt.opts)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Empty(t, location)
+			} else {
+				assert.NoError(t, err)
+				assert.NotEmpty(t, location)
+				require.Contains(t, objects, tt.key)
+				assert.Equal(t, tt.want, objects[tt.key].data)
+			}
+		})
+	}
+}
+
+// TestS3Client_UploadStreamPartSize tests per-call part size validation
+func TestS3Client_UploadStreamPartSize(t *testing.T) {
+	client := setupTestClient(t)
+
+	_, err := client.UploadStream(context.Background(), testBucket, "test-part-size.txt",
+		bytes.NewReader(testFileContent), &UploadOptions{PartSize: 1024})
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+}
+
+// TestS3Client_UploadStreamLarge uploads a 100MB synthetic stream with a small
+// part size and checks that the heap never holds anything close to the full body
+func TestS3Client_UploadStreamLarge(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping large upload test in short mode")
+	}
+
+	// Part bodies are counted and discarded, so the fake holds none of them
+	var mu sync.Mutex
+	var received int64
+	parts := 0
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: req}
+		query := req.URL.Query()
+		switch {
+		case query.Has("uploads"):
+			resp.Body = xmlBody("<InitiateMultipartUploadResult><UploadId>up-1</UploadId></InitiateMultipartUploadResult>")
+		case query.Has("partNumber"):
+			n, err := io.Copy(io.Discard, req.Body)
+			if err != nil {
+				return nil, err
+			}
+			mu.Lock()
+			received += n
+			parts++
+			mu.Unlock()
+			resp.Header.Set("ETag", `"part-`+query.Get("partNumber")+`"`)
+		case query.Has("uploadId"):
+			resp.Body = xmlBody(`<CompleteMultipartUploadResult><ETag>"large-20"</ETag></CompleteMultipartUploadResult>`)
+		default:
+			return fakeErrorResponse(req, http.StatusBadRequest, "UnexpectedRequest"), nil
+		}
+		return resp, nil
+	})
+	client.uploader.PartSize = 5 * 1024 * 1024
+	client.uploader.Concurrency = 2
+
+	const size = 100 * 1024 * 1024
+	r := &patternReader{sampleEvery: 8 * 1024 * 1024}
+
+	// Collect garbage left by earlier tests so only the upload is measured
+	runtime.GC()
+
+	ctx := context.Background()
+	location, err := client.UploadStream(ctx, testBucket, "test-stream-large.bin", io.LimitReader(r, size), nil)
+	require.NoError(t, err)
+	require.NotEmpty(t, location)
+
+	assert.Less(t, r.maxHeap, uint64(size/2))
+	assert.Equal(t, int64(size), received)
+	assert.Equal(t, 20, parts)
+}
+
+// patternReader is an endless, non-seekable reader producing a repeating byte
+// pattern. When sampleEvery is set it records the peak heap size while read.
+type patternReader struct {
+	read        int64
+	sampleEvery int64
+	maxHeap     uint64
+}
+
+func (r *patternReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = byte((r.read + int64(i)) % 251)
+	}
+	before := r.read
+	r.read += int64(len(p))
+	if r.sampleEvery > 0 && before/r.sampleEvery != r.read/r.sampleEvery {
+		var m runtime.MemStats
+		runtime.ReadMemStats(&m)
+		if m.HeapAlloc > r.maxHeap {
+			r.maxHeap = m.HeapAlloc
+		}
+	}
+	return len(p), nil
+}
+
 // TestS3Client_DownloadFile tests the DownloadFile function
 func TestS3Client_DownloadFile(t *testing.T) {
 	client := setupTestClient(t)
@@ -487,3 +1012,84 @@ func ExampleS3Client_DownloadFile() {
 
 	fmt.Printf("Downloaded content: %s\n", string(data))
 }
+
+// TestS3Client_GetFileInfoHeaders tests content headers round trip
+func TestS3Client_GetFileInfoHeaders(t *testing.T) {
+	objects := map[string]*memoryObject{}
+	client := setupFakeClient(t, memoryServer(objects))
+	ctx := context.Background()
+
+	expires := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+	_, err := client.UploadFile(ctx, testBucket, testFileName, testFileContent, &UploadOptions{
+		ContentEncoding: "identity",
+		ContentLanguage: "en-US",
+		Expires:         expires,
+	})
+	require.NoError(t, err)
+	require.Contains(t, objects, testFileName)
+	assert.Equal(t, expires.Format(http.TimeFormat), objects[testFileName].header.Get("Expires"))
+
+	info, err := client.GetFileInfo(ctx, testBucket, testFileName)
+	require.NoError(t, err)
+	assert.Equal(t, "identity", info.ContentEncoding)
+	assert.Equal(t, "en-US", info.ContentLanguage)
+	require.NotNil(t, info.Expires)
+	assert.True(t, expires.Equal(*info.Expires))
+}
+
+// TestS3Client_FileExists tests a missing key is not an error but a denied
+// one is
+func TestS3Client_FileExists(t *testing.T) {
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		switch req.URL.Path {
+		case "/" + testBucket + "/missing.txt":
+			return fakeErrorResponse(req, http.StatusNotFound, "NotFound"), nil
+		case "/" + testBucket + "/secret.txt":
+			return fakeErrorResponse(req, http.StatusForbidden, "Forbidden"), nil
+		}
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: req}, nil
+	})
+	ctx := context.Background()
+
+	exists, err := client.FileExists(ctx, testBucket, testFileName)
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	exists, err = client.FileExists(ctx, testBucket, "missing.txt")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	exists, err = client.FileExists(ctx, testBucket, "secret.txt")
+	assert.ErrorIs(t, err, ErrAccessDenied)
+	assert.False(t, exists)
+
+	_, err = client.FileExists(ctx, "", testFileName)
+	assert.ErrorIs(t, err, ErrInvalidBucket)
+}
+
+// TestS3Client_UploadFileResult tests the fields reported by S3 are returned
+func TestS3Client_UploadFileResult(t *testing.T) {
+	expiration := `expiry-date="Fri, 23 Dec 2033 00:00:00 GMT", rule-id="logs"`
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header: http.Header{
+				"Etag":                  []string{`"9b2cf535f27731c974343645a3985328"`},
+				"X-Amz-Version-Id":      []string{"v1"},
+				"X-Amz-Expiration":      []string{expiration},
+				"X-Amz-Checksum-Sha256": []string{ChecksumSHA256.Checksum(testFileContent)},
+			},
+			Body:    http.NoBody,
+			Request: req,
+		}, nil
+	})
+
+	result, err := client.UploadFileResult(context.Background(), testBucket, testFileName, testFileContent, &UploadOptions{ChecksumAlgorithm: ChecksumSHA256})
+	require.NoError(t, err)
+	assert.Equal(t, testFileName, result.Key)
+	assert.NotEmpty(t, result.Location)
+	assert.Equal(t, `"9b2cf535f27731c974343645a3985328"`, result.ETag)
+	assert.Equal(t, "v1", result.VersionID)
+	assert.Equal(t, expiration, result.Expiration)
+	assert.Equal(t, ChecksumSHA256.Checksum(testFileContent), result.ChecksumSHA256)
+}
@@ -0,0 +1,198 @@
+package s3lib
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// ServeObject writes an object to w as the response to a GET or HEAD request,
+// for handlers that proxy S3. Content-Type, Content-Length, ETag,
+// Last-Modified, Content-Disposition, Content-Encoding and Cache-Control are
+// copied from the object. The Range, If-None-Match, If-Modified-Since,
+// If-Match and If-Unmodified-Since headers of r are forwarded to S3, so
+// partial content is answered with 206 and unchanged objects with 304
+// without their body being fetched. Missing objects are answered with 404,
+// denied access with 403 and other failures with 502. Objects encrypted with
+// Config.ClientSideKey are decrypted as a whole and served with
+// http.ServeContent. The response has been written when ServeObject returns;
+// the error is for logging.
+func (c *S3Client) ServeObject(w http.ResponseWriter, r *http.Request, bucket, key string) error {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return fmt.Errorf("%w: method %s not allowed", ErrInvalidOptions, r.Method)
+	}
+	if bucket == "" {
+		return serveError(w, ErrInvalidBucket)
+	}
+	key, err := c.resolveKey(key)
+	if err != nil {
+		return serveError(w, err)
+	}
+	ctx := r.Context()
+
+	input := &s3.GetObjectInput{
+		Bucket:            aws.String(bucket),
+		Key:               aws.String(key),
+		Range:             headerValue(r, "Range"),
+		IfMatch:           headerValue(r, "If-Match"),
+		IfNoneMatch:       headerValue(r, "If-None-Match"),
+		IfModifiedSince:   headerTime(r, "If-Modified-Since"),
+		IfUnmodifiedSince: headerTime(r, "If-Unmodified-Since"),
+	}
+
+	// Ranged HEAD requests fall through to a GetObject, whose body is not
+	// read, because HeadObject does not report the Content-Range
+	if r.Method == http.MethodHead && input.Range == nil {
+		head, err := c.s3Client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+			Bucket:            input.Bucket,
+			Key:               input.Key,
+			IfMatch:           input.IfMatch,
+			IfNoneMatch:       input.IfNoneMatch,
+			IfModifiedSince:   input.IfModifiedSince,
+			IfUnmodifiedSince: input.IfUnmodifiedSince,
+		})
+		if err != nil {
+			return serveError(w, err)
+		}
+		// The decrypted size of client-side encrypted objects is only known
+		// once they are downloaded
+		if metadataValue(head.Metadata, clientSideEncryptionMetaKey) == "" {
+			setObjectHeaders(w.Header(), head.ContentType, head.ContentDisposition, head.ContentEncoding, head.CacheControl, head.ETag, head.LastModified)
+			setLengthHeaders(w.Header(), head.ContentLength, nil)
+			w.WriteHeader(http.StatusOK)
+			return nil
+		}
+	}
+
+	out, err := c.s3Client.GetObjectWithContext(ctx, input)
+	if err != nil {
+		return serveError(w, err)
+	}
+	encrypted := metadataValue(out.Metadata, clientSideEncryptionMetaKey) != ""
+	if encrypted && input.Range != nil {
+		// Ranges of the ciphertext cannot be decrypted, so the whole object
+		// is fetched and http.ServeContent serves the range instead
+		out.Body.Close()
+		input.Range = nil
+		input.IfMatch = out.ETag
+		if out, err = c.s3Client.GetObjectWithContext(ctx, input); err != nil {
+			return serveError(w, err)
+		}
+	}
+	defer out.Body.Close()
+
+	setObjectHeaders(w.Header(), out.ContentType, out.ContentDisposition, out.ContentEncoding, out.CacheControl, out.ETag, out.LastModified)
+	if encrypted {
+		data, err := io.ReadAll(&contextReader{ctx: ctx, rc: out.Body})
+		if err == nil {
+			data, err = decryptClientSide(c.config.ClientSideKey, data, out.Metadata)
+		}
+		if err != nil {
+			w.Header().Del("ETag")
+			w.Header().Del("Last-Modified")
+			http.Error(w, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
+			return err
+		}
+		http.ServeContent(w, r, "", aws.TimeValue(out.LastModified), bytes.NewReader(data))
+		return nil
+	}
+
+	setLengthHeaders(w.Header(), out.ContentLength, out.ContentRange)
+	w.WriteHeader(servedStatus(out.ContentRange))
+	if r.Method == http.MethodHead {
+		return nil
+	}
+	if n, err := io.Copy(w, &contextReader{ctx: ctx, rc: out.Body}); err != nil {
+		return fmt.Errorf("%w after %d bytes: %w", ErrDownloadInterrupted, n, err)
+	}
+	return nil
+}
+
+// serveError answers a failed ServeObject request with the status matching
+// err and returns err mapped to the library's error values. A 304 is not an
+// error and returns nil.
+func serveError(w http.ResponseWriter, err error) error {
+	err = downloadError(err, nil, "failed to serve object")
+
+	status := http.StatusBadGateway
+	var reqErr awserr.RequestFailure
+	switch {
+	case errors.Is(err, ErrNotModified):
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	case errors.Is(err, ErrFileNotFound), errors.Is(err, ErrInvalidBucket), errors.Is(err, ErrInvalidKey):
+		status = http.StatusNotFound
+	case errors.As(err, &reqErr):
+		switch reqErr.StatusCode() {
+		case http.StatusForbidden, http.StatusPreconditionFailed, http.StatusRequestedRangeNotSatisfiable:
+			status = reqErr.StatusCode()
+		}
+	}
+	http.Error(w, http.StatusText(status), status)
+	return err
+}
+
+// servedStatus returns 206 for a ranged response and 200 otherwise
+func servedStatus(contentRange *string) int {
+	if aws.StringValue(contentRange) != "" {
+		return http.StatusPartialContent
+	}
+	return http.StatusOK
+}
+
+// setObjectHeaders copies the object's representation headers to h
+func setObjectHeaders(h http.Header, contentType, contentDisposition, contentEncoding, cacheControl, etag *string, lastModified *time.Time) {
+	for name, v := range map[string]*string{
+		"Content-Type":        contentType,
+		"Content-Disposition": contentDisposition,
+		"Content-Encoding":    contentEncoding,
+		"Cache-Control":       cacheControl,
+		"ETag":                etag,
+	} {
+		if aws.StringValue(v) != "" {
+			h.Set(name, *v)
+		}
+	}
+	if lastModified != nil {
+		h.Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+	h.Set("Accept-Ranges", "bytes")
+}
+
+// setLengthHeaders sets Content-Length and, for ranged responses, Content-Range
+func setLengthHeaders(h http.Header, contentLength *int64, contentRange *string) {
+	if contentLength != nil {
+		h.Set("Content-Length", strconv.FormatInt(*contentLength, 10))
+	}
+	if aws.StringValue(contentRange) != "" {
+		h.Set("Content-Range", *contentRange)
+	}
+}
+
+// headerValue returns the named request header, or nil if it is absent
+func headerValue(r *http.Request, name string) *string {
+	if v := r.Header.Get(name); v != "" {
+		return aws.String(v)
+	}
+	return nil
+}
+
+// headerTime returns the named request header parsed as an HTTP date, or nil
+// if it is absent or malformed, which HTTP says to ignore
+func headerTime(r *http.Request, name string) *time.Time {
+	t, err := http.ParseTime(r.Header.Get(name))
+	if err != nil {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,147 @@
+package s3lib
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestS3Client_ServeObject tests headers are copied from the object and
+// request headers are forwarded to S3
+func TestS3Client_ServeObject(t *testing.T) {
+	var got *http.Request
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		got = req
+		return &http.Response{
+			StatusCode: http.StatusPartialContent,
+			Header: http.Header{
+				"Content-Type":        []string{"text/plain"},
+				"Content-Length":      []string{"5"},
+				"Content-Range":       []string{"bytes 0-4/13"},
+				"Content-Disposition": []string{`attachment; filename="hello.txt"`},
+				"Etag":                []string{`"abc"`},
+				"Last-Modified":       []string{"Mon, 02 Jan 2006 15:04:05 GMT"},
+			},
+			ContentLength: 5,
+			Body:          io.NopCloser(bytes.NewReader(testFileContent[:5])),
+			Request:       req,
+		}, nil
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/files/"+testFileName, nil)
+	req.Header.Set("Range", "bytes=0-4")
+	req.Header.Set("If-Match", `"abc"`)
+	rec := httptest.NewRecorder()
+
+	require.NoError(t, client.ServeObject(rec, req, testBucket, testFileName))
+	assert.Equal(t, "bytes=0-4", got.Header.Get("Range"))
+	assert.Equal(t, `"abc"`, got.Header.Get("If-Match"))
+
+	assert.Equal(t, http.StatusPartialContent, rec.Code)
+	assert.Equal(t, "text/plain", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "5", rec.Header().Get("Content-Length"))
+	assert.Equal(t, "bytes 0-4/13", rec.Header().Get("Content-Range"))
+	assert.Equal(t, `attachment; filename="hello.txt"`, rec.Header().Get("Content-Disposition"))
+	assert.Equal(t, `"abc"`, rec.Header().Get("ETag"))
+	assert.Equal(t, "Mon, 02 Jan 2006 15:04:05 GMT", rec.Header().Get("Last-Modified"))
+	assert.Equal(t, testFileContent[:5], rec.Body.Bytes())
+}
+
+// TestS3Client_ServeObjectNotModified tests a 304 from S3 is passed on
+// without a body
+func TestS3Client_ServeObjectNotModified(t *testing.T) {
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		assert.Equal(t, `"abc"`, req.Header.Get("If-None-Match"))
+		return &http.Response{
+			StatusCode: http.StatusNotModified,
+			Header:     http.Header{},
+			Body:       http.NoBody,
+			Request:    req,
+		}, nil
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("If-None-Match", `"abc"`)
+	rec := httptest.NewRecorder()
+
+	require.NoError(t, client.ServeObject(rec, req, testBucket, testFileName))
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Empty(t, rec.Body.Bytes())
+}
+
+// TestS3Client_ServeObjectMethod tests only GET and HEAD are served
+func TestS3Client_ServeObjectMethod(t *testing.T) {
+	client := setupTestClient(t)
+	rec := httptest.NewRecorder()
+
+	err := client.ServeObject(rec, httptest.NewRequest(http.MethodPost, "/", nil), testBucket, testFileName)
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	assert.Equal(t, "GET, HEAD", rec.Header().Get("Allow"))
+}
+
+// TestServeError tests the status written for each failure
+func TestServeError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantErr    error
+	}{
+		{
+			name:       "Not found",
+			err:        awserr.NewRequestFailure(awserr.New("NoSuchKey", "not found", nil), http.StatusNotFound, "req-id"),
+			wantStatus: http.StatusNotFound,
+			wantErr:    ErrFileNotFound,
+		},
+		{
+			name:       "Access denied",
+			err:        awserr.NewRequestFailure(awserr.New("AccessDenied", "denied", nil), http.StatusForbidden, "req-id"),
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "Not modified",
+			err:        awserr.NewRequestFailure(awserr.New("NotModified", "not modified", nil), http.StatusNotModified, "req-id"),
+			wantStatus: http.StatusNotModified,
+		},
+		{
+			name:       "Unsatisfiable range",
+			err:        awserr.NewRequestFailure(awserr.New("InvalidRange", "bad range", nil), http.StatusRequestedRangeNotSatisfiable, "req-id"),
+			wantStatus: http.StatusRequestedRangeNotSatisfiable,
+		},
+		{
+			name:       "Server error",
+			err:        awserr.NewRequestFailure(awserr.New("InternalError", "oops", nil), http.StatusInternalServerError, "req-id"),
+			wantStatus: http.StatusBadGateway,
+		},
+		{
+			name:       "Network error",
+			err:        context.DeadlineExceeded,
+			wantStatus: http.StatusBadGateway,
+			wantErr:    context.DeadlineExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			err := serveError(rec, tt.err)
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			if tt.wantStatus == http.StatusNotModified {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package s3lib
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// DownloadedObject is the content of an object downloaded by DownloadAuto.
+// Content up to Config.DownloadSpillThreshold is held in memory; anything
+// larger is held in a temporary file named by Path. It reads and seeks like
+// a file. Cleanup, or Close, releases it and removes the temporary file.
+type DownloadedObject struct {
+	// Info describes the object as reported by S3
+	Info *FileInfo
+
+	// Size is the length of the content, after any decompression or
+	// decryption
+	Size int64
+
+	// Path is the temporary file holding the content, or empty when it is
+	// held in memory
+	Path string
+
+	r    io.ReadSeeker
+	file *os.File
+
+	once sync.Once
+	err  error
+}
+
+// Read implements io.Reader
+func (o *DownloadedObject) Read(p []byte) (int, error) {
+	if o.r == nil {
+		return 0, os.ErrClosed
+	}
+	return o.r.Read(p)
+}
+
+// Seek implements io.Seeker
+func (o *DownloadedObject) Seek(offset int64, whence int) (int64, error) {
+	if o.r == nil {
+		return 0, os.ErrClosed
+	}
+	return o.r.Seek(offset, whence)
+}
+
+// Cleanup releases the content and removes the temporary file, if any. It is
+// safe to call more than once; later calls return the first call's result.
+func (o *DownloadedObject) Cleanup() error {
+	o.once.Do(func() {
+		o.r = nil
+		if o.file == nil {
+			return
+		}
+		o.err = o.file.Close()
+		if err := os.Remove(o.Path); err != nil && o.err == nil {
+			o.err = err
+		}
+	})
+	return o.err
+}
+
+// Close implements io.Closer by calling Cleanup
+func (o *DownloadedObject) Close() error {
+	return o.Cleanup()
+}
+
+// DownloadAuto downloads an object into memory if its content is at most
+// Config.DownloadSpillThreshold bytes and into a temporary file otherwise,
+// so unexpectedly large objects do not exhaust memory. The content is
+// decoded as by DownloadStreamWithOptions, and gzip encoded objects
+// downloaded with AutoDecompress are spilled according to their decompressed
+// size. The caller must call Cleanup on the result.
+func (c *S3Client) DownloadAuto(ctx context.Context, bucket, key string, opts *DownloadOptions) (*DownloadedObject, error) {
+	body, info, err := c.DownloadStreamWithOptions(ctx, bucket, key, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	threshold := c.config.downloadSpillThreshold()
+	data, err := io.ReadAll(io.LimitReader(body, threshold+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download file: %w", err)
+	}
+	if int64(len(data)) <= threshold {
+		return &DownloadedObject{Info: info, Size: int64(len(data)), r: bytes.NewReader(data)}, nil
+	}
+
+	f, err := os.CreateTemp("", "s3lib-download-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	obj := &DownloadedObject{Info: info, Path: f.Name(), r: f, file: f}
+
+	written, err := f.Write(data)
+	n := int64(written)
+	if err == nil {
+		var copied int64
+		copied, err = io.Copy(f, body)
+		n += copied
+	}
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		obj.Cleanup()
+		return nil, fmt.Errorf("%w after %d bytes: %w", ErrDownloadInterrupted, n, err)
+	}
+	obj.Size = n
+	return obj, nil
+}
@@ -0,0 +1,69 @@
+package s3lib
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestS3Client_DownloadAuto tests small objects stay in memory and larger
+// ones are spilled to a temporary file that Cleanup removes
+func TestS3Client_DownloadAuto(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("In memory", func(t *testing.T) {
+		client := setupFakeClient(t, objectServer(testFileContent))
+		obj, err := client.DownloadAuto(ctx, testBucket, testFileName, nil)
+		require.NoError(t, err)
+		defer obj.Cleanup()
+
+		assert.Empty(t, obj.Path)
+		assert.Equal(t, int64(len(testFileContent)), obj.Size)
+		got, err := io.ReadAll(obj)
+		require.NoError(t, err)
+		assert.Equal(t, testFileContent, got)
+	})
+
+	t.Run("Spilled", func(t *testing.T) {
+		data := bytes.Repeat([]byte("s"), 100)
+		client := setupFakeClient(t, objectServer(data))
+		client.config.DownloadSpillThreshold = 10
+
+		obj, err := client.DownloadAuto(ctx, testBucket, testFileName, nil)
+		require.NoError(t, err)
+		require.NotEmpty(t, obj.Path)
+		assert.Equal(t, int64(len(data)), obj.Size)
+
+		_, err = obj.Seek(90, io.SeekStart)
+		require.NoError(t, err)
+		got, err := io.ReadAll(obj)
+		require.NoError(t, err)
+		assert.Equal(t, data[90:], got)
+
+		require.NoError(t, obj.Cleanup())
+		_, err = os.Stat(obj.Path)
+		assert.True(t, os.IsNotExist(err))
+	})
+}
+
+// TestDownloadedObject_Cleanup tests Cleanup removes the file once and can
+// be called again
+func TestDownloadedObject_Cleanup(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "spill-*")
+	require.NoError(t, err)
+	obj := &DownloadedObject{Path: f.Name(), r: f, file: f}
+
+	require.NoError(t, obj.Cleanup())
+	require.NoError(t, obj.Cleanup())
+	require.NoError(t, obj.Close())
+	_, err = os.Stat(f.Name())
+	assert.True(t, os.IsNotExist(err))
+
+	_, err = obj.Read(make([]byte, 1))
+	assert.ErrorIs(t, err, os.ErrClosed)
+}
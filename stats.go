@@ -0,0 +1,96 @@
+package s3lib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// PrefixStats summarizes the objects under a prefix
+type PrefixStats struct {
+	Count      int64 `json:"count"`
+	TotalBytes int64 `json:"total_bytes"`
+
+	// LargestObject and OldestObject are nil when the prefix is empty
+	LargestObject *FileInfo `json:"largest_object,omitempty"`
+	OldestObject  *FileInfo `json:"oldest_object,omitempty"`
+
+	// ByStorageClass breaks Count and TotalBytes down by storage class when
+	// PrefixStatsOptions.ByStorageClass is set
+	ByStorageClass map[StorageClass]*StorageClassStats `json:"by_storage_class,omitempty"`
+
+	// Partial is set when the context ended before every object was counted
+	Partial bool `json:"partial,omitempty"`
+}
+
+// StorageClassStats counts the objects of one storage class
+type StorageClassStats struct {
+	Count      int64 `json:"count"`
+	TotalBytes int64 `json:"total_bytes"`
+}
+
+// PrefixStatsOptions represents optional parameters for GetPrefixStats
+type PrefixStatsOptions struct {
+	// ByStorageClass fills in PrefixStats.ByStorageClass
+	ByStorageClass bool
+}
+
+// GetPrefixStats counts the objects under prefix and sums their sizes.
+// Listing pages are streamed, so memory use does not grow with the number
+// of objects.
+func (c *S3Client) GetPrefixStats(ctx context.Context, bucket, prefix string) (*PrefixStats, error) {
+	return c.GetPrefixStatsWithOptions(ctx, bucket, prefix, nil)
+}
+
+// GetPrefixStatsWithOptions computes the stats of GetPrefixStats with
+// options. If ctx is cancelled or its deadline passes, the stats counted so
+// far are returned, marked Partial, along with the context's error.
+func (c *S3Client) GetPrefixStatsWithOptions(ctx context.Context, bucket, prefix string, opts *PrefixStatsOptions) (*PrefixStats, error) {
+	stats := &PrefixStats{}
+	if opts != nil && opts.ByStorageClass {
+		stats.ByStorageClass = make(map[StorageClass]*StorageClassStats)
+	}
+
+	it := c.ListFilesIter(ctx, bucket, prefix, nil)
+	for it.Next() {
+		stats.add(it.File())
+	}
+	if err := it.Err(); err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			stats.Partial = true
+			return stats, fmt.Errorf("prefix stats incomplete after %d objects: %w", stats.Count, err)
+		}
+		return nil, err
+	}
+	return stats, nil
+}
+
+// add counts one object
+func (s *PrefixStats) add(f FileInfo) {
+	s.Count++
+	s.TotalBytes += f.Size
+	if s.LargestObject == nil || f.Size > s.LargestObject.Size {
+		largest := f
+		s.LargestObject = &largest
+	}
+	if s.OldestObject == nil || f.LastModified.Before(s.OldestObject.LastModified) {
+		oldest := f
+		s.OldestObject = &oldest
+	}
+
+	if s.ByStorageClass == nil {
+		return
+	}
+	class := StorageClass(f.StorageClass)
+	if class == "" {
+		// Some backends leave out the class of STANDARD objects
+		class = StorageClassStandard
+	}
+	classStats := s.ByStorageClass[class]
+	if classStats == nil {
+		classStats = &StorageClassStats{}
+		s.ByStorageClass[class] = classStats
+	}
+	classStats.Count++
+	classStats.TotalBytes += f.Size
+}
@@ -0,0 +1,68 @@
+package s3lib
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPrefixStatsAdd tests totals, the largest and oldest objects and the
+// storage class breakdown
+func TestPrefixStatsAdd(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	stats := &PrefixStats{ByStorageClass: make(map[StorageClass]*StorageClassStats)}
+	stats.add(FileInfo{Key: "a", Size: 10, LastModified: base.Add(time.Hour)})
+	stats.add(FileInfo{Key: "b", Size: 30, LastModified: base.Add(2 * time.Hour), StorageClass: "STANDARD"})
+	stats.add(FileInfo{Key: "c", Size: 5, LastModified: base, StorageClass: "GLACIER"})
+
+	assert.Equal(t, int64(3), stats.Count)
+	assert.Equal(t, int64(45), stats.TotalBytes)
+	assert.Equal(t, "b", stats.LargestObject.Key)
+	assert.Equal(t, "c", stats.OldestObject.Key)
+	assert.Equal(t, &StorageClassStats{Count: 2, TotalBytes: 40}, stats.ByStorageClass[StorageClassStandard])
+	assert.Equal(t, &StorageClassStats{Count: 1, TotalBytes: 5}, stats.ByStorageClass[StorageClassGlacier])
+}
+
+// TestS3Client_GetPrefixStats tests every page is counted
+func TestS3Client_GetPrefixStats(t *testing.T) {
+	var queries []url.Values
+	client := setupFakeClient(t, listServer(2500, &queries))
+
+	stats, err := client.GetPrefixStats(context.Background(), testBucket, "")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2500), stats.Count)
+	assert.Equal(t, int64(2500), stats.TotalBytes)
+	assert.Nil(t, stats.ByStorageClass)
+	assert.False(t, stats.Partial)
+}
+
+// TestS3Client_GetPrefixStatsCancelled tests the stats counted before the
+// context ended are returned with its error
+func TestS3Client_GetPrefixStatsCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var queries []url.Values
+	serve := listServer(2500, &queries)
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		if len(queries) == 1 {
+			cancel()
+		}
+		return serve(req)
+	})
+
+	stats, err := client.GetPrefixStats(ctx, testBucket, "")
+	assert.ErrorIs(t, err, context.Canceled)
+	require.NotNil(t, stats)
+	assert.True(t, stats.Partial)
+	assert.Equal(t, int64(1000), stats.Count)
+}
+
+// TestS3Client_GetPrefixStatsValidation tests an invalid bucket is rejected
+func TestS3Client_GetPrefixStatsValidation(t *testing.T) {
+	_, err := setupTestClient(t).GetPrefixStats(context.Background(), "", "")
+	assert.ErrorIs(t, err, ErrInvalidBucket)
+}
@@ -0,0 +1,73 @@
+package s3lib
+
+import "fmt"
+
+// StorageClass is the S3 storage class an object is stored in
+type StorageClass string
+
+// Storage classes known to the library
+const (
+	StorageClassStandard           StorageClass = "STANDARD"
+	StorageClassReducedRedundancy  StorageClass = "REDUCED_REDUNDANCY"
+	StorageClassStandardIA         StorageClass = "STANDARD_IA"
+	StorageClassOneZoneIA          StorageClass = "ONEZONE_IA"
+	StorageClassIntelligentTiering StorageClass = "INTELLIGENT_TIERING"
+	StorageClassGlacier            StorageClass = "GLACIER"
+	StorageClassGlacierIR          StorageClass = "GLACIER_IR"
+	StorageClassDeepArchive        StorageClass = "DEEP_ARCHIVE"
+	StorageClassOutposts           StorageClass = "OUTPOSTS"
+	StorageClassSnow               StorageClass = "SNOW"
+	StorageClassExpressOneZone     StorageClass = "EXPRESS_ONEZONE"
+)
+
+// valid reports whether the storage class is one the library knows
+func (s StorageClass) valid() bool {
+	switch s {
+	case StorageClassStandard, StorageClassReducedRedundancy, StorageClassStandardIA,
+		StorageClassOneZoneIA, StorageClassIntelligentTiering, StorageClassGlacier,
+		StorageClassGlacierIR, StorageClassDeepArchive, StorageClassOutposts,
+		StorageClassSnow, StorageClassExpressOneZone:
+		return true
+	}
+	return false
+}
+
+// ACL is a canned access control list applied to an object
+type ACL string
+
+// Canned ACLs known to the library
+const (
+	ACLPrivate                ACL = "private"
+	ACLPublicRead             ACL = "public-read"
+	ACLPublicReadWrite        ACL = "public-read-write"
+	ACLAuthenticatedRead      ACL = "authenticated-read"
+	ACLAWSExecRead            ACL = "aws-exec-read"
+	ACLBucketOwnerRead        ACL = "bucket-owner-read"
+	ACLBucketOwnerFullControl ACL = "bucket-owner-full-control"
+)
+
+// valid reports whether the ACL is one the library knows
+func (a ACL) valid() bool {
+	switch a {
+	case ACLPrivate, ACLPublicRead, ACLPublicReadWrite, ACLAuthenticatedRead,
+		ACLAWSExecRead, ACLBucketOwnerRead, ACLBucketOwnerFullControl:
+		return true
+	}
+	return false
+}
+
+// validateStorageClassAndACL checks the storage class and ACL are known
+// values. allowUnknown skips the check so values added to S3 after this
+// library was released can still be used.
+func validateStorageClassAndACL(class StorageClass, acl ACL, allowUnknown bool) error {
+	if allowUnknown {
+		return nil
+	}
+	if class != "" && !class.valid() {
+		return fmt.Errorf("%w: unknown storage class %q", ErrInvalidOptions, class)
+	}
+	if acl != "" && !acl.valid() {
+		return fmt.Errorf("%w: unknown ACL %q", ErrInvalidOptions, acl)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package s3lib
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestValidateStorageClassAndACL tests known values, typos and the escape hatch
+func TestValidateStorageClassAndACL(t *testing.T) {
+	tests := []struct {
+		name         string
+		class        StorageClass
+		acl          ACL
+		allowUnknown bool
+		wantErr      bool
+	}{
+		{name: "Unset"},
+		{name: "Known values", class: StorageClassGlacierIR, acl: ACLBucketOwnerFullControl},
+		{name: "Storage class typo", class: "STANDARD_AI", wantErr: true},
+		{name: "ACL typo", acl: "public_read", wantErr: true},
+		{name: "Lowercase storage class", class: "standard", wantErr: true},
+		{name: "Unknown values allowed", class: "FUTURE_CLASS", acl: "future-acl", allowUnknown: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateStorageClassAndACL(tt.class, tt.acl, tt.allowUnknown)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidOptions)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+// TestS3Client_UploadFileInvalidStorageClass tests typos are rejected before
+// any request is made
+func TestS3Client_UploadFileInvalidStorageClass(t *testing.T) {
+	client := setupTestClient(t)
+
+	_, err := client.UploadFile(context.Background(), testBucket, testFileName, testFileContent, &UploadOptions{StorageClass: "STANDARD_AI"})
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+}
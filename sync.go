@@ -0,0 +1,882 @@
+package s3lib
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// SyncOptions represents optional parameters for operations that transfer a
+// whole prefix between S3 and a local directory
+type SyncOptions struct {
+	// Concurrency is the number of files transferred in parallel (default 5)
+	Concurrency int
+	// Include limits the transfer to files whose path relative to the prefix
+	// matches one of these glob patterns. Patterns without a slash match the
+	// base name.
+	Include []string
+	// Exclude skips files whose relative path matches one of these patterns
+	Exclude []string
+	// DownloadOptions are applied to every downloaded object
+	DownloadOptions *DownloadOptions
+	// UploadOptions are applied to every uploaded file
+	UploadOptions *UploadOptions
+
+	// CompareChecksum detects changed files by their content rather than
+	// by size and modification time. Files of the same size are hashed and
+	// compared with the object's ETag, or with the SHA-256 the library
+	// records on objects it syncs this way.
+	CompareChecksum bool
+	// Delete removes files at the destination that no longer exist at the
+	// source. Files excluded by Include or Exclude are never deleted.
+	Delete bool
+	// DryRun reports what a sync would change without changing anything
+	DryRun bool
+	// BandwidthLimit caps the transfers of a sync at the given bytes per
+	// second in total, across all of its concurrent files
+	BandwidthLimit int64
+}
+
+// validate checks the options shared by the sync operations
+func (o *SyncOptions) validate() error {
+	if err := validatePatterns(o.Include); err != nil {
+		return err
+	}
+	if err := validatePatterns(o.Exclude); err != nil {
+		return err
+	}
+	if o.Concurrency < 0 {
+		return fmt.Errorf("%w: Concurrency must not be negative", ErrInvalidOptions)
+	}
+	if o.BandwidthLimit < 0 {
+		return fmt.Errorf("%w: BandwidthLimit must not be negative", ErrInvalidOptions)
+	}
+	if err := o.UploadOptions.validate(); err != nil {
+		return err
+	}
+	return o.DownloadOptions.validate()
+}
+
+// SyncAction is a change made by a sync
+type SyncAction string
+
+// Sync actions
+const (
+	SyncUpload   SyncAction = "upload"
+	SyncDownload SyncAction = "download"
+	SyncCopy     SyncAction = "copy"
+	SyncDelete   SyncAction = "delete"
+)
+
+// SyncChange is a file a sync transferred or deleted
+type SyncChange struct {
+	Action    SyncAction `json:"action"`
+	Key       string     `json:"key"`
+	LocalPath string     `json:"local_path,omitempty"`
+	Source    string     `json:"source,omitempty"` // The key copied, when mirroring
+}
+
+// SyncError reports a file a sync failed to transfer or delete
+type SyncError struct {
+	Key       string `json:"key"`
+	LocalPath string `json:"local_path,omitempty"`
+	Err       error  `json:"-"`
+}
+
+func (e SyncError) Error() string {
+	if e.LocalPath != "" {
+		return fmt.Sprintf("%s (%s): %v", e.Key, e.LocalPath, e.Err)
+	}
+	return fmt.Sprintf("%s: %v", e.Key, e.Err)
+}
+
+func (e SyncError) Unwrap() error {
+	return e.Err
+}
+
+// SyncReport summarizes a sync. On a dry run the counts and changes are
+// those the sync would have made.
+type SyncReport struct {
+	Uploaded   int `json:"uploaded"`
+	Downloaded int `json:"downloaded"`
+	Copied     int `json:"copied"`
+	Skipped    int `json:"skipped"`
+	Deleted    int `json:"deleted"`
+
+	// Changes lists every transfer and delete in path order
+	Changes []SyncChange `json:"changes"`
+	// Errors are the files that failed; they are not counted above
+	Errors []SyncError `json:"errors"`
+}
+
+// SyncResult represents the outcome of transferring a single file
+type SyncResult struct {
+	Key       string `json:"key"`
+	LocalPath string `json:"local_path"`
+	Bytes     int64  `json:"bytes"`
+	Err       error  `json:"-"`
+}
+
+// DownloadPrefix downloads every object under prefix into localDir,
+// preserving the key hierarchy below prefix and creating directories as
+// needed. A prefix without a trailing slash is treated as a directory, as in
+// UploadDirectory. Keys that would resolve outside localDir, such as ones
+// containing "../" or crossing a symlinked directory, are reported with
+// ErrInvalidKey and never written. A
+// failed file does not abort the run; check Err on each result.
+func (c *S3Client) DownloadPrefix(ctx context.Context, bucket, prefix, localDir string, opts *SyncOptions) ([]SyncResult, error) {
+	if bucket == "" {
+		return nil, ErrInvalidBucket
+	}
+	if opts == nil {
+		opts = &SyncOptions{}
+	}
+	if err := validatePatterns(opts.Include); err != nil {
+		return nil, err
+	}
+	if err := validatePatterns(opts.Exclude); err != nil {
+		return nil, err
+	}
+	if localDir == "" {
+		return nil, fmt.Errorf("%w: empty local directory", ErrInvalidOptions)
+	}
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	files, err := c.ListFiles(ctx, bucket, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []SyncResult
+	for _, f := range files {
+		rel := strings.TrimPrefix(f.Key, prefix)
+		if rel == "" || strings.HasSuffix(rel, "/") {
+			// Directory marker objects have no content to write
+			continue
+		}
+		if len(opts.Include) > 0 && !matchesAny(opts.Include, rel) {
+			continue
+		}
+		if matchesAny(opts.Exclude, rel) {
+			continue
+		}
+
+		result := SyncResult{Key: f.Key}
+		result.LocalPath, result.Err = syncTarget(localDir, rel)
+		results = append(results, result)
+	}
+
+	downloadOpts := DownloadOptions{}
+	if opts.DownloadOptions != nil {
+		downloadOpts = *opts.DownloadOptions
+	}
+	downloadOpts.CreateDirs = true
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				r := &results[idx]
+				r.Bytes, r.Err = c.DownloadToFileWithOptions(ctx, bucket, r.Key, r.LocalPath, &downloadOpts)
+			}
+		}()
+	}
+
+	for i := range results {
+		if results[i].Err != nil {
+			continue
+		}
+		if ctx.Err() != nil {
+			results[i].Err = ctx.Err()
+			continue
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results, ctx.Err()
+}
+
+// localFile is a regular file found under a directory being synced
+type localFile struct {
+	rel     string // slash-separated path relative to the directory
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// walkLocal returns the regular files under dir that pass the include and
+// exclude patterns, in path order. Symlinks are skipped. Files that cannot
+// be read are returned as SyncErrors.
+func walkLocal(dir string, include, exclude []string) ([]localFile, []SyncError, error) {
+	var files []localFile
+	var failed []SyncError
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			if p == dir {
+				return walkErr
+			}
+			failed = append(failed, SyncError{LocalPath: p, Err: walkErr})
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if len(include) > 0 && !matchesAny(include, rel) {
+			return nil
+		}
+		if matchesAny(exclude, rel) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			failed = append(failed, SyncError{LocalPath: p, Err: err})
+			return nil
+		}
+		files = append(files, localFile{rel: rel, path: p, size: info.Size(), modTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to walk local directory: %w", err)
+	}
+	return files, failed, nil
+}
+
+// listRemote returns the objects under prefix, which must be empty or end
+// in "/", that pass the include and exclude patterns, keyed by their path
+// relative to prefix. Folder placeholders are left out.
+func (c *S3Client) listRemote(ctx context.Context, bucket, prefix string, include, exclude []string) (map[string]FileInfo, error) {
+	remote := make(map[string]FileInfo)
+	it := c.ListFilesIter(ctx, bucket, prefix, nil)
+	for it.Next() {
+		f := it.File()
+		rel := strings.TrimPrefix(f.Key, prefix)
+		if rel == "" || strings.HasSuffix(rel, "/") {
+			continue
+		}
+		if len(include) > 0 && !matchesAny(include, rel) {
+			continue
+		}
+		if matchesAny(exclude, rel) {
+			continue
+		}
+		remote[rel] = f
+	}
+	return remote, it.Err()
+}
+
+// syncTask is a file a sync may transfer
+type syncTask struct {
+	local  localFile
+	key    string
+	remote FileInfo // the object, when downloading or mirroring
+	verify bool     // compare content before transferring
+
+	transfer bool // set once the file is known to differ
+	err      error
+}
+
+// SyncDirectoryToBucket makes prefix mirror localDir, like rsync: files that
+// are new or changed are uploaded concurrently and, with opts.Delete, keys
+// whose file no longer exists locally are deleted. The remote side is listed
+// once. A file is changed when its size differs from the object's, or its
+// modification time is after the object's upload; with opts.CompareChecksum
+// its content is compared instead. A failed file does not abort the sync and
+// is reported in the report's Errors; nothing is deleted if the context ends
+// before the uploads finish.
+func (c *S3Client) SyncDirectoryToBucket(ctx context.Context, localDir, bucket, prefix string, opts *SyncOptions) (*SyncReport, error) {
+	if bucket == "" {
+		return nil, ErrInvalidBucket
+	}
+	if err := c.checkClientSideKey(); err != nil {
+		return nil, err
+	}
+	if opts == nil {
+		opts = &SyncOptions{}
+	}
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	if localDir == "" {
+		return nil, fmt.Errorf("%w: empty local directory", ErrInvalidOptions)
+	}
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	files, failed, err := walkLocal(localDir, opts.Include, opts.Exclude)
+	if err != nil {
+		return nil, err
+	}
+	remote, err := c.listRemote(ctx, bucket, prefix, opts.Include, opts.Exclude)
+	if err != nil {
+		return nil, err
+	}
+	report := &SyncReport{Changes: []SyncChange{}, Errors: []SyncError{}}
+	for i := range failed {
+		if rel, err := filepath.Rel(localDir, failed[i].LocalPath); err == nil {
+			failed[i].Key = prefix + filepath.ToSlash(rel)
+		}
+	}
+	report.Errors = append(report.Errors, failed...)
+
+	// Compressed uploads are stored at a different size, so only their
+	// modification time or recorded checksum can be compared
+	compressed := opts.UploadOptions != nil && opts.UploadOptions.Compress
+	tasks := make([]syncTask, len(files))
+	for i, f := range files {
+		tasks[i] = syncTask{local: f, key: prefix + f.rel}
+		obj, exists := remote[f.rel]
+		delete(remote, f.rel)
+		switch {
+		case !exists:
+			tasks[i].transfer = true
+		case opts.CompareChecksum:
+			tasks[i].verify = true
+		case !compressed && f.size != obj.Size:
+			tasks[i].transfer = true
+		default:
+			tasks[i].transfer = f.modTime.After(obj.LastModified)
+		}
+	}
+
+	limiter := newBandwidthLimiter(opts.BandwidthLimit)
+	runSyncTasks(ctx, tasks, opts.Concurrency, func(task *syncTask) {
+		c.runSyncTask(ctx, bucket, task, compressed, limiter, opts)
+	})
+	for _, task := range tasks {
+		switch {
+		case task.err != nil:
+			report.Errors = append(report.Errors, SyncError{Key: task.key, LocalPath: task.local.path, Err: task.err})
+		case task.transfer:
+			report.Uploaded++
+			report.Changes = append(report.Changes, SyncChange{Action: SyncUpload, Key: task.key, LocalPath: task.local.path})
+		default:
+			report.Skipped++
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return report, err
+	}
+
+	if opts.Delete && len(remote) > 0 {
+		keys := make([]string, 0, len(remote))
+		for rel := range remote {
+			keys = append(keys, prefix+rel)
+		}
+		sort.Strings(keys)
+		if err := c.syncDelete(ctx, bucket, keys, opts.DryRun, report); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// runSyncTasks runs the tasks that need a transfer or a comparison on
+// concurrency workers. Tasks not started before ctx is done fail with its
+// error.
+func runSyncTasks(ctx context.Context, tasks []syncTask, concurrency int, run func(*syncTask)) {
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+	jobs := make(chan *syncTask)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for task := range jobs {
+				run(task)
+			}
+		}()
+	}
+	for i := range tasks {
+		if tasks[i].err != nil || (!tasks[i].transfer && !tasks[i].verify) {
+			continue
+		}
+		select {
+		case jobs <- &tasks[i]:
+		case <-ctx.Done():
+			tasks[i].err = ctx.Err()
+		}
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// runSyncTask compares a file with its object if needed and uploads it if it
+// differs. On a dry run nothing is uploaded.
+func (c *S3Client) runSyncTask(ctx context.Context, bucket string, task *syncTask, compressed bool, limiter *bandwidthLimiter, opts *SyncOptions) {
+	uploadOpts := UploadOptions{}
+	if opts.UploadOptions != nil {
+		uploadOpts = *opts.UploadOptions
+	}
+	uploadOpts.limiter = limiter
+	if opts.CompareChecksum {
+		sha256Sum, md5Sum, err := fileDigests(task.local.path)
+		if err != nil {
+			task.err = err
+			return
+		}
+		if task.verify {
+			same, err := c.objectMatches(ctx, bucket, task.key, sha256Sum, md5Sum, task.local.size, compressed)
+			if err != nil {
+				task.err = err
+				return
+			}
+			if same {
+				return
+			}
+		}
+		task.transfer = true
+		// Record the digest so the next sync can compare multipart and
+		// compressed objects, whose ETags are not an MD5 of the file
+		metadata := make(map[string]string, len(uploadOpts.Metadata)+1)
+		for k, v := range uploadOpts.Metadata {
+			metadata[k] = v
+		}
+		metadata[contentSHA256MetaKey] = sha256Sum
+		uploadOpts.Metadata = metadata
+	}
+	if opts.DryRun {
+		return
+	}
+	_, task.err = c.uploadFile(ctx, bucket, task.key, task.local.path, &uploadOpts)
+}
+
+// fileDigests returns the hex SHA-256 and MD5 of a local file
+func fileDigests(path string) (string, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to open local file: %w", err)
+	}
+	defer f.Close()
+	sha256Hash, md5Hash := sha256.New(), md5.New()
+	if _, err := io.Copy(io.MultiWriter(sha256Hash, md5Hash), f); err != nil {
+		return "", "", fmt.Errorf("failed to hash local file: %w", err)
+	}
+	return hex.EncodeToString(sha256Hash.Sum(nil)), hex.EncodeToString(md5Hash.Sum(nil)), nil
+}
+
+// objectMatches reports whether the object at key has the content of a
+// local file with the given digests and size
+func (c *S3Client) objectMatches(ctx context.Context, bucket, key, sha256Sum, md5Sum string, size int64, compressed bool) (bool, error) {
+	resolved, err := c.resolveKey(key)
+	if err != nil {
+		return false, err
+	}
+	head, err := c.s3Client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(resolved),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == "NotFound" {
+			return false, nil
+		}
+		return false, uploadError(err)
+	}
+	return remoteMatches(head, sha256Sum, md5Sum, size, compressed), nil
+}
+
+// SyncBucketToDirectory makes localDir mirror prefix, the reverse of
+// SyncDirectoryToBucket: objects that are new or changed are downloaded
+// concurrently and, with opts.Delete, local files whose object no longer
+// exists are removed. An object is changed when its size differs from the
+// file's, or it was uploaded after the file was last modified; downloaded
+// files take the object's upload time as their modification time. With
+// opts.CompareChecksum, files of the same size are compared with the
+// object's ETag, or with the SHA-256 the library records on objects it
+// syncs, instead. Files hold the objects' stored bytes, so gzip encoded
+// objects are not decompressed even with DownloadOptions.AutoDecompress.
+// Keys that would resolve outside localDir, such as ones containing "../"
+// or leading through a symlink, are reported as errors and never written. A failed file does not abort the sync; nothing is removed
+// if the context ends before the downloads finish.
+func (c *S3Client) SyncBucketToDirectory(ctx context.Context, bucket, prefix, localDir string, opts *SyncOptions) (*SyncReport, error) {
+	if bucket == "" {
+		return nil, ErrInvalidBucket
+	}
+	if opts == nil {
+		opts = &SyncOptions{}
+	}
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	if localDir == "" {
+		return nil, fmt.Errorf("%w: empty local directory", ErrInvalidOptions)
+	}
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	remote, err := c.listRemote(ctx, bucket, prefix, opts.Include, opts.Exclude)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(localDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create local directory: %w", err)
+	}
+	files, failed, err := walkLocal(localDir, opts.Include, opts.Exclude)
+	if err != nil {
+		return nil, err
+	}
+	report := &SyncReport{Changes: []SyncChange{}, Errors: failed}
+	if report.Errors == nil {
+		report.Errors = []SyncError{}
+	}
+	local := make(map[string]localFile, len(files))
+	for _, f := range files {
+		local[f.rel] = f
+	}
+
+	rels := make([]string, 0, len(remote))
+	for rel := range remote {
+		rels = append(rels, rel)
+	}
+	sort.Strings(rels)
+	tasks := make([]syncTask, len(rels))
+	for i, rel := range rels {
+		obj := remote[rel]
+		tasks[i] = syncTask{key: obj.Key, remote: obj}
+		path, err := syncTarget(localDir, rel)
+		if err != nil {
+			tasks[i].err = err
+			continue
+		}
+		f, exists := local[rel]
+		delete(local, rel)
+		if !exists {
+			f = localFile{rel: rel, path: path}
+		}
+		tasks[i].local = f
+		switch {
+		case !exists:
+			tasks[i].transfer = true
+		case f.size != obj.Size:
+			tasks[i].transfer = true
+		case opts.CompareChecksum:
+			tasks[i].verify = true
+		default:
+			tasks[i].transfer = obj.LastModified.After(f.modTime)
+		}
+	}
+
+	limiter := newBandwidthLimiter(opts.BandwidthLimit)
+	runSyncTasks(ctx, tasks, opts.Concurrency, func(task *syncTask) {
+		c.runDownloadTask(ctx, bucket, task, limiter, opts)
+	})
+	for _, task := range tasks {
+		switch {
+		case task.err != nil:
+			report.Errors = append(report.Errors, SyncError{Key: task.key, LocalPath: task.local.path, Err: task.err})
+		case task.transfer:
+			report.Downloaded++
+			report.Changes = append(report.Changes, SyncChange{Action: SyncDownload, Key: task.key, LocalPath: task.local.path})
+		default:
+			report.Skipped++
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return report, err
+	}
+
+	if opts.Delete {
+		stale := make([]localFile, 0, len(local))
+		for _, f := range local {
+			stale = append(stale, f)
+		}
+		sort.Slice(stale, func(i, j int) bool { return stale[i].rel < stale[j].rel })
+		for _, f := range stale {
+			if !opts.DryRun {
+				if err := os.Remove(f.path); err != nil {
+					report.Errors = append(report.Errors, SyncError{LocalPath: f.path, Err: err})
+					continue
+				}
+			}
+			report.Deleted++
+			report.Changes = append(report.Changes, SyncChange{Action: SyncDelete, LocalPath: f.path})
+		}
+	}
+	return report, nil
+}
+
+// runDownloadTask compares an object with its file if needed and downloads
+// it if it differs. On a dry run nothing is downloaded.
+func (c *S3Client) runDownloadTask(ctx context.Context, bucket string, task *syncTask, limiter *bandwidthLimiter, opts *SyncOptions) {
+	if task.verify {
+		sha256Sum, md5Sum, err := fileDigests(task.local.path)
+		if err != nil {
+			task.err = err
+			return
+		}
+		// A single part upload's ETag is the MD5 of the content, which saves
+		// looking up the recorded SHA-256
+		if strings.EqualFold(strings.Trim(task.remote.ETag, `"`), md5Sum) {
+			return
+		}
+		same, err := c.objectMatches(ctx, bucket, task.key, sha256Sum, md5Sum, task.local.size, false)
+		if err != nil {
+			task.err = err
+			return
+		}
+		if same {
+			return
+		}
+		task.transfer = true
+	}
+	if opts.DryRun {
+		return
+	}
+
+	downloadOpts := DownloadOptions{}
+	if opts.DownloadOptions != nil {
+		downloadOpts = *opts.DownloadOptions
+	}
+	downloadOpts.CreateDirs = true
+	// Files hold the stored bytes so the next sync can compare their size
+	// with the listing
+	downloadOpts.AutoDecompress = false
+	downloadOpts.limiter = limiter
+	if _, err := c.DownloadToFileWithOptions(ctx, bucket, task.key, task.local.path, &downloadOpts); err != nil {
+		task.err = err
+		return
+	}
+	// The next sync compares against the upload time of the object
+	if err := os.Chtimes(task.local.path, time.Time{}, task.remote.LastModified); err != nil {
+		task.err = fmt.Errorf("failed to set modification time: %w", err)
+	}
+}
+
+// syncTarget returns the path under dir that rel is synced to. It fails
+// with ErrInvalidKey if the path would be outside dir, either because rel
+// is not a local path or because one of its parent directories is a
+// symlink.
+func syncTarget(dir, rel string) (string, error) {
+	local := filepath.FromSlash(rel)
+	if !filepath.IsLocal(local) {
+		return "", fmt.Errorf("%w: %q would be written outside %s", ErrInvalidKey, rel, dir)
+	}
+	parent := dir
+	for _, name := range strings.Split(filepath.Dir(local), string(filepath.Separator)) {
+		if name == "." {
+			break
+		}
+		parent = filepath.Join(parent, name)
+		info, err := os.Lstat(parent)
+		if err != nil {
+			break
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			return "", fmt.Errorf("%w: %q would be written through the symlink %s", ErrInvalidKey, rel, parent)
+		}
+	}
+	return filepath.Join(dir, local), nil
+}
+
+// MirrorPrefix makes dstPrefix in dstBucket mirror srcPrefix in srcBucket,
+// copying new and changed objects server-side with CopyFile, so objects over
+// 5GB are copied in parts and no data passes through the client. With
+// opts.Delete, destination keys whose source no longer exists are deleted.
+// Both sides are listed once. An object is changed when its size or ETag
+// differs from the destination's. Multipart ETags depend on the part size,
+// so when either is one the SHA-256 the library records in metadata is
+// compared instead, and if that is missing the object is copied only when
+// the source was modified after the destination. The prefixes must not
+// overlap within a bucket. Of opts only Concurrency, Include, Exclude,
+// Delete and DryRun apply. A failed object does not abort the mirror;
+// nothing is deleted if the context ends before the copies finish.
+func (c *S3Client) MirrorPrefix(ctx context.Context, srcBucket, srcPrefix, dstBucket, dstPrefix string, opts *SyncOptions) (*SyncReport, error) {
+	if srcBucket == "" || dstBucket == "" {
+		return nil, ErrInvalidBucket
+	}
+	if opts == nil {
+		opts = &SyncOptions{}
+	}
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	if srcPrefix != "" && !strings.HasSuffix(srcPrefix, "/") {
+		srcPrefix += "/"
+	}
+	if dstPrefix != "" && !strings.HasSuffix(dstPrefix, "/") {
+		dstPrefix += "/"
+	}
+	if srcBucket == dstBucket && (strings.HasPrefix(srcPrefix, dstPrefix) || strings.HasPrefix(dstPrefix, srcPrefix)) {
+		return nil, fmt.Errorf("%w: source and destination prefixes overlap", ErrInvalidOptions)
+	}
+
+	source, err := c.listRemote(ctx, srcBucket, srcPrefix, opts.Include, opts.Exclude)
+	if err != nil {
+		return nil, err
+	}
+	dest, err := c.listRemote(ctx, dstBucket, dstPrefix, opts.Include, opts.Exclude)
+	if err != nil {
+		return nil, err
+	}
+	report := &SyncReport{Changes: []SyncChange{}, Errors: []SyncError{}}
+
+	rels := make([]string, 0, len(source))
+	for rel := range source {
+		rels = append(rels, rel)
+	}
+	sort.Strings(rels)
+	tasks := make([]syncTask, len(rels))
+	for i, rel := range rels {
+		obj := source[rel]
+		tasks[i] = syncTask{key: dstPrefix + rel, remote: obj}
+		existing, exists := dest[rel]
+		delete(dest, rel)
+		switch {
+		case !exists:
+			tasks[i].transfer = true
+		case obj.Size != existing.Size:
+			tasks[i].transfer = true
+		case obj.ETag != "" && obj.ETag == existing.ETag:
+		case isMultipartETag(obj.ETag) || isMultipartETag(existing.ETag):
+			tasks[i].verify = true
+		default:
+			tasks[i].transfer = true
+		}
+	}
+
+	runSyncTasks(ctx, tasks, opts.Concurrency, func(task *syncTask) {
+		c.runMirrorTask(ctx, srcBucket, dstBucket, task, opts)
+	})
+	for _, task := range tasks {
+		switch {
+		case task.err != nil:
+			report.Errors = append(report.Errors, SyncError{Key: task.key, Err: task.err})
+		case task.transfer:
+			report.Copied++
+			report.Changes = append(report.Changes, SyncChange{Action: SyncCopy, Key: task.key, Source: task.remote.Key})
+		default:
+			report.Skipped++
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return report, err
+	}
+
+	if opts.Delete && len(dest) > 0 {
+		keys := make([]string, 0, len(dest))
+		for rel := range dest {
+			keys = append(keys, dstPrefix+rel)
+		}
+		sort.Strings(keys)
+		if err := c.syncDelete(ctx, dstBucket, keys, opts.DryRun, report); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// isMultipartETag reports whether etag is that of a multipart upload, which
+// is not an MD5 of the content
+func isMultipartETag(etag string) bool {
+	return strings.Contains(etag, "-")
+}
+
+// runMirrorTask compares an object with its copy if needed and copies it if
+// it differs. On a dry run nothing is copied.
+func (c *S3Client) runMirrorTask(ctx context.Context, srcBucket, dstBucket string, task *syncTask, opts *SyncOptions) {
+	if task.verify {
+		same, err := c.copyMatches(ctx, srcBucket, task.remote.Key, dstBucket, task.key)
+		if err != nil {
+			task.err = err
+			return
+		}
+		if same {
+			return
+		}
+		task.transfer = true
+	}
+	if opts.DryRun {
+		return
+	}
+	_, task.err = c.CopyFile(ctx, srcBucket, task.remote.Key, dstBucket, task.key, nil)
+}
+
+// copyMatches reports whether the object at dstKey is a copy of the one at
+// srcKey of the same size, by the SHA-256 recorded in their metadata or,
+// when either lacks it, by the destination not being older than the source
+func (c *S3Client) copyMatches(ctx context.Context, srcBucket, srcKey, dstBucket, dstKey string) (bool, error) {
+	srcKey, err := c.resolveKey(srcKey)
+	if err != nil {
+		return false, err
+	}
+	dstKey, err = c.resolveKey(dstKey)
+	if err != nil {
+		return false, err
+	}
+	srcHead, err := c.headCopySource(ctx, srcBucket, srcKey, "")
+	if err != nil {
+		return false, err
+	}
+	dstHead, err := c.headCopySource(ctx, dstBucket, dstKey, "")
+	if err != nil {
+		if errors.Is(err, ErrFileNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	srcSum := metadataValue(srcHead.Metadata, contentSHA256MetaKey)
+	dstSum := metadataValue(dstHead.Metadata, contentSHA256MetaKey)
+	if srcSum != "" && dstSum != "" {
+		return srcSum == dstSum, nil
+	}
+	return !aws.TimeValue(srcHead.LastModified).After(aws.TimeValue(dstHead.LastModified)), nil
+}
+
+// syncDelete deletes keys that no longer exist at the source of a sync and
+// records the outcome in report. On a dry run nothing is deleted.
+func (c *S3Client) syncDelete(ctx context.Context, bucket string, keys []string, dryRun bool, report *SyncReport) error {
+	failed := map[string]error{}
+	if !dryRun {
+		errs, err := c.DeleteFiles(ctx, bucket, keys)
+		if err != nil {
+			return err
+		}
+		for _, e := range errs {
+			failed[e.Key] = e
+		}
+	}
+	for _, key := range keys {
+		if err, ok := failed[key]; ok {
+			report.Errors = append(report.Errors, SyncError{Key: key, Err: err})
+			continue
+		}
+		report.Deleted++
+		report.Changes = append(report.Changes, SyncChange{Action: SyncDelete, Key: key})
+	}
+	return nil
+}
@@ -0,0 +1,386 @@
+package s3lib
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// prefixServer returns a fake transport listing keys and serving each of
+// them with testFileContent
+func prefixServer(keys ...string) roundTripFunc {
+	return func(req *http.Request) (*http.Response, error) {
+		if req.Method == http.MethodGet && req.URL.Query().Has("list-type") {
+			body := "<ListBucketResult>"
+			for _, key := range keys {
+				body += "<Contents><Key>" + key + "</Key><Size>13</Size></Contents>"
+			}
+			body += "</ListBucketResult>"
+			return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: xmlBody(body), Request: req}, nil
+		}
+		return objectServer(testFileContent)(req)
+	}
+}
+
+// TestS3Client_DownloadPrefix tests the key hierarchy is recreated locally
+// and keys escaping the directory or crossing a symlink are refused
+func TestS3Client_DownloadPrefix(t *testing.T) {
+	client := setupFakeClient(t, prefixServer(
+		"data/a.txt",
+		"data/nested/b.txt",
+		"data/skip.log",
+		"data/empty/",
+		"data/../evil.txt",
+		"data/link/c.txt",
+	))
+	localDir := t.TempDir()
+	outside := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(localDir, "link")); err != nil {
+		t.Skipf("cannot create symlink: %v", err)
+	}
+
+	results, err := client.DownloadPrefix(context.Background(), testBucket, "data", localDir, &SyncOptions{Exclude: []string{"*.log"}})
+	require.NoError(t, err)
+	require.Len(t, results, 4)
+
+	for _, r := range results[:2] {
+		require.NoError(t, r.Err, r.Key)
+		assert.Equal(t, int64(len(testFileContent)), r.Bytes)
+		got, err := os.ReadFile(r.LocalPath)
+		require.NoError(t, err)
+		assert.Equal(t, testFileContent, got)
+	}
+	assert.Equal(t, filepath.Join(localDir, "nested", "b.txt"), results[1].LocalPath)
+
+	for _, r := range results[2:] {
+		assert.ErrorIs(t, r.Err, ErrInvalidKey, r.Key)
+		assert.Empty(t, r.LocalPath)
+	}
+	assert.NoFileExists(t, filepath.Join(filepath.Dir(localDir), "evil.txt"))
+	assert.NoFileExists(t, filepath.Join(outside, "c.txt"))
+}
+
+// TestS3Client_DownloadPrefixValidation tests invalid arguments
+func TestS3Client_DownloadPrefixValidation(t *testing.T) {
+	client := setupTestClient(t)
+	ctx := context.Background()
+
+	_, err := client.DownloadPrefix(ctx, "", "data/", t.TempDir(), nil)
+	assert.ErrorIs(t, err, ErrInvalidBucket)
+
+	_, err = client.DownloadPrefix(ctx, testBucket, "data/", "", nil)
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+
+	_, err = client.DownloadPrefix(ctx, testBucket, "data/", t.TempDir(), &SyncOptions{Include: []string{"["}})
+	assert.Error(t, err)
+}
+
+// syncServer returns a fake transport listing the given objects under
+// "site/", serving each with testFileContent and recording the keys
+// transferred and deleted
+func syncServer(objects map[string]int, lastModified time.Time, mu *sync.Mutex, calls *[]string) roundTripFunc {
+	return func(req *http.Request) (*http.Response, error) {
+		resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: req}
+		key := strings.TrimPrefix(req.URL.Path, "/"+testBucket+"/")
+		switch {
+		case req.Method == http.MethodGet && req.URL.Query().Has("list-type"):
+			body := "<ListBucketResult>"
+			for key, size := range objects {
+				body += fmt.Sprintf("<Contents><Key>site/%s</Key><Size>%d</Size><LastModified>%s</LastModified></Contents>",
+					key, size, lastModified.UTC().Format(time.RFC3339))
+			}
+			resp.Body = xmlBody(body + "</ListBucketResult>")
+		case req.Method == http.MethodPost && req.URL.Query().Has("delete"):
+			var batch deleteRequest
+			data, _ := io.ReadAll(req.Body)
+			_ = xml.Unmarshal(data, &batch)
+			mu.Lock()
+			for _, obj := range batch.Objects {
+				*calls = append(*calls, "DELETE "+obj.Key)
+			}
+			mu.Unlock()
+			resp.Body = xmlBody("<DeleteResult></DeleteResult>")
+		case req.Method == http.MethodPut:
+			mu.Lock()
+			*calls = append(*calls, "PUT "+key)
+			mu.Unlock()
+		case req.Method == http.MethodGet:
+			mu.Lock()
+			*calls = append(*calls, "GET "+key)
+			mu.Unlock()
+			return objectServer(testFileContent)(req)
+		case req.Method == http.MethodHead:
+			return objectServer(testFileContent)(req)
+		}
+		return resp, nil
+	}
+}
+
+// TestS3Client_SyncDirectoryToBucket tests only new and changed files are
+// uploaded and stale keys deleted
+func TestS3Client_SyncDirectoryToBucket(t *testing.T) {
+	localDir := t.TempDir()
+	for _, name := range []string{"new.txt", "same.txt", "changed.txt", "nested/touched.txt"} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(localDir, name)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(localDir, name), testFileContent, 0o644))
+	}
+	uploaded := time.Now().Add(-time.Hour)
+	old := uploaded.Add(-time.Hour)
+	for _, name := range []string{"same.txt", "changed.txt"} {
+		require.NoError(t, os.Chtimes(filepath.Join(localDir, name), old, old))
+	}
+	objects := map[string]int{
+		"same.txt":           len(testFileContent),
+		"changed.txt":        1,
+		"nested/touched.txt": len(testFileContent),
+		"stale.txt":          1,
+	}
+
+	var mu sync.Mutex
+	var calls []string
+	client := setupFakeClient(t, syncServer(objects, uploaded, &mu, &calls))
+	ctx := context.Background()
+
+	t.Run("dry run", func(t *testing.T) {
+		report, err := client.SyncDirectoryToBucket(ctx, localDir, testBucket, "site", &SyncOptions{Delete: true, DryRun: true})
+		require.NoError(t, err)
+		assert.Equal(t, 3, report.Uploaded)
+		assert.Equal(t, 1, report.Skipped)
+		assert.Equal(t, 1, report.Deleted)
+		assert.Empty(t, calls)
+	})
+
+	t.Run("sync", func(t *testing.T) {
+		report, err := client.SyncDirectoryToBucket(ctx, localDir, testBucket, "site", &SyncOptions{Delete: true})
+		require.NoError(t, err)
+		assert.Empty(t, report.Errors)
+		assert.Equal(t, []SyncChange{
+			{Action: SyncUpload, Key: "site/changed.txt", LocalPath: filepath.Join(localDir, "changed.txt")},
+			{Action: SyncUpload, Key: "site/nested/touched.txt", LocalPath: filepath.Join(localDir, "nested", "touched.txt")},
+			{Action: SyncUpload, Key: "site/new.txt", LocalPath: filepath.Join(localDir, "new.txt")},
+			{Action: SyncDelete, Key: "site/stale.txt"},
+		}, report.Changes)
+		assert.ElementsMatch(t, []string{"PUT site/changed.txt", "PUT site/nested/touched.txt", "PUT site/new.txt", "DELETE site/stale.txt"}, calls)
+	})
+}
+
+// TestS3Client_SyncDirectoryToBucketValidation tests invalid arguments
+func TestS3Client_SyncDirectoryToBucketValidation(t *testing.T) {
+	client := setupTestClient(t)
+	ctx := context.Background()
+
+	_, err := client.SyncDirectoryToBucket(ctx, t.TempDir(), "", "site/", nil)
+	assert.ErrorIs(t, err, ErrInvalidBucket)
+	_, err = client.SyncDirectoryToBucket(ctx, "", testBucket, "site/", nil)
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+	_, err = client.SyncDirectoryToBucket(ctx, t.TempDir(), testBucket, "site/", &SyncOptions{Exclude: []string{"["}})
+	assert.Error(t, err)
+}
+
+// TestS3Client_SyncBucketToDirectory tests only new and changed objects are
+// downloaded, stale files removed and nothing written outside the directory
+func TestS3Client_SyncBucketToDirectory(t *testing.T) {
+	localDir := t.TempDir()
+	outside := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(localDir, "same.txt"), testFileContent, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(localDir, "changed.txt"), []byte("x"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(localDir, "stale.txt"), []byte("x"), 0o644))
+	if err := os.Symlink(outside, filepath.Join(localDir, "link")); err != nil {
+		t.Skipf("cannot create symlink: %v", err)
+	}
+	size := len(testFileContent)
+	objects := map[string]int{"same.txt": size, "changed.txt": size, "new/a.txt": size, "../evil.txt": size, "link/b.txt": size}
+
+	var mu sync.Mutex
+	var calls []string
+	client := setupFakeClient(t, syncServer(objects, time.Now().Add(-time.Hour), &mu, &calls))
+
+	report, err := client.SyncBucketToDirectory(context.Background(), testBucket, "site/", localDir, &SyncOptions{Delete: true})
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Downloaded)
+	assert.Equal(t, 1, report.Skipped)
+	assert.Equal(t, 1, report.Deleted)
+	assert.ElementsMatch(t, []string{"GET site/changed.txt", "GET site/new/a.txt"}, calls)
+
+	require.Len(t, report.Errors, 2)
+	for _, e := range report.Errors {
+		assert.ErrorIs(t, e, ErrInvalidKey)
+	}
+	got, err := os.ReadFile(filepath.Join(localDir, "new", "a.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, testFileContent, got)
+	assert.NoFileExists(t, filepath.Join(localDir, "stale.txt"))
+	assert.NoFileExists(t, filepath.Join(outside, "b.txt"))
+}
+
+// TestS3Client_SyncBucketToDirectoryGzip tests a gzip encoded object is
+// stored as is, so an unchanged object is not downloaded again
+func TestS3Client_SyncBucketToDirectoryGzip(t *testing.T) {
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	_, err := zw.Write(bytes.Repeat(testFileContent, 100))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	var mu sync.Mutex
+	gets := 0
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: req}
+		if req.Method == http.MethodGet && req.URL.Query().Has("list-type") {
+			resp.Body = xmlBody(fmt.Sprintf("<ListBucketResult><Contents><Key>site/page.html</Key><Size>%d</Size><LastModified>%s</LastModified></Contents></ListBucketResult>",
+				compressed.Len(), time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)))
+			return resp, nil
+		}
+		resp.Header.Set("Content-Encoding", gzipEncoding)
+		resp.Header.Set("Content-Length", strconv.Itoa(compressed.Len()))
+		if req.Method == http.MethodGet {
+			mu.Lock()
+			gets++
+			mu.Unlock()
+			resp.Body = io.NopCloser(bytes.NewReader(compressed.Bytes()))
+		}
+		return resp, nil
+	})
+	localDir := t.TempDir()
+	opts := &SyncOptions{DownloadOptions: &DownloadOptions{AutoDecompress: true}}
+
+	report, err := client.SyncBucketToDirectory(context.Background(), testBucket, "site/", localDir, opts)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Downloaded)
+	got, err := os.ReadFile(filepath.Join(localDir, "page.html"))
+	require.NoError(t, err)
+	assert.Equal(t, compressed.Bytes(), got)
+
+	report, err = client.SyncBucketToDirectory(context.Background(), testBucket, "site/", localDir, opts)
+	require.NoError(t, err)
+	assert.Equal(t, 0, report.Downloaded)
+	assert.Equal(t, 1, report.Skipped)
+	assert.Equal(t, 1, gets)
+}
+
+// mirrorObject is an object served by the fake transport of
+// TestS3Client_MirrorPrefix
+type mirrorObject struct {
+	etag     string
+	sha256   string
+	modified time.Time
+}
+
+// TestS3Client_MirrorPrefix tests new and changed objects are copied,
+// multipart ETags are compared by recorded checksum or age, and extraneous
+// destination keys deleted
+func TestS3Client_MirrorPrefix(t *testing.T) {
+	older := time.Now().Add(-2 * time.Hour)
+	newer := older.Add(time.Hour)
+	objects := map[string]mirrorObject{
+		"src-bucket/prod/new.txt":        {etag: "a"},
+		"src-bucket/prod/same.txt":       {etag: "b"},
+		"src-bucket/prod/changed.txt":    {etag: "c"},
+		"src-bucket/prod/big.bin":        {etag: "m-2", sha256: "sum", modified: newer},
+		"src-bucket/prod/rewritten.bin":  {etag: "n-2", modified: newer},
+		testBucket + "/dr/same.txt":      {etag: "b"},
+		testBucket + "/dr/changed.txt":   {etag: "x"},
+		testBucket + "/dr/big.bin":       {etag: "q-3", sha256: "sum", modified: older},
+		testBucket + "/dr/rewritten.bin": {etag: "r-3", modified: older},
+		testBucket + "/dr/extra.txt":     {etag: "e"},
+	}
+
+	var mu sync.Mutex
+	var calls []string
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: req}
+		path := strings.TrimPrefix(req.URL.Path, "/")
+		switch {
+		case req.Method == http.MethodGet && req.URL.Query().Has("list-type"):
+			listed := strings.TrimSuffix(path, "/") + "/" + req.URL.Query().Get("prefix")
+			body := "<ListBucketResult>"
+			for name, obj := range objects {
+				if key, ok := strings.CutPrefix(name, listed); ok {
+					body += fmt.Sprintf(`<Contents><Key>%s%s</Key><Size>5</Size><ETag>"%s"</ETag></Contents>`,
+						req.URL.Query().Get("prefix"), key, obj.etag)
+				}
+			}
+			resp.Body = xmlBody(body + "</ListBucketResult>")
+		case req.Method == http.MethodHead:
+			obj, ok := objects[path]
+			if !ok {
+				return fakeErrorResponse(req, http.StatusNotFound, "NotFound"), nil
+			}
+			resp.Header.Set("Content-Length", "5")
+			resp.Header.Set("ETag", `"`+obj.etag+`"`)
+			resp.Header.Set("Last-Modified", obj.modified.UTC().Format(http.TimeFormat))
+			if obj.sha256 != "" {
+				resp.Header.Set("x-amz-meta-"+contentSHA256MetaKey, obj.sha256)
+			}
+		case req.Method == http.MethodPut:
+			mu.Lock()
+			calls = append(calls, "COPY "+req.Header.Get("x-amz-copy-source")+" "+path)
+			mu.Unlock()
+			resp.Body = xmlBody(`<CopyObjectResult><ETag>"copied"</ETag></CopyObjectResult>`)
+		case req.Method == http.MethodPost && req.URL.Query().Has("delete"):
+			var batch deleteRequest
+			data, _ := io.ReadAll(req.Body)
+			_ = xml.Unmarshal(data, &batch)
+			mu.Lock()
+			for _, obj := range batch.Objects {
+				calls = append(calls, "DELETE "+obj.Key)
+			}
+			mu.Unlock()
+			resp.Body = xmlBody("<DeleteResult></DeleteResult>")
+		}
+		return resp, nil
+	})
+	ctx := context.Background()
+
+	t.Run("dry run", func(t *testing.T) {
+		report, err := client.MirrorPrefix(ctx, "src-bucket", "prod", testBucket, "dr", &SyncOptions{Delete: true, DryRun: true})
+		require.NoError(t, err)
+		assert.Equal(t, 3, report.Copied)
+		assert.Equal(t, 1, report.Deleted)
+		assert.Empty(t, calls)
+	})
+
+	report, err := client.MirrorPrefix(ctx, "src-bucket", "prod", testBucket, "dr", &SyncOptions{Delete: true, Concurrency: 2})
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.Copied)
+	assert.Equal(t, 2, report.Skipped)
+	assert.Equal(t, 1, report.Deleted)
+	assert.Empty(t, report.Errors)
+	assert.ElementsMatch(t, []string{
+		"COPY src-bucket/prod/new.txt " + testBucket + "/dr/new.txt",
+		"COPY src-bucket/prod/changed.txt " + testBucket + "/dr/changed.txt",
+		"COPY src-bucket/prod/rewritten.bin " + testBucket + "/dr/rewritten.bin",
+		"DELETE dr/extra.txt",
+	}, calls)
+	assert.Equal(t, SyncChange{Action: SyncCopy, Key: "dr/changed.txt", Source: "prod/changed.txt"}, report.Changes[0])
+}
+
+// TestS3Client_MirrorPrefixValidation tests overlapping prefixes are
+// rejected before anything is listed
+func TestS3Client_MirrorPrefixValidation(t *testing.T) {
+	client := setupTestClient(t)
+	ctx := context.Background()
+
+	_, err := client.MirrorPrefix(ctx, "", "prod/", testBucket, "dr/", nil)
+	assert.ErrorIs(t, err, ErrInvalidBucket)
+	_, err = client.MirrorPrefix(ctx, testBucket, "prod/", testBucket, "prod/dr/", nil)
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+	_, err = client.MirrorPrefix(ctx, testBucket, "", testBucket, "dr/", nil)
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+	_, err = client.MirrorPrefix(ctx, "src-bucket", "prod/", testBucket, "dr/", &SyncOptions{Concurrency: -1})
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+}
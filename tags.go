@@ -0,0 +1,149 @@
+package s3lib
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// S3 object and bucket tagging limits
+const (
+	MaxObjectTags     = 10
+	MaxBucketTags     = 50
+	MaxTagKeyLength   = 128
+	MaxTagValueLength = 256
+)
+
+// validateTags checks a tag set against the S3 object tagging limits
+func validateTags(tags map[string]string) error {
+	return validateTagSet(tags, MaxObjectTags, "object")
+}
+
+// validateTagSet checks a tag set of at most limit tags per kind of
+// resource against the S3 tagging limits
+func validateTagSet(tags map[string]string, limit int, kind string) error {
+	if len(tags) > limit {
+		return fmt.Errorf("%w: %d tags exceeds the limit of %d per %s", ErrInvalidOptions, len(tags), limit, kind)
+	}
+	for k, v := range tags {
+		if k == "" {
+			return fmt.Errorf("%w: tag key must not be empty", ErrInvalidOptions)
+		}
+		if n := utf8.RuneCountInString(k); n > MaxTagKeyLength {
+			return fmt.Errorf("%w: tag key %q is %d characters, limit is %d", ErrInvalidOptions, k, n, MaxTagKeyLength)
+		}
+		if n := utf8.RuneCountInString(v); n > MaxTagValueLength {
+			return fmt.Errorf("%w: value of tag %q is %d characters, limit is %d", ErrInvalidOptions, k, n, MaxTagValueLength)
+		}
+		if strings.HasPrefix(strings.ToLower(k), "aws:") {
+			return fmt.Errorf("%w: tag key %q uses the reserved aws: prefix", ErrInvalidOptions, k)
+		}
+	}
+	return nil
+}
+
+// encodeTags returns the URL-encoded form of tags used by the x-amz-tagging
+// header. Keys are sorted so the encoding is deterministic.
+func encodeTags(tags map[string]string) string {
+	values := make(url.Values, len(tags))
+	for k, v := range tags {
+		values.Set(k, v)
+	}
+	return values.Encode()
+}
+
+// SetBucketTags replaces the tags of bucket with tags, which are checked
+// against the S3 limits of 50 tags per bucket and the key and value lengths
+// before the request is made. Use DeleteBucketTags to remove them all.
+func (c *S3Client) SetBucketTags(ctx context.Context, bucket string, tags map[string]string) error {
+	if bucket == "" {
+		return ErrInvalidBucket
+	}
+	if len(tags) == 0 {
+		return fmt.Errorf("%w: no tags; use DeleteBucketTags to remove them", ErrInvalidOptions)
+	}
+	if err := validateTagSet(tags, MaxBucketTags, "bucket"); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	tagSet := make([]*s3.Tag, 0, len(keys))
+	for _, k := range keys {
+		tagSet = append(tagSet, &s3.Tag{Key: aws.String(k), Value: aws.String(tags[k])})
+	}
+	_, err := c.s3Client.PutBucketTaggingWithContext(ctx, &s3.PutBucketTaggingInput{
+		Bucket:  aws.String(bucket),
+		Tagging: &s3.Tagging{TagSet: tagSet},
+	})
+	if err != nil {
+		return bucketTaggingError(err)
+	}
+	return nil
+}
+
+// GetBucketTags returns the tags of bucket, which are empty when it has
+// none
+func (c *S3Client) GetBucketTags(ctx context.Context, bucket string) (map[string]string, error) {
+	if bucket == "" {
+		return nil, ErrInvalidBucket
+	}
+
+	out, err := c.s3Client.GetBucketTaggingWithContext(ctx, &s3.GetBucketTaggingInput{
+		Bucket: aws.String(bucket),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == "NoSuchTagSet" {
+			return map[string]string{}, nil
+		}
+		return nil, bucketTaggingError(err)
+	}
+	tags := make(map[string]string, len(out.TagSet))
+	for _, tag := range out.TagSet {
+		tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+	return tags, nil
+}
+
+// DeleteBucketTags removes every tag of bucket. Removing tags from a bucket
+// without any succeeds.
+func (c *S3Client) DeleteBucketTags(ctx context.Context, bucket string) error {
+	if bucket == "" {
+		return ErrInvalidBucket
+	}
+	_, err := c.s3Client.DeleteBucketTaggingWithContext(ctx, &s3.DeleteBucketTaggingInput{
+		Bucket: aws.String(bucket),
+	})
+	if err != nil {
+		return bucketTaggingError(err)
+	}
+	return nil
+}
+
+// bucketTaggingError maps a failed bucket tagging request to the library's
+// error values
+func bucketTaggingError(err error) error {
+	if aerr, ok := err.(awserr.Error); ok {
+		switch aerr.Code() {
+		case s3.ErrCodeNoSuchBucket:
+			return ErrInvalidBucket
+		case "AccessDenied":
+			return fmt.Errorf("%w: %w", ErrAccessDenied, aerr)
+		case "InvalidTag", "MalformedXML":
+			return fmt.Errorf("%w: %w", ErrInvalidOptions, aerr)
+		default:
+			return fmt.Errorf("AWS error: %w", aerr)
+		}
+	}
+	return fmt.Errorf("failed to access bucket tags: %w", err)
+}
@@ -0,0 +1,149 @@
+package s3lib
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestValidateTags tests the S3 tagging limits are enforced
+func TestValidateTags(t *testing.T) {
+	tooMany := make(map[string]string)
+	for i := 0; i <= MaxObjectTags; i++ {
+		tooMany[fmt.Sprintf("key%d", i)] = "value"
+	}
+
+	tests := []struct {
+		name    string
+		tags    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "Valid tags",
+			tags: map[string]string{"team": "storage", "env": "prod"},
+		},
+		{
+			name: "No tags",
+		},
+		{
+			name:    "Too many tags",
+			tags:    tooMany,
+			wantErr: true,
+		},
+		{
+			name:    "Empty key",
+			tags:    map[string]string{"": "value"},
+			wantErr: true,
+		},
+		{
+			name:    "Key too long",
+			tags:    map[string]string{strings.Repeat("k", MaxTagKeyLength+1): "value"},
+			wantErr: true,
+		},
+		{
+			name:    "Value too long",
+			tags:    map[string]string{"key": strings.Repeat("v", MaxTagValueLength+1)},
+			wantErr: true,
+		},
+		{
+			name:    "Reserved prefix",
+			tags:    map[string]string{"aws:owner": "value"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateTags(tt.tags)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidOptions)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+// TestEncodeTags tests tags are URL-encoded in key order
+func TestEncodeTags(t *testing.T) {
+	got := encodeTags(map[string]string{"team": "storage & backup", "cost center": "42"})
+	assert.Equal(t, "cost+center=42&team=storage+%26+backup", got)
+}
+
+// TestS3Client_UploadFileTags tests the tagging header and fail-fast validation
+func TestS3Client_UploadFileTags(t *testing.T) {
+	var tagging string
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		tagging = req.Header.Get("X-Amz-Tagging")
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: req}, nil
+	})
+	ctx := context.Background()
+
+	_, err := client.UploadFile(ctx, testBucket, testFileName, testFileContent, &UploadOptions{Tags: map[string]string{"env": "test"}})
+	require.NoError(t, err)
+	assert.Equal(t, "env=test", tagging)
+
+	tagging = ""
+	_, err = client.UploadFile(ctx, testBucket, testFileName, testFileContent, &UploadOptions{Tags: map[string]string{"": "x"}})
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+	assert.Empty(t, tagging, "no request should be sent")
+}
+
+// TestS3Client_BucketTags tests tags set are read back, a bucket without
+// tags reads as empty, and the bucket limit of 50 tags is enforced
+func TestS3Client_BucketTags(t *testing.T) {
+	var stored string
+	var requests int
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		requests++
+		resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: req}
+		switch req.Method {
+		case http.MethodPut:
+			data, _ := io.ReadAll(req.Body)
+			stored = string(data)
+		case http.MethodGet:
+			if stored == "" {
+				return fakeErrorResponse(req, http.StatusNotFound, "NoSuchTagSet"), nil
+			}
+			resp.Body = xmlBody(stored)
+		case http.MethodDelete:
+			stored = ""
+			resp.StatusCode = http.StatusNoContent
+		}
+		return resp, nil
+	})
+	ctx := context.Background()
+
+	tags, err := client.GetBucketTags(ctx, testBucket)
+	require.NoError(t, err)
+	assert.Empty(t, tags)
+
+	want := map[string]string{"cost-center": "42", "team": "storage"}
+	require.NoError(t, client.SetBucketTags(ctx, testBucket, want))
+	tags, err = client.GetBucketTags(ctx, testBucket)
+	require.NoError(t, err)
+	assert.Equal(t, want, tags)
+
+	require.NoError(t, client.DeleteBucketTags(ctx, testBucket))
+	tags, err = client.GetBucketTags(ctx, testBucket)
+	require.NoError(t, err)
+	assert.Empty(t, tags)
+
+	// More than an object allows is fine on a bucket, up to its own limit
+	many := make(map[string]string)
+	for i := 0; i < MaxBucketTags; i++ {
+		many[fmt.Sprintf("key%d", i)] = "value"
+	}
+	require.NoError(t, client.SetBucketTags(ctx, testBucket, many))
+	many["one-more"] = "value"
+	sent := requests
+	assert.ErrorIs(t, client.SetBucketTags(ctx, testBucket, many), ErrInvalidOptions)
+	assert.ErrorIs(t, client.SetBucketTags(ctx, testBucket, nil), ErrInvalidOptions)
+	assert.Equal(t, sent, requests)
+}
@@ -0,0 +1,132 @@
+package s3lib
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+// throttleChunk is the most bytes read before waiting on the limiter, so a
+// single large read cannot run far ahead of the configured rate
+const throttleChunk = 32 * 1024
+
+// bandwidthLimiter paces reads to an aggregate rate shared by every reader
+// using it. Time not spent sending is not saved up as a burst allowance.
+type bandwidthLimiter struct {
+	mu   sync.Mutex
+	rate int64 // bytes per second
+	next time.Time
+}
+
+// newBandwidthLimiter returns a limiter for bytesPerSec, or nil when the
+// limit is zero (unlimited)
+func newBandwidthLimiter(bytesPerSec int64) *bandwidthLimiter {
+	if bytesPerSec <= 0 {
+		return nil
+	}
+	return &bandwidthLimiter{rate: bytesPerSec}
+}
+
+// wait blocks until n more bytes fit within the rate or ctx is done
+func (l *bandwidthLimiter) wait(ctx context.Context, n int) error {
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	l.next = l.next.Add(time.Duration(n) * time.Second / time.Duration(l.rate))
+	until := l.next
+	l.mu.Unlock()
+
+	d := time.Until(until)
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// throttledBody is a request body whose reads are paced by a limiter
+type throttledBody struct {
+	ctx     context.Context
+	body    io.ReadCloser
+	limiter *bandwidthLimiter
+}
+
+func (b *throttledBody) Read(p []byte) (int, error) {
+	if len(p) > throttleChunk {
+		p = p[:throttleChunk]
+	}
+	n, err := b.body.Read(p)
+	if n > 0 {
+		if werr := b.limiter.wait(b.ctx, n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+func (b *throttledBody) Close() error {
+	return b.body.Close()
+}
+
+// withBandwidthLimit returns a request option that paces the body of
+// PutObject and UploadPart requests with limiter. The body is wrapped on
+// every send attempt, after signing, so SDK retries are throttled too.
+func withBandwidthLimit(limiter *bandwidthLimiter) request.Option {
+	return func(r *request.Request) {
+		r.Handlers.Send.PushFront(func(r *request.Request) {
+			if r.Operation == nil || (r.Operation.Name != "PutObject" && r.Operation.Name != "UploadPart") {
+				return
+			}
+			if r.HTTPRequest.Body == nil || r.HTTPRequest.Body == http.NoBody || r.HTTPRequest.ContentLength == 0 {
+				return
+			}
+			r.HTTPRequest.Body = &throttledBody{ctx: r.Context(), body: r.HTTPRequest.Body, limiter: limiter}
+		})
+	}
+}
+
+// withDownloadBandwidthLimit returns a request option that paces the
+// response body of GetObject requests with limiter
+func withDownloadBandwidthLimit(limiter *bandwidthLimiter) request.Option {
+	return func(r *request.Request) {
+		r.Handlers.Send.PushBack(func(r *request.Request) {
+			if r.Operation == nil || r.Operation.Name != "GetObject" {
+				return
+			}
+			if r.HTTPResponse == nil || r.HTTPResponse.Body == nil || r.HTTPResponse.Body == http.NoBody {
+				return
+			}
+			r.HTTPResponse.Body = &throttledBody{ctx: r.Context(), body: r.HTTPResponse.Body, limiter: limiter}
+		})
+	}
+}
+
+// bandwidthOptions returns the request options throttling an upload with
+// opts: a per-call BandwidthLimit gets its own limiter, otherwise the
+// limiter shared by a sync or the client-wide Config.UploadBandwidthLimit
+// limiter is used
+func (c *S3Client) bandwidthOptions(opts *UploadOptions) []request.Option {
+	limiter := c.uploadLimiter
+	if opts != nil && opts.limiter != nil {
+		limiter = opts.limiter
+	}
+	if opts != nil && opts.BandwidthLimit > 0 {
+		limiter = newBandwidthLimiter(opts.BandwidthLimit)
+	}
+	if limiter == nil {
+		return nil
+	}
+	return []request.Option{withBandwidthLimit(limiter)}
+}
@@ -0,0 +1,74 @@
+package s3lib
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBandwidthLimiter_SharedRate tests concurrent readers share one rate
+func TestBandwidthLimiter_SharedRate(t *testing.T) {
+	limiter := newBandwidthLimiter(200 * 1024)
+	ctx := context.Background()
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			body := &throttledBody{ctx: ctx, body: io.NopCloser(io.LimitReader(&patternReader{}, 20*1024)), limiter: limiter}
+			n, err := io.Copy(io.Discard, body)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(20*1024), n)
+		}()
+	}
+	wg.Wait()
+
+	// 80KB at 200KB/s takes 400ms in aggregate, not 100ms per reader
+	assert.GreaterOrEqual(t, time.Since(start), 350*time.Millisecond)
+}
+
+// TestBandwidthLimiter_Cancel tests a throttled read returns when the
+// context is cancelled instead of waiting out the limit
+func TestBandwidthLimiter_Cancel(t *testing.T) {
+	limiter := newBandwidthLimiter(1)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	body := &throttledBody{ctx: ctx, body: io.NopCloser(bytes.NewReader(make([]byte, 1024))), limiter: limiter}
+	start := time.Now()
+	_, err := io.Copy(io.Discard, body)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+// TestS3Client_bandwidthOptions tests the zero value disables throttling and
+// a per-call limit overrides the client limit
+func TestS3Client_bandwidthOptions(t *testing.T) {
+	cfg := testConfig
+	client, err := NewS3Client(cfg)
+	require.NoError(t, err)
+	assert.Nil(t, client.uploadLimiter)
+	assert.Empty(t, client.bandwidthOptions(nil))
+	assert.Len(t, client.bandwidthOptions(&UploadOptions{BandwidthLimit: 1024}), 1)
+
+	cfg.UploadBandwidthLimit = 1024
+	client, err = NewS3Client(cfg)
+	require.NoError(t, err)
+	require.NotNil(t, client.uploadLimiter)
+	assert.Len(t, client.bandwidthOptions(nil), 1)
+
+	cfg.UploadBandwidthLimit = -1
+	_, err = NewS3Client(cfg)
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+
+	_, err = client.UploadFile(context.Background(), testBucket, testFileName, testFileContent, &UploadOptions{BandwidthLimit: -1})
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+}
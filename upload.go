@@ -0,0 +1,153 @@
+package s3lib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path/filepath"
+
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// UploadFromFile streams a local file to the specified bucket with options.
+// The file is read directly from disk rather than loaded into memory first.
+// If opts does not set a ContentType it is derived from the file extension,
+// unless UploadOptions.DisableContentTypeDetection is set.
+func (c *S3Client) UploadFromFile(ctx context.Context, bucket, key, localPath string, opts *UploadOptions) (string, error) {
+	result, err := c.uploadFile(ctx, bucket, key, localPath, opts)
+	if err != nil {
+		return "", err
+	}
+	return result.Location, nil
+}
+
+// uploadFile is UploadFromFile returning the full result
+func (c *S3Client) uploadFile(ctx context.Context, bucket, key, localPath string, opts *UploadOptions) (*UploadResult, error) {
+	if bucket == "" {
+		return nil, ErrInvalidBucket
+	}
+	if key == "" {
+		return nil, ErrInvalidKey
+	}
+
+	f, err := os.Open(localPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("local file %s: %w", localPath, ErrFileNotFound)
+		}
+		return nil, fmt.Errorf("failed to open local file: %w", err)
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat local file: %w", err)
+	}
+	if stat.IsDir() {
+		return nil, fmt.Errorf("local path %s is a directory", localPath)
+	}
+
+	uploadOpts := UploadOptions{}
+	if opts != nil {
+		uploadOpts = *opts
+	}
+	if uploadOpts.ContentType == "" && !uploadOpts.DisableContentTypeDetection {
+		uploadOpts.ContentType = mime.TypeByExtension(filepath.Ext(localPath))
+	}
+
+	return c.uploadSeeker(ctx, bucket, key, f, stat.Size(), uploadOpts)
+}
+
+// uploadSeeker uploads size bytes from r, rewinding it for every retry
+// attempt. It implements UploadOptions.SkipIfUnchanged for sources that are
+// not held in memory.
+func (c *S3Client) uploadSeeker(ctx context.Context, bucket, key string, r io.ReadSeeker, size int64, opts UploadOptions) (*UploadResult, error) {
+	if err := c.checkClientSideKey(); err != nil {
+		return nil, err
+	}
+	if opts.SkipIfUnchanged {
+		resolved, err := c.resolveKey(key)
+		if err != nil {
+			return nil, err
+		}
+		skipped, recorded, err := c.skipUnchanged(ctx, bucket, resolved, r, size, opts.Compress, &opts)
+		if err != nil || skipped != nil {
+			return skipped, err
+		}
+		opts = *recorded
+	}
+
+	return withRetry(ctx, c.config.MaxRetries, func() (*UploadResult, error) {
+		if _, err := r.Seek(0, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("failed to rewind upload body: %w", err)
+		}
+		return c.uploadReader(ctx, bucket, key, r, &opts, partSizeFor(size))
+	})
+}
+
+// partSizeFor returns an uploader option that grows the part size when an
+// object of the given size would otherwise exceed the multipart part limit
+func partSizeFor(size int64) func(*s3manager.Uploader) {
+	return func(u *s3manager.Uploader) {
+		partSize := u.PartSize
+		if partSize < s3manager.MinUploadPartSize {
+			partSize = s3manager.MinUploadPartSize
+		}
+		if size/partSize >= int64(s3manager.MaxUploadParts) {
+			u.PartSize = size/int64(s3manager.MaxUploadParts) + 1
+		}
+	}
+}
+
+// sizeLimitReader fails reads once more than limit bytes have been read
+type sizeLimitReader struct {
+	r        io.Reader
+	limit    int64
+	read     int64
+	exceeded bool
+}
+
+func (l *sizeLimitReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.read += int64(n)
+	if l.read > l.limit {
+		l.exceeded = true
+		return 0, tooLargeError(l.limit)
+	}
+	return n, err
+}
+
+// limitUploadSize enforces Config.MaxUploadSize on r. Seekable readers are
+// measured up front; other streams fail as soon as the limit is crossed, and
+// the returned sizeLimitReader records whether that happened.
+func limitUploadSize(r io.Reader, limit int64) (io.Reader, *sizeLimitReader, error) {
+	if rs, ok := r.(io.ReadSeeker); ok {
+		start, err := rs.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read upload body: %w", err)
+		}
+		end, err := rs.Seek(0, io.SeekEnd)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read upload body: %w", err)
+		}
+		if _, err := rs.Seek(start, io.SeekStart); err != nil {
+			return nil, nil, fmt.Errorf("failed to read upload body: %w", err)
+		}
+		if end-start > limit {
+			return nil, nil, tooLargeError(limit)
+		}
+		return r, nil, nil
+	}
+
+	l := &sizeLimitReader{r: r, limit: limit}
+	return l, l, nil
+}
+
+// tooLargeError reports an object exceeding limit bytes
+func tooLargeError(limit int64) error {
+	return fmt.Errorf("%w: limit is %d bytes", ErrObjectTooLarge, limit)
+}
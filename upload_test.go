@@ -0,0 +1,175 @@
+package s3lib
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestS3Client_UploadFromFile tests the UploadFromFile function
+func TestS3Client_UploadFromFile(t *testing.T) {
+	objects := map[string]*memoryObject{}
+	client := setupFakeClient(t, memoryServer(objects))
+
+	dir := t.TempDir()
+	localFile := filepath.Join(dir, "report.json")
+	require.NoError(t, os.WriteFile(localFile, []byte(`{"ok":true}`), 0o644))
+
+	tests := []struct {
+		name        string
+		bucket      string
+		key         string
+		path        string
+		wantErr     bool
+		wantMissing bool
+	}{
+		{
+			name:    "Valid file",
+			bucket:  testBucket,
+			key:     "test-from-file.json",
+			path:    localFile,
+			wantErr: false,
+		},
+		{
+			name:        "Missing local file",
+			bucket:      testBucket,
+			key:         "test-from-file.json",
+			path:        filepath.Join(dir, "missing.txt"),
+			wantErr:     true,
+			wantMissing: true,
+		},
+		{
+			name:    "Directory path",
+			bucket:  testBucket,
+			key:     "test-from-file.json",
+			path:    dir,
+			wantErr: true,
+		},
+		{
+			name:    "Empty bucket",
+			bucket:  "",
+			key:     "test-from-file.json",
+			path:    localFile,
+			wantErr: true,
+		},
+		{
+			name:    "Empty key",
+			bucket:  testBucket,
+			key:     "",
+			path:    localFile,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			location, err := client.UploadFromFile(ctx, tt.bucket, tt.key, tt.path, nil)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Empty(t, location)
+				if tt.wantMissing {
+					assert.ErrorIs(t, err, ErrFileNotFound)
+				}
+			} else {
+				assert.NoError(t, err)
+				assert.NotEmpty(t, location)
+				require.Contains(t, objects, tt.key)
+				assert.Equal(t, `{"ok":true}`, string(objects[tt.key].data))
+				assert.Equal(t, "application/json", objects[tt.key].header.Get("Content-Type"))
+			}
+		})
+	}
+}
+
+// TestPartSizeFor tests that the part size grows for very large files
+func TestPartSizeFor(t *testing.T) {
+	tests := []struct {
+		name string
+		size int64
+		want int64
+	}{
+		{
+			name: "Small file keeps default",
+			size: 10 * 1024 * 1024,
+			want: s3manager.DefaultUploadPartSize,
+		},
+		{
+			name: "Huge file grows part size",
+			size: 100 * 1024 * 1024 * 1024,
+			want: 100*1024*1024*1024/s3manager.MaxUploadParts + 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := &s3manager.Uploader{PartSize: s3manager.DefaultUploadPartSize}
+			partSizeFor(tt.size)(u)
+			assert.Equal(t, tt.want, u.PartSize)
+		})
+	}
+}
+
+// TestLimitUploadSize tests the size guard for seekable and streamed bodies
+func TestLimitUploadSize(t *testing.T) {
+	t.Run("Seekable within limit", func(t *testing.T) {
+		r := bytes.NewReader(make([]byte, 10))
+		got, limited, err := limitUploadSize(r, 10)
+		require.NoError(t, err)
+		assert.Same(t, r, got)
+		assert.Nil(t, limited)
+	})
+
+	t.Run("Seekable over limit", func(t *testing.T) {
+		_, _, err := limitUploadSize(bytes.NewReader(make([]byte, 11)), 10)
+		assert.ErrorIs(t, err, ErrObjectTooLarge)
+	})
+
+	t.Run("Stream within limit", func(t *testing.T) {
+		got, limited, err := limitUploadSize(io.NopCloser(bytes.NewReader(make([]byte, 10))), 10)
+		require.NoError(t, err)
+		data, err := io.ReadAll(got)
+		require.NoError(t, err)
+		assert.Len(t, data, 10)
+		assert.False(t, limited.exceeded)
+	})
+
+	t.Run("Stream over limit", func(t *testing.T) {
+		got, limited, err := limitUploadSize(io.LimitReader(&patternReader{}, 1024), 1000)
+		require.NoError(t, err)
+		_, err = io.ReadAll(got)
+		assert.ErrorIs(t, err, ErrObjectTooLarge)
+		assert.True(t, limited.exceeded)
+	})
+}
+
+// TestS3Client_MaxUploadSize tests known-size uploads fail before any request
+func TestS3Client_MaxUploadSize(t *testing.T) {
+	var requests int
+	cfg := testConfig
+	cfg.Endpoint = "http://s3.fake.local"
+	cfg.MaxUploadSize = 4
+	cfg.HTTPClient = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requests++
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: req}, nil
+	})}
+	client, err := NewS3Client(cfg)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	_, err = client.UploadFile(ctx, testBucket, testFileName, testFileContent, nil)
+	assert.ErrorIs(t, err, ErrObjectTooLarge)
+	_, err = client.UploadStream(ctx, testBucket, testFileName, bytes.NewReader(testFileContent), nil)
+	assert.ErrorIs(t, err, ErrObjectTooLarge)
+	_, err = client.UploadStream(ctx, testBucket, testFileName, io.NopCloser(bytes.NewReader(testFileContent)), nil)
+	assert.ErrorIs(t, err, ErrObjectTooLarge)
+	assert.Zero(t, requests)
+}
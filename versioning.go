@@ -0,0 +1,105 @@
+package s3lib
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// VersioningStatus is the versioning state of a bucket
+type VersioningStatus string
+
+// Versioning statuses
+const (
+	// VersioningUnversioned is a bucket that never had versioning enabled
+	VersioningUnversioned VersioningStatus = "Unversioned"
+	// VersioningEnabled keeps every version of every object
+	VersioningEnabled VersioningStatus = "Enabled"
+	// VersioningSuspended stops creating new versions but keeps the
+	// existing ones. A bucket cannot go back to being unversioned.
+	VersioningSuspended VersioningStatus = "Suspended"
+)
+
+// BucketVersioning is the versioning configuration of a bucket
+type BucketVersioning struct {
+	Status VersioningStatus `json:"status"`
+	// MFADelete reports that deleting versions or changing the versioning
+	// state requires multi-factor authentication
+	MFADelete bool `json:"mfa_delete"`
+}
+
+// SetBucketVersioning enables or suspends versioning on bucket. Suspending
+// keeps the versions the bucket already has. Buckets with MFA delete
+// enabled cannot be changed by this library.
+func (c *S3Client) SetBucketVersioning(ctx context.Context, bucket string, enabled bool) error {
+	if bucket == "" {
+		return ErrInvalidBucket
+	}
+	status := s3.BucketVersioningStatusSuspended
+	if enabled {
+		status = s3.BucketVersioningStatusEnabled
+	}
+
+	_, err := c.s3Client.PutBucketVersioningWithContext(ctx, &s3.PutBucketVersioningInput{
+		Bucket: aws.String(bucket),
+		VersioningConfiguration: &s3.VersioningConfiguration{
+			Status: aws.String(status),
+		},
+	})
+	if err != nil {
+		return versioningError(err)
+	}
+	return nil
+}
+
+// GetBucketVersioning returns the versioning status of bucket
+func (c *S3Client) GetBucketVersioning(ctx context.Context, bucket string) (VersioningStatus, error) {
+	config, err := c.GetBucketVersioningConfig(ctx, bucket)
+	if err != nil {
+		return "", err
+	}
+	return config.Status, nil
+}
+
+// GetBucketVersioningConfig returns the versioning status of bucket along
+// with whether MFA delete is enabled on it
+func (c *S3Client) GetBucketVersioningConfig(ctx context.Context, bucket string) (*BucketVersioning, error) {
+	if bucket == "" {
+		return nil, ErrInvalidBucket
+	}
+
+	out, err := c.s3Client.GetBucketVersioningWithContext(ctx, &s3.GetBucketVersioningInput{
+		Bucket: aws.String(bucket),
+	})
+	if err != nil {
+		return nil, versioningError(err)
+	}
+	// S3 omits the status of a bucket that was never versioned
+	config := &BucketVersioning{
+		Status:    VersioningUnversioned,
+		MFADelete: aws.StringValue(out.MFADelete) == s3.MFADeleteStatusEnabled,
+	}
+	if status := aws.StringValue(out.Status); status != "" {
+		config.Status = VersioningStatus(status)
+	}
+	return config, nil
+}
+
+// versioningError maps a failed versioning request to the library's error
+// values
+func versioningError(err error) error {
+	if aerr, ok := err.(awserr.Error); ok {
+		switch aerr.Code() {
+		case s3.ErrCodeNoSuchBucket:
+			return ErrInvalidBucket
+		case "AccessDenied":
+			return fmt.Errorf("%w: %w", ErrAccessDenied, aerr)
+		default:
+			return fmt.Errorf("AWS error: %w", aerr)
+		}
+	}
+	return fmt.Errorf("failed to access bucket versioning: %w", err)
+}
@@ -0,0 +1,63 @@
+package s3lib
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestS3Client_SetBucketVersioning tests enabling and suspending send the
+// matching status
+func TestS3Client_SetBucketVersioning(t *testing.T) {
+	var body string
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		data, _ := io.ReadAll(req.Body)
+		body = string(data)
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: req}, nil
+	})
+	ctx := context.Background()
+
+	require.NoError(t, client.SetBucketVersioning(ctx, testBucket, true))
+	assert.Contains(t, body, "<Status>Enabled</Status>")
+	require.NoError(t, client.SetBucketVersioning(ctx, testBucket, false))
+	assert.Contains(t, body, "<Status>Suspended</Status>")
+
+	assert.ErrorIs(t, client.SetBucketVersioning(ctx, "", true), ErrInvalidBucket)
+}
+
+// TestS3Client_GetBucketVersioning tests each status is reported, a bucket
+// that was never versioned as Unversioned, along with MFA delete
+func TestS3Client_GetBucketVersioning(t *testing.T) {
+	bodies := map[string]string{
+		"/plain":     `<VersioningConfiguration></VersioningConfiguration>`,
+		"/versioned": `<VersioningConfiguration><Status>Enabled</Status><MfaDelete>Enabled</MfaDelete></VersioningConfiguration>`,
+		"/paused":    `<VersioningConfiguration><Status>Suspended</Status></VersioningConfiguration>`,
+	}
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		body, ok := bodies[req.URL.Path]
+		if !ok {
+			return fakeErrorResponse(req, http.StatusForbidden, "AccessDenied"), nil
+		}
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: xmlBody(body), Request: req}, nil
+	})
+	ctx := context.Background()
+
+	status, err := client.GetBucketVersioning(ctx, "plain")
+	require.NoError(t, err)
+	assert.Equal(t, VersioningUnversioned, status)
+
+	status, err = client.GetBucketVersioning(ctx, "paused")
+	require.NoError(t, err)
+	assert.Equal(t, VersioningSuspended, status)
+
+	config, err := client.GetBucketVersioningConfig(ctx, "versioned")
+	require.NoError(t, err)
+	assert.Equal(t, &BucketVersioning{Status: VersioningEnabled, MFADelete: true}, config)
+
+	_, err = client.GetBucketVersioning(ctx, "denied")
+	assert.ErrorIs(t, err, ErrAccessDenied)
+}
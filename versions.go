@@ -0,0 +1,304 @@
+package s3lib
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// DeleteMarker is a delete marker left in a versioned bucket when an object
+// was deleted without a version ID
+type DeleteMarker struct {
+	Key          string    `json:"key"`
+	VersionID    string    `json:"version_id"`
+	LastModified time.Time `json:"last_modified"`
+
+	// IsLatest is set when the marker is the current version of its key,
+	// hiding the object from reads and listings
+	IsLatest bool `json:"is_latest"`
+}
+
+// ListDeleteMarkers lists the delete markers under prefix in a versioned
+// bucket, paging through every version of the keys. Removing a marker with
+// DeleteFileVersion restores the version below it, if any.
+func (c *S3Client) ListDeleteMarkers(ctx context.Context, bucket, prefix string) ([]DeleteMarker, error) {
+	if bucket == "" {
+		return nil, ErrInvalidBucket
+	}
+
+	input := &s3.ListObjectVersionsInput{
+		Bucket: aws.String(bucket),
+	}
+	if prefix = c.config.KeyPrefix + prefix; prefix != "" {
+		input.Prefix = aws.String(prefix)
+	}
+
+	markers := []DeleteMarker{}
+	err := c.s3Client.ListObjectVersionsPagesWithContext(ctx, input,
+		func(page *s3.ListObjectVersionsOutput, lastPage bool) bool {
+			for _, m := range page.DeleteMarkers {
+				markers = append(markers, DeleteMarker{
+					Key:          c.relativeKey(aws.StringValue(m.Key)),
+					VersionID:    aws.StringValue(m.VersionId),
+					LastModified: aws.TimeValue(m.LastModified),
+					IsLatest:     aws.BoolValue(m.IsLatest),
+				})
+			}
+			return true
+		})
+	if err != nil {
+		return nil, listError(err)
+	}
+
+	return markers, nil
+}
+
+// FileVersion is one version of an object in a versioned bucket
+type FileVersion struct {
+	Key          string    `json:"key"`
+	VersionID    string    `json:"version_id"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"last_modified"`
+	ETag         string    `json:"etag"`
+	StorageClass string    `json:"storage_class"`
+	IsLatest     bool      `json:"is_latest"`
+}
+
+// keyVersions holds the versions and delete markers of one key, with the
+// key as S3 stores it
+type keyVersions struct {
+	key      string
+	versions []FileVersion
+	markers  []DeleteMarker
+}
+
+// UndeleteFile restores a key deleted from a versioned bucket by removing
+// the delete markers above its newest version, and returns the version that
+// is current again. It returns ErrNotDeleted if the key's current version is
+// not a delete marker, and ErrFileNotFound if there is no version to
+// restore.
+func (c *S3Client) UndeleteFile(ctx context.Context, bucket, key string) (*FileVersion, error) {
+	if bucket == "" {
+		return nil, ErrInvalidBucket
+	}
+	key, err := c.resolveKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var found *keyVersions
+	err = c.listKeyVersions(ctx, bucket, key, func(kv *keyVersions) bool {
+		if kv.key == key {
+			found = kv
+		}
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+	if found == nil {
+		return nil, ErrFileNotFound
+	}
+	restored, markers, err := found.undelete()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, m := range markers {
+		if err := c.DeleteFileVersion(ctx, bucket, m.Key, m.VersionID); err != nil && !errors.Is(err, ErrFileNotFound) {
+			return nil, err
+		}
+	}
+	return restored, nil
+}
+
+// UndeletePrefix restores every deleted key under prefix like UndeleteFile
+// and returns the versions that became current. Keys that are not deleted,
+// or have no version to restore, are skipped. Delete markers S3 fails to
+// remove are returned as DeleteErrors, and their keys are left out of the
+// restored versions.
+func (c *S3Client) UndeletePrefix(ctx context.Context, bucket, prefix string) ([]FileVersion, []DeleteError, error) {
+	if bucket == "" {
+		return nil, nil, ErrInvalidBucket
+	}
+
+	restored := []FileVersion{}
+	failed := []DeleteError{}
+	var batch []*s3.ObjectIdentifier
+	pending := make(map[string]FileVersion)
+	// flush removes the markers collected so far, then reports the keys
+	// whose markers were all removed as restored
+	flush := func() error {
+		for start := 0; start < len(batch); start += maxDeleteKeys {
+			errs, err := c.deleteBatch(ctx, bucket, batch[start:min(start+maxDeleteKeys, len(batch))])
+			if err != nil {
+				return err
+			}
+			failed = append(failed, errs...)
+			for _, e := range errs {
+				delete(pending, e.Key)
+			}
+		}
+		for _, obj := range batch {
+			if v, ok := pending[c.relativeKey(aws.StringValue(obj.Key))]; ok {
+				restored = append(restored, v)
+				delete(pending, v.Key)
+			}
+		}
+		batch = nil
+		return nil
+	}
+
+	var flushErr error
+	err := c.listKeyVersions(ctx, bucket, c.config.KeyPrefix+prefix, func(kv *keyVersions) bool {
+		version, markers, err := kv.undelete()
+		if err != nil {
+			return true
+		}
+		pending[version.Key] = *version
+		for _, m := range markers {
+			batch = append(batch, &s3.ObjectIdentifier{Key: aws.String(kv.key), VersionId: aws.String(m.VersionID)})
+		}
+		if len(batch) >= maxDeleteKeys {
+			if flushErr = flush(); flushErr != nil {
+				return false
+			}
+		}
+		return true
+	})
+	if flushErr != nil {
+		return restored, failed, flushErr
+	}
+	if err != nil {
+		return restored, failed, err
+	}
+	if len(batch) > 0 {
+		if err := flush(); err != nil {
+			return restored, failed, err
+		}
+	}
+	return restored, failed, nil
+}
+
+// listKeyVersions lists the versions under prefix and calls fn with those
+// of each key in turn, in key order, until fn returns false
+func (c *S3Client) listKeyVersions(ctx context.Context, bucket, prefix string, fn func(*keyVersions) bool) error {
+	input := &s3.ListObjectVersionsInput{
+		Bucket: aws.String(bucket),
+	}
+	if prefix != "" {
+		input.Prefix = aws.String(prefix)
+	}
+
+	// A key's versions may continue on the next page, so the last key of a
+	// page is held back until a later key or the end of the listing
+	var current *keyVersions
+	stopped := false
+	get := func(key string) *keyVersions {
+		if current != nil && current.key == key {
+			return current
+		}
+		if current != nil && !stopped {
+			stopped = !fn(current)
+		}
+		current = &keyVersions{key: key}
+		return current
+	}
+
+	err := c.s3Client.ListObjectVersionsPagesWithContext(ctx, input,
+		func(page *s3.ListObjectVersionsOutput, lastPage bool) bool {
+			for _, e := range mergeVersions(page) {
+				kv := get(e.key)
+				if stopped {
+					return false
+				}
+				if e.version != nil {
+					kv.versions = append(kv.versions, c.fileVersion(e.version))
+				} else {
+					kv.markers = append(kv.markers, DeleteMarker{
+						Key:          c.relativeKey(aws.StringValue(e.marker.Key)),
+						VersionID:    aws.StringValue(e.marker.VersionId),
+						LastModified: aws.TimeValue(e.marker.LastModified),
+						IsLatest:     aws.BoolValue(e.marker.IsLatest),
+					})
+				}
+			}
+			return true
+		})
+	if err != nil {
+		return listError(err)
+	}
+	if current != nil && !stopped {
+		fn(current)
+	}
+	return nil
+}
+
+// versionEntry is a version or a delete marker of a listing page
+type versionEntry struct {
+	key     string
+	version *s3.ObjectVersion
+	marker  *s3.DeleteMarkerEntry
+}
+
+// mergeVersions returns the versions and delete markers of a page, which
+// the SDK splits into two lists, merged back into key order
+func mergeVersions(page *s3.ListObjectVersionsOutput) []versionEntry {
+	entries := make([]versionEntry, 0, len(page.Versions)+len(page.DeleteMarkers))
+	v, m := page.Versions, page.DeleteMarkers
+	for len(v) > 0 || len(m) > 0 {
+		if len(m) == 0 || (len(v) > 0 && aws.StringValue(v[0].Key) <= aws.StringValue(m[0].Key)) {
+			entries = append(entries, versionEntry{key: aws.StringValue(v[0].Key), version: v[0]})
+			v = v[1:]
+		} else {
+			entries = append(entries, versionEntry{key: aws.StringValue(m[0].Key), marker: m[0]})
+			m = m[1:]
+		}
+	}
+	return entries
+}
+
+// fileVersion converts a listed version
+func (c *S3Client) fileVersion(v *s3.ObjectVersion) FileVersion {
+	return FileVersion{
+		Key:          c.relativeKey(aws.StringValue(v.Key)),
+		VersionID:    aws.StringValue(v.VersionId),
+		Size:         aws.Int64Value(v.Size),
+		LastModified: aws.TimeValue(v.LastModified),
+		ETag:         aws.StringValue(v.ETag),
+		StorageClass: aws.StringValue(v.StorageClass),
+		IsLatest:     aws.BoolValue(v.IsLatest),
+	}
+}
+
+// undelete returns the newest version of a deleted key and the delete
+// markers above it that must be removed to make it current again
+func (kv *keyVersions) undelete() (*FileVersion, []DeleteMarker, error) {
+	for _, v := range kv.versions {
+		if v.IsLatest {
+			return nil, nil, ErrNotDeleted
+		}
+	}
+	var newest *FileVersion
+	for i := range kv.versions {
+		if newest == nil || kv.versions[i].LastModified.After(newest.LastModified) {
+			newest = &kv.versions[i]
+		}
+	}
+	if newest == nil {
+		return nil, nil, ErrFileNotFound
+	}
+
+	var markers []DeleteMarker
+	for _, m := range kv.markers {
+		if !m.LastModified.Before(newest.LastModified) {
+			markers = append(markers, m)
+		}
+	}
+	restored := *newest
+	restored.IsLatest = true
+	return &restored, markers, nil
+}
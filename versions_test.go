@@ -0,0 +1,116 @@
+package s3lib
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestS3Client_ListDeleteMarkers tests markers are collected across pages,
+// resuming from both the key and version ID markers
+func TestS3Client_ListDeleteMarkers(t *testing.T) {
+	var markers [][2]string
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		query := req.URL.Query()
+		markers = append(markers, [2]string{query.Get("key-marker"), query.Get("version-id-marker")})
+		body := "<ListVersionsResult><IsTruncated>true</IsTruncated>" +
+			"<NextKeyMarker>tenant/a.txt</NextKeyMarker><NextVersionIdMarker>v2</NextVersionIdMarker>" +
+			"<Version><Key>tenant/a.txt</Key><VersionId>v1</VersionId></Version>" +
+			"<DeleteMarker><Key>tenant/a.txt</Key><VersionId>v2</VersionId><IsLatest>true</IsLatest>" +
+			"<LastModified>2024-01-02T03:04:05.000Z</LastModified></DeleteMarker>" +
+			"</ListVersionsResult>"
+		if query.Get("key-marker") != "" {
+			body = "<ListVersionsResult><DeleteMarker><Key>tenant/b.txt</Key><VersionId>v9</VersionId></DeleteMarker></ListVersionsResult>"
+		}
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: xmlBody(body), Request: req}, nil
+	}).WithPrefix("tenant/")
+
+	got, err := client.ListDeleteMarkers(context.Background(), testBucket, "")
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	assert.Equal(t, DeleteMarker{
+		Key:          "a.txt",
+		VersionID:    "v2",
+		LastModified: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		IsLatest:     true,
+	}, got[0])
+	assert.Equal(t, "b.txt", got[1].Key)
+	assert.Equal(t, [][2]string{{"", ""}, {"tenant/a.txt", "v2"}}, markers)
+}
+
+// TestS3Client_ListDeleteMarkersValidation tests an invalid bucket is
+// rejected
+func TestS3Client_ListDeleteMarkersValidation(t *testing.T) {
+	client := setupTestClient(t)
+	_, err := client.ListDeleteMarkers(context.Background(), "", "")
+	assert.ErrorIs(t, err, ErrInvalidBucket)
+}
+
+// TestS3Client_UndeleteFile tests the delete markers above the newest
+// version are removed and that version is returned
+func TestS3Client_UndeleteFile(t *testing.T) {
+	entries := []struct{ key, xml string }{
+		{"a.txt", "<Version><Key>a.txt</Key><VersionId>v2</VersionId><Size>5</Size><LastModified>2024-01-02T00:00:00.000Z</LastModified></Version>"},
+		{"a.txt", "<Version><Key>a.txt</Key><VersionId>v1</VersionId><LastModified>2024-01-01T00:00:00.000Z</LastModified></Version>"},
+		{"b.txt", "<Version><Key>b.txt</Key><VersionId>v5</VersionId><IsLatest>true</IsLatest></Version>"},
+		{"a.txt", "<DeleteMarker><Key>a.txt</Key><VersionId>m2</VersionId><IsLatest>true</IsLatest><LastModified>2024-01-04T00:00:00.000Z</LastModified></DeleteMarker>"},
+		{"a.txt", "<DeleteMarker><Key>a.txt</Key><VersionId>m1</VersionId><LastModified>2024-01-03T00:00:00.000Z</LastModified></DeleteMarker>"},
+	}
+	var deleted []string
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		if req.Method == http.MethodDelete {
+			deleted = append(deleted, req.URL.Query().Get("versionId"))
+			return &http.Response{StatusCode: http.StatusNoContent, Header: http.Header{}, Body: xmlBody(""), Request: req}, nil
+		}
+		// Like S3, only list the versions of keys under the requested prefix
+		prefix := req.URL.Query().Get("prefix")
+		listing := "<ListVersionsResult>"
+		for _, e := range entries {
+			if strings.HasPrefix(e.key, prefix) {
+				listing += e.xml
+			}
+		}
+		listing += "</ListVersionsResult>"
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: xmlBody(listing), Request: req}, nil
+	})
+	ctx := context.Background()
+
+	version, err := client.UndeleteFile(ctx, testBucket, "a.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "v2", version.VersionID)
+	assert.Equal(t, int64(5), version.Size)
+	assert.True(t, version.IsLatest)
+	assert.ElementsMatch(t, []string{"m1", "m2"}, deleted)
+
+	_, err = client.UndeleteFile(ctx, testBucket, "b.txt")
+	assert.ErrorIs(t, err, ErrNotDeleted)
+}
+
+// TestKeyVersionsUndelete tests which markers are removed to restore a key
+func TestKeyVersionsUndelete(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+
+	kv := &keyVersions{
+		versions: []FileVersion{{VersionID: "v1", LastModified: day(1)}, {VersionID: "v3", LastModified: day(3)}},
+		markers:  []DeleteMarker{{VersionID: "m2", LastModified: day(2)}, {VersionID: "m4", LastModified: day(4), IsLatest: true}},
+	}
+	version, markers, err := kv.undelete()
+	require.NoError(t, err)
+	assert.Equal(t, "v3", version.VersionID)
+	assert.True(t, version.IsLatest)
+	require.Len(t, markers, 1)
+	assert.Equal(t, "m4", markers[0].VersionID)
+
+	kv = &keyVersions{markers: []DeleteMarker{{VersionID: "m1", IsLatest: true}}}
+	_, _, err = kv.undelete()
+	assert.ErrorIs(t, err, ErrFileNotFound)
+
+	kv = &keyVersions{versions: []FileVersion{{VersionID: "v1", IsLatest: true}}}
+	_, _, err = kv.undelete()
+	assert.ErrorIs(t, err, ErrNotDeleted)
+}
@@ -0,0 +1,125 @@
+package s3lib
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// defaultWaitInterval is the time between checks when WaitOptions.Interval
+// is not set
+const defaultWaitInterval = 5 * time.Second
+
+// WaitOptions represents optional parameters for the WaitUntil functions
+type WaitOptions struct {
+	// Interval is the time between checks (default 5s)
+	Interval time.Duration
+	// Jitter varies each interval randomly by up to this fraction of it,
+	// between 0 and 1, so many waiters do not poll in step
+	Jitter float64
+}
+
+// validate checks the options are in range
+func (o *WaitOptions) validate() error {
+	if o == nil {
+		return nil
+	}
+	if o.Interval < 0 {
+		return fmt.Errorf("%w: Interval must not be negative", ErrInvalidOptions)
+	}
+	if o.Jitter < 0 || o.Jitter > 1 {
+		return fmt.Errorf("%w: Jitter must be between 0 and 1", ErrInvalidOptions)
+	}
+	return nil
+}
+
+// delay returns the jittered time before the next check
+func (o *WaitOptions) delay() time.Duration {
+	interval, jitter := defaultWaitInterval, 0.0
+	if o != nil {
+		if o.Interval > 0 {
+			interval = o.Interval
+		}
+		jitter = o.Jitter
+	}
+	if jitter == 0 {
+		return interval
+	}
+	spread := time.Duration(float64(interval) * jitter)
+	return interval - spread + time.Duration(rand.Int63n(int64(2*spread)+1))
+}
+
+// WaitUntilObjectExists polls HeadObject every interval until an object
+// exists at key. It returns ErrWaitTimeout when ctx ends first, and any
+// failure other than the object being missing or a transient error as
+// soon as it happens.
+func (c *S3Client) WaitUntilObjectExists(ctx context.Context, bucket, key string, interval time.Duration) error {
+	return c.WaitUntilObjectExistsWithOptions(ctx, bucket, key, &WaitOptions{Interval: interval})
+}
+
+// WaitUntilObjectExistsWithOptions waits like WaitUntilObjectExists, with
+// the interval and jitter of opts
+func (c *S3Client) WaitUntilObjectExistsWithOptions(ctx context.Context, bucket, key string, opts *WaitOptions) error {
+	return waitFor(ctx, opts, fmt.Sprintf("object %s to exist", key), func() (bool, error) {
+		return c.FileExists(ctx, bucket, key)
+	})
+}
+
+// WaitUntilObjectNotExists polls HeadObject every interval until no object
+// exists at key, such as after it was deleted. It fails like
+// WaitUntilObjectExists.
+func (c *S3Client) WaitUntilObjectNotExists(ctx context.Context, bucket, key string, interval time.Duration) error {
+	return c.WaitUntilObjectNotExistsWithOptions(ctx, bucket, key, &WaitOptions{Interval: interval})
+}
+
+// WaitUntilObjectNotExistsWithOptions waits like WaitUntilObjectNotExists,
+// with the interval and jitter of opts
+func (c *S3Client) WaitUntilObjectNotExistsWithOptions(ctx context.Context, bucket, key string, opts *WaitOptions) error {
+	return waitFor(ctx, opts, fmt.Sprintf("object %s to be deleted", key), func() (bool, error) {
+		exists, err := c.FileExists(ctx, bucket, key)
+		return !exists, err
+	})
+}
+
+// WaitUntilBucketExists polls HeadBucket every interval until bucket
+// exists. A bucket that exists but may not be accessed fails at once with
+// ErrAccessDenied, and one in another region with ErrWrongRegion.
+func (c *S3Client) WaitUntilBucketExists(ctx context.Context, bucket string, interval time.Duration) error {
+	return c.WaitUntilBucketExistsWithOptions(ctx, bucket, &WaitOptions{Interval: interval})
+}
+
+// WaitUntilBucketExistsWithOptions waits like WaitUntilBucketExists, with
+// the interval and jitter of opts
+func (c *S3Client) WaitUntilBucketExistsWithOptions(ctx context.Context, bucket string, opts *WaitOptions) error {
+	return waitFor(ctx, opts, fmt.Sprintf("bucket %s to exist", bucket), func() (bool, error) {
+		return c.BucketExists(ctx, bucket)
+	})
+}
+
+// waitFor calls check until it reports the condition is met, fails with an
+// error that is not transient, or ctx ends. Transient errors, like
+// throttling or 5xx responses, are polled through rather than returned.
+func waitFor(ctx context.Context, opts *WaitOptions, what string, check func() (bool, error)) error {
+	if err := opts.validate(); err != nil {
+		return err
+	}
+	start := time.Now()
+	for {
+		done, err := check()
+		switch {
+		case err == nil && done:
+			return nil
+		case err != nil && ctx.Err() == nil && !isRetryable(err):
+			return err
+		}
+
+		timer := time.NewTimer(opts.delay())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w for %s after %s: %w", ErrWaitTimeout, what, time.Since(start).Round(time.Millisecond), ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
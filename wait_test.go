@@ -0,0 +1,80 @@
+package s3lib
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestWaitOptionsDelay tests the delay stays within the jitter of the
+// interval
+func TestWaitOptionsDelay(t *testing.T) {
+	var opts *WaitOptions
+	assert.Equal(t, defaultWaitInterval, opts.delay())
+
+	opts = &WaitOptions{Interval: time.Second, Jitter: 0.2}
+	for i := 0; i < 100; i++ {
+		d := opts.delay()
+		assert.GreaterOrEqual(t, d, 800*time.Millisecond)
+		assert.LessOrEqual(t, d, 1200*time.Millisecond)
+	}
+
+	assert.ErrorIs(t, (&WaitOptions{Jitter: 1.5}).validate(), ErrInvalidOptions)
+	assert.ErrorIs(t, (&WaitOptions{Interval: -time.Second}).validate(), ErrInvalidOptions)
+}
+
+// TestS3Client_WaitUntilObjectExists tests polling continues through
+// missing objects and transient errors until the object appears
+func TestS3Client_WaitUntilObjectExists(t *testing.T) {
+	var heads atomic.Int32
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		switch heads.Add(1) {
+		case 1:
+			return fakeErrorResponse(req, http.StatusNotFound, "NotFound"), nil
+		case 2:
+			return fakeErrorResponse(req, http.StatusServiceUnavailable, "SlowDown"), nil
+		}
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: req}, nil
+	})
+
+	err := client.WaitUntilObjectExistsWithOptions(context.Background(), testBucket, testFileName, &WaitOptions{
+		Interval: time.Millisecond,
+		Jitter:   0.5,
+	})
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, heads.Load())
+}
+
+// TestS3Client_WaitUntilTimeout tests a condition never met ends with
+// ErrWaitTimeout and a real failure ends the wait at once
+func TestS3Client_WaitUntilTimeout(t *testing.T) {
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		switch req.URL.Path {
+		case "/" + testBucket + "/secret.txt":
+			return fakeErrorResponse(req, http.StatusForbidden, "Forbidden"), nil
+		case "/" + testBucket:
+			return fakeErrorResponse(req, http.StatusNotFound, "NotFound"), nil
+		}
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: req}, nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := client.WaitUntilObjectNotExists(ctx, testBucket, testFileName, time.Millisecond)
+	assert.ErrorIs(t, err, ErrWaitTimeout)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err = client.WaitUntilBucketExists(ctx, testBucket, time.Millisecond)
+	assert.ErrorIs(t, err, ErrWaitTimeout)
+
+	err = client.WaitUntilObjectExists(context.Background(), testBucket, "secret.txt", time.Millisecond)
+	assert.ErrorIs(t, err, ErrAccessDenied)
+	assert.NotErrorIs(t, err, ErrWaitTimeout)
+}
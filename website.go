@@ -0,0 +1,19 @@
+package s3lib
+
+import (
+	"fmt"
+	"strings"
+)
+
+// validateWebsiteRedirectLocation checks a redirect target is either a path
+// within the bucket or an absolute http(s) URL, the forms S3 accepts
+func validateWebsiteRedirectLocation(location string) error {
+	if location == "" {
+		return nil
+	}
+	lower := strings.ToLower(location)
+	if strings.HasPrefix(location, "/") || strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://") {
+		return nil
+	}
+	return fmt.Errorf("%w: website redirect location %q must start with \"/\", \"http://\" or \"https://\"", ErrInvalidOptions, location)
+}
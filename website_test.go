@@ -0,0 +1,53 @@
+package s3lib
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestValidateWebsiteRedirectLocation tests the accepted redirect forms
+func TestValidateWebsiteRedirectLocation(t *testing.T) {
+	tests := []struct {
+		name     string
+		location string
+		wantErr  bool
+	}{
+		{name: "Empty", location: ""},
+		{name: "Bucket path", location: "/docs/index.html"},
+		{name: "HTTP URL", location: "http://example.com/"},
+		{name: "HTTPS URL", location: "HTTPS://example.com/page"},
+		{name: "Relative path", location: "docs/index.html", wantErr: true},
+		{name: "Other scheme", location: "ftp://example.com/file", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateWebsiteRedirectLocation(tt.location)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidOptions)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+// TestS3Client_UploadFileWebsiteRedirect tests the redirect is stored and
+// reported by GetFileInfo
+func TestS3Client_UploadFileWebsiteRedirect(t *testing.T) {
+	objects := map[string]*memoryObject{}
+	client := setupFakeClient(t, memoryServer(objects))
+	ctx := context.Background()
+
+	_, err := client.UploadFile(ctx, testBucket, "old-page.html", nil, &UploadOptions{WebsiteRedirectLocation: "/new-page.html"})
+	require.NoError(t, err)
+	require.Contains(t, objects, "old-page.html")
+	assert.Empty(t, objects["old-page.html"].data)
+
+	info, err := client.GetFileInfo(ctx, testBucket, "old-page.html")
+	require.NoError(t, err)
+	assert.Equal(t, "/new-page.html", info.WebsiteRedirectLocation)
+}
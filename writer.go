@@ -0,0 +1,73 @@
+package s3lib
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// objectWriter is the io.WriteCloser returned by NewWriter. Writes feed a
+// pipe consumed by the multipart uploader running in its own goroutine.
+type objectWriter struct {
+	pw   *io.PipeWriter
+	done chan struct{}
+	err  error // upload result, set before done is closed
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// NewWriter returns a writer that uploads everything written to it as the
+// object key. Writes are buffered into multipart parts and the object is
+// created when Close returns nil. If Close returns an error the object was
+// not created and any uploaded parts have been aborted. Cancel ctx to abandon
+// the upload without creating the object.
+func (c *S3Client) NewWriter(ctx context.Context, bucket, key string, opts *UploadOptions) (io.WriteCloser, error) {
+	if err := c.checkClientSideKey(); err != nil {
+		return nil, err
+	}
+	if _, err := newUploadInput(bucket, key, opts); err != nil {
+		return nil, err
+	}
+
+	pr, pw := io.Pipe()
+	w := &objectWriter{
+		pw:   pw,
+		done: make(chan struct{}),
+	}
+	go func() {
+		defer close(w.done)
+		_, w.err = c.uploadReader(ctx, bucket, key, pr, opts)
+		// Unblock pending and future writes once the upload has finished
+		if w.err != nil {
+			pr.CloseWithError(w.err)
+		} else {
+			pr.Close()
+		}
+	}()
+	return w, nil
+}
+
+// Write implements io.Writer
+func (w *objectWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	closed := w.closed
+	w.mu.Unlock()
+	if closed {
+		return 0, io.ErrClosedPipe
+	}
+	return w.pw.Write(p)
+}
+
+// Close completes the upload and waits for the result
+func (w *objectWriter) Close() error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		w.pw.Close()
+	}
+	w.mu.Unlock()
+
+	<-w.done
+	return w.err
+}
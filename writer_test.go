@@ -0,0 +1,64 @@
+package s3lib
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestS3Client_NewWriter tests writing an object and writes after Close
+func TestS3Client_NewWriter(t *testing.T) {
+	var body []byte
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		if req.Body != nil {
+			body, _ = io.ReadAll(req.Body)
+		}
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: req}, nil
+	})
+
+	w, err := client.NewWriter(context.Background(), testBucket, testFileName, nil)
+	require.NoError(t, err)
+
+	_, err = w.Write(testFileContent[:5])
+	require.NoError(t, err)
+	_, err = w.Write(testFileContent[5:])
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	assert.Equal(t, testFileContent, body)
+
+	_, err = w.Write([]byte("late"))
+	assert.ErrorIs(t, err, io.ErrClosedPipe)
+}
+
+// TestS3Client_NewWriterCancel tests cancelling the context fails the upload
+func TestS3Client_NewWriterCancel(t *testing.T) {
+	client := setupFakeClient(t, func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: req}, nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w, err := client.NewWriter(ctx, testBucket, testFileName, nil)
+	require.NoError(t, err)
+
+	_, err = w.Write(testFileContent)
+	require.NoError(t, err)
+	cancel()
+	assert.Error(t, w.Close())
+}
+
+// TestS3Client_NewWriterValidation tests invalid arguments fail before writing
+func TestS3Client_NewWriterValidation(t *testing.T) {
+	client := setupTestClient(t)
+	ctx := context.Background()
+
+	_, err := client.NewWriter(ctx, "", testFileName, nil)
+	assert.ErrorIs(t, err, ErrInvalidBucket)
+	_, err = client.NewWriter(ctx, testBucket, "", nil)
+	assert.ErrorIs(t, err, ErrInvalidKey)
+	_, err = client.NewWriter(ctx, testBucket, testFileName, &UploadOptions{PartSize: 1})
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+}